@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+const accountsUsage = `usage: firstly-api accounts <command>
+
+commands:
+  role <username> <role>   set the role of an account, one of admin, member or read-only
+  renamed                  list the accounts renamed because their username collided with another's`
+
+// accountsCommand is the admin command for managing accounts outside of the api, such as making the
+// first account an admin.
+func accountsCommand(ctx context.Context, args []string, store db.Store, out io.Writer) error {
+	switch {
+	case len(args) == 3 && args[0] == "role":
+		role, err := security.ParseRole(args[2])
+		if err != nil {
+			return err
+		}
+		account, err := store.GetAccountByUsername(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("error finding account %q: %w", args[1], err)
+		}
+		if _, err := store.UpdateAccountRole(ctx, db.UpdateAccountRoleParams{Role: role, ID: account.ID}); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s is now %s, which applies to their tokens once they are refreshed.\n", account.Username, role)
+		return nil
+	case len(args) == 1 && args[0] == "renamed":
+		collisions, err := store.ListUsernameCollisions(ctx)
+		if err != nil {
+			return err
+		}
+		for _, collision := range collisions {
+			fmt.Fprintf(out, "%d\t%s\t%s\t%s\n", collision.AccountID, collision.PreviousUsername, collision.Username, collision.Created.Format(time.RFC3339))
+		}
+		return nil
+	default:
+		return errors.New(accountsUsage)
+	}
+}
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const accountExists = `-- name: AccountExists :one
@@ -26,7 +28,7 @@ INSERT INTO account (
 ) VALUES (
   $1, $2, $3, NOW()
 )
-RETURNING id, username, phrase, salt, created, updated, deleted
+RETURNING id, username, phrase, salt, created, updated, deleted, role, totp_secret, totp_enabled, email, email_verified, totp_last_step
 `
 
 type CreateAccountParams struct {
@@ -46,6 +48,12 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Created,
 		&i.Updated,
 		&i.Deleted,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.Email,
+		&i.EmailVerified,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
@@ -61,7 +69,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, username, phrase, salt, created, updated, deleted FROM account
+SELECT id, username, phrase, salt, created, updated, deleted, role, totp_secret, totp_enabled, email, email_verified, totp_last_step FROM account
 WHERE id = $1 LIMIT 1
 `
 
@@ -76,13 +84,45 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Created,
 		&i.Updated,
 		&i.Deleted,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.Email,
+		&i.EmailVerified,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const getAccountByEmail = `-- name: GetAccountByEmail :one
+SELECT id, username, phrase, salt, created, updated, deleted, role, totp_secret, totp_enabled, email, email_verified, totp_last_step FROM account
+WHERE LOWER(email) = LOWER($1) AND email <> '' LIMIT 1
+`
+
+func (q *Queries) GetAccountByEmail(ctx context.Context, email string) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByEmail, email)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Phrase,
+		&i.Salt,
+		&i.Created,
+		&i.Updated,
+		&i.Deleted,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.Email,
+		&i.EmailVerified,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getAccountByUsername = `-- name: GetAccountByUsername :one
-SELECT id, username, phrase, salt, created, updated, deleted FROM account
-WHERE username = $1 LIMIT 1
+SELECT id, username, phrase, salt, created, updated, deleted, role, totp_secret, totp_enabled, email, email_verified, totp_last_step FROM account
+WHERE LOWER(NORMALIZE(username, NFKC)) = LOWER(NORMALIZE($1, NFKC)) LIMIT 1
 `
 
 func (q *Queries) GetAccountByUsername(ctx context.Context, username string) (Account, error) {
@@ -96,12 +136,58 @@ func (q *Queries) GetAccountByUsername(ctx context.Context, username string) (Ac
 		&i.Created,
 		&i.Updated,
 		&i.Deleted,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.Email,
+		&i.EmailVerified,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
+const getAccountUsernameForUpdate = `-- name: GetAccountUsernameForUpdate :one
+SELECT username FROM account
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetAccountUsernameForUpdate(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getAccountUsernameForUpdate, id)
+	var username string
+	err := row.Scan(&username)
+	return username, err
+}
+
+const listAccountPhrases = `-- name: ListAccountPhrases :many
+SELECT phrase FROM account
+`
+
+func (q *Queries) ListAccountPhrases(ctx context.Context) ([][]byte, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountPhrases)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := [][]byte{}
+	for rows.Next() {
+		var phrase []byte
+		if err := rows.Scan(&phrase); err != nil {
+			return nil, err
+		}
+		items = append(items, phrase)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, username, created, deleted FROM account LIMIT $1 OFFSET $2
+SELECT id, username, role, created, deleted FROM account LIMIT $1 OFFSET $2
 `
 
 type ListAccountsParams struct {
@@ -112,6 +198,7 @@ type ListAccountsParams struct {
 type ListAccountsRow struct {
 	ID       int64  `json:"id"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	Created  string `json:"created"`
 	Deleted  bool   `json:"deleted"`
 }
@@ -128,6 +215,109 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]L
 		if err := rows.Scan(
 			&i.ID,
 			&i.Username,
+			&i.Role,
+			&i.Created,
+			&i.Deleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsPageAsc = `-- name: ListAccountsPageAsc :many
+SELECT id, username, role, created, deleted FROM account
+WHERE $1::varchar IS NULL
+   OR (created::timestamptz, id) > (CAST($1::varchar AS timestamptz), $2::bigint)
+ORDER BY created::timestamptz ASC, id ASC
+LIMIT $3
+`
+
+type ListAccountsPageAscParams struct {
+	CursorCreated sql.NullString `json:"cursorCreated"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListAccountsPageAscRow struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Created  string `json:"created"`
+	Deleted  bool   `json:"deleted"`
+}
+
+func (q *Queries) ListAccountsPageAsc(ctx context.Context, arg ListAccountsPageAscParams) ([]ListAccountsPageAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsPageAsc, arg.CursorCreated, arg.CursorID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountsPageAscRow{}
+	for rows.Next() {
+		var i ListAccountsPageAscRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Role,
+			&i.Created,
+			&i.Deleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsPageDesc = `-- name: ListAccountsPageDesc :many
+SELECT id, username, role, created, deleted FROM account
+WHERE $1::varchar IS NULL
+   OR (created::timestamptz, id) < (CAST($1::varchar AS timestamptz), $2::bigint)
+ORDER BY created::timestamptz DESC, id DESC
+LIMIT $3
+`
+
+type ListAccountsPageDescParams struct {
+	CursorCreated sql.NullString `json:"cursorCreated"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListAccountsPageDescRow struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Created  string `json:"created"`
+	Deleted  bool   `json:"deleted"`
+}
+
+func (q *Queries) ListAccountsPageDesc(ctx context.Context, arg ListAccountsPageDescParams) ([]ListAccountsPageDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsPageDesc, arg.CursorCreated, arg.CursorID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountsPageDescRow{}
+	for rows.Next() {
+		var i ListAccountsPageDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Role,
 			&i.Created,
 			&i.Deleted,
 		); err != nil {
@@ -144,6 +334,46 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]L
 	return items, nil
 }
 
+const listUsernameCollisions = `-- name: ListUsernameCollisions :many
+SELECT account_id, previous_username, username, created FROM username_collision
+ORDER BY account_id
+`
+
+type ListUsernameCollisionsRow struct {
+	AccountID        int64     `json:"accountID"`
+	PreviousUsername string    `json:"previousUsername"`
+	Username         string    `json:"username"`
+	Created          time.Time `json:"created"`
+}
+
+func (q *Queries) ListUsernameCollisions(ctx context.Context) ([]ListUsernameCollisionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsernameCollisions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUsernameCollisionsRow{}
+	for rows.Next() {
+		var i ListUsernameCollisionsRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.PreviousUsername,
+			&i.Username,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const softDeleteAccount = `-- name: SoftDeleteAccount :exec
 UPDATE account
 SET deleted = 1
@@ -171,3 +401,90 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) er
 	_, err := q.db.ExecContext(ctx, updateAccount, arg.Phrase, arg.ID)
 	return err
 }
+
+const updateAccountCredentials = `-- name: UpdateAccountCredentials :exec
+UPDATE account
+SET phrase = $1, salt = $2, updated = NOW()
+WHERE id = $3
+`
+
+type UpdateAccountCredentialsParams struct {
+	Phrase []byte `json:"phrase"`
+	Salt   string `json:"salt"`
+	ID     int64  `json:"id"`
+}
+
+func (q *Queries) UpdateAccountCredentials(ctx context.Context, arg UpdateAccountCredentialsParams) error {
+	_, err := q.db.ExecContext(ctx, updateAccountCredentials, arg.Phrase, arg.Salt, arg.ID)
+	return err
+}
+
+const updateAccountEmail = `-- name: UpdateAccountEmail :exec
+UPDATE account
+SET email = $1, email_verified = FALSE, updated = NOW()
+WHERE id = $2
+`
+
+type UpdateAccountEmailParams struct {
+	Email string `json:"email"`
+	ID    int64  `json:"id"`
+}
+
+func (q *Queries) UpdateAccountEmail(ctx context.Context, arg UpdateAccountEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateAccountEmail, arg.Email, arg.ID)
+	return err
+}
+
+const updateAccountRole = `-- name: UpdateAccountRole :execrows
+UPDATE account
+SET role = $1, updated = NOW()
+WHERE id = $2
+`
+
+type UpdateAccountRoleParams struct {
+	Role string `json:"role"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) UpdateAccountRole(ctx context.Context, arg UpdateAccountRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateAccountRole, arg.Role, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateAccountUsername = `-- name: UpdateAccountUsername :exec
+UPDATE account
+SET username = $1, updated = NOW()
+WHERE id = $2
+`
+
+type UpdateAccountUsernameParams struct {
+	Username string `json:"username"`
+	ID       int64  `json:"id"`
+}
+
+func (q *Queries) UpdateAccountUsername(ctx context.Context, arg UpdateAccountUsernameParams) error {
+	_, err := q.db.ExecContext(ctx, updateAccountUsername, arg.Username, arg.ID)
+	return err
+}
+
+const verifyAccountEmail = `-- name: VerifyAccountEmail :execrows
+UPDATE account
+SET email_verified = TRUE, updated = NOW()
+WHERE id = $1 AND email = $2
+`
+
+type VerifyAccountEmailParams struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+func (q *Queries) VerifyAccountEmail(ctx context.Context, arg VerifyAccountEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, verifyAccountEmail, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,20 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Unique indexes whose violations callers tell apart.
+const (
+	AccountUsernameIndex = "account_username_idx"
+	AccountEmailIndex    = "account_email_idx"
+)
+
+// IsUniqueViolation reports whether err is postgres refusing a row because it would duplicate a value of
+// the unique index or constraint named constraint.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == constraint
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type CompleteAccountExportParams struct {
+	ID      int64     `json:"id"`
+	Archive []byte    `json:"archive"`
+	Expires time.Time `json:"expires"`
+}
+
+// CompleteAccountExport stores the export's archive and marks it ready to download until Expires, within
+// one transaction so that a ready export always has its archive.
+func (store *SQLStore) CompleteAccountExport(ctx context.Context, arg CompleteAccountExportParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		if err := q.CreateAccountExportArchive(ctx, CreateAccountExportArchiveParams{ExportID: arg.ID, Data: arg.Archive}); err != nil {
+			return err
+		}
+		return q.MarkAccountExportReady(ctx, MarkAccountExportReadyParams{
+			Size:    int64(len(arg.Archive)),
+			Expires: sql.NullTime{Time: arg.Expires, Valid: true},
+			ID:      arg.ID,
+		})
+	})
+}
@@ -7,19 +7,32 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 const createImage = `-- name: CreateImage :one
 INSERT INTO image (
-  data, created
+  data, mime_type, size, width, height, taken_at, created
 ) VALUES (
-  $1, NOW()
+  $1, $2, $3, $4, $5, $6, NOW()
 )
-RETURNING id, data, memo, created, updated, deleted
+RETURNING id, data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
 `
 
-func (q *Queries) CreateImage(ctx context.Context, data string) (Image, error) {
-	row := q.db.QueryRowContext(ctx, createImage, data)
+type CreateImageParams struct {
+	Data     string `json:"data"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+	TakenAt  string `json:"takenAt"`
+}
+
+func (q *Queries) CreateImage(ctx context.Context, arg CreateImageParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, createImage, arg.Data, arg.MimeType, arg.Size, arg.Width, arg.Height, arg.TakenAt)
 	var i Image
 	err := row.Scan(
 		&i.ID,
@@ -28,10 +41,33 @@ func (q *Queries) CreateImage(ctx context.Context, data string) (Image, error) {
 		&i.Created,
 		&i.Updated,
 		&i.Deleted,
+		&i.MimeType,
+		&i.Size,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
 	)
 	return i, err
 }
 
+const createImageOwner = `-- name: CreateImageOwner :exec
+INSERT INTO image_owner (
+  image_id, account_id
+) VALUES (
+  $1, $2
+)
+`
+
+type CreateImageOwnerParams struct {
+	ImageID   int64 `json:"imageID"`
+	AccountID int64 `json:"accountID"`
+}
+
+func (q *Queries) CreateImageOwner(ctx context.Context, arg CreateImageOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, createImageOwner, arg.ImageID, arg.AccountID)
+	return err
+}
+
 const deleteImage = `-- name: DeleteImage :exec
 DELETE FROM image
 WHERE id = $1
@@ -43,7 +79,7 @@ func (q *Queries) DeleteImage(ctx context.Context, id int64) error {
 }
 
 const getImage = `-- name: GetImage :one
-SELECT id, data, memo, created, updated, deleted FROM image
+SELECT id, data, memo, created, updated, deleted, mime_type, size, taken_at, width, height FROM image
 WHERE id = $1 LIMIT 1
 `
 
@@ -57,12 +93,113 @@ func (q *Queries) GetImage(ctx context.Context, id int64) (Image, error) {
 		&i.Created,
 		&i.Updated,
 		&i.Deleted,
+		&i.MimeType,
+		&i.Size,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
 	)
 	return i, err
 }
 
+const imageTimeline = `-- name: ImageTimeline :many
+SELECT bucket::timestamp AS bucket, image_count::bigint AS image_count, image_ids::bigint[] AS image_ids FROM (
+  SELECT date_trunc($1::text, COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz) AT TIME ZONE $2::text) AS bucket,
+         COUNT(*) AS image_count,
+         (array_agg(id ORDER BY COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz) DESC, id DESC))[1:$3::int] AS image_ids
+  FROM image
+  GROUP BY 1
+) AS timeline
+WHERE $4::timestamp IS NULL OR bucket < $4::timestamp
+ORDER BY bucket DESC
+LIMIT $5
+`
+
+type ImageTimelineParams struct {
+	Granularity  string       `json:"granularity"`
+	Tz           string       `json:"tz"`
+	SampleSize   int32        `json:"sampleSize"`
+	CursorBucket sql.NullTime `json:"cursorBucket"`
+	Limit        int32        `json:"limit"`
+}
+
+type ImageTimelineRow struct {
+	Bucket     time.Time `json:"bucket"`
+	ImageCount int64     `json:"imageCount"`
+	ImageIds   []int64   `json:"imageIds"`
+}
+
+func (q *Queries) ImageTimeline(ctx context.Context, arg ImageTimelineParams) ([]ImageTimelineRow, error) {
+	rows, err := q.db.QueryContext(ctx, imageTimeline, arg.Granularity, arg.Tz, arg.SampleSize, arg.CursorBucket, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ImageTimelineRow{}
+	for rows.Next() {
+		var i ImageTimelineRow
+		if err := rows.Scan(
+			&i.Bucket,
+			&i.ImageCount,
+			pq.Array(&i.ImageIds),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountImages = `-- name: ListAccountImages :many
+SELECT image.id, image.data, image.memo, image.created, image.updated, image.deleted, image.mime_type, image.size, image.taken_at, image.width, image.height FROM image
+JOIN image_owner ON image_owner.image_id = image.id
+WHERE image_owner.account_id = $1
+ORDER BY image.id
+`
+
+func (q *Queries) ListAccountImages(ctx context.Context, accountID int64) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountImages, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Image{}
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listImages = `-- name: ListImages :many
-SELECT id, data, memo, created, updated, deleted FROM image LIMIT $1 OFFSET $2
+SELECT id, data, memo, created, updated, deleted, mime_type, size, taken_at, width, height FROM image LIMIT $1 OFFSET $2
 `
 
 type ListImagesParams struct {
@@ -86,6 +223,595 @@ func (q *Queries) ListImages(ctx context.Context, arg ListImagesParams) ([]Image
 			&i.Created,
 			&i.Updated,
 			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImagesByCreatedAsc = `-- name: ListImagesByCreatedAsc :many
+SELECT id, CAST(CASE WHEN $1::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE ($2::varchar IS NULL
+    OR (created::timestamptz, id) > (CAST($2::varchar AS timestamptz), $3::bigint))
+  AND ($4::timestamptz IS NULL OR created::timestamptz > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created::timestamptz < $5::timestamptz)
+  AND ($6::bool IS NULL OR (memo <> '') = $6::bool)
+  AND ($7::text IS NULL OR mime_type = $7::text)
+ORDER BY created::timestamptz ASC, id ASC
+LIMIT $8
+`
+
+type ListImagesByCreatedAscParams struct {
+	IncludeData   bool           `json:"includeData"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListImagesByCreatedAscRow struct {
+	ID       int64  `json:"id"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) ListImagesByCreatedAsc(ctx context.Context, arg ListImagesByCreatedAscParams) ([]ListImagesByCreatedAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, listImagesByCreatedAsc, arg.IncludeData, arg.CursorValue, arg.CursorID, arg.CreatedAfter, arg.CreatedBefore, arg.HasMemo, arg.MimeType, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListImagesByCreatedAscRow{}
+	for rows.Next() {
+		var i ListImagesByCreatedAscRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImagesByCreatedDesc = `-- name: ListImagesByCreatedDesc :many
+SELECT id, CAST(CASE WHEN $1::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE ($2::varchar IS NULL
+    OR (created::timestamptz, id) < (CAST($2::varchar AS timestamptz), $3::bigint))
+  AND ($4::timestamptz IS NULL OR created::timestamptz > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created::timestamptz < $5::timestamptz)
+  AND ($6::bool IS NULL OR (memo <> '') = $6::bool)
+  AND ($7::text IS NULL OR mime_type = $7::text)
+ORDER BY created::timestamptz DESC, id DESC
+LIMIT $8
+`
+
+type ListImagesByCreatedDescParams struct {
+	IncludeData   bool           `json:"includeData"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListImagesByCreatedDescRow struct {
+	ID       int64  `json:"id"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) ListImagesByCreatedDesc(ctx context.Context, arg ListImagesByCreatedDescParams) ([]ListImagesByCreatedDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listImagesByCreatedDesc, arg.IncludeData, arg.CursorValue, arg.CursorID, arg.CreatedAfter, arg.CreatedBefore, arg.HasMemo, arg.MimeType, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListImagesByCreatedDescRow{}
+	for rows.Next() {
+		var i ListImagesByCreatedDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImagesBySizeAsc = `-- name: ListImagesBySizeAsc :many
+SELECT id, CAST(CASE WHEN $1::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE ($2::varchar IS NULL
+    OR (size, id) > (CAST($2::varchar AS bigint), $3::bigint))
+  AND ($4::timestamptz IS NULL OR created::timestamptz > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created::timestamptz < $5::timestamptz)
+  AND ($6::bool IS NULL OR (memo <> '') = $6::bool)
+  AND ($7::text IS NULL OR mime_type = $7::text)
+ORDER BY size ASC, id ASC
+LIMIT $8
+`
+
+type ListImagesBySizeAscParams struct {
+	IncludeData   bool           `json:"includeData"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListImagesBySizeAscRow struct {
+	ID       int64  `json:"id"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) ListImagesBySizeAsc(ctx context.Context, arg ListImagesBySizeAscParams) ([]ListImagesBySizeAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, listImagesBySizeAsc, arg.IncludeData, arg.CursorValue, arg.CursorID, arg.CreatedAfter, arg.CreatedBefore, arg.HasMemo, arg.MimeType, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListImagesBySizeAscRow{}
+	for rows.Next() {
+		var i ListImagesBySizeAscRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImagesBySizeDesc = `-- name: ListImagesBySizeDesc :many
+SELECT id, CAST(CASE WHEN $1::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE ($2::varchar IS NULL
+    OR (size, id) < (CAST($2::varchar AS bigint), $3::bigint))
+  AND ($4::timestamptz IS NULL OR created::timestamptz > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created::timestamptz < $5::timestamptz)
+  AND ($6::bool IS NULL OR (memo <> '') = $6::bool)
+  AND ($7::text IS NULL OR mime_type = $7::text)
+ORDER BY size DESC, id DESC
+LIMIT $8
+`
+
+type ListImagesBySizeDescParams struct {
+	IncludeData   bool           `json:"includeData"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListImagesBySizeDescRow struct {
+	ID       int64  `json:"id"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) ListImagesBySizeDesc(ctx context.Context, arg ListImagesBySizeDescParams) ([]ListImagesBySizeDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listImagesBySizeDesc, arg.IncludeData, arg.CursorValue, arg.CursorID, arg.CreatedAfter, arg.CreatedBefore, arg.HasMemo, arg.MimeType, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListImagesBySizeDescRow{}
+	for rows.Next() {
+		var i ListImagesBySizeDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImagesByTakenAtAsc = `-- name: ListImagesByTakenAtAsc :many
+SELECT id, CAST(CASE WHEN $1::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE ($2::varchar IS NULL
+    OR (COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz), id) > (CAST($2::varchar AS timestamptz), $3::bigint))
+  AND ($4::timestamptz IS NULL OR created::timestamptz > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created::timestamptz < $5::timestamptz)
+  AND ($6::bool IS NULL OR (memo <> '') = $6::bool)
+  AND ($7::text IS NULL OR mime_type = $7::text)
+ORDER BY COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz) ASC, id ASC
+LIMIT $8
+`
+
+type ListImagesByTakenAtAscParams struct {
+	IncludeData   bool           `json:"includeData"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListImagesByTakenAtAscRow struct {
+	ID       int64  `json:"id"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) ListImagesByTakenAtAsc(ctx context.Context, arg ListImagesByTakenAtAscParams) ([]ListImagesByTakenAtAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, listImagesByTakenAtAsc, arg.IncludeData, arg.CursorValue, arg.CursorID, arg.CreatedAfter, arg.CreatedBefore, arg.HasMemo, arg.MimeType, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListImagesByTakenAtAscRow{}
+	for rows.Next() {
+		var i ListImagesByTakenAtAscRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImagesByTakenAtDesc = `-- name: ListImagesByTakenAtDesc :many
+SELECT id, CAST(CASE WHEN $1::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE ($2::varchar IS NULL
+    OR (COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz), id) < (CAST($2::varchar AS timestamptz), $3::bigint))
+  AND ($4::timestamptz IS NULL OR created::timestamptz > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created::timestamptz < $5::timestamptz)
+  AND ($6::bool IS NULL OR (memo <> '') = $6::bool)
+  AND ($7::text IS NULL OR mime_type = $7::text)
+ORDER BY COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz) DESC, id DESC
+LIMIT $8
+`
+
+type ListImagesByTakenAtDescParams struct {
+	IncludeData   bool           `json:"includeData"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListImagesByTakenAtDescRow struct {
+	ID       int64  `json:"id"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) ListImagesByTakenAtDesc(ctx context.Context, arg ListImagesByTakenAtDescParams) ([]ListImagesByTakenAtDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listImagesByTakenAtDesc, arg.IncludeData, arg.CursorValue, arg.CursorID, arg.CreatedAfter, arg.CreatedBefore, arg.HasMemo, arg.MimeType, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListImagesByTakenAtDescRow{}
+	for rows.Next() {
+		var i ListImagesByTakenAtDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImagesByUpdatedAsc = `-- name: ListImagesByUpdatedAsc :many
+SELECT id, CAST(CASE WHEN $1::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE ($2::varchar IS NULL
+    OR (COALESCE(NULLIF(updated, '')::timestamptz, created::timestamptz), id) > (CAST($2::varchar AS timestamptz), $3::bigint))
+  AND ($4::timestamptz IS NULL OR created::timestamptz > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created::timestamptz < $5::timestamptz)
+  AND ($6::bool IS NULL OR (memo <> '') = $6::bool)
+  AND ($7::text IS NULL OR mime_type = $7::text)
+ORDER BY COALESCE(NULLIF(updated, '')::timestamptz, created::timestamptz) ASC, id ASC
+LIMIT $8
+`
+
+type ListImagesByUpdatedAscParams struct {
+	IncludeData   bool           `json:"includeData"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListImagesByUpdatedAscRow struct {
+	ID       int64  `json:"id"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) ListImagesByUpdatedAsc(ctx context.Context, arg ListImagesByUpdatedAscParams) ([]ListImagesByUpdatedAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, listImagesByUpdatedAsc, arg.IncludeData, arg.CursorValue, arg.CursorID, arg.CreatedAfter, arg.CreatedBefore, arg.HasMemo, arg.MimeType, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListImagesByUpdatedAscRow{}
+	for rows.Next() {
+		var i ListImagesByUpdatedAscRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImagesByUpdatedDesc = `-- name: ListImagesByUpdatedDesc :many
+SELECT id, CAST(CASE WHEN $1::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE ($2::varchar IS NULL
+    OR (COALESCE(NULLIF(updated, '')::timestamptz, created::timestamptz), id) < (CAST($2::varchar AS timestamptz), $3::bigint))
+  AND ($4::timestamptz IS NULL OR created::timestamptz > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created::timestamptz < $5::timestamptz)
+  AND ($6::bool IS NULL OR (memo <> '') = $6::bool)
+  AND ($7::text IS NULL OR mime_type = $7::text)
+ORDER BY COALESCE(NULLIF(updated, '')::timestamptz, created::timestamptz) DESC, id DESC
+LIMIT $8
+`
+
+type ListImagesByUpdatedDescParams struct {
+	IncludeData   bool           `json:"includeData"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	Limit         int32          `json:"limit"`
+}
+
+type ListImagesByUpdatedDescRow struct {
+	ID       int64  `json:"id"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) ListImagesByUpdatedDesc(ctx context.Context, arg ListImagesByUpdatedDescParams) ([]ListImagesByUpdatedDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listImagesByUpdatedDesc, arg.IncludeData, arg.CursorValue, arg.CursorID, arg.CreatedAfter, arg.CreatedBefore, arg.HasMemo, arg.MimeType, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListImagesByUpdatedDescRow{}
+	for rows.Next() {
+		var i ListImagesByUpdatedDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Data,
+			&i.Memo,
+			&i.Created,
+			&i.Updated,
+			&i.Deleted,
+			&i.MimeType,
+			&i.Size,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
 		); err != nil {
 			return nil, err
 		}
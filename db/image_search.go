@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// imageSorts are the sort options of SearchImages.
+var imageSorts = map[string]bool{"created": true, "updated": true, "taken_at": true, "size": true}
+
+// IsImageSort reports whether sort is one of the columns SearchImages can order by.
+func IsImageSort(sort string) bool {
+	return imageSorts[sort]
+}
+
+// ImageSortValue returns the value image is ordered by for sort, as used in a keyset cursor.
+func ImageSortValue(image Image, sort string) string {
+	switch sort {
+	case "updated":
+		if image.Updated != "" {
+			return image.Updated
+		}
+		return image.Created
+	case "taken_at":
+		if image.TakenAt != "" {
+			return image.TakenAt
+		}
+		return image.Created
+	case "size":
+		return strconv.FormatInt(image.Size, 10)
+	default:
+		return image.Created
+	}
+}
+
+type SearchImagesParams struct {
+	Sort          string         `json:"sort"`
+	Desc          bool           `json:"desc"`
+	CursorValue   sql.NullString `json:"cursorValue"`
+	CursorID      sql.NullInt64  `json:"cursorID"`
+	CreatedAfter  sql.NullTime   `json:"createdAfter"`
+	CreatedBefore sql.NullTime   `json:"createdBefore"`
+	HasMemo       sql.NullBool   `json:"hasMemo"`
+	MimeType      sql.NullString `json:"mimeType"`
+	IncludeData   bool           `json:"includeData"`
+	Limit         int32          `json:"limit"`
+}
+
+// SearchImages lists images matching the optional filters, ordered by arg.Sort then id and continuing
+// after the cursor when one is given, using the query for that sort and direction. Data is left empty
+// unless arg.IncludeData is set, so listings don't have to read the pixel data.
+func (q *Queries) SearchImages(ctx context.Context, arg SearchImagesParams) ([]Image, error) {
+	page := ListImagesByCreatedDescParams{
+		IncludeData:   arg.IncludeData,
+		CursorValue:   arg.CursorValue,
+		CursorID:      arg.CursorID,
+		CreatedAfter:  arg.CreatedAfter,
+		CreatedBefore: arg.CreatedBefore,
+		HasMemo:       arg.HasMemo,
+		MimeType:      arg.MimeType,
+		Limit:         arg.Limit,
+	}
+	if !page.CursorValue.Valid || !page.CursorID.Valid {
+		page.CursorValue, page.CursorID = sql.NullString{}, sql.NullInt64{}
+	}
+
+	var images []Image
+	switch {
+	case arg.Sort == "created" && arg.Desc:
+		rows, err := q.ListImagesByCreatedDesc(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			images = append(images, Image(row))
+		}
+	case arg.Sort == "created":
+		rows, err := q.ListImagesByCreatedAsc(ctx, ListImagesByCreatedAscParams(page))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			images = append(images, Image(row))
+		}
+	case arg.Sort == "updated" && arg.Desc:
+		rows, err := q.ListImagesByUpdatedDesc(ctx, ListImagesByUpdatedDescParams(page))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			images = append(images, Image(row))
+		}
+	case arg.Sort == "updated":
+		rows, err := q.ListImagesByUpdatedAsc(ctx, ListImagesByUpdatedAscParams(page))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			images = append(images, Image(row))
+		}
+	case arg.Sort == "taken_at" && arg.Desc:
+		rows, err := q.ListImagesByTakenAtDesc(ctx, ListImagesByTakenAtDescParams(page))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			images = append(images, Image(row))
+		}
+	case arg.Sort == "taken_at":
+		rows, err := q.ListImagesByTakenAtAsc(ctx, ListImagesByTakenAtAscParams(page))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			images = append(images, Image(row))
+		}
+	case arg.Sort == "size" && arg.Desc:
+		rows, err := q.ListImagesBySizeDesc(ctx, ListImagesBySizeDescParams(page))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			images = append(images, Image(row))
+		}
+	case arg.Sort == "size":
+		rows, err := q.ListImagesBySizeAsc(ctx, ListImagesBySizeAscParams(page))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			images = append(images, Image(row))
+		}
+	default:
+		return nil, fmt.Errorf("cannot sort images by %q", arg.Sort)
+	}
+	if images == nil {
+		images = []Image{}
+	}
+	return images, nil
+}
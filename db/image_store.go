@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+)
+
+type CreateAccountImageParams struct {
+	CreateImageParams
+	// AccountID is the account uploading the image, 0 leaves the image without an owner
+	AccountID int64 `json:"accountId"`
+}
+
+// CreateAccountImage adds an image to the shared library and records the account that uploaded it, within
+// one transaction so that an image is never left without the owner it was uploaded by.
+func (store *SQLStore) CreateAccountImage(ctx context.Context, arg CreateAccountImageParams) (Image, error) {
+	var image Image
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		image, err = q.CreateImage(ctx, arg.CreateImageParams)
+		if err != nil || arg.AccountID == 0 {
+			return err
+		}
+		return q.CreateImageOwner(ctx, CreateImageOwnerParams{ImageID: image.ID, AccountID: arg.AccountID})
+	})
+	return image, err
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTotpNotPending is returned by EnableTotp when the account has no TOTP secret waiting to be confirmed,
+// either because it wasn't enrolled or because two-factor authentication is already enabled.
+var ErrTotpNotPending = errors.New("two-factor authentication isn't waiting to be confirmed")
+
+type EnableTotpParams struct {
+	AccountID          int64    `json:"accountID"`
+	RecoveryCodeHashes [][]byte `json:"recoveryCodeHashes"`
+}
+
+// EnableTotp enables two-factor authentication with the account's enrolled secret and replaces its
+// recovery codes within one transaction.
+func (store *SQLStore) EnableTotp(ctx context.Context, arg EnableTotpParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		rows, err := q.EnableAccountTotp(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrTotpNotPending
+		}
+		if err := q.DeleteAccountRecoveryCodes(ctx, arg.AccountID); err != nil {
+			return err
+		}
+		for _, hash := range arg.RecoveryCodeHashes {
+			if err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{AccountID: arg.AccountID, CodeHash: hash}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DisableTotp turns two-factor authentication off, forgetting the secret and recovery codes, within one
+// transaction.
+func (store *SQLStore) DisableTotp(ctx context.Context, accountID int64) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		if err := q.DisableAccountTotp(ctx, accountID); err != nil {
+			return err
+		}
+		return q.DeleteAccountRecoveryCodes(ctx, accountID)
+	})
+}
@@ -0,0 +1,7 @@
+ALTER TABLE "image"
+  ADD COLUMN "mime_type" TEXT    NOT NULL DEFAULT '',
+  ADD COLUMN "size"      BIGINT  NOT NULL DEFAULT 0,
+  ADD COLUMN "taken_at"  VARCHAR NOT NULL DEFAULT '';
+
+-- Approximate the decoded size of images uploaded before it was recorded.
+UPDATE "image" SET "size" = (length("data") * 3) / 4;
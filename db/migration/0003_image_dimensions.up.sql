@@ -0,0 +1,3 @@
+ALTER TABLE "image"
+  ADD COLUMN "width"  INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN "height" INTEGER NOT NULL DEFAULT 0;
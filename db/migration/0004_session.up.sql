@@ -0,0 +1,12 @@
+CREATE TABLE "session" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "account_id" BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "family_id"  TEXT        NOT NULL,
+  "token_hash" BYTEA       NOT NULL UNIQUE,
+  "rotated"    BOOLEAN     NOT NULL DEFAULT FALSE,
+  "revoked"    BOOLEAN     NOT NULL DEFAULT FALSE,
+  "expires"    TIMESTAMPTZ NOT NULL,
+  "created"    VARCHAR     NOT NULL
+);
+
+CREATE INDEX "session_family_id_idx" ON "session" ("family_id");
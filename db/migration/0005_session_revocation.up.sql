@@ -0,0 +1,13 @@
+ALTER TABLE "session"
+  ADD COLUMN "user_agent"     TEXT        NOT NULL DEFAULT '',
+  ADD COLUMN "ip"             TEXT        NOT NULL DEFAULT '',
+  ADD COLUMN "access_jti"     TEXT        NOT NULL DEFAULT '',
+  ADD COLUMN "access_expires" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN "last_seen"      TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX "session_account_id_idx" ON "session" ("account_id");
+
+CREATE TABLE "revoked_token" (
+  "jti"     TEXT        PRIMARY KEY,
+  "expires" TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,13 @@
+CREATE TABLE "api_key" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "account_id" BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "name"       TEXT        NOT NULL,
+  "prefix"     TEXT        NOT NULL UNIQUE,
+  "token_hash" BYTEA       NOT NULL UNIQUE,
+  "scopes"     TEXT        NOT NULL,
+  "expires"    TIMESTAMPTZ,
+  "last_used"  TIMESTAMPTZ,
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "api_key_account_id_idx" ON "api_key" ("account_id");
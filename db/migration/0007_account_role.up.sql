@@ -0,0 +1,2 @@
+ALTER TABLE "account"
+  ADD COLUMN "role" TEXT NOT NULL DEFAULT 'member' CHECK ("role" IN ('admin', 'member', 'read-only'));
@@ -0,0 +1,13 @@
+CREATE TABLE "login_attempt" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "username"   TEXT        NOT NULL,
+  "ip"         TEXT        NOT NULL DEFAULT '',
+  "user_agent" TEXT        NOT NULL DEFAULT '',
+  "success"    BOOLEAN     NOT NULL,
+  "reason"     TEXT        NOT NULL,
+  "cleared"    BOOLEAN     NOT NULL DEFAULT FALSE,
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "login_attempt_username_created_idx" ON "login_attempt" ("username", "created");
+CREATE INDEX "login_attempt_ip_created_idx" ON "login_attempt" ("ip", "created");
@@ -0,0 +1,12 @@
+ALTER TABLE "account"
+  ADD COLUMN "totp_secret"  TEXT    NOT NULL DEFAULT '',
+  ADD COLUMN "totp_enabled" BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE "recovery_code" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "account_id" BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "code_hash"  BYTEA       NOT NULL,
+  "used"       TIMESTAMPTZ,
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE ("account_id", "code_hash")
+);
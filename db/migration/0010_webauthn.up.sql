@@ -0,0 +1,21 @@
+CREATE TABLE "webauthn_credential" (
+  "id"            BIGSERIAL   PRIMARY KEY,
+  "account_id"    BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "credential_id" BYTEA       NOT NULL UNIQUE,
+  "public_key"    BYTEA       NOT NULL,
+  "sign_count"    BIGINT      NOT NULL DEFAULT 0,
+  "name"          TEXT        NOT NULL,
+  "created"       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "last_used"     TIMESTAMPTZ
+);
+
+CREATE INDEX "webauthn_credential_account_id_idx" ON "webauthn_credential" ("account_id");
+
+CREATE TABLE "webauthn_challenge" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "challenge"  BYTEA       NOT NULL UNIQUE,
+  "account_id" BIGINT      REFERENCES "account" ("id") ON DELETE CASCADE,
+  "ceremony"   TEXT        NOT NULL CHECK ("ceremony" IN ('register', 'login')),
+  "expires"    TIMESTAMPTZ NOT NULL,
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,18 @@
+ALTER TABLE "account"
+  ADD COLUMN "email"          TEXT    NOT NULL DEFAULT '',
+  ADD COLUMN "email_verified" BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX "account_email_idx" ON "account" (LOWER("email")) WHERE "email" <> '';
+
+CREATE TABLE "account_token" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "account_id" BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "purpose"    TEXT        NOT NULL CHECK ("purpose" IN ('verify_email', 'reset_phrase')),
+  "token_hash" BYTEA       NOT NULL UNIQUE,
+  "email"      TEXT        NOT NULL,
+  "expires"    TIMESTAMPTZ NOT NULL,
+  "used"       TIMESTAMPTZ,
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "account_token_account_id_idx" ON "account_token" ("account_id");
@@ -0,0 +1,23 @@
+CREATE TABLE "account_identity" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "account_id" BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "provider"   TEXT        NOT NULL,
+  "subject"    TEXT        NOT NULL,
+  "email"      TEXT        NOT NULL DEFAULT '',
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "last_used"  TIMESTAMPTZ,
+  UNIQUE ("provider", "subject")
+);
+
+CREATE INDEX "account_identity_account_id_idx" ON "account_identity" ("account_id");
+
+CREATE TABLE "oidc_state" (
+  "id"            BIGSERIAL   PRIMARY KEY,
+  "state"         TEXT        NOT NULL UNIQUE,
+  "provider"      TEXT        NOT NULL,
+  "nonce"         TEXT        NOT NULL,
+  "code_verifier" TEXT        NOT NULL,
+  "account_id"    BIGINT      REFERENCES "account" ("id") ON DELETE CASCADE,
+  "expires"       TIMESTAMPTZ NOT NULL,
+  "created"       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
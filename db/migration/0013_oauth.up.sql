@@ -0,0 +1,43 @@
+CREATE TABLE "oauth_client" (
+  "id"            BIGSERIAL   PRIMARY KEY,
+  "client_id"     TEXT        NOT NULL UNIQUE,
+  "account_id"    BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "name"          TEXT        NOT NULL,
+  "redirect_uris" TEXT        NOT NULL,
+  "scopes"        TEXT        NOT NULL,
+  "secret_hash"   BYTEA       NOT NULL DEFAULT '',
+  "created"       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "oauth_client_account_id_idx" ON "oauth_client" ("account_id");
+
+-- an authorization is waiting for consent until it is approved with its ticket, after which it waits to
+-- be exchanged for tokens with its code
+CREATE TABLE "oauth_authorization" (
+  "id"             BIGSERIAL   PRIMARY KEY,
+  "ticket"         TEXT        NOT NULL UNIQUE,
+  "code_hash"      BYTEA       UNIQUE,
+  "client_id"      TEXT        NOT NULL REFERENCES "oauth_client" ("client_id") ON DELETE CASCADE,
+  "account_id"     BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "redirect_uri"   TEXT        NOT NULL,
+  "scope"          TEXT        NOT NULL,
+  "state"          TEXT        NOT NULL DEFAULT '',
+  "code_challenge" TEXT        NOT NULL,
+  "expires"        TIMESTAMPTZ NOT NULL,
+  "created"        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE "oauth_refresh_token" (
+  "id"             BIGSERIAL   PRIMARY KEY,
+  "token_hash"     BYTEA       NOT NULL UNIQUE,
+  "client_id"      TEXT        NOT NULL REFERENCES "oauth_client" ("client_id") ON DELETE CASCADE,
+  "account_id"     BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "scope"          TEXT        NOT NULL,
+  "access_jti"     TEXT        NOT NULL,
+  "access_expires" TIMESTAMPTZ NOT NULL,
+  "expires"        TIMESTAMPTZ NOT NULL,
+  "revoked"        BOOLEAN     NOT NULL DEFAULT FALSE,
+  "created"        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "oauth_refresh_token_grant_idx" ON "oauth_refresh_token" ("client_id", "account_id");
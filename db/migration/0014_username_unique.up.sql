@@ -0,0 +1,39 @@
+-- usernames are compared lowercased and NFKC normalized, so "Bob", "bob" and "ｂｏｂ" are the same name.
+-- Accounts that already collide keep the name in the oldest account of each group, the others have their
+-- id appended, e.g. "Bob-42", and are recorded so that they can be told their new name.
+CREATE TABLE "username_collision" (
+  "account_id"        BIGINT      PRIMARY KEY REFERENCES "account" ("id") ON DELETE CASCADE,
+  "previous_username" TEXT        NOT NULL,
+  "username"          TEXT        NOT NULL,
+  "created"           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+DO $$
+DECLARE
+  collision RECORD;
+  candidate TEXT;
+BEGIN
+  FOR collision IN
+    SELECT "id", "username" FROM (
+      SELECT "id", "username", ROW_NUMBER() OVER (PARTITION BY LOWER(NORMALIZE("username", NFKC)) ORDER BY "id") AS "rank"
+      FROM "account"
+    ) AS ranked
+    WHERE "rank" > 1
+    ORDER BY "id"
+  LOOP
+    candidate := collision."username" || '-' || collision."id";
+    WHILE EXISTS (SELECT 1 FROM "account" WHERE LOWER(NORMALIZE("username", NFKC)) = LOWER(NORMALIZE(candidate, NFKC))) LOOP
+      candidate := candidate || '-' || collision."id";
+    END LOOP;
+
+    UPDATE "account" SET "username" = candidate, "updated" = NOW() WHERE "id" = collision."id";
+    INSERT INTO "username_collision" ("account_id", "previous_username", "username")
+    VALUES (collision."id", collision."username", candidate);
+  END LOOP;
+END
+$$;
+
+CREATE UNIQUE INDEX "account_username_idx" ON "account" (LOWER(NORMALIZE("username", NFKC)));
+
+DROP INDEX "login_attempt_username_created_idx";
+CREATE INDEX "login_attempt_username_created_idx" ON "login_attempt" (LOWER(NORMALIZE("username", NFKC)), "created");
@@ -0,0 +1,10 @@
+CREATE TABLE "username_history" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "account_id" BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "username"   TEXT        NOT NULL,
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "expires"    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX "username_history_username_idx" ON "username_history" (LOWER(NORMALIZE("username", NFKC)));
+CREATE INDEX "username_history_account_id_idx" ON "username_history" ("account_id", "created");
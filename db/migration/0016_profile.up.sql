@@ -0,0 +1,13 @@
+CREATE TABLE "profile" (
+  "account_id"       BIGINT      PRIMARY KEY REFERENCES "account" ("id") ON DELETE CASCADE,
+  "display_name"     TEXT        NOT NULL DEFAULT '',
+  "bio"              TEXT        NOT NULL DEFAULT '',
+  "locale"           TEXT        NOT NULL DEFAULT '',
+  "timezone"         TEXT        NOT NULL DEFAULT '',
+  "visibility"       TEXT        NOT NULL DEFAULT 'public' CHECK ("visibility" IN ('public', 'members', 'private')),
+  "show_timezone"    BOOLEAN     NOT NULL DEFAULT FALSE,
+  -- the avatar is base64 encoded like image data, but kept out of the shared image library
+  "avatar"           TEXT        NOT NULL DEFAULT '',
+  "avatar_mime_type" TEXT        NOT NULL DEFAULT '',
+  "updated"          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,21 @@
+CREATE TABLE "account_export" (
+  "id"         BIGSERIAL   PRIMARY KEY,
+  "account_id" BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "status"     TEXT        NOT NULL DEFAULT 'pending' CHECK ("status" IN ('pending', 'running', 'ready', 'failed')),
+  "error"      TEXT        NOT NULL DEFAULT '',
+  "size"       BIGINT      NOT NULL DEFAULT 0,
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "started"    TIMESTAMPTZ,
+  "completed"  TIMESTAMPTZ,
+  -- once expired the export and its archive are deleted
+  "expires"    TIMESTAMPTZ
+);
+
+CREATE INDEX "account_export_account_id_created_idx" ON "account_export" ("account_id", "created");
+CREATE INDEX "account_export_waiting_idx" ON "account_export" ("created") WHERE "status" IN ('pending', 'running');
+
+-- the archive is kept apart so that polling an export's status doesn't read it
+CREATE TABLE "account_export_archive" (
+  "export_id" BIGINT PRIMARY KEY REFERENCES "account_export" ("id") ON DELETE CASCADE,
+  "data"      BYTEA  NOT NULL
+);
@@ -0,0 +1,9 @@
+-- records which account uploaded an image, so that it can be exported with the account's data. The image
+-- library stays shared, images uploaded before this have no owner.
+CREATE TABLE "image_owner" (
+  "image_id"   BIGINT      PRIMARY KEY REFERENCES "image" ("id") ON DELETE CASCADE,
+  "account_id" BIGINT      NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+  "created"    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "image_owner_account_id_idx" ON "image_owner" ("account_id", "image_id");
@@ -0,0 +1,6 @@
+-- the account that held the username when the attempt was made, so that an account's sign in history
+-- doesn't include attempts made while another account had the username. Earlier attempts can't be told
+-- apart and are left without one.
+ALTER TABLE "login_attempt" ADD COLUMN "account_id" BIGINT REFERENCES "account" ("id") ON DELETE SET NULL;
+
+CREATE INDEX "login_attempt_account_id_created_idx" ON "login_attempt" ("account_id", "created");
@@ -0,0 +1,2 @@
+-- the last TOTP time step a code was accepted for, so that a code can't be used twice
+ALTER TABLE "account" ADD COLUMN "totp_last_step" BIGINT NOT NULL DEFAULT 0;
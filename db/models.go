@@ -4,23 +4,130 @@
 
 package db
 
-import ()
+import (
+	"database/sql"
+	"time"
+)
 
 type Account struct {
+	ID            int64  `json:"id"`
+	Username      string `json:"username"`
+	Phrase        []byte `json:"phrase"`
+	Salt          string `json:"salt"`
+	Created       string `json:"created"`
+	Updated       string `json:"updated"`
+	Deleted       bool   `json:"deleted"`
+	Role          string `json:"role"`
+	TotpSecret    string `json:"totpSecret"`
+	TotpEnabled   bool   `json:"totpEnabled"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	TotpLastStep  int64  `json:"totpLastStep"`
+}
+
+type AccountExport struct {
+	ID        int64        `json:"id"`
+	AccountID int64        `json:"accountID"`
+	Status    string       `json:"status"`
+	Error     string       `json:"error"`
+	Size      int64        `json:"size"`
+	Created   time.Time    `json:"created"`
+	Started   sql.NullTime `json:"started"`
+	Completed sql.NullTime `json:"completed"`
+	Expires   sql.NullTime `json:"expires"`
+}
+
+type AccountIdentity struct {
+	ID        int64        `json:"id"`
+	AccountID int64        `json:"accountID"`
+	Provider  string       `json:"provider"`
+	Subject   string       `json:"subject"`
+	Email     string       `json:"email"`
+	Created   time.Time    `json:"created"`
+	LastUsed  sql.NullTime `json:"lastUsed"`
+}
+
+type ApiKey struct {
+	ID        int64        `json:"id"`
+	AccountID int64        `json:"accountID"`
+	Name      string       `json:"name"`
+	Prefix    string       `json:"prefix"`
+	TokenHash []byte       `json:"tokenHash"`
+	Scopes    string       `json:"scopes"`
+	Expires   sql.NullTime `json:"expires"`
+	LastUsed  sql.NullTime `json:"lastUsed"`
+	Created   time.Time    `json:"created"`
+}
+
+type Image struct {
 	ID       int64  `json:"id"`
-	Username string `json:"username"`
-	Phrase   []byte `json:"phrase"`
-	Salt     string `json:"salt"`
+	Data     string `json:"data"`
+	Memo     string `json:"memo"`
 	Created  string `json:"created"`
 	Updated  string `json:"updated"`
 	Deleted  bool   `json:"deleted"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	TakenAt  string `json:"takenAt"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
 }
 
-type Image struct {
-	ID      int64  `json:"id"`
-	Data    string `json:"data"`
-	Memo    string `json:"memo"`
-	Created string `json:"created"`
-	Updated string `json:"updated"`
-	Deleted bool   `json:"deleted"`
+type OauthClient struct {
+	ID           int64     `json:"id"`
+	ClientID     string    `json:"clientID"`
+	AccountID    int64     `json:"accountID"`
+	Name         string    `json:"name"`
+	RedirectUris string    `json:"redirectUris"`
+	Scopes       string    `json:"scopes"`
+	SecretHash   []byte    `json:"secretHash"`
+	Created      time.Time `json:"created"`
+}
+
+type Profile struct {
+	AccountID      int64     `json:"accountID"`
+	DisplayName    string    `json:"displayName"`
+	Bio            string    `json:"bio"`
+	Locale         string    `json:"locale"`
+	Timezone       string    `json:"timezone"`
+	Visibility     string    `json:"visibility"`
+	ShowTimezone   bool      `json:"showTimezone"`
+	Avatar         string    `json:"avatar"`
+	AvatarMimeType string    `json:"avatarMimeType"`
+	Updated        time.Time `json:"updated"`
+}
+
+type Session struct {
+	ID            int64     `json:"id"`
+	AccountID     int64     `json:"accountID"`
+	FamilyID      string    `json:"familyID"`
+	TokenHash     []byte    `json:"tokenHash"`
+	Rotated       bool      `json:"rotated"`
+	Revoked       bool      `json:"revoked"`
+	Expires       time.Time `json:"expires"`
+	Created       string    `json:"created"`
+	UserAgent     string    `json:"userAgent"`
+	IP            string    `json:"ip"`
+	AccessJti     string    `json:"accessJti"`
+	AccessExpires time.Time `json:"accessExpires"`
+	LastSeen      time.Time `json:"lastSeen"`
+}
+
+type UsernameHistory struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"accountID"`
+	Username  string    `json:"username"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires"`
+}
+
+type WebauthnCredential struct {
+	ID           int64        `json:"id"`
+	AccountID    int64        `json:"accountID"`
+	CredentialID []byte       `json:"credentialID"`
+	PublicKey    []byte       `json:"publicKey"`
+	SignCount    int64        `json:"signCount"`
+	Name         string       `json:"name"`
+	Created      time.Time    `json:"created"`
+	LastUsed     sql.NullTime `json:"lastUsed"`
 }
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+)
+
+type CreateAccountWithIdentityParams struct {
+	Username string `json:"username"`
+	Phrase   []byte `json:"phrase"`
+	Salt     string `json:"salt"`
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	// Email is given to the account as its verified address when it is set, the provider having verified it
+	Email string `json:"email"`
+}
+
+// CreateAccountWithIdentity creates an account signed up with an OpenID Connect provider, linked to the
+// identity, within one transaction. Like RegisterAccount it returns ErrUsernameHeld when another account
+// still holds the username.
+func (store *SQLStore) CreateAccountWithIdentity(ctx context.Context, arg CreateAccountWithIdentityParams) (Account, error) {
+	var account Account
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := lockUsernames(ctx, q, arg.Username); err != nil {
+			return err
+		}
+		if err := checkUsernameHeld(ctx, q, arg.Username, 0); err != nil {
+			return err
+		}
+		var err error
+		account, err = q.CreateAccount(ctx, CreateAccountParams{Username: arg.Username, Phrase: arg.Phrase, Salt: arg.Salt})
+		if err != nil {
+			return err
+		}
+		if _, err := q.CreateAccountIdentity(ctx, CreateAccountIdentityParams{
+			AccountID: account.ID,
+			Provider:  arg.Provider,
+			Subject:   arg.Subject,
+			Email:     arg.Email,
+		}); err != nil {
+			return err
+		}
+		if arg.Email == "" {
+			return nil
+		}
+		if err := q.UpdateAccountEmail(ctx, UpdateAccountEmailParams{Email: arg.Email, ID: account.ID}); err != nil {
+			return err
+		}
+		if _, err := q.VerifyAccountEmail(ctx, VerifyAccountEmailParams{ID: account.ID, Email: arg.Email}); err != nil {
+			return err
+		}
+		account.Email = arg.Email
+		account.EmailVerified = true
+		return nil
+	})
+	return account, err
+}
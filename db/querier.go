@@ -6,23 +6,124 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
 	AccountExists(ctx context.Context, id int64) (bool, error)
+	ApproveOauthAuthorization(ctx context.Context, arg ApproveOauthAuthorizationParams) (ApproveOauthAuthorizationRow, error)
+	ClaimAccountExport(ctx context.Context, started time.Time) (AccountExport, error)
+	ClearIPLoginFailures(ctx context.Context, ip string) (int64, error)
+	ClearUsernameLoginFailures(ctx context.Context, username string) (int64, error)
+	ConsumeOauthCode(ctx context.Context, codeHash []byte) (ConsumeOauthCodeRow, error)
+	ConsumeOidcState(ctx context.Context, arg ConsumeOidcStateParams) (ConsumeOidcStateRow, error)
+	ConsumeWebauthnChallenge(ctx context.Context, arg ConsumeWebauthnChallengeParams) (sql.NullInt64, error)
+	CountIPLoginFailures(ctx context.Context, arg CountIPLoginFailuresParams) (CountIPLoginFailuresRow, error)
+	CountUsernameLoginFailures(ctx context.Context, arg CountUsernameLoginFailuresParams) (CountUsernameLoginFailuresRow, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
-	CreateImage(ctx context.Context, data string) (Image, error)
+	CreateAccountExport(ctx context.Context, accountID int64) (AccountExport, error)
+	CreateAccountExportArchive(ctx context.Context, arg CreateAccountExportArchiveParams) error
+	CreateAccountIdentity(ctx context.Context, arg CreateAccountIdentityParams) (AccountIdentity, error)
+	CreateAccountToken(ctx context.Context, arg CreateAccountTokenParams) error
+	CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error)
+	CreateImage(ctx context.Context, arg CreateImageParams) (Image, error)
+	CreateImageOwner(ctx context.Context, arg CreateImageOwnerParams) error
+	CreateLoginAttempt(ctx context.Context, arg CreateLoginAttemptParams) error
+	CreateOauthAuthorization(ctx context.Context, arg CreateOauthAuthorizationParams) error
+	CreateOauthClient(ctx context.Context, arg CreateOauthClientParams) (OauthClient, error)
+	CreateOauthRefreshToken(ctx context.Context, arg CreateOauthRefreshTokenParams) error
+	CreateOidcState(ctx context.Context, arg CreateOidcStateParams) error
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateUsernameHistory(ctx context.Context, arg CreateUsernameHistoryParams) error
+	CreateWebauthnChallenge(ctx context.Context, arg CreateWebauthnChallengeParams) error
+	CreateWebauthnCredential(ctx context.Context, arg CreateWebauthnCredentialParams) (WebauthnCredential, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteAccountApiKey(ctx context.Context, arg DeleteAccountApiKeyParams) (int64, error)
+	DeleteAccountIdentity(ctx context.Context, arg DeleteAccountIdentityParams) (int64, error)
+	DeleteAccountOauthClient(ctx context.Context, arg DeleteAccountOauthClientParams) (int64, error)
+	DeleteAccountRecoveryCodes(ctx context.Context, accountID int64) error
+	DeleteAccountTokens(ctx context.Context, arg DeleteAccountTokensParams) error
+	DeleteAccountUsernameHistory(ctx context.Context, arg DeleteAccountUsernameHistoryParams) error
+	DeleteAccountWebauthnCredential(ctx context.Context, arg DeleteAccountWebauthnCredentialParams) (int64, error)
+	DeleteExpiredAccountExports(ctx context.Context) (int64, error)
+	DeleteExpiredRevokedTokens(ctx context.Context) error
 	DeleteImage(ctx context.Context, id int64) error
+	DenyOauthAuthorization(ctx context.Context, arg DenyOauthAuthorizationParams) (DenyOauthAuthorizationRow, error)
+	DisableAccountTotp(ctx context.Context, id int64) error
+	EnableAccountTotp(ctx context.Context, id int64) (int64, error)
+	FailAccountExport(ctx context.Context, arg FailAccountExportParams) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountByEmail(ctx context.Context, email string) (Account, error)
 	GetAccountByUsername(ctx context.Context, username string) (Account, error)
+	GetAccountExport(ctx context.Context, arg GetAccountExportParams) (AccountExport, error)
+	GetAccountExportArchive(ctx context.Context, id int64) ([]byte, error)
+	GetAccountIdentity(ctx context.Context, arg GetAccountIdentityParams) (AccountIdentity, error)
+	GetAccountSession(ctx context.Context, arg GetAccountSessionParams) (Session, error)
+	GetAccountUsernameForUpdate(ctx context.Context, id int64) (string, error)
+	GetApiKeyByPrefix(ctx context.Context, prefix string) (GetApiKeyByPrefixRow, error)
 	GetImage(ctx context.Context, id int64) (Image, error)
+	GetLastUsernameChange(ctx context.Context, accountID int64) (time.Time, error)
+	GetLatestAccountExport(ctx context.Context, accountID int64) (AccountExport, error)
+	GetOauthClient(ctx context.Context, clientID string) (OauthClient, error)
+	GetOauthRefreshToken(ctx context.Context, tokenHash []byte) (GetOauthRefreshTokenRow, error)
+	GetProfile(ctx context.Context, accountID int64) (GetProfileRow, error)
+	GetProfileAvatar(ctx context.Context, accountID int64) (GetProfileAvatarRow, error)
+	GetSessionByTokenHash(ctx context.Context, tokenHash []byte) (Session, error)
+	GetUsernameHistory(ctx context.Context, username string) (UsernameHistory, error)
+	GetWebauthnCredential(ctx context.Context, credentialID []byte) (WebauthnCredential, error)
+	ImageTimeline(ctx context.Context, arg ImageTimelineParams) ([]ImageTimelineRow, error)
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	ListAccountApiKeys(ctx context.Context, accountID int64) ([]ApiKey, error)
+	ListAccountIdentities(ctx context.Context, accountID int64) ([]AccountIdentity, error)
+	ListAccountImages(ctx context.Context, accountID int64) ([]Image, error)
+	ListAccountLoginAttempts(ctx context.Context, arg ListAccountLoginAttemptsParams) ([]ListAccountLoginAttemptsRow, error)
+	ListAccountOauthClients(ctx context.Context, accountID int64) ([]OauthClient, error)
+	ListAccountPhrases(ctx context.Context) ([][]byte, error)
+	ListAccountSessions(ctx context.Context, accountID int64) ([]Session, error)
+	ListAccountUsernameHistory(ctx context.Context, accountID int64) ([]UsernameHistory, error)
+	ListAccountWebauthnCredentials(ctx context.Context, accountID int64) ([]WebauthnCredential, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]ListAccountsRow, error)
+	ListAccountsPageAsc(ctx context.Context, arg ListAccountsPageAscParams) ([]ListAccountsPageAscRow, error)
+	ListAccountsPageDesc(ctx context.Context, arg ListAccountsPageDescParams) ([]ListAccountsPageDescRow, error)
 	ListImages(ctx context.Context, arg ListImagesParams) ([]Image, error)
+	ListImagesByCreatedAsc(ctx context.Context, arg ListImagesByCreatedAscParams) ([]ListImagesByCreatedAscRow, error)
+	ListImagesByCreatedDesc(ctx context.Context, arg ListImagesByCreatedDescParams) ([]ListImagesByCreatedDescRow, error)
+	ListImagesBySizeAsc(ctx context.Context, arg ListImagesBySizeAscParams) ([]ListImagesBySizeAscRow, error)
+	ListImagesBySizeDesc(ctx context.Context, arg ListImagesBySizeDescParams) ([]ListImagesBySizeDescRow, error)
+	ListImagesByTakenAtAsc(ctx context.Context, arg ListImagesByTakenAtAscParams) ([]ListImagesByTakenAtAscRow, error)
+	ListImagesByTakenAtDesc(ctx context.Context, arg ListImagesByTakenAtDescParams) ([]ListImagesByTakenAtDescRow, error)
+	ListImagesByUpdatedAsc(ctx context.Context, arg ListImagesByUpdatedAscParams) ([]ListImagesByUpdatedAscRow, error)
+	ListImagesByUpdatedDesc(ctx context.Context, arg ListImagesByUpdatedDescParams) ([]ListImagesByUpdatedDescRow, error)
+	ListUsernameCollisions(ctx context.Context) ([]ListUsernameCollisionsRow, error)
+	LockUsername(ctx context.Context, username string) error
+	MarkAccountExportReady(ctx context.Context, arg MarkAccountExportReadyParams) error
+	MarkSessionRotated(ctx context.Context, id int64) (int64, error)
+	RevokeAccountSessions(ctx context.Context, accountID int64) ([]RevokeAccountSessionsRow, error)
+	RevokeOauthGrant(ctx context.Context, arg RevokeOauthGrantParams) ([]RevokeOauthGrantRow, error)
+	RevokeOauthRefreshToken(ctx context.Context, id int64) (int64, error)
+	RevokeSessionFamily(ctx context.Context, familyID string) ([]RevokeSessionFamilyRow, error)
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	SetAccountTotpSecret(ctx context.Context, arg SetAccountTotpSecretParams) (int64, error)
 	SoftDeleteAccount(ctx context.Context, id int64) error
 	SoftDeleteImage(ctx context.Context, id int64) error
+	TouchAccountIdentity(ctx context.Context, arg TouchAccountIdentityParams) error
+	TouchApiKey(ctx context.Context, id int64) error
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) error
+	UpdateAccountCredentials(ctx context.Context, arg UpdateAccountCredentialsParams) error
+	UpdateAccountEmail(ctx context.Context, arg UpdateAccountEmailParams) error
+	UpdateAccountRole(ctx context.Context, arg UpdateAccountRoleParams) (int64, error)
+	UpdateAccountUsername(ctx context.Context, arg UpdateAccountUsernameParams) error
 	UpdateImage(ctx context.Context, arg UpdateImageParams) error
+	UpdateProfileAvatar(ctx context.Context, arg UpdateProfileAvatarParams) error
+	UpdateSessionAccessToken(ctx context.Context, arg UpdateSessionAccessTokenParams) error
+	UpdateWebauthnCredentialSignCount(ctx context.Context, arg UpdateWebauthnCredentialSignCountParams) error
+	UpsertProfile(ctx context.Context, arg UpsertProfileParams) error
+	UseAccountToken(ctx context.Context, arg UseAccountTokenParams) (UseAccountTokenRow, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTotpStep(ctx context.Context, arg UseTotpStepParams) (int64, error)
+	VerifyAccountEmail(ctx context.Context, arg VerifyAccountEmailParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
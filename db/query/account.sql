@@ -7,10 +7,27 @@ WHERE id = $1 LIMIT 1;
 
 -- name: GetAccountByUsername :one
 SELECT * FROM account
-WHERE username = $1 LIMIT 1;
+WHERE LOWER(NORMALIZE(username, NFKC)) = LOWER(NORMALIZE($1, NFKC)) LIMIT 1;
 
 -- name: ListAccounts :many
-SELECT id, username, created, deleted FROM account LIMIT $1 OFFSET $2;
+SELECT id, username, role, created, deleted FROM account LIMIT $1 OFFSET $2;
+
+-- name: ListAccountsPageDesc :many
+SELECT id, username, role, created, deleted FROM account
+WHERE sqlc.narg('cursor_created')::varchar IS NULL
+   OR (created::timestamptz, id) < (CAST(sqlc.narg('cursor_created')::varchar AS timestamptz), sqlc.narg('cursor_id')::bigint)
+ORDER BY created::timestamptz DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListAccountsPageAsc :many
+SELECT id, username, role, created, deleted FROM account
+WHERE sqlc.narg('cursor_created')::varchar IS NULL
+   OR (created::timestamptz, id) > (CAST(sqlc.narg('cursor_created')::varchar AS timestamptz), sqlc.narg('cursor_id')::bigint)
+ORDER BY created::timestamptz ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListAccountPhrases :many
+SELECT phrase FROM account;
 
 -- name: CreateAccount :one
 INSERT INTO account (
@@ -35,3 +52,40 @@ SET phrase = $1, updated = NOW()
 WHERE id = $2
 RETURNING updated;
 
+-- name: GetAccountUsernameForUpdate :one
+SELECT username FROM account
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateAccountUsername :exec
+UPDATE account
+SET username = $1, updated = NOW()
+WHERE id = $2;
+
+-- name: UpdateAccountCredentials :exec
+UPDATE account
+SET phrase = $1, salt = $2, updated = NOW()
+WHERE id = $3;
+
+-- name: UpdateAccountRole :execrows
+UPDATE account
+SET role = $1, updated = NOW()
+WHERE id = $2;
+
+-- name: ListUsernameCollisions :many
+SELECT account_id, previous_username, username, created FROM username_collision
+ORDER BY account_id;
+
+-- name: GetAccountByEmail :one
+SELECT * FROM account
+WHERE LOWER(email) = LOWER(sqlc.arg('email')) AND email <> '' LIMIT 1;
+
+-- name: UpdateAccountEmail :exec
+UPDATE account
+SET email = $1, email_verified = FALSE, updated = NOW()
+WHERE id = $2;
+
+-- name: VerifyAccountEmail :execrows
+UPDATE account
+SET email_verified = TRUE, updated = NOW()
+WHERE id = $1 AND email = $2;
@@ -0,0 +1,56 @@
+-- name: CreateAccountExport :one
+INSERT INTO account_export (
+  account_id
+) VALUES (
+  $1
+)
+RETURNING *;
+
+-- name: GetAccountExport :one
+SELECT * FROM account_export
+WHERE id = $1 AND account_id = $2 LIMIT 1;
+
+-- name: GetLatestAccountExport :one
+SELECT * FROM account_export
+WHERE account_id = $1
+ORDER BY created DESC, id DESC LIMIT 1;
+
+-- name: ClaimAccountExport :one
+UPDATE account_export
+SET status = 'running', started = NOW()
+WHERE id = (
+  SELECT id FROM account_export
+  WHERE status = 'pending' OR (status = 'running' AND started < $1)
+  ORDER BY created, id
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CreateAccountExportArchive :exec
+INSERT INTO account_export_archive (
+  export_id, data
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (export_id) DO UPDATE SET data = EXCLUDED.data;
+
+-- name: MarkAccountExportReady :exec
+UPDATE account_export
+SET status = 'ready', error = '', size = $1, completed = NOW(), expires = $2
+WHERE id = $3;
+
+-- name: FailAccountExport :exec
+UPDATE account_export
+SET status = 'failed', error = $1, completed = NOW(), expires = $2
+WHERE id = $3;
+
+-- name: GetAccountExportArchive :one
+SELECT account_export_archive.data FROM account_export_archive
+JOIN account_export ON account_export.id = account_export_archive.export_id
+WHERE account_export.id = $1 AND account_export.status = 'ready' AND account_export.expires > NOW()
+LIMIT 1;
+
+-- name: DeleteExpiredAccountExports :execrows
+DELETE FROM account_export
+WHERE expires < NOW();
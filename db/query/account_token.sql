@@ -0,0 +1,16 @@
+-- name: CreateAccountToken :exec
+INSERT INTO account_token (
+  account_id, purpose, token_hash, email, expires
+) VALUES (
+  $1, $2, $3, $4, $5
+);
+
+-- name: DeleteAccountTokens :exec
+DELETE FROM account_token
+WHERE account_id = $1 AND purpose = $2 AND used IS NULL;
+
+-- name: UseAccountToken :one
+UPDATE account_token
+SET used = NOW()
+WHERE token_hash = $1 AND purpose = $2 AND used IS NULL AND expires > NOW()
+RETURNING account_id, email;
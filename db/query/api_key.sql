@@ -0,0 +1,27 @@
+-- name: CreateApiKey :one
+INSERT INTO api_key (
+  account_id, name, prefix, token_hash, scopes, expires
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetApiKeyByPrefix :one
+SELECT api_key.id, api_key.account_id, account.username, account.role, api_key.token_hash, api_key.scopes, api_key.expires
+FROM api_key
+JOIN account ON account.id = api_key.account_id
+WHERE api_key.prefix = $1 LIMIT 1;
+
+-- name: ListAccountApiKeys :many
+SELECT * FROM api_key
+WHERE account_id = $1
+ORDER BY id DESC;
+
+-- name: DeleteAccountApiKey :execrows
+DELETE FROM api_key
+WHERE id = $1 AND account_id = $2;
+
+-- name: TouchApiKey :exec
+UPDATE api_key
+SET last_used = NOW()
+WHERE id = $1 AND (last_used IS NULL OR last_used < NOW() - INTERVAL '1 minute');
@@ -8,9 +8,9 @@ SELECT * FROM image LIMIT $1 OFFSET $2;
 
 -- name: CreateImage :one
 INSERT INTO image (
-  data, created
+  data, mime_type, size, width, height, taken_at, created
 ) VALUES (
-  $1, NOW()
+  $1, $2, $3, $4, $5, $6, NOW()
 )
 RETURNING *;
 
@@ -29,3 +29,125 @@ SET memo = $1, updated = NOW()
 WHERE id = $2
 RETURNING updated;
 
+
+-- name: ListImagesByCreatedDesc :many
+SELECT id, CAST(CASE WHEN sqlc.arg('include_data')::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE (sqlc.narg('cursor_value')::varchar IS NULL
+    OR (created::timestamptz, id) < (CAST(sqlc.narg('cursor_value')::varchar AS timestamptz), sqlc.narg('cursor_id')::bigint))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created::timestamptz > sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created::timestamptz < sqlc.narg('created_before')::timestamptz)
+  AND (sqlc.narg('has_memo')::bool IS NULL OR (memo <> '') = sqlc.narg('has_memo')::bool)
+  AND (sqlc.narg('mime_type')::text IS NULL OR mime_type = sqlc.narg('mime_type')::text)
+ORDER BY created::timestamptz DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListImagesByCreatedAsc :many
+SELECT id, CAST(CASE WHEN sqlc.arg('include_data')::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE (sqlc.narg('cursor_value')::varchar IS NULL
+    OR (created::timestamptz, id) > (CAST(sqlc.narg('cursor_value')::varchar AS timestamptz), sqlc.narg('cursor_id')::bigint))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created::timestamptz > sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created::timestamptz < sqlc.narg('created_before')::timestamptz)
+  AND (sqlc.narg('has_memo')::bool IS NULL OR (memo <> '') = sqlc.narg('has_memo')::bool)
+  AND (sqlc.narg('mime_type')::text IS NULL OR mime_type = sqlc.narg('mime_type')::text)
+ORDER BY created::timestamptz ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListImagesByUpdatedDesc :many
+SELECT id, CAST(CASE WHEN sqlc.arg('include_data')::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE (sqlc.narg('cursor_value')::varchar IS NULL
+    OR (COALESCE(NULLIF(updated, '')::timestamptz, created::timestamptz), id) < (CAST(sqlc.narg('cursor_value')::varchar AS timestamptz), sqlc.narg('cursor_id')::bigint))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created::timestamptz > sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created::timestamptz < sqlc.narg('created_before')::timestamptz)
+  AND (sqlc.narg('has_memo')::bool IS NULL OR (memo <> '') = sqlc.narg('has_memo')::bool)
+  AND (sqlc.narg('mime_type')::text IS NULL OR mime_type = sqlc.narg('mime_type')::text)
+ORDER BY COALESCE(NULLIF(updated, '')::timestamptz, created::timestamptz) DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListImagesByUpdatedAsc :many
+SELECT id, CAST(CASE WHEN sqlc.arg('include_data')::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE (sqlc.narg('cursor_value')::varchar IS NULL
+    OR (COALESCE(NULLIF(updated, '')::timestamptz, created::timestamptz), id) > (CAST(sqlc.narg('cursor_value')::varchar AS timestamptz), sqlc.narg('cursor_id')::bigint))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created::timestamptz > sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created::timestamptz < sqlc.narg('created_before')::timestamptz)
+  AND (sqlc.narg('has_memo')::bool IS NULL OR (memo <> '') = sqlc.narg('has_memo')::bool)
+  AND (sqlc.narg('mime_type')::text IS NULL OR mime_type = sqlc.narg('mime_type')::text)
+ORDER BY COALESCE(NULLIF(updated, '')::timestamptz, created::timestamptz) ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListImagesByTakenAtDesc :many
+SELECT id, CAST(CASE WHEN sqlc.arg('include_data')::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE (sqlc.narg('cursor_value')::varchar IS NULL
+    OR (COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz), id) < (CAST(sqlc.narg('cursor_value')::varchar AS timestamptz), sqlc.narg('cursor_id')::bigint))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created::timestamptz > sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created::timestamptz < sqlc.narg('created_before')::timestamptz)
+  AND (sqlc.narg('has_memo')::bool IS NULL OR (memo <> '') = sqlc.narg('has_memo')::bool)
+  AND (sqlc.narg('mime_type')::text IS NULL OR mime_type = sqlc.narg('mime_type')::text)
+ORDER BY COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz) DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListImagesByTakenAtAsc :many
+SELECT id, CAST(CASE WHEN sqlc.arg('include_data')::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE (sqlc.narg('cursor_value')::varchar IS NULL
+    OR (COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz), id) > (CAST(sqlc.narg('cursor_value')::varchar AS timestamptz), sqlc.narg('cursor_id')::bigint))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created::timestamptz > sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created::timestamptz < sqlc.narg('created_before')::timestamptz)
+  AND (sqlc.narg('has_memo')::bool IS NULL OR (memo <> '') = sqlc.narg('has_memo')::bool)
+  AND (sqlc.narg('mime_type')::text IS NULL OR mime_type = sqlc.narg('mime_type')::text)
+ORDER BY COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz) ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListImagesBySizeDesc :many
+SELECT id, CAST(CASE WHEN sqlc.arg('include_data')::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE (sqlc.narg('cursor_value')::varchar IS NULL
+    OR (size, id) < (CAST(sqlc.narg('cursor_value')::varchar AS bigint), sqlc.narg('cursor_id')::bigint))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created::timestamptz > sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created::timestamptz < sqlc.narg('created_before')::timestamptz)
+  AND (sqlc.narg('has_memo')::bool IS NULL OR (memo <> '') = sqlc.narg('has_memo')::bool)
+  AND (sqlc.narg('mime_type')::text IS NULL OR mime_type = sqlc.narg('mime_type')::text)
+ORDER BY size DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListImagesBySizeAsc :many
+SELECT id, CAST(CASE WHEN sqlc.arg('include_data')::bool THEN data ELSE '' END AS text) AS data, memo, created, updated, deleted, mime_type, size, taken_at, width, height
+FROM image
+WHERE (sqlc.narg('cursor_value')::varchar IS NULL
+    OR (size, id) > (CAST(sqlc.narg('cursor_value')::varchar AS bigint), sqlc.narg('cursor_id')::bigint))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created::timestamptz > sqlc.narg('created_after')::timestamptz)
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created::timestamptz < sqlc.narg('created_before')::timestamptz)
+  AND (sqlc.narg('has_memo')::bool IS NULL OR (memo <> '') = sqlc.narg('has_memo')::bool)
+  AND (sqlc.narg('mime_type')::text IS NULL OR mime_type = sqlc.narg('mime_type')::text)
+ORDER BY size ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+
+-- name: CreateImageOwner :exec
+INSERT INTO image_owner (
+  image_id, account_id
+) VALUES (
+  $1, $2
+);
+
+-- name: ListAccountImages :many
+SELECT image.* FROM image
+JOIN image_owner ON image_owner.image_id = image.id
+WHERE image_owner.account_id = $1
+ORDER BY image.id;
+
+-- name: ImageTimeline :many
+SELECT bucket::timestamp AS bucket, image_count::bigint AS image_count, image_ids::bigint[] AS image_ids FROM (
+  SELECT date_trunc(sqlc.arg('granularity')::text, COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz) AT TIME ZONE sqlc.arg('tz')::text) AS bucket,
+         COUNT(*) AS image_count,
+         (array_agg(id ORDER BY COALESCE(NULLIF(taken_at, '')::timestamptz, created::timestamptz) DESC, id DESC))[1:sqlc.arg('sample_size')::int] AS image_ids
+  FROM image
+  GROUP BY 1
+) AS timeline
+WHERE sqlc.narg('cursor_bucket')::timestamp IS NULL OR bucket < sqlc.narg('cursor_bucket')::timestamp
+ORDER BY bucket DESC
+LIMIT sqlc.arg('limit');
@@ -1,47 +1,30 @@
--- CREATE TABLE "login" (
---   "id"        BIGSERIAL PRIMARY KEY,
---   "ipaddress" TEXT NOT NULL DEFAULT '',
---   "headers"   TEXT NOT NULL DEFAULT '',
---   "token"     VARCHAR NOT NULL,
---   "valid"     BOOLEAN NOT NULL DEFAULT TRUE  
---   "created"   VARCHAR NOT NULL DEFAULT NOW()
--- );
-
--- name: LoginExists :one
--- SELECT EXISTS(SELECT 1 FROM account WHERE id = $1);
-
--- name: GetLogin :one
--- SELECT * FROM "login"
--- WHERE id = $1 LIMIT 1;
-
--- -- name: GetCountLoginByIpAddress :one
--- -- SELECT * FROM "login"
--- -- WHERE ipaddress = $1 LIMIT 1;
-
--- -- name: ListAccounts :many
--- SELECT * FROM account LIMIT $1 OFFSET $2;
-
--- -- name: CreateAccount :one
--- INSERT INTO account (
---   username, phrase, salt, created
--- ) VALUES (
---   $1, $2, $3, NOW()
--- )
--- RETURNING *;
-
--- -- name: SoftDeleteAccount :exec
--- UPDATE account
--- SET deleted = 1
--- WHERE id = $1;
-
--- -- name: DeleteAccount :exec
--- DELETE FROM account
--- WHERE id = $1;
-
--- -- name: UpdateAccount :exec
--- UPDATE account
--- SET phrase = $1, updated = NOW()
--- WHERE id = $2
--- RETURNING updated;
-
-
+-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempt (
+  account_id, username, ip, user_agent, success, reason
+) VALUES (
+  (SELECT id FROM account WHERE LOWER(NORMALIZE(account.username, NFKC)) = LOWER(NORMALIZE($1, NFKC))), $1, $2, $3, $4, $5
+);
+
+-- name: CountUsernameLoginFailures :one
+SELECT COUNT(*)::bigint AS failures, MAX(created)::timestamptz AS last_failure FROM login_attempt
+WHERE LOWER(NORMALIZE(username, NFKC)) = LOWER(NORMALIZE($1, NFKC)) AND NOT success AND NOT cleared AND reason <> 'throttled' AND created > $2;
+
+-- name: CountIPLoginFailures :one
+SELECT COUNT(*)::bigint AS failures, MAX(created)::timestamptz AS last_failure FROM login_attempt
+WHERE ip = $1 AND NOT success AND NOT cleared AND reason <> 'throttled' AND created > $2;
+
+-- name: ClearUsernameLoginFailures :execrows
+UPDATE login_attempt
+SET cleared = TRUE
+WHERE LOWER(NORMALIZE(username, NFKC)) = LOWER(NORMALIZE($1, NFKC)) AND NOT success AND NOT cleared;
+
+-- name: ListAccountLoginAttempts :many
+SELECT username, ip, user_agent, success, reason, created FROM login_attempt
+WHERE account_id = $1
+ORDER BY created DESC
+LIMIT $2;
+
+-- name: ClearIPLoginFailures :execrows
+UPDATE login_attempt
+SET cleared = TRUE
+WHERE ip = $1 AND NOT success AND NOT cleared;
@@ -0,0 +1,35 @@
+-- name: SetAccountTotpSecret :execrows
+UPDATE account
+SET totp_secret = $1, totp_last_step = 0, updated = NOW()
+WHERE id = $2 AND NOT totp_enabled;
+
+-- name: EnableAccountTotp :execrows
+UPDATE account
+SET totp_enabled = TRUE, updated = NOW()
+WHERE id = $1 AND totp_secret <> '' AND NOT totp_enabled;
+
+-- name: DisableAccountTotp :exec
+UPDATE account
+SET totp_secret = '', totp_enabled = FALSE, updated = NOW()
+WHERE id = $1;
+
+-- name: UseTotpStep :execrows
+UPDATE account
+SET totp_last_step = $1
+WHERE id = $2 AND totp_last_step < $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_code (
+  account_id, code_hash
+) VALUES (
+  $1, $2
+);
+
+-- name: DeleteAccountRecoveryCodes :exec
+DELETE FROM recovery_code
+WHERE account_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_code
+SET used = NOW()
+WHERE account_id = $1 AND code_hash = $2 AND used IS NULL;
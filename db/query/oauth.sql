@@ -0,0 +1,72 @@
+-- name: CreateOauthClient :one
+INSERT INTO oauth_client (
+  client_id, account_id, name, redirect_uris, scopes, secret_hash
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetOauthClient :one
+SELECT * FROM oauth_client
+WHERE client_id = $1 LIMIT 1;
+
+-- name: ListAccountOauthClients :many
+SELECT * FROM oauth_client
+WHERE account_id = $1
+ORDER BY id DESC;
+
+-- name: DeleteAccountOauthClient :execrows
+DELETE FROM oauth_client
+WHERE client_id = $1 AND account_id = $2;
+
+-- name: CreateOauthAuthorization :exec
+WITH expired AS (
+  DELETE FROM oauth_authorization WHERE expires < NOW()
+)
+INSERT INTO oauth_authorization (
+  ticket, client_id, account_id, redirect_uri, scope, state, code_challenge, expires
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: ApproveOauthAuthorization :one
+UPDATE oauth_authorization
+SET code_hash = $1, expires = $2
+WHERE ticket = $3 AND account_id = $4 AND code_hash IS NULL AND expires > NOW()
+RETURNING redirect_uri, state;
+
+-- name: DenyOauthAuthorization :one
+DELETE FROM oauth_authorization
+WHERE ticket = $1 AND account_id = $2 AND code_hash IS NULL
+RETURNING redirect_uri, state;
+
+-- name: ConsumeOauthCode :one
+DELETE FROM oauth_authorization
+WHERE code_hash = $1 AND expires > NOW()
+RETURNING client_id, account_id, redirect_uri, scope, code_challenge;
+
+-- name: CreateOauthRefreshToken :exec
+INSERT INTO oauth_refresh_token (
+  token_hash, client_id, account_id, scope, access_jti, access_expires, expires
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: GetOauthRefreshToken :one
+SELECT oauth_refresh_token.id, oauth_refresh_token.client_id, oauth_refresh_token.account_id, account.username, account.role,
+  oauth_refresh_token.scope, oauth_refresh_token.access_jti, oauth_refresh_token.access_expires, oauth_refresh_token.expires,
+  oauth_refresh_token.revoked, oauth_refresh_token.created
+FROM oauth_refresh_token
+JOIN account ON account.id = oauth_refresh_token.account_id
+WHERE oauth_refresh_token.token_hash = $1 LIMIT 1;
+
+-- name: RevokeOauthRefreshToken :execrows
+UPDATE oauth_refresh_token
+SET revoked = TRUE
+WHERE id = $1 AND NOT revoked;
+
+-- name: RevokeOauthGrant :many
+UPDATE oauth_refresh_token
+SET revoked = TRUE
+WHERE client_id = $1 AND account_id = $2 AND NOT revoked
+RETURNING access_jti, access_expires;
@@ -0,0 +1,40 @@
+-- name: CreateOidcState :exec
+WITH expired AS (
+  DELETE FROM oidc_state WHERE expires < NOW()
+)
+INSERT INTO oidc_state (
+  state, provider, nonce, code_verifier, account_id, expires
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+);
+
+-- name: ConsumeOidcState :one
+DELETE FROM oidc_state
+WHERE state = $1 AND provider = $2 AND expires > NOW()
+RETURNING nonce, code_verifier, account_id;
+
+-- name: CreateAccountIdentity :one
+INSERT INTO account_identity (
+  account_id, provider, subject, email
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetAccountIdentity :one
+SELECT * FROM account_identity
+WHERE provider = $1 AND subject = $2 LIMIT 1;
+
+-- name: ListAccountIdentities :many
+SELECT * FROM account_identity
+WHERE account_id = $1
+ORDER BY id DESC;
+
+-- name: TouchAccountIdentity :exec
+UPDATE account_identity
+SET email = $1, last_used = NOW()
+WHERE id = $2;
+
+-- name: DeleteAccountIdentity :execrows
+DELETE FROM account_identity
+WHERE id = $1 AND account_id = $2;
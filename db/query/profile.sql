@@ -0,0 +1,27 @@
+-- name: GetProfile :one
+SELECT account_id, display_name, bio, locale, timezone, visibility, show_timezone, avatar_mime_type <> '' AS has_avatar, updated
+FROM profile
+WHERE account_id = $1 LIMIT 1;
+
+-- name: UpsertProfile :exec
+INSERT INTO profile (
+  account_id, display_name, bio, locale, timezone, visibility, show_timezone
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (account_id) DO UPDATE
+SET display_name = EXCLUDED.display_name, bio = EXCLUDED.bio, locale = EXCLUDED.locale, timezone = EXCLUDED.timezone,
+    visibility = EXCLUDED.visibility, show_timezone = EXCLUDED.show_timezone, updated = NOW();
+
+-- name: UpdateProfileAvatar :exec
+INSERT INTO profile (
+  account_id, avatar, avatar_mime_type
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (account_id) DO UPDATE
+SET avatar = EXCLUDED.avatar, avatar_mime_type = EXCLUDED.avatar_mime_type, updated = NOW();
+
+-- name: GetProfileAvatar :one
+SELECT avatar, avatar_mime_type, visibility, updated FROM profile
+WHERE account_id = $1 AND avatar <> '' LIMIT 1;
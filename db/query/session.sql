@@ -0,0 +1,57 @@
+-- name: CreateSession :one
+INSERT INTO session (
+  account_id, family_id, token_hash, expires, user_agent, ip, access_jti, access_expires, created
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, NOW()
+)
+RETURNING *;
+
+-- name: GetSessionByTokenHash :one
+SELECT * FROM session
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: GetAccountSession :one
+SELECT * FROM session
+WHERE id = $1 AND account_id = $2 LIMIT 1;
+
+-- name: ListAccountSessions :many
+SELECT * FROM session
+WHERE account_id = $1 AND NOT rotated AND NOT revoked AND expires > NOW()
+ORDER BY last_seen DESC, id DESC;
+
+-- name: UpdateSessionAccessToken :exec
+UPDATE session
+SET access_jti = $1, access_expires = $2
+WHERE account_id = $3 AND access_jti = sqlc.arg('previous_jti') AND NOT revoked;
+
+-- name: MarkSessionRotated :execrows
+UPDATE session
+SET rotated = TRUE
+WHERE id = $1 AND NOT rotated AND NOT revoked;
+
+-- name: RevokeSessionFamily :many
+UPDATE session
+SET revoked = TRUE
+WHERE family_id = $1 AND NOT revoked
+RETURNING access_jti, access_expires;
+
+-- name: RevokeAccountSessions :many
+UPDATE session
+SET revoked = TRUE
+WHERE account_id = $1 AND NOT revoked
+RETURNING access_jti, access_expires;
+
+-- name: RevokeToken :exec
+INSERT INTO revoked_token (
+  jti, expires
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS(SELECT 1 FROM revoked_token WHERE jti = $1);
+
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_token
+WHERE expires < NOW();
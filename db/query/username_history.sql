@@ -0,0 +1,29 @@
+-- name: CreateUsernameHistory :exec
+INSERT INTO username_history (
+  account_id, username, expires
+) VALUES (
+  $1, $2, $3
+);
+
+-- name: LockUsername :exec
+-- locks the username until the transaction ends, taken by whatever creates an account with it or gives it up
+SELECT pg_advisory_xact_lock(hashtext(LOWER(NORMALIZE(sqlc.arg(username)::text, NFKC))));
+
+-- name: GetUsernameHistory :one
+SELECT * FROM username_history
+WHERE LOWER(NORMALIZE(username, NFKC)) = LOWER(NORMALIZE($1, NFKC)) AND expires > NOW()
+ORDER BY created DESC LIMIT 1;
+
+-- name: GetLastUsernameChange :one
+SELECT created FROM username_history
+WHERE account_id = $1
+ORDER BY created DESC LIMIT 1;
+
+-- name: DeleteAccountUsernameHistory :exec
+DELETE FROM username_history
+WHERE account_id = $1 AND LOWER(NORMALIZE(username, NFKC)) = LOWER(NORMALIZE($2, NFKC));
+
+-- name: ListAccountUsernameHistory :many
+SELECT * FROM username_history
+WHERE account_id = $1
+ORDER BY created DESC;
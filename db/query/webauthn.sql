@@ -0,0 +1,40 @@
+-- name: CreateWebauthnChallenge :exec
+WITH expired AS (
+  DELETE FROM webauthn_challenge WHERE expires < NOW()
+)
+INSERT INTO webauthn_challenge (
+  challenge, account_id, ceremony, expires
+) VALUES (
+  $1, $2, $3, $4
+);
+
+-- name: ConsumeWebauthnChallenge :one
+DELETE FROM webauthn_challenge
+WHERE challenge = $1 AND ceremony = $2 AND expires > NOW()
+RETURNING account_id;
+
+-- name: CreateWebauthnCredential :one
+INSERT INTO webauthn_credential (
+  account_id, credential_id, public_key, sign_count, name
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetWebauthnCredential :one
+SELECT * FROM webauthn_credential
+WHERE credential_id = $1 LIMIT 1;
+
+-- name: ListAccountWebauthnCredentials :many
+SELECT * FROM webauthn_credential
+WHERE account_id = $1
+ORDER BY id DESC;
+
+-- name: UpdateWebauthnCredentialSignCount :exec
+UPDATE webauthn_credential
+SET sign_count = $1, last_used = NOW()
+WHERE id = $2;
+
+-- name: DeleteAccountWebauthnCredential :execrows
+DELETE FROM webauthn_credential
+WHERE id = $1 AND account_id = $2;
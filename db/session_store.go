@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrSessionReused is returned by RotateSession when the session has already been rotated or revoked,
+// meaning its refresh token was presented more than once.
+var ErrSessionReused = errors.New("session has already been used")
+
+type RotateSessionParams struct {
+	ID   int64               `json:"id"`
+	Next CreateSessionParams `json:"next"`
+}
+
+// RotateSession marks the session rotated and creates its replacement in the same family within one
+// transaction. Only one caller can rotate a session, the others getting ErrSessionReused.
+func (store *SQLStore) RotateSession(ctx context.Context, arg RotateSessionParams) (Session, error) {
+	var session Session
+	err := store.execTx(ctx, func(q *Queries) error {
+		rows, err := q.MarkSessionRotated(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrSessionReused
+		}
+		session, err = q.CreateSession(ctx, arg.Next)
+		return err
+	})
+	return session, err
+}
@@ -8,6 +8,15 @@ import (
 
 type Store interface {
 	Querier
+	SearchImages(ctx context.Context, arg SearchImagesParams) ([]Image, error)
+	CreateAccountImage(ctx context.Context, arg CreateAccountImageParams) (Image, error)
+	RotateSession(ctx context.Context, arg RotateSessionParams) (Session, error)
+	EnableTotp(ctx context.Context, arg EnableTotpParams) error
+	DisableTotp(ctx context.Context, accountID int64) error
+	CreateAccountWithIdentity(ctx context.Context, arg CreateAccountWithIdentityParams) (Account, error)
+	RenameAccount(ctx context.Context, arg RenameAccountParams) error
+	RegisterAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CompleteAccountExport(ctx context.Context, arg CompleteAccountExportParams) error
 	Tx(ctx context.Context, cb func(*Queries, *interface{}) (interface{}, error)) (interface{}, error)
 }
 
@@ -6,7 +6,9 @@ package db
 
 import (
 	context "context"
+	sql "database/sql"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -49,137 +51,1524 @@ func (mr *MockStoreMockRecorder) AccountExists(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccountExists", reflect.TypeOf((*MockStore)(nil).AccountExists), arg0, arg1)
 }
 
+// ApproveOauthAuthorization mocks base method.
+func (m *MockStore) ApproveOauthAuthorization(arg0 context.Context, arg1 ApproveOauthAuthorizationParams) (ApproveOauthAuthorizationRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveOauthAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(ApproveOauthAuthorizationRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveOauthAuthorization indicates an expected call of ApproveOauthAuthorization.
+func (mr *MockStoreMockRecorder) ApproveOauthAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveOauthAuthorization", reflect.TypeOf((*MockStore)(nil).ApproveOauthAuthorization), arg0, arg1)
+}
+
+// ClaimAccountExport mocks base method.
+func (m *MockStore) ClaimAccountExport(arg0 context.Context, arg1 time.Time) (AccountExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimAccountExport", arg0, arg1)
+	ret0, _ := ret[0].(AccountExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimAccountExport indicates an expected call of ClaimAccountExport.
+func (mr *MockStoreMockRecorder) ClaimAccountExport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimAccountExport", reflect.TypeOf((*MockStore)(nil).ClaimAccountExport), arg0, arg1)
+}
+
+// ClearIPLoginFailures mocks base method.
+func (m *MockStore) ClearIPLoginFailures(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearIPLoginFailures", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClearIPLoginFailures indicates an expected call of ClearIPLoginFailures.
+func (mr *MockStoreMockRecorder) ClearIPLoginFailures(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearIPLoginFailures", reflect.TypeOf((*MockStore)(nil).ClearIPLoginFailures), arg0, arg1)
+}
+
+// ClearUsernameLoginFailures mocks base method.
+func (m *MockStore) ClearUsernameLoginFailures(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearUsernameLoginFailures", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClearUsernameLoginFailures indicates an expected call of ClearUsernameLoginFailures.
+func (mr *MockStoreMockRecorder) ClearUsernameLoginFailures(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearUsernameLoginFailures", reflect.TypeOf((*MockStore)(nil).ClearUsernameLoginFailures), arg0, arg1)
+}
+
+// CompleteAccountExport mocks base method.
+func (m *MockStore) CompleteAccountExport(arg0 context.Context, arg1 CompleteAccountExportParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteAccountExport", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteAccountExport indicates an expected call of CompleteAccountExport.
+func (mr *MockStoreMockRecorder) CompleteAccountExport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteAccountExport", reflect.TypeOf((*MockStore)(nil).CompleteAccountExport), arg0, arg1)
+}
+
+// ConsumeOauthCode mocks base method.
+func (m *MockStore) ConsumeOauthCode(arg0 context.Context, arg1 []byte) (ConsumeOauthCodeRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOauthCode", arg0, arg1)
+	ret0, _ := ret[0].(ConsumeOauthCodeRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOauthCode indicates an expected call of ConsumeOauthCode.
+func (mr *MockStoreMockRecorder) ConsumeOauthCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOauthCode", reflect.TypeOf((*MockStore)(nil).ConsumeOauthCode), arg0, arg1)
+}
+
+// ConsumeOidcState mocks base method.
+func (m *MockStore) ConsumeOidcState(arg0 context.Context, arg1 ConsumeOidcStateParams) (ConsumeOidcStateRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOidcState", arg0, arg1)
+	ret0, _ := ret[0].(ConsumeOidcStateRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOidcState indicates an expected call of ConsumeOidcState.
+func (mr *MockStoreMockRecorder) ConsumeOidcState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOidcState", reflect.TypeOf((*MockStore)(nil).ConsumeOidcState), arg0, arg1)
+}
+
+// ConsumeWebauthnChallenge mocks base method.
+func (m *MockStore) ConsumeWebauthnChallenge(arg0 context.Context, arg1 ConsumeWebauthnChallengeParams) (sql.NullInt64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeWebauthnChallenge", arg0, arg1)
+	ret0, _ := ret[0].(sql.NullInt64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeWebauthnChallenge indicates an expected call of ConsumeWebauthnChallenge.
+func (mr *MockStoreMockRecorder) ConsumeWebauthnChallenge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeWebauthnChallenge", reflect.TypeOf((*MockStore)(nil).ConsumeWebauthnChallenge), arg0, arg1)
+}
+
+// CountIPLoginFailures mocks base method.
+func (m *MockStore) CountIPLoginFailures(arg0 context.Context, arg1 CountIPLoginFailuresParams) (CountIPLoginFailuresRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountIPLoginFailures", arg0, arg1)
+	ret0, _ := ret[0].(CountIPLoginFailuresRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountIPLoginFailures indicates an expected call of CountIPLoginFailures.
+func (mr *MockStoreMockRecorder) CountIPLoginFailures(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountIPLoginFailures", reflect.TypeOf((*MockStore)(nil).CountIPLoginFailures), arg0, arg1)
+}
+
+// CountUsernameLoginFailures mocks base method.
+func (m *MockStore) CountUsernameLoginFailures(arg0 context.Context, arg1 CountUsernameLoginFailuresParams) (CountUsernameLoginFailuresRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsernameLoginFailures", arg0, arg1)
+	ret0, _ := ret[0].(CountUsernameLoginFailuresRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsernameLoginFailures indicates an expected call of CountUsernameLoginFailures.
+func (mr *MockStoreMockRecorder) CountUsernameLoginFailures(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsernameLoginFailures", reflect.TypeOf((*MockStore)(nil).CountUsernameLoginFailures), arg0, arg1)
+}
+
 // CreateAccount mocks base method.
 func (m *MockStore) CreateAccount(arg0 context.Context, arg1 CreateAccountParams) (Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAccount", arg0, arg1)
-	ret0, _ := ret[0].(Account)
+	ret := m.ctrl.Call(m, "CreateAccount", arg0, arg1)
+	ret0, _ := ret[0].(Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccount indicates an expected call of CreateAccount.
+func (mr *MockStoreMockRecorder) CreateAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStore)(nil).CreateAccount), arg0, arg1)
+}
+
+// CreateAccountExport mocks base method.
+func (m *MockStore) CreateAccountExport(arg0 context.Context, arg1 int64) (AccountExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountExport", arg0, arg1)
+	ret0, _ := ret[0].(AccountExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccountExport indicates an expected call of CreateAccountExport.
+func (mr *MockStoreMockRecorder) CreateAccountExport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountExport", reflect.TypeOf((*MockStore)(nil).CreateAccountExport), arg0, arg1)
+}
+
+// CreateAccountExportArchive mocks base method.
+func (m *MockStore) CreateAccountExportArchive(arg0 context.Context, arg1 CreateAccountExportArchiveParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountExportArchive", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAccountExportArchive indicates an expected call of CreateAccountExportArchive.
+func (mr *MockStoreMockRecorder) CreateAccountExportArchive(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountExportArchive", reflect.TypeOf((*MockStore)(nil).CreateAccountExportArchive), arg0, arg1)
+}
+
+// CreateAccountIdentity mocks base method.
+func (m *MockStore) CreateAccountIdentity(arg0 context.Context, arg1 CreateAccountIdentityParams) (AccountIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountIdentity", arg0, arg1)
+	ret0, _ := ret[0].(AccountIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccountIdentity indicates an expected call of CreateAccountIdentity.
+func (mr *MockStoreMockRecorder) CreateAccountIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountIdentity", reflect.TypeOf((*MockStore)(nil).CreateAccountIdentity), arg0, arg1)
+}
+
+// CreateAccountImage mocks base method.
+func (m *MockStore) CreateAccountImage(arg0 context.Context, arg1 CreateAccountImageParams) (Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountImage", arg0, arg1)
+	ret0, _ := ret[0].(Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccountImage indicates an expected call of CreateAccountImage.
+func (mr *MockStoreMockRecorder) CreateAccountImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountImage", reflect.TypeOf((*MockStore)(nil).CreateAccountImage), arg0, arg1)
+}
+
+// CreateAccountToken mocks base method.
+func (m *MockStore) CreateAccountToken(arg0 context.Context, arg1 CreateAccountTokenParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAccountToken indicates an expected call of CreateAccountToken.
+func (mr *MockStoreMockRecorder) CreateAccountToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountToken", reflect.TypeOf((*MockStore)(nil).CreateAccountToken), arg0, arg1)
+}
+
+// CreateAccountWithIdentity mocks base method.
+func (m *MockStore) CreateAccountWithIdentity(arg0 context.Context, arg1 CreateAccountWithIdentityParams) (Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountWithIdentity", arg0, arg1)
+	ret0, _ := ret[0].(Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccountWithIdentity indicates an expected call of CreateAccountWithIdentity.
+func (mr *MockStoreMockRecorder) CreateAccountWithIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountWithIdentity", reflect.TypeOf((*MockStore)(nil).CreateAccountWithIdentity), arg0, arg1)
+}
+
+// CreateApiKey mocks base method.
+func (m *MockStore) CreateApiKey(arg0 context.Context, arg1 CreateApiKeyParams) (ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApiKey", arg0, arg1)
+	ret0, _ := ret[0].(ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApiKey indicates an expected call of CreateApiKey.
+func (mr *MockStoreMockRecorder) CreateApiKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApiKey", reflect.TypeOf((*MockStore)(nil).CreateApiKey), arg0, arg1)
+}
+
+// CreateImage mocks base method.
+func (m *MockStore) CreateImage(arg0 context.Context, arg1 CreateImageParams) (Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImage", arg0, arg1)
+	ret0, _ := ret[0].(Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateImage indicates an expected call of CreateImage.
+func (mr *MockStoreMockRecorder) CreateImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImage", reflect.TypeOf((*MockStore)(nil).CreateImage), arg0, arg1)
+}
+
+// CreateImageOwner mocks base method.
+func (m *MockStore) CreateImageOwner(arg0 context.Context, arg1 CreateImageOwnerParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImageOwner", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateImageOwner indicates an expected call of CreateImageOwner.
+func (mr *MockStoreMockRecorder) CreateImageOwner(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImageOwner", reflect.TypeOf((*MockStore)(nil).CreateImageOwner), arg0, arg1)
+}
+
+// CreateLoginAttempt mocks base method.
+func (m *MockStore) CreateLoginAttempt(arg0 context.Context, arg1 CreateLoginAttemptParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginAttempt", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLoginAttempt indicates an expected call of CreateLoginAttempt.
+func (mr *MockStoreMockRecorder) CreateLoginAttempt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginAttempt", reflect.TypeOf((*MockStore)(nil).CreateLoginAttempt), arg0, arg1)
+}
+
+// CreateOauthAuthorization mocks base method.
+func (m *MockStore) CreateOauthAuthorization(arg0 context.Context, arg1 CreateOauthAuthorizationParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOauthAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOauthAuthorization indicates an expected call of CreateOauthAuthorization.
+func (mr *MockStoreMockRecorder) CreateOauthAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOauthAuthorization", reflect.TypeOf((*MockStore)(nil).CreateOauthAuthorization), arg0, arg1)
+}
+
+// CreateOauthClient mocks base method.
+func (m *MockStore) CreateOauthClient(arg0 context.Context, arg1 CreateOauthClientParams) (OauthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOauthClient", arg0, arg1)
+	ret0, _ := ret[0].(OauthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOauthClient indicates an expected call of CreateOauthClient.
+func (mr *MockStoreMockRecorder) CreateOauthClient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOauthClient", reflect.TypeOf((*MockStore)(nil).CreateOauthClient), arg0, arg1)
+}
+
+// CreateOauthRefreshToken mocks base method.
+func (m *MockStore) CreateOauthRefreshToken(arg0 context.Context, arg1 CreateOauthRefreshTokenParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOauthRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOauthRefreshToken indicates an expected call of CreateOauthRefreshToken.
+func (mr *MockStoreMockRecorder) CreateOauthRefreshToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOauthRefreshToken", reflect.TypeOf((*MockStore)(nil).CreateOauthRefreshToken), arg0, arg1)
+}
+
+// CreateOidcState mocks base method.
+func (m *MockStore) CreateOidcState(arg0 context.Context, arg1 CreateOidcStateParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOidcState", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOidcState indicates an expected call of CreateOidcState.
+func (mr *MockStoreMockRecorder) CreateOidcState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOidcState", reflect.TypeOf((*MockStore)(nil).CreateOidcState), arg0, arg1)
+}
+
+// CreateRecoveryCode mocks base method.
+func (m *MockStore) CreateRecoveryCode(arg0 context.Context, arg1 CreateRecoveryCodeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecoveryCode", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRecoveryCode indicates an expected call of CreateRecoveryCode.
+func (mr *MockStoreMockRecorder) CreateRecoveryCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecoveryCode", reflect.TypeOf((*MockStore)(nil).CreateRecoveryCode), arg0, arg1)
+}
+
+// CreateSession mocks base method.
+func (m *MockStore) CreateSession(arg0 context.Context, arg1 CreateSessionParams) (Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", arg0, arg1)
+	ret0, _ := ret[0].(Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockStoreMockRecorder) CreateSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockStore)(nil).CreateSession), arg0, arg1)
+}
+
+// CreateUsernameHistory mocks base method.
+func (m *MockStore) CreateUsernameHistory(arg0 context.Context, arg1 CreateUsernameHistoryParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUsernameHistory", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUsernameHistory indicates an expected call of CreateUsernameHistory.
+func (mr *MockStoreMockRecorder) CreateUsernameHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUsernameHistory", reflect.TypeOf((*MockStore)(nil).CreateUsernameHistory), arg0, arg1)
+}
+
+// CreateWebauthnChallenge mocks base method.
+func (m *MockStore) CreateWebauthnChallenge(arg0 context.Context, arg1 CreateWebauthnChallengeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebauthnChallenge", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWebauthnChallenge indicates an expected call of CreateWebauthnChallenge.
+func (mr *MockStoreMockRecorder) CreateWebauthnChallenge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebauthnChallenge", reflect.TypeOf((*MockStore)(nil).CreateWebauthnChallenge), arg0, arg1)
+}
+
+// CreateWebauthnCredential mocks base method.
+func (m *MockStore) CreateWebauthnCredential(arg0 context.Context, arg1 CreateWebauthnCredentialParams) (WebauthnCredential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebauthnCredential", arg0, arg1)
+	ret0, _ := ret[0].(WebauthnCredential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebauthnCredential indicates an expected call of CreateWebauthnCredential.
+func (mr *MockStoreMockRecorder) CreateWebauthnCredential(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebauthnCredential", reflect.TypeOf((*MockStore)(nil).CreateWebauthnCredential), arg0, arg1)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockStore) DeleteAccount(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockStoreMockRecorder) DeleteAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockStore)(nil).DeleteAccount), arg0, arg1)
+}
+
+// DeleteAccountApiKey mocks base method.
+func (m *MockStore) DeleteAccountApiKey(arg0 context.Context, arg1 DeleteAccountApiKeyParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccountApiKey", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAccountApiKey indicates an expected call of DeleteAccountApiKey.
+func (mr *MockStoreMockRecorder) DeleteAccountApiKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccountApiKey", reflect.TypeOf((*MockStore)(nil).DeleteAccountApiKey), arg0, arg1)
+}
+
+// DeleteAccountIdentity mocks base method.
+func (m *MockStore) DeleteAccountIdentity(arg0 context.Context, arg1 DeleteAccountIdentityParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccountIdentity", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAccountIdentity indicates an expected call of DeleteAccountIdentity.
+func (mr *MockStoreMockRecorder) DeleteAccountIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccountIdentity", reflect.TypeOf((*MockStore)(nil).DeleteAccountIdentity), arg0, arg1)
+}
+
+// DeleteAccountOauthClient mocks base method.
+func (m *MockStore) DeleteAccountOauthClient(arg0 context.Context, arg1 DeleteAccountOauthClientParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccountOauthClient", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAccountOauthClient indicates an expected call of DeleteAccountOauthClient.
+func (mr *MockStoreMockRecorder) DeleteAccountOauthClient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccountOauthClient", reflect.TypeOf((*MockStore)(nil).DeleteAccountOauthClient), arg0, arg1)
+}
+
+// DeleteAccountRecoveryCodes mocks base method.
+func (m *MockStore) DeleteAccountRecoveryCodes(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccountRecoveryCodes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccountRecoveryCodes indicates an expected call of DeleteAccountRecoveryCodes.
+func (mr *MockStoreMockRecorder) DeleteAccountRecoveryCodes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccountRecoveryCodes", reflect.TypeOf((*MockStore)(nil).DeleteAccountRecoveryCodes), arg0, arg1)
+}
+
+// DeleteAccountTokens mocks base method.
+func (m *MockStore) DeleteAccountTokens(arg0 context.Context, arg1 DeleteAccountTokensParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccountTokens", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccountTokens indicates an expected call of DeleteAccountTokens.
+func (mr *MockStoreMockRecorder) DeleteAccountTokens(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccountTokens", reflect.TypeOf((*MockStore)(nil).DeleteAccountTokens), arg0, arg1)
+}
+
+// DeleteAccountUsernameHistory mocks base method.
+func (m *MockStore) DeleteAccountUsernameHistory(arg0 context.Context, arg1 DeleteAccountUsernameHistoryParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccountUsernameHistory", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccountUsernameHistory indicates an expected call of DeleteAccountUsernameHistory.
+func (mr *MockStoreMockRecorder) DeleteAccountUsernameHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccountUsernameHistory", reflect.TypeOf((*MockStore)(nil).DeleteAccountUsernameHistory), arg0, arg1)
+}
+
+// DeleteAccountWebauthnCredential mocks base method.
+func (m *MockStore) DeleteAccountWebauthnCredential(arg0 context.Context, arg1 DeleteAccountWebauthnCredentialParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccountWebauthnCredential", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAccountWebauthnCredential indicates an expected call of DeleteAccountWebauthnCredential.
+func (mr *MockStoreMockRecorder) DeleteAccountWebauthnCredential(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccountWebauthnCredential", reflect.TypeOf((*MockStore)(nil).DeleteAccountWebauthnCredential), arg0, arg1)
+}
+
+// DeleteExpiredAccountExports mocks base method.
+func (m *MockStore) DeleteExpiredAccountExports(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredAccountExports", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredAccountExports indicates an expected call of DeleteExpiredAccountExports.
+func (mr *MockStoreMockRecorder) DeleteExpiredAccountExports(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredAccountExports", reflect.TypeOf((*MockStore)(nil).DeleteExpiredAccountExports), arg0)
+}
+
+// DeleteExpiredRevokedTokens mocks base method.
+func (m *MockStore) DeleteExpiredRevokedTokens(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredRevokedTokens", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExpiredRevokedTokens indicates an expected call of DeleteExpiredRevokedTokens.
+func (mr *MockStoreMockRecorder) DeleteExpiredRevokedTokens(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredRevokedTokens", reflect.TypeOf((*MockStore)(nil).DeleteExpiredRevokedTokens), arg0)
+}
+
+// DeleteImage mocks base method.
+func (m *MockStore) DeleteImage(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImage", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteImage indicates an expected call of DeleteImage.
+func (mr *MockStoreMockRecorder) DeleteImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImage", reflect.TypeOf((*MockStore)(nil).DeleteImage), arg0, arg1)
+}
+
+// DenyOauthAuthorization mocks base method.
+func (m *MockStore) DenyOauthAuthorization(arg0 context.Context, arg1 DenyOauthAuthorizationParams) (DenyOauthAuthorizationRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DenyOauthAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(DenyOauthAuthorizationRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DenyOauthAuthorization indicates an expected call of DenyOauthAuthorization.
+func (mr *MockStoreMockRecorder) DenyOauthAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DenyOauthAuthorization", reflect.TypeOf((*MockStore)(nil).DenyOauthAuthorization), arg0, arg1)
+}
+
+// DisableAccountTotp mocks base method.
+func (m *MockStore) DisableAccountTotp(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableAccountTotp", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableAccountTotp indicates an expected call of DisableAccountTotp.
+func (mr *MockStoreMockRecorder) DisableAccountTotp(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableAccountTotp", reflect.TypeOf((*MockStore)(nil).DisableAccountTotp), arg0, arg1)
+}
+
+// DisableTotp mocks base method.
+func (m *MockStore) DisableTotp(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTotp", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTotp indicates an expected call of DisableTotp.
+func (mr *MockStoreMockRecorder) DisableTotp(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTotp", reflect.TypeOf((*MockStore)(nil).DisableTotp), arg0, arg1)
+}
+
+// EnableAccountTotp mocks base method.
+func (m *MockStore) EnableAccountTotp(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableAccountTotp", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableAccountTotp indicates an expected call of EnableAccountTotp.
+func (mr *MockStoreMockRecorder) EnableAccountTotp(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableAccountTotp", reflect.TypeOf((*MockStore)(nil).EnableAccountTotp), arg0, arg1)
+}
+
+// EnableTotp mocks base method.
+func (m *MockStore) EnableTotp(arg0 context.Context, arg1 EnableTotpParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTotp", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTotp indicates an expected call of EnableTotp.
+func (mr *MockStoreMockRecorder) EnableTotp(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTotp", reflect.TypeOf((*MockStore)(nil).EnableTotp), arg0, arg1)
+}
+
+// FailAccountExport mocks base method.
+func (m *MockStore) FailAccountExport(arg0 context.Context, arg1 FailAccountExportParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailAccountExport", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailAccountExport indicates an expected call of FailAccountExport.
+func (mr *MockStoreMockRecorder) FailAccountExport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailAccountExport", reflect.TypeOf((*MockStore)(nil).FailAccountExport), arg0, arg1)
+}
+
+// GetAccount mocks base method.
+func (m *MockStore) GetAccount(arg0 context.Context, arg1 int64) (Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", arg0, arg1)
+	ret0, _ := ret[0].(Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockStoreMockRecorder) GetAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockStore)(nil).GetAccount), arg0, arg1)
+}
+
+// GetAccountByEmail mocks base method.
+func (m *MockStore) GetAccountByEmail(arg0 context.Context, arg1 string) (Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountByEmail", arg0, arg1)
+	ret0, _ := ret[0].(Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountByEmail indicates an expected call of GetAccountByEmail.
+func (mr *MockStoreMockRecorder) GetAccountByEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountByEmail", reflect.TypeOf((*MockStore)(nil).GetAccountByEmail), arg0, arg1)
+}
+
+// GetAccountByUsername mocks base method.
+func (m *MockStore) GetAccountByUsername(arg0 context.Context, arg1 string) (Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountByUsername", arg0, arg1)
+	ret0, _ := ret[0].(Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountByUsername indicates an expected call of GetAccountByUsername.
+func (mr *MockStoreMockRecorder) GetAccountByUsername(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountByUsername", reflect.TypeOf((*MockStore)(nil).GetAccountByUsername), arg0, arg1)
+}
+
+// GetAccountExport mocks base method.
+func (m *MockStore) GetAccountExport(arg0 context.Context, arg1 GetAccountExportParams) (AccountExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountExport", arg0, arg1)
+	ret0, _ := ret[0].(AccountExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountExport indicates an expected call of GetAccountExport.
+func (mr *MockStoreMockRecorder) GetAccountExport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountExport", reflect.TypeOf((*MockStore)(nil).GetAccountExport), arg0, arg1)
+}
+
+// GetAccountExportArchive mocks base method.
+func (m *MockStore) GetAccountExportArchive(arg0 context.Context, arg1 int64) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountExportArchive", arg0, arg1)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountExportArchive indicates an expected call of GetAccountExportArchive.
+func (mr *MockStoreMockRecorder) GetAccountExportArchive(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountExportArchive", reflect.TypeOf((*MockStore)(nil).GetAccountExportArchive), arg0, arg1)
+}
+
+// GetAccountIdentity mocks base method.
+func (m *MockStore) GetAccountIdentity(arg0 context.Context, arg1 GetAccountIdentityParams) (AccountIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountIdentity", arg0, arg1)
+	ret0, _ := ret[0].(AccountIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountIdentity indicates an expected call of GetAccountIdentity.
+func (mr *MockStoreMockRecorder) GetAccountIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountIdentity", reflect.TypeOf((*MockStore)(nil).GetAccountIdentity), arg0, arg1)
+}
+
+// GetAccountSession mocks base method.
+func (m *MockStore) GetAccountSession(arg0 context.Context, arg1 GetAccountSessionParams) (Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountSession", arg0, arg1)
+	ret0, _ := ret[0].(Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountSession indicates an expected call of GetAccountSession.
+func (mr *MockStoreMockRecorder) GetAccountSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountSession", reflect.TypeOf((*MockStore)(nil).GetAccountSession), arg0, arg1)
+}
+
+// GetAccountUsernameForUpdate mocks base method.
+func (m *MockStore) GetAccountUsernameForUpdate(arg0 context.Context, arg1 int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountUsernameForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountUsernameForUpdate indicates an expected call of GetAccountUsernameForUpdate.
+func (mr *MockStoreMockRecorder) GetAccountUsernameForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountUsernameForUpdate", reflect.TypeOf((*MockStore)(nil).GetAccountUsernameForUpdate), arg0, arg1)
+}
+
+// GetApiKeyByPrefix mocks base method.
+func (m *MockStore) GetApiKeyByPrefix(arg0 context.Context, arg1 string) (GetApiKeyByPrefixRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKeyByPrefix", arg0, arg1)
+	ret0, _ := ret[0].(GetApiKeyByPrefixRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApiKeyByPrefix indicates an expected call of GetApiKeyByPrefix.
+func (mr *MockStoreMockRecorder) GetApiKeyByPrefix(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKeyByPrefix", reflect.TypeOf((*MockStore)(nil).GetApiKeyByPrefix), arg0, arg1)
+}
+
+// GetImage mocks base method.
+func (m *MockStore) GetImage(arg0 context.Context, arg1 int64) (Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImage", arg0, arg1)
+	ret0, _ := ret[0].(Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImage indicates an expected call of GetImage.
+func (mr *MockStoreMockRecorder) GetImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockStore)(nil).GetImage), arg0, arg1)
+}
+
+// GetLastUsernameChange mocks base method.
+func (m *MockStore) GetLastUsernameChange(arg0 context.Context, arg1 int64) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastUsernameChange", arg0, arg1)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLastUsernameChange indicates an expected call of GetLastUsernameChange.
+func (mr *MockStoreMockRecorder) GetLastUsernameChange(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastUsernameChange", reflect.TypeOf((*MockStore)(nil).GetLastUsernameChange), arg0, arg1)
+}
+
+// GetLatestAccountExport mocks base method.
+func (m *MockStore) GetLatestAccountExport(arg0 context.Context, arg1 int64) (AccountExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestAccountExport", arg0, arg1)
+	ret0, _ := ret[0].(AccountExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestAccountExport indicates an expected call of GetLatestAccountExport.
+func (mr *MockStoreMockRecorder) GetLatestAccountExport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestAccountExport", reflect.TypeOf((*MockStore)(nil).GetLatestAccountExport), arg0, arg1)
+}
+
+// GetOauthClient mocks base method.
+func (m *MockStore) GetOauthClient(arg0 context.Context, arg1 string) (OauthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOauthClient", arg0, arg1)
+	ret0, _ := ret[0].(OauthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOauthClient indicates an expected call of GetOauthClient.
+func (mr *MockStoreMockRecorder) GetOauthClient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOauthClient", reflect.TypeOf((*MockStore)(nil).GetOauthClient), arg0, arg1)
+}
+
+// GetOauthRefreshToken mocks base method.
+func (m *MockStore) GetOauthRefreshToken(arg0 context.Context, arg1 []byte) (GetOauthRefreshTokenRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOauthRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(GetOauthRefreshTokenRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOauthRefreshToken indicates an expected call of GetOauthRefreshToken.
+func (mr *MockStoreMockRecorder) GetOauthRefreshToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOauthRefreshToken", reflect.TypeOf((*MockStore)(nil).GetOauthRefreshToken), arg0, arg1)
+}
+
+// GetProfile mocks base method.
+func (m *MockStore) GetProfile(arg0 context.Context, arg1 int64) (GetProfileRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfile", arg0, arg1)
+	ret0, _ := ret[0].(GetProfileRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfile indicates an expected call of GetProfile.
+func (mr *MockStoreMockRecorder) GetProfile(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfile", reflect.TypeOf((*MockStore)(nil).GetProfile), arg0, arg1)
+}
+
+// GetProfileAvatar mocks base method.
+func (m *MockStore) GetProfileAvatar(arg0 context.Context, arg1 int64) (GetProfileAvatarRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfileAvatar", arg0, arg1)
+	ret0, _ := ret[0].(GetProfileAvatarRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfileAvatar indicates an expected call of GetProfileAvatar.
+func (mr *MockStoreMockRecorder) GetProfileAvatar(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileAvatar", reflect.TypeOf((*MockStore)(nil).GetProfileAvatar), arg0, arg1)
+}
+
+// GetSessionByTokenHash mocks base method.
+func (m *MockStore) GetSessionByTokenHash(arg0 context.Context, arg1 []byte) (Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByTokenHash", arg0, arg1)
+	ret0, _ := ret[0].(Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionByTokenHash indicates an expected call of GetSessionByTokenHash.
+func (mr *MockStoreMockRecorder) GetSessionByTokenHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByTokenHash", reflect.TypeOf((*MockStore)(nil).GetSessionByTokenHash), arg0, arg1)
+}
+
+// GetUsernameHistory mocks base method.
+func (m *MockStore) GetUsernameHistory(arg0 context.Context, arg1 string) (UsernameHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsernameHistory", arg0, arg1)
+	ret0, _ := ret[0].(UsernameHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsernameHistory indicates an expected call of GetUsernameHistory.
+func (mr *MockStoreMockRecorder) GetUsernameHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsernameHistory", reflect.TypeOf((*MockStore)(nil).GetUsernameHistory), arg0, arg1)
+}
+
+// GetWebauthnCredential mocks base method.
+func (m *MockStore) GetWebauthnCredential(arg0 context.Context, arg1 []byte) (WebauthnCredential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebauthnCredential", arg0, arg1)
+	ret0, _ := ret[0].(WebauthnCredential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebauthnCredential indicates an expected call of GetWebauthnCredential.
+func (mr *MockStoreMockRecorder) GetWebauthnCredential(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebauthnCredential", reflect.TypeOf((*MockStore)(nil).GetWebauthnCredential), arg0, arg1)
+}
+
+// ImageTimeline mocks base method.
+func (m *MockStore) ImageTimeline(arg0 context.Context, arg1 ImageTimelineParams) ([]ImageTimelineRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImageTimeline", arg0, arg1)
+	ret0, _ := ret[0].([]ImageTimelineRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImageTimeline indicates an expected call of ImageTimeline.
+func (mr *MockStoreMockRecorder) ImageTimeline(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImageTimeline", reflect.TypeOf((*MockStore)(nil).ImageTimeline), arg0, arg1)
+}
+
+// IsTokenRevoked mocks base method.
+func (m *MockStore) IsTokenRevoked(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTokenRevoked", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTokenRevoked indicates an expected call of IsTokenRevoked.
+func (mr *MockStoreMockRecorder) IsTokenRevoked(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTokenRevoked", reflect.TypeOf((*MockStore)(nil).IsTokenRevoked), arg0, arg1)
+}
+
+// ListAccountApiKeys mocks base method.
+func (m *MockStore) ListAccountApiKeys(arg0 context.Context, arg1 int64) ([]ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountApiKeys", arg0, arg1)
+	ret0, _ := ret[0].([]ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountApiKeys indicates an expected call of ListAccountApiKeys.
+func (mr *MockStoreMockRecorder) ListAccountApiKeys(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountApiKeys", reflect.TypeOf((*MockStore)(nil).ListAccountApiKeys), arg0, arg1)
+}
+
+// ListAccountIdentities mocks base method.
+func (m *MockStore) ListAccountIdentities(arg0 context.Context, arg1 int64) ([]AccountIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountIdentities", arg0, arg1)
+	ret0, _ := ret[0].([]AccountIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountIdentities indicates an expected call of ListAccountIdentities.
+func (mr *MockStoreMockRecorder) ListAccountIdentities(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountIdentities", reflect.TypeOf((*MockStore)(nil).ListAccountIdentities), arg0, arg1)
+}
+
+// ListAccountImages mocks base method.
+func (m *MockStore) ListAccountImages(arg0 context.Context, arg1 int64) ([]Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountImages", arg0, arg1)
+	ret0, _ := ret[0].([]Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountImages indicates an expected call of ListAccountImages.
+func (mr *MockStoreMockRecorder) ListAccountImages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountImages", reflect.TypeOf((*MockStore)(nil).ListAccountImages), arg0, arg1)
+}
+
+// ListAccountLoginAttempts mocks base method.
+func (m *MockStore) ListAccountLoginAttempts(arg0 context.Context, arg1 ListAccountLoginAttemptsParams) ([]ListAccountLoginAttemptsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountLoginAttempts", arg0, arg1)
+	ret0, _ := ret[0].([]ListAccountLoginAttemptsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountLoginAttempts indicates an expected call of ListAccountLoginAttempts.
+func (mr *MockStoreMockRecorder) ListAccountLoginAttempts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountLoginAttempts", reflect.TypeOf((*MockStore)(nil).ListAccountLoginAttempts), arg0, arg1)
+}
+
+// ListAccountOauthClients mocks base method.
+func (m *MockStore) ListAccountOauthClients(arg0 context.Context, arg1 int64) ([]OauthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountOauthClients", arg0, arg1)
+	ret0, _ := ret[0].([]OauthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountOauthClients indicates an expected call of ListAccountOauthClients.
+func (mr *MockStoreMockRecorder) ListAccountOauthClients(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountOauthClients", reflect.TypeOf((*MockStore)(nil).ListAccountOauthClients), arg0, arg1)
+}
+
+// ListAccountPhrases mocks base method.
+func (m *MockStore) ListAccountPhrases(arg0 context.Context) ([][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountPhrases", arg0)
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountPhrases indicates an expected call of ListAccountPhrases.
+func (mr *MockStoreMockRecorder) ListAccountPhrases(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountPhrases", reflect.TypeOf((*MockStore)(nil).ListAccountPhrases), arg0)
+}
+
+// ListAccountSessions mocks base method.
+func (m *MockStore) ListAccountSessions(arg0 context.Context, arg1 int64) ([]Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountSessions", arg0, arg1)
+	ret0, _ := ret[0].([]Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountSessions indicates an expected call of ListAccountSessions.
+func (mr *MockStoreMockRecorder) ListAccountSessions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountSessions", reflect.TypeOf((*MockStore)(nil).ListAccountSessions), arg0, arg1)
+}
+
+// ListAccountUsernameHistory mocks base method.
+func (m *MockStore) ListAccountUsernameHistory(arg0 context.Context, arg1 int64) ([]UsernameHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountUsernameHistory", arg0, arg1)
+	ret0, _ := ret[0].([]UsernameHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountUsernameHistory indicates an expected call of ListAccountUsernameHistory.
+func (mr *MockStoreMockRecorder) ListAccountUsernameHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountUsernameHistory", reflect.TypeOf((*MockStore)(nil).ListAccountUsernameHistory), arg0, arg1)
+}
+
+// ListAccountWebauthnCredentials mocks base method.
+func (m *MockStore) ListAccountWebauthnCredentials(arg0 context.Context, arg1 int64) ([]WebauthnCredential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountWebauthnCredentials", arg0, arg1)
+	ret0, _ := ret[0].([]WebauthnCredential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountWebauthnCredentials indicates an expected call of ListAccountWebauthnCredentials.
+func (mr *MockStoreMockRecorder) ListAccountWebauthnCredentials(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountWebauthnCredentials", reflect.TypeOf((*MockStore)(nil).ListAccountWebauthnCredentials), arg0, arg1)
+}
+
+// ListAccounts mocks base method.
+func (m *MockStore) ListAccounts(arg0 context.Context, arg1 ListAccountsParams) ([]ListAccountsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccounts", arg0, arg1)
+	ret0, _ := ret[0].([]ListAccountsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccounts indicates an expected call of ListAccounts.
+func (mr *MockStoreMockRecorder) ListAccounts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockStore)(nil).ListAccounts), arg0, arg1)
+}
+
+// ListAccountsPageAsc mocks base method.
+func (m *MockStore) ListAccountsPageAsc(arg0 context.Context, arg1 ListAccountsPageAscParams) ([]ListAccountsPageAscRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountsPageAsc", arg0, arg1)
+	ret0, _ := ret[0].([]ListAccountsPageAscRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountsPageAsc indicates an expected call of ListAccountsPageAsc.
+func (mr *MockStoreMockRecorder) ListAccountsPageAsc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountsPageAsc", reflect.TypeOf((*MockStore)(nil).ListAccountsPageAsc), arg0, arg1)
+}
+
+// ListAccountsPageDesc mocks base method.
+func (m *MockStore) ListAccountsPageDesc(arg0 context.Context, arg1 ListAccountsPageDescParams) ([]ListAccountsPageDescRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountsPageDesc", arg0, arg1)
+	ret0, _ := ret[0].([]ListAccountsPageDescRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountsPageDesc indicates an expected call of ListAccountsPageDesc.
+func (mr *MockStoreMockRecorder) ListAccountsPageDesc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountsPageDesc", reflect.TypeOf((*MockStore)(nil).ListAccountsPageDesc), arg0, arg1)
+}
+
+// ListImages mocks base method.
+func (m *MockStore) ListImages(arg0 context.Context, arg1 ListImagesParams) ([]Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImages", arg0, arg1)
+	ret0, _ := ret[0].([]Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImages indicates an expected call of ListImages.
+func (mr *MockStoreMockRecorder) ListImages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImages", reflect.TypeOf((*MockStore)(nil).ListImages), arg0, arg1)
+}
+
+// ListImagesByCreatedAsc mocks base method.
+func (m *MockStore) ListImagesByCreatedAsc(arg0 context.Context, arg1 ListImagesByCreatedAscParams) ([]ListImagesByCreatedAscRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImagesByCreatedAsc", arg0, arg1)
+	ret0, _ := ret[0].([]ListImagesByCreatedAscRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImagesByCreatedAsc indicates an expected call of ListImagesByCreatedAsc.
+func (mr *MockStoreMockRecorder) ListImagesByCreatedAsc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImagesByCreatedAsc", reflect.TypeOf((*MockStore)(nil).ListImagesByCreatedAsc), arg0, arg1)
+}
+
+// ListImagesByCreatedDesc mocks base method.
+func (m *MockStore) ListImagesByCreatedDesc(arg0 context.Context, arg1 ListImagesByCreatedDescParams) ([]ListImagesByCreatedDescRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImagesByCreatedDesc", arg0, arg1)
+	ret0, _ := ret[0].([]ListImagesByCreatedDescRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImagesByCreatedDesc indicates an expected call of ListImagesByCreatedDesc.
+func (mr *MockStoreMockRecorder) ListImagesByCreatedDesc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImagesByCreatedDesc", reflect.TypeOf((*MockStore)(nil).ListImagesByCreatedDesc), arg0, arg1)
+}
+
+// ListImagesBySizeAsc mocks base method.
+func (m *MockStore) ListImagesBySizeAsc(arg0 context.Context, arg1 ListImagesBySizeAscParams) ([]ListImagesBySizeAscRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImagesBySizeAsc", arg0, arg1)
+	ret0, _ := ret[0].([]ListImagesBySizeAscRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateAccount indicates an expected call of CreateAccount.
-func (mr *MockStoreMockRecorder) CreateAccount(arg0, arg1 interface{}) *gomock.Call {
+// ListImagesBySizeAsc indicates an expected call of ListImagesBySizeAsc.
+func (mr *MockStoreMockRecorder) ListImagesBySizeAsc(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStore)(nil).CreateAccount), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImagesBySizeAsc", reflect.TypeOf((*MockStore)(nil).ListImagesBySizeAsc), arg0, arg1)
 }
 
-// CreateImage mocks base method.
-func (m *MockStore) CreateImage(arg0 context.Context, arg1 string) (Image, error) {
+// ListImagesBySizeDesc mocks base method.
+func (m *MockStore) ListImagesBySizeDesc(arg0 context.Context, arg1 ListImagesBySizeDescParams) ([]ListImagesBySizeDescRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateImage", arg0, arg1)
-	ret0, _ := ret[0].(Image)
+	ret := m.ctrl.Call(m, "ListImagesBySizeDesc", arg0, arg1)
+	ret0, _ := ret[0].([]ListImagesBySizeDescRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateImage indicates an expected call of CreateImage.
-func (mr *MockStoreMockRecorder) CreateImage(arg0, arg1 interface{}) *gomock.Call {
+// ListImagesBySizeDesc indicates an expected call of ListImagesBySizeDesc.
+func (mr *MockStoreMockRecorder) ListImagesBySizeDesc(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImage", reflect.TypeOf((*MockStore)(nil).CreateImage), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImagesBySizeDesc", reflect.TypeOf((*MockStore)(nil).ListImagesBySizeDesc), arg0, arg1)
 }
 
-// DeleteAccount mocks base method.
-func (m *MockStore) DeleteAccount(arg0 context.Context, arg1 int64) error {
+// ListImagesByTakenAtAsc mocks base method.
+func (m *MockStore) ListImagesByTakenAtAsc(arg0 context.Context, arg1 ListImagesByTakenAtAscParams) ([]ListImagesByTakenAtAscRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAccount", arg0, arg1)
+	ret := m.ctrl.Call(m, "ListImagesByTakenAtAsc", arg0, arg1)
+	ret0, _ := ret[0].([]ListImagesByTakenAtAscRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImagesByTakenAtAsc indicates an expected call of ListImagesByTakenAtAsc.
+func (mr *MockStoreMockRecorder) ListImagesByTakenAtAsc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImagesByTakenAtAsc", reflect.TypeOf((*MockStore)(nil).ListImagesByTakenAtAsc), arg0, arg1)
+}
+
+// ListImagesByTakenAtDesc mocks base method.
+func (m *MockStore) ListImagesByTakenAtDesc(arg0 context.Context, arg1 ListImagesByTakenAtDescParams) ([]ListImagesByTakenAtDescRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImagesByTakenAtDesc", arg0, arg1)
+	ret0, _ := ret[0].([]ListImagesByTakenAtDescRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImagesByTakenAtDesc indicates an expected call of ListImagesByTakenAtDesc.
+func (mr *MockStoreMockRecorder) ListImagesByTakenAtDesc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImagesByTakenAtDesc", reflect.TypeOf((*MockStore)(nil).ListImagesByTakenAtDesc), arg0, arg1)
+}
+
+// ListImagesByUpdatedAsc mocks base method.
+func (m *MockStore) ListImagesByUpdatedAsc(arg0 context.Context, arg1 ListImagesByUpdatedAscParams) ([]ListImagesByUpdatedAscRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImagesByUpdatedAsc", arg0, arg1)
+	ret0, _ := ret[0].([]ListImagesByUpdatedAscRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImagesByUpdatedAsc indicates an expected call of ListImagesByUpdatedAsc.
+func (mr *MockStoreMockRecorder) ListImagesByUpdatedAsc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImagesByUpdatedAsc", reflect.TypeOf((*MockStore)(nil).ListImagesByUpdatedAsc), arg0, arg1)
+}
+
+// ListImagesByUpdatedDesc mocks base method.
+func (m *MockStore) ListImagesByUpdatedDesc(arg0 context.Context, arg1 ListImagesByUpdatedDescParams) ([]ListImagesByUpdatedDescRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImagesByUpdatedDesc", arg0, arg1)
+	ret0, _ := ret[0].([]ListImagesByUpdatedDescRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImagesByUpdatedDesc indicates an expected call of ListImagesByUpdatedDesc.
+func (mr *MockStoreMockRecorder) ListImagesByUpdatedDesc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImagesByUpdatedDesc", reflect.TypeOf((*MockStore)(nil).ListImagesByUpdatedDesc), arg0, arg1)
+}
+
+// ListUsernameCollisions mocks base method.
+func (m *MockStore) ListUsernameCollisions(arg0 context.Context) ([]ListUsernameCollisionsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsernameCollisions", arg0)
+	ret0, _ := ret[0].([]ListUsernameCollisionsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsernameCollisions indicates an expected call of ListUsernameCollisions.
+func (mr *MockStoreMockRecorder) ListUsernameCollisions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsernameCollisions", reflect.TypeOf((*MockStore)(nil).ListUsernameCollisions), arg0)
+}
+
+// LockUsername mocks base method.
+func (m *MockStore) LockUsername(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUsername", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteAccount indicates an expected call of DeleteAccount.
-func (mr *MockStoreMockRecorder) DeleteAccount(arg0, arg1 interface{}) *gomock.Call {
+// LockUsername indicates an expected call of LockUsername.
+func (mr *MockStoreMockRecorder) LockUsername(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockStore)(nil).DeleteAccount), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUsername", reflect.TypeOf((*MockStore)(nil).LockUsername), arg0, arg1)
 }
 
-// DeleteImage mocks base method.
-func (m *MockStore) DeleteImage(arg0 context.Context, arg1 int64) error {
+// MarkAccountExportReady mocks base method.
+func (m *MockStore) MarkAccountExportReady(arg0 context.Context, arg1 MarkAccountExportReadyParams) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteImage", arg0, arg1)
+	ret := m.ctrl.Call(m, "MarkAccountExportReady", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteImage indicates an expected call of DeleteImage.
-func (mr *MockStoreMockRecorder) DeleteImage(arg0, arg1 interface{}) *gomock.Call {
+// MarkAccountExportReady indicates an expected call of MarkAccountExportReady.
+func (mr *MockStoreMockRecorder) MarkAccountExportReady(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImage", reflect.TypeOf((*MockStore)(nil).DeleteImage), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAccountExportReady", reflect.TypeOf((*MockStore)(nil).MarkAccountExportReady), arg0, arg1)
 }
 
-// GetAccount mocks base method.
-func (m *MockStore) GetAccount(arg0 context.Context, arg1 int64) (Account, error) {
+// MarkSessionRotated mocks base method.
+func (m *MockStore) MarkSessionRotated(arg0 context.Context, arg1 int64) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAccount", arg0, arg1)
-	ret0, _ := ret[0].(Account)
+	ret := m.ctrl.Call(m, "MarkSessionRotated", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAccount indicates an expected call of GetAccount.
-func (mr *MockStoreMockRecorder) GetAccount(arg0, arg1 interface{}) *gomock.Call {
+// MarkSessionRotated indicates an expected call of MarkSessionRotated.
+func (mr *MockStoreMockRecorder) MarkSessionRotated(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockStore)(nil).GetAccount), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkSessionRotated", reflect.TypeOf((*MockStore)(nil).MarkSessionRotated), arg0, arg1)
 }
 
-// GetAccountByUsername mocks base method.
-func (m *MockStore) GetAccountByUsername(arg0 context.Context, arg1 string) (Account, error) {
+// RegisterAccount mocks base method.
+func (m *MockStore) RegisterAccount(arg0 context.Context, arg1 CreateAccountParams) (Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAccountByUsername", arg0, arg1)
+	ret := m.ctrl.Call(m, "RegisterAccount", arg0, arg1)
 	ret0, _ := ret[0].(Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAccountByUsername indicates an expected call of GetAccountByUsername.
-func (mr *MockStoreMockRecorder) GetAccountByUsername(arg0, arg1 interface{}) *gomock.Call {
+// RegisterAccount indicates an expected call of RegisterAccount.
+func (mr *MockStoreMockRecorder) RegisterAccount(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountByUsername", reflect.TypeOf((*MockStore)(nil).GetAccountByUsername), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterAccount", reflect.TypeOf((*MockStore)(nil).RegisterAccount), arg0, arg1)
 }
 
-// GetImage mocks base method.
-func (m *MockStore) GetImage(arg0 context.Context, arg1 int64) (Image, error) {
+// RenameAccount mocks base method.
+func (m *MockStore) RenameAccount(arg0 context.Context, arg1 RenameAccountParams) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImage", arg0, arg1)
-	ret0, _ := ret[0].(Image)
+	ret := m.ctrl.Call(m, "RenameAccount", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenameAccount indicates an expected call of RenameAccount.
+func (mr *MockStoreMockRecorder) RenameAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameAccount", reflect.TypeOf((*MockStore)(nil).RenameAccount), arg0, arg1)
+}
+
+// RevokeAccountSessions mocks base method.
+func (m *MockStore) RevokeAccountSessions(arg0 context.Context, arg1 int64) ([]RevokeAccountSessionsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAccountSessions", arg0, arg1)
+	ret0, _ := ret[0].([]RevokeAccountSessionsRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetImage indicates an expected call of GetImage.
-func (mr *MockStoreMockRecorder) GetImage(arg0, arg1 interface{}) *gomock.Call {
+// RevokeAccountSessions indicates an expected call of RevokeAccountSessions.
+func (mr *MockStoreMockRecorder) RevokeAccountSessions(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockStore)(nil).GetImage), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccountSessions", reflect.TypeOf((*MockStore)(nil).RevokeAccountSessions), arg0, arg1)
 }
 
-// ListAccounts mocks base method.
-func (m *MockStore) ListAccounts(arg0 context.Context, arg1 ListAccountsParams) ([]ListAccountsRow, error) {
+// RevokeOauthGrant mocks base method.
+func (m *MockStore) RevokeOauthGrant(arg0 context.Context, arg1 RevokeOauthGrantParams) ([]RevokeOauthGrantRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListAccounts", arg0, arg1)
-	ret0, _ := ret[0].([]ListAccountsRow)
+	ret := m.ctrl.Call(m, "RevokeOauthGrant", arg0, arg1)
+	ret0, _ := ret[0].([]RevokeOauthGrantRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListAccounts indicates an expected call of ListAccounts.
-func (mr *MockStoreMockRecorder) ListAccounts(arg0, arg1 interface{}) *gomock.Call {
+// RevokeOauthGrant indicates an expected call of RevokeOauthGrant.
+func (mr *MockStoreMockRecorder) RevokeOauthGrant(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockStore)(nil).ListAccounts), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOauthGrant", reflect.TypeOf((*MockStore)(nil).RevokeOauthGrant), arg0, arg1)
 }
 
-// ListImages mocks base method.
-func (m *MockStore) ListImages(arg0 context.Context, arg1 ListImagesParams) ([]Image, error) {
+// RevokeOauthRefreshToken mocks base method.
+func (m *MockStore) RevokeOauthRefreshToken(arg0 context.Context, arg1 int64) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListImages", arg0, arg1)
+	ret := m.ctrl.Call(m, "RevokeOauthRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeOauthRefreshToken indicates an expected call of RevokeOauthRefreshToken.
+func (mr *MockStoreMockRecorder) RevokeOauthRefreshToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOauthRefreshToken", reflect.TypeOf((*MockStore)(nil).RevokeOauthRefreshToken), arg0, arg1)
+}
+
+// RevokeSessionFamily mocks base method.
+func (m *MockStore) RevokeSessionFamily(arg0 context.Context, arg1 string) ([]RevokeSessionFamilyRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessionFamily", arg0, arg1)
+	ret0, _ := ret[0].([]RevokeSessionFamilyRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeSessionFamily indicates an expected call of RevokeSessionFamily.
+func (mr *MockStoreMockRecorder) RevokeSessionFamily(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessionFamily", reflect.TypeOf((*MockStore)(nil).RevokeSessionFamily), arg0, arg1)
+}
+
+// RevokeToken mocks base method.
+func (m *MockStore) RevokeToken(arg0 context.Context, arg1 RevokeTokenParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken.
+func (mr *MockStoreMockRecorder) RevokeToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockStore)(nil).RevokeToken), arg0, arg1)
+}
+
+// RotateSession mocks base method.
+func (m *MockStore) RotateSession(arg0 context.Context, arg1 RotateSessionParams) (Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", arg0, arg1)
+	ret0, _ := ret[0].(Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockStoreMockRecorder) RotateSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockStore)(nil).RotateSession), arg0, arg1)
+}
+
+// SearchImages mocks base method.
+func (m *MockStore) SearchImages(arg0 context.Context, arg1 SearchImagesParams) ([]Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchImages", arg0, arg1)
 	ret0, _ := ret[0].([]Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListImages indicates an expected call of ListImages.
-func (mr *MockStoreMockRecorder) ListImages(arg0, arg1 interface{}) *gomock.Call {
+// SearchImages indicates an expected call of SearchImages.
+func (mr *MockStoreMockRecorder) SearchImages(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImages", reflect.TypeOf((*MockStore)(nil).ListImages), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchImages", reflect.TypeOf((*MockStore)(nil).SearchImages), arg0, arg1)
+}
+
+// SetAccountTotpSecret mocks base method.
+func (m *MockStore) SetAccountTotpSecret(arg0 context.Context, arg1 SetAccountTotpSecretParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAccountTotpSecret", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetAccountTotpSecret indicates an expected call of SetAccountTotpSecret.
+func (mr *MockStoreMockRecorder) SetAccountTotpSecret(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccountTotpSecret", reflect.TypeOf((*MockStore)(nil).SetAccountTotpSecret), arg0, arg1)
 }
 
 // SoftDeleteAccount mocks base method.
@@ -210,6 +1599,34 @@ func (mr *MockStoreMockRecorder) SoftDeleteImage(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteImage", reflect.TypeOf((*MockStore)(nil).SoftDeleteImage), arg0, arg1)
 }
 
+// TouchAccountIdentity mocks base method.
+func (m *MockStore) TouchAccountIdentity(arg0 context.Context, arg1 TouchAccountIdentityParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAccountIdentity", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAccountIdentity indicates an expected call of TouchAccountIdentity.
+func (mr *MockStoreMockRecorder) TouchAccountIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAccountIdentity", reflect.TypeOf((*MockStore)(nil).TouchAccountIdentity), arg0, arg1)
+}
+
+// TouchApiKey mocks base method.
+func (m *MockStore) TouchApiKey(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchApiKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchApiKey indicates an expected call of TouchApiKey.
+func (mr *MockStoreMockRecorder) TouchApiKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchApiKey", reflect.TypeOf((*MockStore)(nil).TouchApiKey), arg0, arg1)
+}
+
 // Tx mocks base method.
 func (m *MockStore) Tx(arg0 context.Context, arg1 func(*Queries, *interface{}) (interface{}, error)) (interface{}, error) {
 	m.ctrl.T.Helper()
@@ -239,6 +1656,63 @@ func (mr *MockStoreMockRecorder) UpdateAccount(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockStore)(nil).UpdateAccount), arg0, arg1)
 }
 
+// UpdateAccountCredentials mocks base method.
+func (m *MockStore) UpdateAccountCredentials(arg0 context.Context, arg1 UpdateAccountCredentialsParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountCredentials", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAccountCredentials indicates an expected call of UpdateAccountCredentials.
+func (mr *MockStoreMockRecorder) UpdateAccountCredentials(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountCredentials", reflect.TypeOf((*MockStore)(nil).UpdateAccountCredentials), arg0, arg1)
+}
+
+// UpdateAccountEmail mocks base method.
+func (m *MockStore) UpdateAccountEmail(arg0 context.Context, arg1 UpdateAccountEmailParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountEmail", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAccountEmail indicates an expected call of UpdateAccountEmail.
+func (mr *MockStoreMockRecorder) UpdateAccountEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountEmail", reflect.TypeOf((*MockStore)(nil).UpdateAccountEmail), arg0, arg1)
+}
+
+// UpdateAccountRole mocks base method.
+func (m *MockStore) UpdateAccountRole(arg0 context.Context, arg1 UpdateAccountRoleParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountRole", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccountRole indicates an expected call of UpdateAccountRole.
+func (mr *MockStoreMockRecorder) UpdateAccountRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountRole", reflect.TypeOf((*MockStore)(nil).UpdateAccountRole), arg0, arg1)
+}
+
+// UpdateAccountUsername mocks base method.
+func (m *MockStore) UpdateAccountUsername(arg0 context.Context, arg1 UpdateAccountUsernameParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountUsername", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAccountUsername indicates an expected call of UpdateAccountUsername.
+func (mr *MockStoreMockRecorder) UpdateAccountUsername(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountUsername", reflect.TypeOf((*MockStore)(nil).UpdateAccountUsername), arg0, arg1)
+}
+
 // UpdateImage mocks base method.
 func (m *MockStore) UpdateImage(arg0 context.Context, arg1 UpdateImageParams) error {
 	m.ctrl.T.Helper()
@@ -252,3 +1726,119 @@ func (mr *MockStoreMockRecorder) UpdateImage(arg0, arg1 interface{}) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImage", reflect.TypeOf((*MockStore)(nil).UpdateImage), arg0, arg1)
 }
+
+// UpdateProfileAvatar mocks base method.
+func (m *MockStore) UpdateProfileAvatar(arg0 context.Context, arg1 UpdateProfileAvatarParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfileAvatar", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfileAvatar indicates an expected call of UpdateProfileAvatar.
+func (mr *MockStoreMockRecorder) UpdateProfileAvatar(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfileAvatar", reflect.TypeOf((*MockStore)(nil).UpdateProfileAvatar), arg0, arg1)
+}
+
+// UpdateSessionAccessToken mocks base method.
+func (m *MockStore) UpdateSessionAccessToken(arg0 context.Context, arg1 UpdateSessionAccessTokenParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionAccessToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionAccessToken indicates an expected call of UpdateSessionAccessToken.
+func (mr *MockStoreMockRecorder) UpdateSessionAccessToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionAccessToken", reflect.TypeOf((*MockStore)(nil).UpdateSessionAccessToken), arg0, arg1)
+}
+
+// UpdateWebauthnCredentialSignCount mocks base method.
+func (m *MockStore) UpdateWebauthnCredentialSignCount(arg0 context.Context, arg1 UpdateWebauthnCredentialSignCountParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebauthnCredentialSignCount", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebauthnCredentialSignCount indicates an expected call of UpdateWebauthnCredentialSignCount.
+func (mr *MockStoreMockRecorder) UpdateWebauthnCredentialSignCount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebauthnCredentialSignCount", reflect.TypeOf((*MockStore)(nil).UpdateWebauthnCredentialSignCount), arg0, arg1)
+}
+
+// UpsertProfile mocks base method.
+func (m *MockStore) UpsertProfile(arg0 context.Context, arg1 UpsertProfileParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertProfile", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertProfile indicates an expected call of UpsertProfile.
+func (mr *MockStoreMockRecorder) UpsertProfile(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertProfile", reflect.TypeOf((*MockStore)(nil).UpsertProfile), arg0, arg1)
+}
+
+// UseAccountToken mocks base method.
+func (m *MockStore) UseAccountToken(arg0 context.Context, arg1 UseAccountTokenParams) (UseAccountTokenRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseAccountToken", arg0, arg1)
+	ret0, _ := ret[0].(UseAccountTokenRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseAccountToken indicates an expected call of UseAccountToken.
+func (mr *MockStoreMockRecorder) UseAccountToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseAccountToken", reflect.TypeOf((*MockStore)(nil).UseAccountToken), arg0, arg1)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockStore) UseRecoveryCode(arg0 context.Context, arg1 UseRecoveryCodeParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockStoreMockRecorder) UseRecoveryCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockStore)(nil).UseRecoveryCode), arg0, arg1)
+}
+
+// UseTotpStep mocks base method.
+func (m *MockStore) UseTotpStep(arg0 context.Context, arg1 UseTotpStepParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTotpStep", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTotpStep indicates an expected call of UseTotpStep.
+func (mr *MockStoreMockRecorder) UseTotpStep(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTotpStep", reflect.TypeOf((*MockStore)(nil).UseTotpStep), arg0, arg1)
+}
+
+// VerifyAccountEmail mocks base method.
+func (m *MockStore) VerifyAccountEmail(arg0 context.Context, arg1 VerifyAccountEmailParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyAccountEmail", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyAccountEmail indicates an expected call of VerifyAccountEmail.
+func (mr *MockStoreMockRecorder) VerifyAccountEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAccountEmail", reflect.TypeOf((*MockStore)(nil).VerifyAccountEmail), arg0, arg1)
+}
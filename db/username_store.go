@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUsernameHeld is returned by RenameAccount when another account gave up the username recently enough
+// that it still resolves to them.
+var ErrUsernameHeld = errors.New("username is held by another account")
+
+// UsernameCooldownError is returned by RenameAccount when the account changed its username within the
+// cooldown.
+type UsernameCooldownError struct {
+	// Until is when the account can change its username again
+	Until time.Time
+}
+
+func (err *UsernameCooldownError) Error() string {
+	return "username was changed recently, try again later"
+}
+
+type RenameAccountParams struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// Cooldown is how long the account has to wait after changing its username before changing it again
+	Cooldown time.Duration `json:"cooldown"`
+	// HeldUntil is how long the username being given up keeps resolving to the account
+	HeldUntil time.Time `json:"heldUntil"`
+}
+
+// RenameAccount changes the account's username within one transaction, keeping the previous one in its
+// history. The account row is locked first so that concurrent renames of the account are checked against
+// the cooldown one after the other. The account can take back one of its own previous usernames, but not
+// one another account still holds. Both usernames are locked, so that an account created meanwhile either
+// takes the username first or sees it held.
+func (store *SQLStore) RenameAccount(ctx context.Context, arg RenameAccountParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		previous, err := q.GetAccountUsernameForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		last, err := q.GetLastUsernameChange(ctx, arg.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if until := last.Add(arg.Cooldown); err == nil && until.After(time.Now()) {
+			return &UsernameCooldownError{Until: until}
+		}
+
+		if err := lockUsernames(ctx, q, previous, arg.Username); err != nil {
+			return err
+		}
+		if err := checkUsernameHeld(ctx, q, arg.Username, arg.ID); err != nil {
+			return err
+		}
+		if err := q.UpdateAccountUsername(ctx, UpdateAccountUsernameParams{Username: arg.Username, ID: arg.ID}); err != nil {
+			return err
+		}
+		if err := q.DeleteAccountUsernameHistory(ctx, DeleteAccountUsernameHistoryParams{AccountID: arg.ID, Username: arg.Username}); err != nil {
+			return err
+		}
+		return q.CreateUsernameHistory(ctx, CreateUsernameHistoryParams{AccountID: arg.ID, Username: previous, Expires: arg.HeldUntil})
+	})
+}
+
+// RegisterAccount creates an account signed up with a username and phrase, unless another account still
+// holds the username, within one transaction.
+func (store *SQLStore) RegisterAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := lockUsernames(ctx, q, arg.Username); err != nil {
+			return err
+		}
+		if err := checkUsernameHeld(ctx, q, arg.Username, 0); err != nil {
+			return err
+		}
+		var err error
+		account, err = q.CreateAccount(ctx, arg)
+		return err
+	})
+	return account, err
+}
+
+// lockUsernames locks the usernames until the transaction ends. They are locked in order, so that two
+// transactions locking the same usernames don't wait on each other.
+func lockUsernames(ctx context.Context, q *Queries, usernames ...string) error {
+	sort.Slice(usernames, func(i, j int) bool { return strings.ToLower(usernames[i]) < strings.ToLower(usernames[j]) })
+	for _, username := range usernames {
+		if err := q.LockUsername(ctx, username); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUsernameHeld returns ErrUsernameHeld when an account other than accountID gave up the username
+// recently enough that it still resolves to them.
+func checkUsernameHeld(ctx context.Context, q *Queries, username string, accountID int64) error {
+	held, err := q.GetUsernameHistory(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if held.AccountID != accountID {
+		return ErrUsernameHeld
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	db "github.com/meads/firstly-api/db"
+)
+
+// maxLoginAttempts is how many of the most recent sign in attempts of the account are exported.
+const maxLoginAttempts = 1000
+
+const readme = `This archive holds the data Firstly keeps about your account.
+
+account.json           your account, without its phrase or two factor secret
+profile.json           your profile, and avatar.* its avatar when it has one
+username_history.json  usernames the account gave up and when they stop resolving to it
+sessions.json          the devices signed in to the account
+login_history.json     sign in attempts made on the account, under its current or previous usernames
+identities.json        accounts of other providers linked to sign in with
+passkeys.json          passkeys registered to sign in with
+api_keys.json          api keys, without the keys themselves
+oauth_clients.json     third-party apps registered by the account, without their secrets
+images.json            images the account uploaded to the shared library, and images/ their data
+
+Images uploaded before the library recorded who uploaded them can't be told apart, so they aren't
+included. Firstly has no albums or comments, so there are none to include.
+`
+
+type accountRecord struct {
+	ID            int64  `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	TotpEnabled   bool   `json:"totp_enabled"`
+	Created       string `json:"created"`
+	Updated       string `json:"updated"`
+}
+
+type profileRecord struct {
+	DisplayName  string    `json:"display_name"`
+	Bio          string    `json:"bio"`
+	Locale       string    `json:"locale"`
+	Timezone     string    `json:"timezone"`
+	Visibility   string    `json:"visibility"`
+	ShowTimezone bool      `json:"show_timezone"`
+	Avatar       string    `json:"avatar,omitempty"`
+	Updated      time.Time `json:"updated"`
+}
+
+type usernameRecord struct {
+	Username string    `json:"username"`
+	Changed  time.Time `json:"changed"`
+	HeldTill time.Time `json:"held_until"`
+}
+
+type sessionRecord struct {
+	ID        int64     `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	Created   string    `json:"created"`
+	LastSeen  time.Time `json:"last_seen"`
+	Expires   time.Time `json:"expires"`
+}
+
+type loginRecord struct {
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+type imageRecord struct {
+	ID       int64  `json:"id"`
+	Memo     string `json:"memo"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+	TakenAt  string `json:"taken_at"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+	Deleted  bool   `json:"deleted"`
+	File     string `json:"file"`
+}
+
+type identityRecord struct {
+	Provider string     `json:"provider"`
+	Subject  string     `json:"subject"`
+	Email    string     `json:"email"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+type passkeyRecord struct {
+	Name     string     `json:"name"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+type apiKeyRecord struct {
+	Name     string     `json:"name"`
+	Prefix   string     `json:"prefix"`
+	Scopes   string     `json:"scopes"`
+	Created  time.Time  `json:"created"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+type oauthClientRecord struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectUris string    `json:"redirect_uris"`
+	Scopes       string    `json:"scopes"`
+	Created      time.Time `json:"created"`
+}
+
+// Build assembles the data kept about the account into a zip archive. Secrets, such as the account's phrase
+// and the hashes of its api keys, are left out.
+func Build(ctx context.Context, store db.Store, accountID int64) ([]byte, error) {
+	account, err := store.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := []struct {
+		name  string
+		build func() (interface{}, error)
+	}{
+		{"account.json", func() (interface{}, error) {
+			return accountRecord{
+				ID:            account.ID,
+				Username:      account.Username,
+				Email:         account.Email,
+				EmailVerified: account.EmailVerified,
+				Role:          account.Role,
+				TotpEnabled:   account.TotpEnabled,
+				Created:       account.Created,
+				Updated:       account.Updated,
+			}, nil
+		}},
+		{"username_history.json", func() (interface{}, error) { return usernameHistory(ctx, store, accountID) }},
+		{"sessions.json", func() (interface{}, error) { return sessions(ctx, store, accountID) }},
+		{"login_history.json", func() (interface{}, error) { return loginHistory(ctx, store, accountID) }},
+		{"identities.json", func() (interface{}, error) { return identities(ctx, store, accountID) }},
+		{"passkeys.json", func() (interface{}, error) { return passkeys(ctx, store, accountID) }},
+		{"api_keys.json", func() (interface{}, error) { return apiKeys(ctx, store, accountID) }},
+		{"oauth_clients.json", func() (interface{}, error) { return oauthClients(ctx, store, accountID) }},
+	}
+
+	if err := writeFile(archive, "README.txt", []byte(readme)); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := file.build()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeJSON(archive, file.name, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeProfile(ctx, archive, store, accountID); err != nil {
+		return nil, err
+	}
+	if err := writeImages(ctx, archive, store, accountID); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeProfile adds the account's profile and avatar, when it has set them up.
+func writeProfile(ctx context.Context, archive *zip.Writer, store db.Store, accountID int64) error {
+	profile, err := store.GetProfile(ctx, accountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	record := profileRecord{
+		DisplayName:  profile.DisplayName,
+		Bio:          profile.Bio,
+		Locale:       profile.Locale,
+		Timezone:     profile.Timezone,
+		Visibility:   profile.Visibility,
+		ShowTimezone: profile.ShowTimezone,
+		Updated:      profile.Updated,
+	}
+
+	if profile.HasAvatar {
+		avatar, err := store.GetProfileAvatar(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		data, err := decodeImageData(avatar.Avatar)
+		if err != nil {
+			return err
+		}
+		// avatars are decoded gif, jpeg or png images, so their subtype is the usual extension
+		record.Avatar = "avatar." + strings.TrimPrefix(avatar.AvatarMimeType, "image/")
+		if err := writeFile(archive, record.Avatar, data); err != nil {
+			return err
+		}
+	}
+	return writeJSON(archive, "profile.json", record)
+}
+
+// writeImages adds the images the account uploaded, each next to the others under images/.
+func writeImages(ctx context.Context, archive *zip.Writer, store db.Store, accountID int64) error {
+	images, err := store.ListAccountImages(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	records := []imageRecord{}
+	for _, image := range images {
+		data, err := decodeImageData(image.Data)
+		if err != nil {
+			return err
+		}
+		record := imageRecord{
+			ID:       image.ID,
+			Memo:     image.Memo,
+			MimeType: image.MimeType,
+			Size:     image.Size,
+			Width:    image.Width,
+			Height:   image.Height,
+			TakenAt:  image.TakenAt,
+			Created:  image.Created,
+			Updated:  image.Updated,
+			Deleted:  image.Deleted,
+			File:     fmt.Sprintf("images/%d.%s", image.ID, strings.TrimPrefix(image.MimeType, "image/")),
+		}
+		if err := writeFile(archive, record.File, data); err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+	return writeJSON(archive, "images.json", records)
+}
+
+func usernameHistory(ctx context.Context, store db.Store, accountID int64) ([]usernameRecord, error) {
+	history, err := store.ListAccountUsernameHistory(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	records := []usernameRecord{}
+	for _, previous := range history {
+		records = append(records, usernameRecord{Username: previous.Username, Changed: previous.Created, HeldTill: previous.Expires})
+	}
+	return records, nil
+}
+
+func sessions(ctx context.Context, store db.Store, accountID int64) ([]sessionRecord, error) {
+	sessions, err := store.ListAccountSessions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	records := []sessionRecord{}
+	for _, session := range sessions {
+		records = append(records, sessionRecord{
+			ID:        session.ID,
+			UserAgent: session.UserAgent,
+			IP:        session.IP,
+			Created:   session.Created,
+			LastSeen:  session.LastSeen,
+			Expires:   session.Expires,
+		})
+	}
+	return records, nil
+}
+
+// loginHistory returns the sign in attempts made on the account. Attempts are attributed to the account
+// that held the username when they were made, so those on a username before or after the account had it
+// aren't included.
+func loginHistory(ctx context.Context, store db.Store, accountID int64) ([]loginRecord, error) {
+	attempts, err := store.ListAccountLoginAttempts(ctx, db.ListAccountLoginAttemptsParams{AccountID: accountID, Limit: maxLoginAttempts})
+	if err != nil {
+		return nil, err
+	}
+	records := []loginRecord{}
+	for _, attempt := range attempts {
+		records = append(records, loginRecord{
+			Username:  attempt.Username,
+			IP:        attempt.IP,
+			UserAgent: attempt.UserAgent,
+			Success:   attempt.Success,
+			Reason:    attempt.Reason,
+			Created:   attempt.Created,
+		})
+	}
+	return records, nil
+}
+
+func identities(ctx context.Context, store db.Store, accountID int64) ([]identityRecord, error) {
+	identities, err := store.ListAccountIdentities(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	records := []identityRecord{}
+	for _, identity := range identities {
+		records = append(records, identityRecord{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+			Created:  identity.Created,
+			LastUsed: nullTime(identity.LastUsed),
+		})
+	}
+	return records, nil
+}
+
+func passkeys(ctx context.Context, store db.Store, accountID int64) ([]passkeyRecord, error) {
+	credentials, err := store.ListAccountWebauthnCredentials(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	records := []passkeyRecord{}
+	for _, credential := range credentials {
+		records = append(records, passkeyRecord{Name: credential.Name, Created: credential.Created, LastUsed: nullTime(credential.LastUsed)})
+	}
+	return records, nil
+}
+
+func apiKeys(ctx context.Context, store db.Store, accountID int64) ([]apiKeyRecord, error) {
+	keys, err := store.ListAccountApiKeys(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	records := []apiKeyRecord{}
+	for _, key := range keys {
+		records = append(records, apiKeyRecord{
+			Name:     key.Name,
+			Prefix:   key.Prefix,
+			Scopes:   key.Scopes,
+			Created:  key.Created,
+			Expires:  nullTime(key.Expires),
+			LastUsed: nullTime(key.LastUsed),
+		})
+	}
+	return records, nil
+}
+
+func oauthClients(ctx context.Context, store db.Store, accountID int64) ([]oauthClientRecord, error) {
+	clients, err := store.ListAccountOauthClients(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	records := []oauthClientRecord{}
+	for _, client := range clients {
+		records = append(records, oauthClientRecord{
+			ClientID:     client.ClientID,
+			Name:         client.Name,
+			RedirectUris: client.RedirectUris,
+			Scopes:       client.Scopes,
+			Created:      client.Created,
+		})
+	}
+	return records, nil
+}
+
+// decodeImageData returns the bytes of an image or avatar, kept as base64 image data which may be given as
+// a data URI.
+func decodeImageData(data string) ([]byte, error) {
+	if i := strings.Index(data, ","); strings.HasPrefix(data, "data:") && i > 0 {
+		data = data[i+1:]
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		raw, err = base64.RawStdEncoding.DecodeString(data)
+	}
+	return raw, err
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func writeJSON(archive *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(archive, name, append(data, '\n'))
+}
+
+func writeFile(archive *zip.Writer, name string, data []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
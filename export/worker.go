@@ -0,0 +1,121 @@
+// Package export assembles archives of the data kept about an account, which the account can download to
+// take its data elsewhere.
+package export
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	db "github.com/meads/firstly-api/db"
+)
+
+const (
+	// Status of an export, as kept in the account_export table.
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+
+	defaultTTL = 48 * time.Hour
+	// staleAfter is how long an export can run before it is taken to have been abandoned by a worker that
+	// stopped, and is claimed again.
+	staleAfter   = 15 * time.Minute
+	pollInterval = time.Minute
+)
+
+// errBuildFailed is kept as the reason an export failed, rather than the error itself, which may tell more
+// about the database than the account should know.
+var errBuildFailed = errors.New("the export couldn't be assembled, request another")
+
+// TTL reads how long an export can be downloaded once it is ready from the EXPORT_TTL env variable, a Go
+// duration such as 48h.
+func TTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("EXPORT_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return defaultTTL
+}
+
+// Worker builds the archives of requested exports in the background, and deletes expired ones. Exports
+// are claimed from the database, so any number of workers can run alongside each other.
+type Worker struct {
+	store db.Store
+	kick  chan struct{}
+}
+
+func NewWorker(store db.Store) *Worker {
+	return &Worker{store: store, kick: make(chan struct{}, 1)}
+}
+
+// Kick wakes the worker to build a newly requested export rather than waiting for it to poll. It does
+// nothing on a nil worker, such as in a server that doesn't run one.
+func (w *Worker) Kick() {
+	if w == nil {
+		return
+	}
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Run builds exports until ctx is done, whenever it is kicked and every pollInterval in case an export was
+// requested from another instance.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		w.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.kick:
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain deletes expired exports and builds every waiting one.
+func (w *Worker) drain(ctx context.Context) {
+	if _, err := w.store.DeleteExpiredAccountExports(ctx); err != nil {
+		log.Printf("error deleting expired account exports: %s", err)
+	}
+	for ctx.Err() == nil {
+		built, err := w.RunOnce(ctx)
+		if err != nil {
+			log.Printf("error building account export: %s", err)
+			return
+		}
+		if !built {
+			return
+		}
+	}
+}
+
+// RunOnce claims the oldest waiting export and builds its archive, reporting whether there was one. An
+// export whose archive can't be built is marked failed so that it isn't retried forever.
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	job, err := w.store.ClaimAccountExport(ctx, time.Now().Add(-staleAfter))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	expires := time.Now().Add(TTL())
+	archive, err := Build(ctx, w.store, job.AccountID)
+	if err != nil {
+		log.Printf("error assembling account export %d: %s", job.ID, err)
+		return true, w.store.FailAccountExport(ctx, db.FailAccountExportParams{
+			Error:   errBuildFailed.Error(),
+			Expires: sql.NullTime{Time: expires, Valid: true},
+			ID:      job.ID,
+		})
+	}
+	return true, w.store.CompleteAccountExport(ctx, db.CompleteAccountExportParams{ID: job.ID, Archive: archive, Expires: expires})
+}
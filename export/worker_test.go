@@ -0,0 +1,184 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	db "github.com/meads/firstly-api/db"
+)
+
+// expectAccountData sets up the store to hold an account with a previous username, a session, a sign in
+// attempt, an avatar and an image.
+func expectAccountData(store *db.MockStore) {
+	store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Username: "valid", Phrase: []byte("hunter2"), TotpSecret: "hunter2"}, nil)
+	store.EXPECT().ListAccountUsernameHistory(gomock.Any(), int64(1)).Return([]db.UsernameHistory{{AccountID: 1, Username: "old"}}, nil)
+	store.EXPECT().ListAccountSessions(gomock.Any(), int64(1)).Return([]db.Session{{ID: 3, AccountID: 1, TokenHash: []byte("hunter2"), IP: "127.0.0.1"}}, nil)
+	store.EXPECT().ListAccountLoginAttempts(gomock.Any(), db.ListAccountLoginAttemptsParams{AccountID: 1, Limit: maxLoginAttempts}).
+		Return([]db.ListAccountLoginAttemptsRow{{Username: "valid", IP: "127.0.0.1", Success: true}, {Username: "old", IP: "127.0.0.2", Reason: "wrong_phrase"}}, nil)
+	store.EXPECT().ListAccountIdentities(gomock.Any(), int64(1)).Return([]db.AccountIdentity{}, nil)
+	store.EXPECT().ListAccountWebauthnCredentials(gomock.Any(), int64(1)).Return([]db.WebauthnCredential{}, nil)
+	store.EXPECT().ListAccountApiKeys(gomock.Any(), int64(1)).Return([]db.ApiKey{{Name: "ci", Prefix: "abc", TokenHash: []byte("hunter2")}}, nil)
+	store.EXPECT().ListAccountOauthClients(gomock.Any(), int64(1)).Return([]db.OauthClient{}, nil)
+	store.EXPECT().GetProfile(gomock.Any(), int64(1)).Return(db.GetProfileRow{AccountID: 1, DisplayName: "Valid", HasAvatar: true}, nil)
+	store.EXPECT().GetProfileAvatar(gomock.Any(), int64(1)).Return(db.GetProfileAvatarRow{Avatar: "data:image/png;base64,iVBORw0KGgo=", AvatarMimeType: "image/png"}, nil)
+	store.EXPECT().ListAccountImages(gomock.Any(), int64(1)).Return([]db.Image{{ID: 9, Data: "iVBORw0KGgo=", MimeType: "image/png"}}, nil)
+}
+
+func TestBuild(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := db.NewMockStore(ctrl)
+	expectAccountData(store)
+
+	archive, err := Build(context.Background(), store, 1)
+	if err != nil {
+		t.Fatalf("unexpected error building export: %s", err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("expected a zip archive: %s", err)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("unexpected error opening %s: %s", file.Name, err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("unexpected error reading %s: %s", file.Name, err)
+		}
+		files[file.Name] = data
+	}
+	for _, name := range []string{"README.txt", "account.json", "profile.json", "avatar.png", "username_history.json", "sessions.json",
+		"login_history.json", "identities.json", "passkeys.json", "api_keys.json", "oauth_clients.json", "images.json", "images/9.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected archive to contain %s", name)
+		}
+	}
+	for name, data := range files {
+		if bytes.Contains(data, []byte("hunter2")) {
+			t.Errorf("expected %s to leave out secrets but got: %s", name, data)
+		}
+	}
+	if !bytes.Equal(files["avatar.png"], []byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("expected the decoded avatar but got %q", files["avatar.png"])
+	}
+
+	var images []imageRecord
+	if err := json.Unmarshal(files["images.json"], &images); err != nil || len(images) != 1 || images[0].File != "images/9.png" {
+		t.Errorf("expected the uploaded image but got %s, err: %v", files["images.json"], err)
+	}
+	if !bytes.Equal(files["images/9.png"], []byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("expected the decoded image but got %q", files["images/9.png"])
+	}
+
+	var logins []loginRecord
+	if err := json.Unmarshal(files["login_history.json"], &logins); err != nil {
+		t.Fatalf("unexpected error decoding login history: %s", err)
+	}
+	if len(logins) != 2 || logins[0].Username != "valid" || logins[1].Username != "old" {
+		t.Errorf("expected sign in attempts of the current and previous username but got %+v", logins)
+	}
+	var passkeys []passkeyRecord
+	if err := json.Unmarshal(files["passkeys.json"], &passkeys); err != nil || passkeys == nil {
+		t.Errorf("expected an empty list of passkeys but got %s, err: %v", files["passkeys.json"], err)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	tests := []struct {
+		name              string
+		setupExpectations func(store *db.MockStore)
+		built             bool
+		err               bool
+	}{
+		{
+			name: "nothing to build",
+			setupExpectations: func(store *db.MockStore) {
+				store.EXPECT().ClaimAccountExport(gomock.Any(), gomock.Any()).Return(db.AccountExport{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "claims and completes an export",
+			setupExpectations: func(store *db.MockStore) {
+				store.EXPECT().ClaimAccountExport(gomock.Any(), gomock.Any()).
+					Return(db.AccountExport{ID: 7, AccountID: 1, Status: StatusRunning}, nil)
+				expectAccountData(store)
+				store.EXPECT().CompleteAccountExport(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, arg db.CompleteAccountExportParams) error {
+						if arg.ID != 7 || len(arg.Archive) == 0 {
+							t.Errorf("expected the archive of export 7 but got %+v", arg)
+						}
+						if ttl := time.Until(arg.Expires); ttl <= defaultTTL-time.Minute || ttl > defaultTTL {
+							t.Errorf("expected the export to expire in %s but was %s", defaultTTL, ttl)
+						}
+						return nil
+					})
+			},
+			built: true,
+		},
+		{
+			name: "marks an export that can't be built as failed",
+			setupExpectations: func(store *db.MockStore) {
+				store.EXPECT().ClaimAccountExport(gomock.Any(), gomock.Any()).
+					Return(db.AccountExport{ID: 7, AccountID: 1, Status: StatusRunning}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{}, errors.New("connection reset"))
+				store.EXPECT().FailAccountExport(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, arg db.FailAccountExportParams) error {
+						if arg.ID != 7 || arg.Error != errBuildFailed.Error() || !arg.Expires.Valid {
+							t.Errorf("expected export 7 to fail without the underlying error but got %+v", arg)
+						}
+						return nil
+					})
+			},
+			built: true,
+		},
+		{
+			name: "claim error",
+			setupExpectations: func(store *db.MockStore) {
+				store.EXPECT().ClaimAccountExport(gomock.Any(), gomock.Any()).Return(db.AccountExport{}, errors.New("connection reset"))
+			},
+			err: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := db.NewMockStore(ctrl)
+			test.setupExpectations(store)
+
+			built, err := NewWorker(store).RunOnce(context.Background())
+			if (err != nil) != test.err {
+				t.Fatalf("expected error %t but got: %v", test.err, err)
+			}
+			if built != test.built {
+				t.Fatalf("expected built %t but got %t", test.built, built)
+			}
+		})
+	}
+}
+
+func TestKick(t *testing.T) {
+	var w *Worker
+	w.Kick()
+
+	w = NewWorker(nil)
+	w.Kick()
+	w.Kick()
+	if len(w.kick) != 1 {
+		t.Fatalf("expected kicks to be coalesced but %d are waiting", len(w.kick))
+	}
+}
@@ -3,13 +3,60 @@ package http
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
 )
 
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 32
+)
+
+var errUsernameTaken = errors.New("please choose another username")
+
+// validateUsername returns why username can't name an account: it has to be 3 to 32 letters, digits,
+// dots, dashes and underscores, starting and ending with a letter or digit.
+func validateUsername(username string) error {
+	if length := utf8.RuneCountInString(username); length < minUsernameLength || length > maxUsernameLength {
+		return fmt.Errorf("username must be %d to %d characters long", minUsernameLength, maxUsernameLength)
+	}
+	runes := []rune(username)
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			continue
+		}
+		if (r == '.' || r == '-' || r == '_') && i > 0 && i < len(runes)-1 {
+			continue
+		}
+		return errors.New("username can only have letters, digits, dots, dashes and underscores, and must start and end with a letter or digit")
+	}
+	return nil
+}
+
+// checkPhrasePolicy responds with status bad request, and the code and reason, when phrase doesn't satisfy
+// the phrase policy for the account named username.
+func checkPhrasePolicy(ctx *gin.Context, phrase string, username string) bool {
+	err := firstly.phrasePolicy.Check(phrase, username)
+	if err == nil {
+		return true
+	}
+	var policyErr *security.PhrasePolicyError
+	if errors.As(err, &policyErr) {
+		ctx.JSON(http.StatusBadRequest, errorCodeResponse(policyErr.Code, err))
+		return false
+	}
+	ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	return false
+}
+
 type createAccountRequest struct {
 	Username string `json:"username" binding:"required"`
 	Phrase   string `json:"phrase" binding:"required"`
@@ -22,6 +69,12 @@ func createAccountHandler(ctx *gin.Context) {
 		return
 	}
 
+	if err := validateUsername(req.Username); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the unique index settles races, checking first saves hashing a phrase for a name that's taken
 	tmpAccount, err := firstly.store.GetAccountByUsername(ctx, req.Username)
 	if err != nil && !errors.Is(sql.ErrNoRows, err) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -29,7 +82,11 @@ func createAccountHandler(ctx *gin.Context) {
 	}
 
 	if tmpAccount.ID > 0 {
-		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("please choose another username")))
+		ctx.JSON(http.StatusConflict, errorResponse(errUsernameTaken))
+		return
+	}
+
+	if !checkPhrasePolicy(ctx, req.Phrase, req.Username) {
 		return
 	}
 
@@ -44,13 +101,22 @@ func createAccountHandler(ctx *gin.Context) {
 	}
 	param.Phrase = phrase
 
-	account, err := firstly.store.CreateAccount(ctx, param)
+	account, err := firstly.store.RegisterAccount(ctx, param)
+	if errors.Is(err, db.ErrUsernameHeld) || db.IsUniqueViolation(err, db.AccountUsernameIndex) {
+		ctx.JSON(http.StatusConflict, errorResponse(errUsernameTaken))
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	tokenString, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(account.Username)
+	claims, err := security.NewAccessClaims(account.ID, account.Username, account.Role)
+	if err != nil {
+		ctx.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	tokenString, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(claims)
 	if err != nil {
 		// If there is an error in creating the JWT return an internal server error
 		ctx.Writer.WriteHeader(http.StatusInternalServerError)
@@ -60,10 +126,15 @@ func createAccountHandler(ctx *gin.Context) {
 	// Finally, we set the client cookie for "token" as the JWT we just generated
 	// we also set an expiry time which is the same as the token itself
 	http.SetCookie(ctx.Writer, &http.Cookie{
-		Name:    "token",
+		Name:    accessTokenCookie,
 		Value:   tokenString,
 		Expires: expirationTime,
 	})
+
+	if _, _, err := startSession(ctx, account.ID, claims.Id, expirationTime); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
 }
 
 type loginAccountRequest struct {
@@ -92,7 +163,76 @@ func deleteAccountHandler(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, nil)
 }
 
+// listAccountsHandler responds with a page of accounts, newest first unless order=asc is given. Pages are
+// linked with signed cursors over (created, id); limit/offset paging and the bare array response are
+// still served to older clients.
 func listAccountsHandler(ctx *gin.Context) {
+	if usesOffsetPagination(ctx) {
+		listAccountsByOffset(ctx)
+		return
+	}
+
+	page, err := parsePageRequest(ctx, "created")
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	cursorCreated, cursorID := page.cursorValue()
+	var accounts []db.ListAccountsRow
+	if page.Desc {
+		rows, err := firstly.store.ListAccountsPageDesc(ctx, db.ListAccountsPageDescParams{
+			CursorCreated: cursorCreated,
+			CursorID:      cursorID,
+			Limit:         page.Limit + 1,
+		})
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		for _, row := range rows {
+			accounts = append(accounts, db.ListAccountsRow(row))
+		}
+	} else {
+		rows, err := firstly.store.ListAccountsPageAsc(ctx, db.ListAccountsPageAscParams{
+			CursorCreated: cursorCreated,
+			CursorID:      cursorID,
+			Limit:         page.Limit + 1,
+		})
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		for _, row := range rows {
+			accounts = append(accounts, db.ListAccountsRow(row))
+		}
+	}
+
+	var nextCursor string
+	if len(accounts) > int(page.Limit) {
+		accounts = accounts[:page.Limit]
+		last := accounts[len(accounts)-1]
+		nextCursor, err = page.nextCursor(last.Created, last.ID)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		setNextLink(ctx, nextCursor)
+	}
+	if accounts == nil {
+		accounts = []db.ListAccountsRow{}
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	if !wantsPageResponse(ctx) {
+		ctx.JSON(http.StatusOK, accounts)
+		return
+	}
+	ctx.JSON(http.StatusOK, pageResponse{Items: accounts, NextCursor: nextCursor})
+}
+
+// listAccountsByOffset is the deprecated limit/offset listing.
+func listAccountsByOffset(ctx *gin.Context) {
 	getLimitAndOffset := func(ctx *gin.Context) (string, string) {
 		limit := ctx.Query("limit")
 		if limit == "0" || limit == "" {
@@ -124,6 +264,7 @@ func listAccountsHandler(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("Deprecation", "true")
 	ctx.Header("Access-Control-Allow-Origin", "*")
 	ctx.JSON(http.StatusOK, images)
 }
@@ -160,6 +301,10 @@ func updateAccountHandler(ctx *gin.Context) {
 		return
 	}
 
+	if !checkPhrasePolicy(ctx, req.Phrase, account.Username) {
+		return
+	}
+
 	// update the phrase for the account using the current salt for the account
 	newPhrase, err := firstly.hasher.GeneratePasswordHash([]byte(req.Phrase), account.Salt)
 	if err != nil {
@@ -183,3 +328,185 @@ func updateAccountHandler(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, struct{ username string }{username: account.Username})
 }
+
+type accountResponse struct {
+	ID            int64  `json:"id"`
+	Username      string `json:"username"`
+	Role          string `json:"role"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	Created       string `json:"created"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		ID:            account.ID,
+		Username:      account.Username,
+		Role:          account.Role,
+		Email:         account.Email,
+		EmailVerified: account.EmailVerified,
+		Created:       account.Created,
+	}
+}
+
+type deleteMyAccountRequest struct {
+	CurrentPhrase string `json:"current_phrase" binding:"required"`
+}
+
+type updateMyAccountRequest struct {
+	CurrentPhrase string `json:"current_phrase" binding:"required"`
+	Phrase        string `json:"phrase"`
+	Username      string `json:"username"`
+}
+
+// getMyAccountHandler responds with the signed in account.
+func getMyAccountHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
+}
+
+// updateMyAccountHandler changes the signed in account's phrase, username or both, which needs the current
+// phrase so that a stolen token can't be used to take the account over. Changing the username reissues
+// the access token, which names it.
+func updateMyAccountHandler(ctx *gin.Context) {
+	var req updateMyAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Phrase == "" && req.Username == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("phrase or username is required")))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	valid, err := firstly.hasher.IsValidPassword(account.Phrase, account.Salt, req.CurrentPhrase)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("current phrase is incorrect")))
+		return
+	}
+	rename := req.Username != "" && req.Username != account.Username
+	if rename {
+		if err := validateUsername(req.Username); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	username := account.Username
+	if rename {
+		username = req.Username
+	}
+	if req.Phrase != "" && !checkPhrasePolicy(ctx, req.Phrase, username) {
+		return
+	}
+
+	if rename {
+		if !renameAccount(ctx, account, req.Username) {
+			return
+		}
+		account.Username = req.Username
+	}
+	if req.Phrase != "" {
+		if err := rehashPhrase(ctx, account, req.Phrase); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	// API keys look up the account's username each time they're used, only tokens from signing in name it
+	if !rename || !currentClaims(ctx).IsFirstParty() {
+		ctx.JSON(http.StatusOK, newAccountResponse(account))
+		return
+	}
+	tokenString, expirationTime, err := reissueAccessToken(ctx, account)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, renameResponse{
+		accountResponse: newAccountResponse(account),
+		AccessToken:     tokenString,
+		TokenType:       "Bearer",
+		ExpiresAt:       expirationTime,
+	})
+}
+
+// deleteMyAccountHandler deletes the signed in account along with its sessions given the current phrase,
+// revokes the access token and clears the cookies.
+func deleteMyAccountHandler(ctx *gin.Context) {
+	var req deleteMyAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	valid, err := firstly.hasher.IsValidPassword(account.Phrase, account.Salt, req.CurrentPhrase)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("current phrase is incorrect")))
+		return
+	}
+
+	if err := firstly.store.DeleteAccount(ctx, account.ID); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if err := revokeAccessToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	clearAuthCookies(ctx)
+	ctx.Status(http.StatusNoContent)
+}
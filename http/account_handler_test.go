@@ -14,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert/v2"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/meads/firstly-api/db"
 	"github.com/meads/firstly-api/security"
 )
@@ -27,10 +28,11 @@ func TestAccountHandler(t *testing.T) {
 		responseCode      int
 		route             string
 		isList            bool
+		isLegacyList      bool
 		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
 	}{
 		{
-			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"message\"}"),
+			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"paper lantern message\"}"),
 			method:       http.MethodPost,
 			name:         "create handler responds with Status Code 200 when valid data supplied",
 			responseCode: http.StatusOK,
@@ -40,14 +42,15 @@ func TestAccountHandler(t *testing.T) {
 				os.Setenv("SECRET", "test")
 				hash := []byte("generated_hash")
 				hasher.EXPECT().GenerateSalt().Return("salt").Times(1)
-				hasher.EXPECT().GeneratePasswordHash([]byte("message"), "salt").Return(hash, nil)
-				store.EXPECT().CreateAccount(
+				hasher.EXPECT().GeneratePasswordHash([]byte("paper lantern message"), "salt").Return(hash, nil)
+				store.EXPECT().RegisterAccount(
 					gomock.Any(),
 					db.CreateAccountParams{Username: "newuser", Phrase: hash, Salt: "salt"},
 				).Return(db.Account{Username: "newuser"}, nil)
 				tokenString := "mocktoken"
 				expirationTime := time.Now().Add(5 * time.Minute)
-				claimer.EXPECT().GetFiveMinuteExpirationToken("newuser").Return(tokenString, expirationTime, nil)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("newuser")).Return(tokenString, expirationTime, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Return(db.Session{ID: 1}, nil)
 
 			},
 		},
@@ -62,7 +65,7 @@ func TestAccountHandler(t *testing.T) {
 		},
 		{
 			name:         "create handler responds with Status Code 500 given there is an error hashing the phrase, no env var set",
-			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"message\"}"),
+			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"paper lantern message\"}"),
 			method:       http.MethodPost,
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/",
@@ -78,7 +81,7 @@ func TestAccountHandler(t *testing.T) {
 		},
 		{
 			name:         "create handler responds with Status Code 500 given there is some server error with get account",
-			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"message\"}"),
+			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"paper lantern message\"}"),
 			method:       http.MethodPost,
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/",
@@ -88,7 +91,7 @@ func TestAccountHandler(t *testing.T) {
 		},
 		{
 			name:         "create handler responds with Status Code 500 given there is some server error before create",
-			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"message\"}"),
+			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"paper lantern message\"}"),
 			method:       http.MethodPost,
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/",
@@ -97,26 +100,90 @@ func TestAccountHandler(t *testing.T) {
 				os.Setenv("SECRET", "test")
 				hash := []byte("generated_hash")
 				hasher.EXPECT().GenerateSalt().Return("salt").Times(1)
-				hasher.EXPECT().GeneratePasswordHash([]byte("message"), "salt").Return(hash, nil)
-				store.EXPECT().CreateAccount(
+				hasher.EXPECT().GeneratePasswordHash([]byte("paper lantern message"), "salt").Return(hash, nil)
+				store.EXPECT().RegisterAccount(
 					gomock.Any(),
 					db.CreateAccountParams{Username: "newuser", Phrase: []byte("generated_hash"), Salt: "salt"}).
 					Return(db.Account{}, errors.New("oops"))
 			},
 		},
 		{
-			name:         "create handler responds with Status Code 400 given a user already exists with username x",
-			body:         bytes.NewBufferString("{\"username\":\"invalid\",\"phrase\":\"valid\"}"),
+			name:         "create handler responds with Status Code 400 given a phrase that is too short",
+			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"message\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "newuser").Return(db.Account{ID: 0}, nil)
+				hasher.EXPECT().GeneratePasswordHash(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RegisterAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "create handler responds with Status Code 400 given a predictable phrase",
+			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"1234567890\"}"),
 			method:       http.MethodPost,
 			responseCode: http.StatusBadRequest,
 			route:        "/account/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "newuser").Return(db.Account{ID: 0}, nil)
+				store.EXPECT().RegisterAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "create handler responds with Status Code 409 given a user already exists with username x",
+			body:         bytes.NewBufferString("{\"username\":\"invalid\",\"phrase\":\"valid\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusConflict,
+			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
 				store.EXPECT().GetAccountByUsername(gomock.Any(), "invalid").
 					Return(db.Account{ID: 1}, nil)
 			},
 		},
 		{
-			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"message\"}"),
+			name:         "create handler responds with Status Code 409 given the username is taken while the account is created",
+			body:         bytes.NewBufferString("{\"username\":\"NewUser\",\"phrase\":\"paper lantern message\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusConflict,
+			route:        "/account/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "NewUser").Return(db.Account{ID: 0}, nil)
+				hash := []byte("generated_hash")
+				hasher.EXPECT().GenerateSalt().Return("salt")
+				hasher.EXPECT().GeneratePasswordHash([]byte("paper lantern message"), "salt").Return(hash, nil)
+				store.EXPECT().RegisterAccount(gomock.Any(), db.CreateAccountParams{Username: "NewUser", Phrase: hash, Salt: "salt"}).
+					Return(db.Account{}, &pq.Error{Code: "23505", Constraint: db.AccountUsernameIndex})
+				claimer.EXPECT().GetFiveMinuteExpirationToken(gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "create handler responds with Status Code 400 given a username with characters that aren't allowed",
+			body:         bytes.NewBufferString("{\"username\":\"new user\",\"phrase\":\"paper lantern message\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RegisterAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"paper lantern message\"}"),
+			method:       http.MethodPost,
+			name:         "create handler responds with Status Code 409 given a username another account still holds",
+			responseCode: http.StatusConflict,
+			route:        "/account/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "newuser").Return(db.Account{ID: 0}, nil)
+				hasher.EXPECT().GenerateSalt().Return("salt")
+				hasher.EXPECT().GeneratePasswordHash([]byte("paper lantern message"), "salt").Return([]byte("generated_hash"), nil)
+				store.EXPECT().RegisterAccount(gomock.Any(), gomock.Any()).Return(db.Account{}, db.ErrUsernameHeld)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"username\":\"newuser\",\"phrase\":\"paper lantern message\"}"),
 			method:       http.MethodPost,
 			name:         "create handler responds with Status Code 500 when get five minute expiration token returns an error",
 			responseCode: http.StatusInternalServerError,
@@ -126,14 +193,14 @@ func TestAccountHandler(t *testing.T) {
 				os.Setenv("SECRET", "test")
 				hash := []byte("generated_hash")
 				hasher.EXPECT().GenerateSalt().Return("salt").Times(1)
-				hasher.EXPECT().GeneratePasswordHash([]byte("message"), "salt").Return(hash, nil)
-				store.EXPECT().CreateAccount(
+				hasher.EXPECT().GeneratePasswordHash([]byte("paper lantern message"), "salt").Return(hash, nil)
+				store.EXPECT().RegisterAccount(
 					gomock.Any(),
 					db.CreateAccountParams{Username: "newuser", Phrase: hash, Salt: "salt"},
 				).Return(db.Account{Username: "newuser"}, nil)
 				tokenString := ""
 				expirationTime := time.Time{}
-				claimer.EXPECT().GetFiveMinuteExpirationToken("newuser").Return(tokenString, expirationTime, errors.New("oops"))
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("newuser")).Return(tokenString, expirationTime, errors.New("oops"))
 			},
 		},
 		{
@@ -143,7 +210,7 @@ func TestAccountHandler(t *testing.T) {
 			responseCode: http.StatusOK,
 			route:        "/account/69/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				store.EXPECT().DeleteAccount(gomock.Any(), int64(69)).Return(nil)
 			},
 		},
@@ -154,7 +221,7 @@ func TestAccountHandler(t *testing.T) {
 			responseCode: http.StatusBadRequest,
 			route:        "/account//",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 			},
 		},
 		{
@@ -164,7 +231,7 @@ func TestAccountHandler(t *testing.T) {
 			responseCode: http.StatusBadRequest,
 			route:        "/account/invalid/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 			},
 		},
 		{
@@ -174,7 +241,7 @@ func TestAccountHandler(t *testing.T) {
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/69/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				store.EXPECT().DeleteAccount(gomock.Any(), int64(69)).Return(errors.New("oops"))
 			},
 		},
@@ -185,7 +252,7 @@ func TestAccountHandler(t *testing.T) {
 			responseCode: http.StatusBadRequest,
 			route:        "/account/?limit=invalid",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 			},
 		},
 		{
@@ -195,7 +262,7 @@ func TestAccountHandler(t *testing.T) {
 			responseCode: http.StatusBadRequest,
 			route:        "/account/?offset=invalid",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 			},
 		},
 		{
@@ -205,20 +272,52 @@ func TestAccountHandler(t *testing.T) {
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
-				params := db.ListAccountsParams{Limit: 50, Offset: 0}
-				store.EXPECT().ListAccounts(gomock.Any(), params).Return([]db.ListAccountsRow{}, errors.New("oops."))
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+				params := db.ListAccountsPageDescParams{Limit: 51}
+				store.EXPECT().ListAccountsPageDesc(gomock.Any(), params).Return([]db.ListAccountsPageDescRow{}, errors.New("oops."))
 			},
 		},
 		{
 			body:         bytes.NewBufferString(""),
-			name:         "list handler responds with Status Code 200 given a valid request",
+			name:         "list handler responds with the bare array of accounts given no cursor",
 			method:       http.MethodGet,
 			responseCode: http.StatusOK,
 			route:        "/account/",
+			isLegacyList: true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+				params := db.ListAccountsPageDescParams{Limit: 51}
+				store.EXPECT().ListAccountsPageDesc(gomock.Any(), params).Return([]db.ListAccountsPageDescRow{
+					{ID: 69, Username: "foo", Created: "", Deleted: false},
+				}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with oldest first given order asc",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/account/?order=asc&limit=1&pagination=cursor",
 			isList:       true,
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				params := db.ListAccountsPageAscParams{Limit: 2}
+				store.EXPECT().ListAccountsPageAsc(gomock.Any(), params).Return([]db.ListAccountsPageAscRow{
+					{ID: 1, Username: "first", Created: "2022-10-30 12:00:00+00"},
+					{ID: 2, Username: "second", Created: "2022-10-30 12:00:01+00"},
+				}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler still pages with limit and offset given an offset param",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/account/?offset=0",
+			isLegacyList: true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				params := db.ListAccountsParams{Limit: 50, Offset: 0}
 				store.EXPECT().ListAccounts(gomock.Any(), params).Return([]db.ListAccountsRow{
 					{ID: 69, Username: "foo", Created: "", Deleted: false},
@@ -226,66 +325,88 @@ func TestAccountHandler(t *testing.T) {
 			},
 		},
 		{
-			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"newpass\"}"),
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 403 given the account isn't an admin",
+			method:       http.MethodGet,
+			responseCode: http.StatusForbidden,
+			route:        "/account/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ListAccountsPageDesc(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "delete handler responds with Status Code 403 given the account isn't an admin",
+			method:       http.MethodDelete,
+			responseCode: http.StatusForbidden,
+			route:        "/account/69/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"new pass phrase\"}"),
 			method:       http.MethodPatch,
 			name:         "update handler responds with Status Code 200 when valid data supplied",
 			responseCode: http.StatusOK,
 			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				store.EXPECT().AccountExists(gomock.Any(), int64(69)).Return(true, nil)
-				store.EXPECT().GetAccount(gomock.Any(), int64(69)).Return(db.Account{ID: 69, Phrase: []byte("newpass"), Salt: "salt the snail"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(69)).Return(db.Account{ID: 69, Phrase: []byte("new pass phrase"), Salt: "salt the snail"}, nil)
 				hasher.EXPECT().GenerateSalt().Times(0)
-				hasher.EXPECT().GeneratePasswordHash([]byte("newpass"), "salt the snail").Return([]byte("newhash"), nil)
+				hasher.EXPECT().GeneratePasswordHash([]byte("new pass phrase"), "salt the snail").Return([]byte("newhash"), nil)
 				params := db.UpdateAccountParams{ID: int64(69), Phrase: []byte("newhash")}
 				store.EXPECT().UpdateAccount(gomock.Any(), params).Return(nil)
 			},
 		},
 		{
-			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"wrong\":\"newpass\"}"),
+			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"wrong\":\"new pass phrase\"}"),
 			method:       http.MethodPatch,
 			name:         "update handler responds with Status Code 400 when invalid data supplied",
 			responseCode: http.StatusBadRequest,
 			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 			},
 		},
 		{
-			body:         bytes.NewBufferString("{\"id\":68,\"username\":\"user\",\"phrase\":\"newpass\"}"),
+			body:         bytes.NewBufferString("{\"id\":68,\"username\":\"user\",\"phrase\":\"new pass phrase\"}"),
 			method:       http.MethodPatch,
 			name:         "update handler responds with Status Code 404 when record not found",
 			responseCode: http.StatusNotFound,
 			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				store.EXPECT().AccountExists(gomock.Any(), int64(68)).Return(false, nil)
 			},
 		},
 		{
-			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"newpass\"}"),
+			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"new pass phrase\"}"),
 			method:       http.MethodPatch,
 			name:         "update handler responds with Status Code 500 when server error on get before update",
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				store.EXPECT().AccountExists(gomock.Any(), int64(69)).Return(true, nil)
 				store.EXPECT().GetAccount(gomock.Any(), int64(69)).
 					Return(db.Account{}, errors.New("oops"))
 			},
 		},
 		{
-			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"newpass\"}"),
+			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"new pass phrase\"}"),
 			method:       http.MethodPatch,
 			name:         "update handler responds with Status Code 500 when server error on update hash",
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				store.EXPECT().AccountExists(gomock.Any(), int64(69)).Return(true, nil)
 				store.EXPECT().GetAccount(gomock.Any(), int64(69)).Return(
-					db.Account{ID: 69, Salt: "somesalt", Phrase: []byte("newpass")}, nil)
+					db.Account{ID: 69, Salt: "somesalt", Phrase: []byte("new pass phrase")}, nil)
 				hasher.EXPECT().GenerateSalt().Times(0)
 				os.Unsetenv("SECRET")
 				hasher.EXPECT().GeneratePasswordHash(gomock.Any(), "somesalt").Return(
@@ -295,33 +416,151 @@ func TestAccountHandler(t *testing.T) {
 			},
 		},
 		{
-			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"newpass\"}"),
+			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"new pass phrase\"}"),
 			method:       http.MethodPatch,
 			name:         "update handler responds with Status Code 500 when server error on update",
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				store.EXPECT().AccountExists(gomock.Any(), int64(69)).Return(true, nil)
-				store.EXPECT().GetAccount(gomock.Any(), int64(69)).Return(db.Account{ID: 69, Phrase: []byte("newpass"), Salt: "salt the snail"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(69)).Return(db.Account{ID: 69, Phrase: []byte("new pass phrase"), Salt: "salt the snail"}, nil)
 				hasher.EXPECT().GenerateSalt().Times(0)
-				hasher.EXPECT().GeneratePasswordHash([]byte("newpass"), "salt the snail").Return([]byte("newhash"), nil)
+				hasher.EXPECT().GeneratePasswordHash([]byte("new pass phrase"), "salt the snail").Return([]byte("newhash"), nil)
 				params := db.UpdateAccountParams{ID: 69, Phrase: []byte("newhash")}
 				store.EXPECT().UpdateAccount(gomock.Any(), params).Return(errors.New("oops"))
 			},
 		},
 		{
-			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"newpass\"}"),
+			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"new pass phrase\"}"),
 			method:       http.MethodPatch,
 			name:         "update handler responds with Status Code 500 when server error on update account exists",
 			responseCode: http.StatusInternalServerError,
 			route:        "/account/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
-				passClaimsMiddleware(r, claimer, hasher, store)
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
 				store.EXPECT().AccountExists(gomock.Any(), int64(69)).Return(false, errors.New("oops"))
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
 		},
+		{
+			body:         bytes.NewBufferString("{\"id\":69,\"username\":\"user\",\"phrase\":\"new pass phrase\"}"),
+			method:       http.MethodPatch,
+			name:         "update handler responds with Status Code 403 given the account isn't an admin",
+			responseCode: http.StatusForbidden,
+			route:        "/account/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().AccountExists(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			method:       http.MethodGet,
+			name:         "get my account handler responds with the signed in account",
+			responseCode: http.StatusOK,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Username: "valid", Role: security.RoleMember}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"current_phrase\":\"oldpass\",\"phrase\":\"new pass phrase\"}"),
+			method:       http.MethodPatch,
+			name:         "update my account handler changes the phrase given the current phrase",
+			responseCode: http.StatusOK,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				account := db.Account{ID: 1, Username: "valid", Phrase: []byte("oldhash"), Salt: "salt"}
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "oldpass").Return(true, nil)
+				hasher.EXPECT().GenerateSalt().Return("")
+				hasher.EXPECT().GeneratePasswordHash([]byte("new pass phrase"), "").Return([]byte("newhash"), nil)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), db.UpdateAccountCredentialsParams{Phrase: []byte("newhash"), Salt: "", ID: 1}).Return(nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"current_phrase\":\"oldpass\",\"phrase\":\"valid-1234\"}"),
+			method:       http.MethodPatch,
+			name:         "update my account handler responds with Status Code 400 given a phrase containing the username",
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				account := db.Account{ID: 1, Username: "valid", Phrase: []byte("oldhash"), Salt: "salt"}
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "oldpass").Return(true, nil)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"current_phrase\":\"wrong\",\"phrase\":\"new pass phrase\"}"),
+			method:       http.MethodPatch,
+			name:         "update my account handler responds with Status Code 403 given the wrong current phrase",
+			responseCode: http.StatusForbidden,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				account := db.Account{ID: 1, Username: "valid", Phrase: []byte("oldhash"), Salt: "salt"}
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "wrong").Return(false, nil)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"phrase\":\"new pass phrase\"}"),
+			method:       http.MethodPatch,
+			name:         "update my account handler responds with Status Code 400 without the current phrase",
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"current_phrase\":\"valid\"}"),
+			method:       http.MethodDelete,
+			name:         "delete my account handler given the current phrase deletes the signed in account",
+			responseCode: http.StatusNoContent,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				account := db.Account{ID: 1, Username: "valid", Phrase: []byte("hash"), Salt: "salt"}
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "valid").Return(true, nil)
+				store.EXPECT().DeleteAccount(gomock.Any(), int64(1)).Return(nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"current_phrase\":\"wrong\"}"),
+			method:       http.MethodDelete,
+			name:         "delete my account handler responds with Status Code 403 given a wrong current phrase",
+			responseCode: http.StatusForbidden,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				account := db.Account{ID: 1, Username: "valid", Phrase: []byte("hash"), Salt: "salt"}
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "wrong").Return(false, nil)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			method:       http.MethodDelete,
+			name:         "delete my account handler responds with Status Code 400 without the current phrase",
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -348,7 +587,7 @@ func TestAccountHandler(t *testing.T) {
 			// Assert
 			assert.Equal(t, test.responseCode, result.StatusCode)
 
-			if !test.isList {
+			if !test.isList && !test.isLegacyList {
 				response := db.Account{}
 
 				if result.Body != http.NoBody {
@@ -359,17 +598,41 @@ func TestAccountHandler(t *testing.T) {
 						t.Log()
 					}
 				}
-			} else {
+			} else if test.isLegacyList {
 				response := []db.Account{}
-				if result.Body != http.NoBody {
-					if err := json.NewDecoder(result.Body).Decode(&response); err != nil && !errors.Is(err, io.EOF) {
-						t.Errorf("Error decoding response body: %v", err)
-						t.Log()
-						t.Log(responseRecorder.Body)
-						t.Log()
-					}
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+			} else {
+				response := struct {
+					Items      []db.Account `json:"items"`
+					NextCursor string       `json:"next_cursor"`
+				}{}
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
 				}
 			}
 		})
 	}
 }
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{username: "bob", valid: true},
+		{username: "Bob.Smith_2", valid: true},
+		{username: "zoë-ñ", valid: true},
+		{username: "ｂｏｂ", valid: true},
+		{username: "bo", valid: false},
+		{username: "abcdefghijklmnopqrstuvwxyz0123456", valid: false},
+		{username: ".bob", valid: false},
+		{username: "bob-", valid: false},
+		{username: "bob smith", valid: false},
+		{username: "bob@example.com", valid: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.valid, validateUsername(test.username) == nil)
+	}
+}
@@ -0,0 +1,200 @@
+package http
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+var errInvalidAPIKey = errors.New("api key is invalid or has expired")
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+	Created   time.Time  `json:"created"`
+	// Key is only returned when the key is created, it can't be retrieved afterwards
+	Key string `json:"key,omitempty"`
+}
+
+func newAPIKeyResponse(key db.ApiKey) apiKeyResponse {
+	response := apiKeyResponse{
+		ID:      key.ID,
+		Name:    key.Name,
+		Prefix:  security.APIKeyPrefix + key.Prefix,
+		Scopes:  strings.Fields(key.Scopes),
+		Created: key.Created,
+	}
+	if key.Expires.Valid {
+		response.ExpiresAt = &key.Expires.Time
+	}
+	if key.LastUsed.Valid {
+		response.LastUsed = &key.LastUsed.Time
+	}
+	return response
+}
+
+// apiKeyClaims returns the claims of the account an API key belongs to, limited to the key's scopes, and
+// records that the key was used. errInvalidAPIKey is returned for unknown or expired keys, and for keys
+// whose scopes the account's role no longer allows.
+func apiKeyClaims(ctx *gin.Context, key string) (*security.UsernameClaims, error) {
+	prefix, ok := security.APIKeyLookupPrefix(key)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+	apiKey, err := firstly.store.GetApiKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errInvalidAPIKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(apiKey.TokenHash, security.HashAPIKey(key)) != 1 {
+		return nil, errInvalidAPIKey
+	}
+	if apiKey.Expires.Valid && !apiKey.Expires.Time.After(time.Now()) {
+		return nil, errInvalidAPIKey
+	}
+
+	// last used is only informational, so failing to record it doesn't fail the request
+	if err := firstly.store.TouchApiKey(ctx, apiKey.ID); err != nil {
+		log.Printf("error recording use of api key %d: %s", apiKey.ID, err)
+	}
+
+	// a key can't do more than its account's current role allows, whatever it was created with
+	scope := security.LimitScopes(apiKey.Scopes, apiKey.Role)
+	if scope == "" {
+		return nil, errInvalidAPIKey
+	}
+
+	claims := security.NewUsernameClaims()
+	claims.Username = apiKey.Username
+	claims.Subject = strconv.FormatInt(apiKey.AccountID, 10)
+	claims.Role = apiKey.Role
+	claims.Scope = scope
+	return claims, nil
+}
+
+// createAPIKeyHandler creates an API key for the signed in account. A key can't be given scopes the
+// token creating it doesn't have.
+func createAPIKeyHandler(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	scopes, err := security.ParseScopes(req.Scopes)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !claims.HasScope(scope) {
+			ctx.JSON(http.StatusForbidden, errorResponse(fmt.Errorf("token is missing the %s scope", scope)))
+			return
+		}
+	}
+	var expires sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("expires_at must be in the future")))
+			return
+		}
+		expires = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	key, prefix, hash, err := security.NewAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	apiKey, err := firstly.store.CreateApiKey(ctx, db.CreateApiKeyParams{
+		AccountID: accountID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		TokenHash: hash,
+		Scopes:    scopes,
+		Expires:   expires,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := newAPIKeyResponse(apiKey)
+	response.Key = key
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// listAPIKeysHandler responds with the signed in account's API keys, newest first.
+func listAPIKeysHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	keys, err := firstly.store.ListAccountApiKeys(ctx, accountID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	items := make([]apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, newAPIKeyResponse(key))
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.JSON(http.StatusOK, items)
+}
+
+// deleteAPIKeyHandler deletes one of the signed in account's API keys, after which it is rejected.
+func deleteAPIKeyHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("id parameter must be a valid integer"))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	deleted, err := firstly.store.DeleteAccountApiKey(ctx, db.DeleteAccountApiKeyParams{ID: id, AccountID: accountID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if deleted == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
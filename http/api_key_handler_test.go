@@ -0,0 +1,214 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestAPIKeyHandlers(t *testing.T) {
+	key, prefix, hash, err := security.NewAPIKey()
+	if err != nil {
+		t.Fatalf("Error generating api key: %v", err)
+	}
+	created := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+
+	// expectAPIKey expects the request to be authenticated with key, limited to scopes
+	expectAPIKey := func(store *db.MockStore, scopes string, expires sql.NullTime) {
+		store.EXPECT().GetApiKeyByPrefix(gomock.Any(), prefix).Return(db.GetApiKeyByPrefixRow{
+			ID:        7,
+			AccountID: 1,
+			Username:  "valid",
+			Role:      security.RoleMember,
+			TokenHash: hash,
+			Scopes:    scopes,
+			Expires:   expires,
+		}, nil)
+	}
+
+	tests := []struct {
+		name              string
+		method            string
+		route             string
+		body              io.Reader
+		responseCode      int
+		challenge         string
+		createdKey        bool
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:         "create api key handler creates a key and responds with it once",
+			method:       http.MethodPost,
+			route:        "/account/me/tokens",
+			body:         bytes.NewBufferString(`{"name":"ci","scopes":["images:read","images:write"]}`),
+			responseCode: http.StatusCreated,
+			createdKey:   true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+						assert.Equal(t, int64(1), arg.AccountID)
+						assert.Equal(t, "ci", arg.Name)
+						assert.Equal(t, "images:read images:write", arg.Scopes)
+						assert.Equal(t, false, arg.Expires.Valid)
+						return db.ApiKey{ID: 7, AccountID: 1, Name: arg.Name, Prefix: arg.Prefix, Scopes: arg.Scopes, Created: created}, nil
+					})
+			},
+		},
+		{
+			name:         "create api key handler given an unknown scope responds with status bad request",
+			method:       http.MethodPost,
+			route:        "/account/me/tokens",
+			body:         bytes.NewBufferString(`{"name":"ci","scopes":["everything"]}`),
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "create api key handler given an expiry in the past responds with status bad request",
+			method:       http.MethodPost,
+			route:        "/account/me/tokens",
+			body:         bytes.NewBufferString(`{"name":"ci","scopes":["images:read"],"expires_at":"2020-01-01T00:00:00Z"}`),
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "create api key handler given an api key can't grant scopes the key doesn't have",
+			method:       http.MethodPost,
+			route:        "/account/me/tokens",
+			body:         bytes.NewBufferString(`{"name":"ci","scopes":["images:write"]}`),
+			responseCode: http.StatusForbidden,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectAPIKey(store, "account:write images:read", sql.NullTime{})
+				store.EXPECT().TouchApiKey(gomock.Any(), int64(7)).Return(nil)
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).Times(0)
+				r.Header.Set("Authorization", "Bearer "+key)
+			},
+		},
+		{
+			name:         "list api keys handler authenticated with an api key records its use",
+			method:       http.MethodGet,
+			route:        "/account/me/tokens",
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectAPIKey(store, "account:read", sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true})
+				store.EXPECT().TouchApiKey(gomock.Any(), int64(7)).Return(nil)
+				store.EXPECT().ListAccountApiKeys(gomock.Any(), int64(1)).Return([]db.ApiKey{{ID: 7, Prefix: prefix, Scopes: "account:read"}}, nil)
+				claimer.EXPECT().GetFromTokenString(gomock.Any()).Times(0)
+				r.Header.Set("Authorization", "Bearer "+key)
+			},
+		},
+		{
+			name:         "claims middleware given an api key without the route's scope responds with insufficient_scope",
+			method:       http.MethodGet,
+			route:        "/image/",
+			responseCode: http.StatusForbidden,
+			challenge:    `Bearer realm="firstly-api", error="insufficient_scope", error_description="token is missing the images:read scope"`,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectAPIKey(store, "account:read", sql.NullTime{})
+				store.EXPECT().TouchApiKey(gomock.Any(), int64(7)).Return(nil)
+				r.Header.Set("Authorization", "Bearer "+key)
+			},
+		},
+		{
+			name:         "claims middleware given an expired api key responds with status unauthorized",
+			method:       http.MethodGet,
+			route:        "/account/me/tokens",
+			responseCode: http.StatusUnauthorized,
+			challenge:    `Bearer realm="firstly-api", error="invalid_token", error_description="api key is invalid or has expired"`,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectAPIKey(store, "account:read", sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true})
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(0)
+				r.Header.Set("Authorization", "Bearer "+key)
+			},
+		},
+		{
+			name:         "claims middleware given an api key with the wrong secret responds with status unauthorized",
+			method:       http.MethodGet,
+			route:        "/account/me/tokens",
+			responseCode: http.StatusUnauthorized,
+			challenge:    `Bearer realm="firstly-api", error="invalid_token", error_description="api key is invalid or has expired"`,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectAPIKey(store, "account:read", sql.NullTime{})
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(0)
+				r.Header.Set("Authorization", "Bearer "+security.APIKeyPrefix+prefix+"_guessed")
+			},
+		},
+		{
+			name:         "delete api key handler deletes the key",
+			method:       http.MethodDelete,
+			route:        "/account/me/tokens/7",
+			responseCode: http.StatusNoContent,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().DeleteAccountApiKey(gomock.Any(), db.DeleteAccountApiKeyParams{ID: 7, AccountID: 1}).Return(int64(1), nil)
+			},
+		},
+		{
+			name:         "delete api key handler given another account's key responds with status not found",
+			method:       http.MethodDelete,
+			route:        "/account/me/tokens/8",
+			responseCode: http.StatusNotFound,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().DeleteAccountApiKey(gomock.Any(), db.DeleteAccountApiKeyParams{ID: 8, AccountID: 1}).Return(int64(0), nil)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(test.method, test.route, test.body)
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+			if test.challenge != "" {
+				assert.Equal(t, test.challenge, result.Header.Get("WWW-Authenticate"))
+			}
+
+			if test.createdKey {
+				var response apiKeyResponse
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, true, strings.HasPrefix(response.Key, response.Prefix+"_"))
+				assert.Equal(t, []string{"images:read", "images:write"}, response.Scopes)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	accessTokenCookie = "token"
+	bearerRealm       = "firstly-api"
+
+	// token error codes from RFC 6750 section 3.1
+	bearerInvalidRequest    = "invalid_request"
+	bearerInvalidToken      = "invalid_token"
+	bearerInsufficientScope = "insufficient_scope"
+)
+
+var errMalformedAuthorization = errors.New("authorization header must be of the form Bearer <token>")
+
+// headerTakesPrecedence reads which of the Authorization header and the token cookie is used when a
+// request has both from the AUTH_TOKEN_PRECEDENCE env variable, header (the default) or cookie.
+func headerTakesPrecedence() bool {
+	return !strings.EqualFold(os.Getenv("AUTH_TOKEN_PRECEDENCE"), "cookie")
+}
+
+// bearerToken returns the token of an Authorization: Bearer header, or an empty string when the request
+// has no Authorization header. Any other Authorization header is malformed.
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", nil
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+		return "", errMalformedAuthorization
+	}
+	return fields[1], nil
+}
+
+// accessToken returns the access token sent with the request, from either the Authorization header or
+// the token cookie, or an empty string when there isn't one.
+func accessToken(r *http.Request) (string, error) {
+	header, err := bearerToken(r)
+	if err != nil {
+		return "", err
+	}
+	var cookie string
+	if c, err := r.Cookie(accessTokenCookie); err == nil {
+		cookie = c.Value
+	}
+
+	if headerTakesPrecedence() {
+		if header != "" {
+			return header, nil
+		}
+		return cookie, nil
+	}
+	if cookie != "" {
+		return cookie, nil
+	}
+	return header, nil
+}
+
+// abortWithBearerChallenge responds with status and a WWW-Authenticate challenge as described in
+// RFC 6750 section 3. A request without any token is only given the realm, without an error code.
+func abortWithBearerChallenge(ctx *gin.Context, status int, code string, err error) {
+	challenge := fmt.Sprintf("Bearer realm=%q", bearerRealm)
+	if code != "" {
+		challenge += fmt.Sprintf(", error=%q", code)
+	}
+	if err != nil {
+		challenge += fmt.Sprintf(", error_description=%q", err.Error())
+	}
+	ctx.Header("WWW-Authenticate", challenge)
+
+	if err != nil {
+		ctx.AbortWithStatusJSON(status, errorCodeResponse(code, err))
+		return
+	}
+	ctx.AbortWithStatus(status)
+}
+
+// requireScope only calls h when the verified token allows scope, responding with status forbidden
+// otherwise. It must be wrapped by claimsMiddleware.
+func requireScope(scope string, h gin.HandlerFunc) gin.HandlerFunc {
+	return gin.HandlerFunc(func(ctx *gin.Context) {
+		if !currentClaims(ctx).HasScope(scope) {
+			abortWithBearerChallenge(ctx, http.StatusForbidden, bearerInsufficientScope, fmt.Errorf("token is missing the %s scope", scope))
+			return
+		}
+		h(ctx)
+	})
+}
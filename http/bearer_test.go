@@ -0,0 +1,132 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestBearerAuthentication(t *testing.T) {
+	// expectToken expects the request to be authenticated with tokenString
+	expectToken := func(tokenString string, claimer *security.MockClaimer, store *db.MockStore) {
+		claims := security.NewUsernameClaims()
+		claims.Username = "valid"
+		claims.Role = security.RoleMember
+		claims.Scope = strings.Join(security.RoleScopes(security.RoleMember), " ")
+		claims.Subject = "1"
+		claims.Id = "mockjti"
+		claimer.EXPECT().GetFromTokenString(tokenString).Return(&security.ClaimToken{Token: &jwt.Token{Valid: true}}, claims, nil)
+		store.EXPECT().IsTokenRevoked(gomock.Any(), "mockjti").Return(false, nil)
+		store.EXPECT().ListAccountSessions(gomock.Any(), int64(1)).Return([]db.Session{}, nil)
+	}
+
+	tests := []struct {
+		name              string
+		precedence        string
+		authorization     string
+		cookie            string
+		responseCode      int
+		challenge         string
+		setupExpectations func(claimer *security.MockClaimer, store *db.MockStore)
+	}{
+		{
+			name:          "claims middleware accepts a token in the authorization header",
+			authorization: "Bearer headertoken",
+			responseCode:  http.StatusOK,
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				expectToken("headertoken", claimer, store)
+			},
+		},
+		{
+			name:          "claims middleware prefers the authorization header over the cookie by default",
+			authorization: "Bearer headertoken",
+			cookie:        "cookietoken",
+			responseCode:  http.StatusOK,
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				expectToken("headertoken", claimer, store)
+			},
+		},
+		{
+			name:          "claims middleware prefers the cookie when AUTH_TOKEN_PRECEDENCE is cookie",
+			precedence:    "cookie",
+			authorization: "Bearer headertoken",
+			cookie:        "cookietoken",
+			responseCode:  http.StatusOK,
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				expectToken("cookietoken", claimer, store)
+			},
+		},
+		{
+			name:         "claims middleware given no token challenges with only the realm",
+			responseCode: http.StatusUnauthorized,
+			challenge:    `Bearer realm="firstly-api"`,
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				claimer.EXPECT().GetFromTokenString(gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "claims middleware given another authorization scheme responds with invalid_request",
+			authorization: "Basic dmFsaWQ6dmFsaWQ=",
+			cookie:        "cookietoken",
+			responseCode:  http.StatusBadRequest,
+			challenge:     `Bearer realm="firstly-api", error="invalid_request", error_description="authorization header must be of the form Bearer <token>"`,
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				claimer.EXPECT().GetFromTokenString(gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "claims middleware given an invalid token responds with invalid_token",
+			authorization: "Bearer expired",
+			responseCode:  http.StatusUnauthorized,
+			challenge:     `Bearer realm="firstly-api", error="invalid_token", error_description="access token is invalid or has expired"`,
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				claimer.EXPECT().GetFromTokenString("expired").
+					Return(&security.ClaimToken{Token: &jwt.Token{Valid: false}}, security.NewUsernameClaims(), &jwt.ValidationError{})
+				store.EXPECT().ListAccountSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			t.Setenv("AUTH_TOKEN_PRECEDENCE", test.precedence)
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodGet, "/account/me/sessions", nil)
+			if test.authorization != "" {
+				request.Header.Set("Authorization", test.authorization)
+			}
+			if test.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: accessTokenCookie, Value: test.cookie})
+			}
+			test.setupExpectations(mockClaimer, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+			assert.Equal(t, test.challenge, result.Header.Get("WWW-Authenticate"))
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	mailer "github.com/meads/firstly-api/mail"
+	"github.com/meads/firstly-api/security"
+)
+
+const (
+	defaultAppURL         = "http://localhost:5000"
+	defaultResetTokenTTL  = time.Hour
+	verifyEmailTokenTTL   = 48 * time.Hour
+	tokenPurposeVerify    = "verify_email"
+	tokenPurposeResetPass = "reset_phrase"
+
+	// backgroundMailTimeout limits how long an email sent after the response can take
+	backgroundMailTimeout = time.Minute
+)
+
+var (
+	errInvalidEmail        = errors.New("email must be a plain address, such as bob@example.com")
+	errEmailTaken          = errors.New("email is used by another account")
+	errInvalidAccountToken = errors.New("token is invalid, has expired or has already been used")
+)
+
+type updateMyEmailRequest struct {
+	Email         string `json:"email" binding:"required"`
+	CurrentPhrase string `json:"current_phrase" binding:"required"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type forgotPhraseRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type resetPhraseRequest struct {
+	Token  string `json:"token" binding:"required"`
+	Phrase string `json:"phrase" binding:"required"`
+}
+
+// appURL is where the links in emails point, from the APP_URL env variable.
+func appURL() string {
+	if base := os.Getenv("APP_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return defaultAppURL
+}
+
+// resetTokenTTL is how long a phrase reset link lasts, from the RESET_TOKEN_TTL env variable.
+func resetTokenTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("RESET_TOKEN_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return defaultResetTokenTTL
+}
+
+// parseEmail checks email is a plain address, without a name.
+func parseEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", errInvalidEmail
+	}
+	return email, nil
+}
+
+// sendAccountToken replaces the account's unused tokens for purpose with a new one lasting ttl, returning
+// the link to email with it.
+func sendAccountToken(ctx context.Context, accountID int64, email string, purpose string, ttl time.Duration, path string) (string, time.Time, error) {
+	if err := firstly.store.DeleteAccountTokens(ctx, db.DeleteAccountTokensParams{AccountID: accountID, Purpose: purpose}); err != nil {
+		return "", time.Time{}, err
+	}
+	token, hash, err := security.NewOneTimeToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(ttl)
+	err = firstly.store.CreateAccountToken(ctx, db.CreateAccountTokenParams{
+		AccountID: accountID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		Email:     email,
+		Expires:   expires,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return fmt.Sprintf("%s%s?token=%s", appURL(), path, url.QueryEscape(token)), expires, nil
+}
+
+// sendVerificationEmail emails a link verifying the account's new email address.
+func sendVerificationEmail(ctx context.Context, account db.Account, email string) error {
+	link, _, err := sendAccountToken(ctx, account.ID, email, tokenPurposeVerify, verifyEmailTokenTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+	return firstly.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below within two days to confirm this is your email address.\n\n%s\n\n"+
+			"If you didn't add it to an account you can ignore this email.\n", account.Username, link),
+	})
+}
+
+// sendResetEmail emails a link for resetting the account's phrase.
+func sendResetEmail(ctx context.Context, account db.Account) error {
+	link, expires, err := sendAccountToken(ctx, account.ID, account.Email, tokenPurposeResetPass, resetTokenTTL(), "/reset-phrase")
+	if err != nil {
+		return err
+	}
+	return firstly.mailer.Send(ctx, mailer.Message{
+		To:      account.Email,
+		Subject: "Reset your phrase",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to choose a new phrase. It can be used once, until %s.\n\n%s\n\n"+
+			"If you didn't ask to reset your phrase you can ignore this email.\n", account.Username, expires.UTC().Format(time.RFC1123), link),
+	})
+}
+
+// useAccountToken marks a token for purpose used, returning the account and the email address it was sent
+// to. errInvalidAccountToken is returned for unknown, expired and used tokens.
+func useAccountToken(ctx *gin.Context, token string, purpose string) (db.UseAccountTokenRow, error) {
+	row, err := firstly.store.UseAccountToken(ctx, db.UseAccountTokenParams{TokenHash: security.HashOneTimeToken(token), Purpose: purpose})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return row, errInvalidAccountToken
+		}
+		return row, err
+	}
+	return row, nil
+}
+
+// updateMyEmailHandler sets the signed in account's email address, which is unverified until the link
+// emailed to it is opened. It needs the current phrase, since the address can be used to reset the phrase.
+func updateMyEmailHandler(ctx *gin.Context) {
+	var req updateMyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	email, err := parseEmail(req.Email)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	valid, err := firstly.hasher.IsValidPassword(account.Phrase, account.Salt, req.CurrentPhrase)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("current phrase is incorrect")))
+		return
+	}
+
+	owner, err := firstly.store.GetAccountByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && owner.ID != account.ID {
+		ctx.JSON(http.StatusConflict, errorResponse(errEmailTaken))
+		return
+	}
+	if account.EmailVerified && account.Email == email {
+		ctx.JSON(http.StatusOK, newAccountResponse(account))
+		return
+	}
+
+	if err := firstly.store.UpdateAccountEmail(ctx, db.UpdateAccountEmailParams{Email: email, ID: account.ID}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	account.Email = email
+	account.EmailVerified = false
+	if err := sendVerificationEmail(ctx, account, email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusAccepted, newAccountResponse(account))
+}
+
+// verifyEmailHandler verifies the email address a verification link was sent to, as long as it is still
+// the account's address.
+func verifyEmailHandler(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	row, err := useAccountToken(ctx, req.Token, tokenPurposeVerify)
+	if err != nil {
+		if errors.Is(err, errInvalidAccountToken) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	verified, err := firstly.store.VerifyAccountEmail(ctx, db.VerifyAccountEmailParams{ID: row.AccountID, Email: row.Email})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if verified == 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidAccountToken))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// forgotPhraseHandler emails a phrase reset link to the account with the verified email address. It
+// responds the same whether or not there is such an account, so that addresses can't be probed for.
+func forgotPhraseHandler(ctx *gin.Context) {
+	var req forgotPhraseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	email, err := parseEmail(req.Email)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := firstly.store.GetAccountByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && account.EmailVerified {
+		// sent in the background, so that how long the response takes doesn't tell whether there is an account
+		firstly.mailing.Add(1)
+		go func() {
+			defer firstly.mailing.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), backgroundMailTimeout)
+			defer cancel()
+			if err := sendResetEmail(ctx, account); err != nil {
+				log.Printf("error sending a phrase reset email to account %d: %s", account.ID, err)
+			}
+		}()
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+// resetPhraseHandler sets a new phrase given a reset link's token, signing the account out everywhere and
+// lifting any sign in lockout.
+func resetPhraseHandler(ctx *gin.Context) {
+	var req resetPhraseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	// the account isn't known until the token is used up, so everything but the username is checked first
+	// to keep a weak phrase from wasting the link
+	if !checkPhrasePolicy(ctx, req.Phrase, "") {
+		return
+	}
+
+	row, err := useAccountToken(ctx, req.Token, tokenPurposeResetPass)
+	if err != nil {
+		if errors.Is(err, errInvalidAccountToken) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	account, err := firstly.store.GetAccount(ctx, row.AccountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidAccountToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// the link is only good for the address it was sent to
+	if account.Email != row.Email || !account.EmailVerified {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidAccountToken))
+		return
+	}
+	if !checkPhrasePolicy(ctx, req.Phrase, account.Username) {
+		return
+	}
+
+	if err := rehashPhrase(ctx, account, req.Phrase); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err := revokeAccountSessions(ctx, account.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if _, err := firstly.store.ClearUsernameLoginFailures(ctx, account.Username); err != nil {
+		log.Printf("error clearing sign in failures for %q: %s", account.Username, err)
+	}
+	ctx.Status(http.StatusNoContent)
+}
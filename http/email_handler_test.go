@@ -0,0 +1,335 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/mail"
+	"github.com/meads/firstly-api/security"
+)
+
+// linkToken returns the token of the link in an email.
+func linkToken(t *testing.T, body string) string {
+	start := strings.Index(body, "?token=")
+	if start < 0 {
+		t.Fatalf("Email has no link: %q", body)
+	}
+	token := body[start+len("?token="):]
+	if end := strings.IndexAny(token, " \n"); end >= 0 {
+		token = token[:end]
+	}
+	token, err := url.QueryUnescape(token)
+	if err != nil {
+		t.Fatalf("Error unescaping link token: %v", err)
+	}
+	return token
+}
+
+func TestEmailHandlers(t *testing.T) {
+	account := db.Account{ID: 1, Username: "valid", Phrase: []byte("valid"), Salt: "salt", Role: security.RoleMember}
+	verifiedAccount := account
+	verifiedAccount.Email = "valid@example.com"
+	verifiedAccount.EmailVerified = true
+
+	var tokenHash []byte
+
+	tests := []struct {
+		name              string
+		method            string
+		route             string
+		body              io.Reader
+		responseCode      int
+		expectedError     string
+		assertBody        func(t *testing.T, body io.Reader)
+		assertOutbox      func(t *testing.T, outbox *mail.Outbox)
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:         "update my email handler given a new address stores it unverified and emails a verification link",
+			method:       http.MethodPut,
+			route:        "/account/me/email",
+			body:         bytes.NewBufferString(`{"email":"new@example.com","current_phrase":"valid"}`),
+			responseCode: http.StatusAccepted,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response accountResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, "new@example.com", response.Email)
+				assert.Equal(t, false, response.EmailVerified)
+			},
+			assertOutbox: func(t *testing.T, outbox *mail.Outbox) {
+				message, ok := outbox.Last("new@example.com")
+				if !ok {
+					t.Fatal("No verification email was sent")
+				}
+				assert.Equal(t, true, strings.Contains(message.Body, defaultAppURL+"/verify-email?token="))
+				// only the hash of the emailed token is stored
+				assert.Equal(t, tokenHash, security.HashOneTimeToken(linkToken(t, message.Body)))
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "valid").Return(true, nil)
+				store.EXPECT().GetAccountByEmail(gomock.Any(), "new@example.com").Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().UpdateAccountEmail(gomock.Any(), db.UpdateAccountEmailParams{Email: "new@example.com", ID: 1}).Return(nil)
+				store.EXPECT().DeleteAccountTokens(gomock.Any(), db.DeleteAccountTokensParams{AccountID: 1, Purpose: tokenPurposeVerify}).Return(nil)
+				store.EXPECT().CreateAccountToken(gomock.Any(), gomock.AssignableToTypeOf(db.CreateAccountTokenParams{})).
+					DoAndReturn(func(_ interface{}, arg db.CreateAccountTokenParams) error {
+						assert.Equal(t, tokenPurposeVerify, arg.Purpose)
+						assert.Equal(t, "new@example.com", arg.Email)
+						assert.Equal(t, true, arg.Expires.After(time.Now().Add(verifyEmailTokenTTL-time.Minute)))
+						tokenHash = arg.TokenHash
+						return nil
+					})
+			},
+		},
+		{
+			name:          "update my email handler given an address that isn't plain responds with status bad request",
+			method:        http.MethodPut,
+			route:         "/account/me/email",
+			body:          bytes.NewBufferString(`{"email":"Bob <bob@example.com>","current_phrase":"valid"}`),
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidEmail.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			name:          "update my email handler given the wrong current phrase responds with status forbidden",
+			method:        http.MethodPut,
+			route:         "/account/me/email",
+			body:          bytes.NewBufferString(`{"email":"new@example.com","current_phrase":"wrong"}`),
+			responseCode:  http.StatusForbidden,
+			expectedError: "current phrase is incorrect",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "wrong").Return(false, nil)
+				store.EXPECT().UpdateAccountEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "update my email handler given the address of another account responds with status conflict",
+			method:        http.MethodPut,
+			route:         "/account/me/email",
+			body:          bytes.NewBufferString(`{"email":"taken@example.com","current_phrase":"valid"}`),
+			responseCode:  http.StatusConflict,
+			expectedError: errEmailTaken.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "valid").Return(true, nil)
+				store.EXPECT().GetAccountByEmail(gomock.Any(), "taken@example.com").Return(db.Account{ID: 2, Email: "taken@example.com"}, nil)
+				store.EXPECT().UpdateAccountEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "verify email handler given a valid token verifies the address it was sent to",
+			method:       http.MethodPost,
+			route:        "/auth/verify-email",
+			body:         bytes.NewBufferString(`{"token":"verifytoken"}`),
+			responseCode: http.StatusNoContent,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().UseAccountToken(gomock.Any(), db.UseAccountTokenParams{TokenHash: security.HashOneTimeToken("verifytoken"), Purpose: tokenPurposeVerify}).
+					Return(db.UseAccountTokenRow{AccountID: 1, Email: "valid@example.com"}, nil)
+				store.EXPECT().VerifyAccountEmail(gomock.Any(), db.VerifyAccountEmailParams{ID: 1, Email: "valid@example.com"}).Return(int64(1), nil)
+			},
+		},
+		{
+			name:          "verify email handler given a used or expired token responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/auth/verify-email",
+			body:          bytes.NewBufferString(`{"token":"usedtoken"}`),
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidAccountToken.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().UseAccountToken(gomock.Any(), gomock.Any()).Return(db.UseAccountTokenRow{}, sql.ErrNoRows)
+				store.EXPECT().VerifyAccountEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "verify email handler given a token for an address the account has since changed responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/auth/verify-email",
+			body:          bytes.NewBufferString(`{"token":"oldtoken"}`),
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidAccountToken.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().UseAccountToken(gomock.Any(), gomock.Any()).Return(db.UseAccountTokenRow{AccountID: 1, Email: "old@example.com"}, nil)
+				store.EXPECT().VerifyAccountEmail(gomock.Any(), db.VerifyAccountEmailParams{ID: 1, Email: "old@example.com"}).Return(int64(0), nil)
+			},
+		},
+		{
+			name:         "forgot phrase handler given a verified address emails a reset link",
+			method:       http.MethodPost,
+			route:        "/auth/forgot",
+			body:         bytes.NewBufferString(`{"email":"valid@example.com"}`),
+			responseCode: http.StatusAccepted,
+			assertOutbox: func(t *testing.T, outbox *mail.Outbox) {
+				message, ok := outbox.Last("valid@example.com")
+				if !ok {
+					t.Fatal("No reset email was sent")
+				}
+				assert.Equal(t, true, strings.Contains(message.Body, defaultAppURL+"/reset-phrase?token="))
+				assert.Equal(t, tokenHash, security.HashOneTimeToken(linkToken(t, message.Body)))
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByEmail(gomock.Any(), "valid@example.com").Return(verifiedAccount, nil)
+				store.EXPECT().DeleteAccountTokens(gomock.Any(), db.DeleteAccountTokensParams{AccountID: 1, Purpose: tokenPurposeResetPass}).Return(nil)
+				store.EXPECT().CreateAccountToken(gomock.Any(), gomock.AssignableToTypeOf(db.CreateAccountTokenParams{})).
+					DoAndReturn(func(_ interface{}, arg db.CreateAccountTokenParams) error {
+						assert.Equal(t, tokenPurposeResetPass, arg.Purpose)
+						assert.Equal(t, true, arg.Expires.Before(time.Now().Add(defaultResetTokenTTL+time.Minute)))
+						tokenHash = arg.TokenHash
+						return nil
+					})
+			},
+		},
+		{
+			name:         "forgot phrase handler given an unknown address responds the same without sending mail",
+			method:       http.MethodPost,
+			route:        "/auth/forgot",
+			body:         bytes.NewBufferString(`{"email":"nobody@example.com"}`),
+			responseCode: http.StatusAccepted,
+			assertOutbox: func(t *testing.T, outbox *mail.Outbox) {
+				assert.Equal(t, 0, len(outbox.Messages()))
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByEmail(gomock.Any(), "nobody@example.com").Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CreateAccountToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "forgot phrase handler given an unverified address doesn't send mail",
+			method:       http.MethodPost,
+			route:        "/auth/forgot",
+			body:         bytes.NewBufferString(`{"email":"new@example.com"}`),
+			responseCode: http.StatusAccepted,
+			assertOutbox: func(t *testing.T, outbox *mail.Outbox) {
+				assert.Equal(t, 0, len(outbox.Messages()))
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				unverified := account
+				unverified.Email = "new@example.com"
+				store.EXPECT().GetAccountByEmail(gomock.Any(), "new@example.com").Return(unverified, nil)
+				store.EXPECT().CreateAccountToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "reset phrase handler given a valid token sets the phrase and signs out everywhere",
+			method:       http.MethodPost,
+			route:        "/auth/reset",
+			body:         bytes.NewBufferString(`{"token":"resettoken","phrase":"new phrase"}`),
+			responseCode: http.StatusNoContent,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().UseAccountToken(gomock.Any(), db.UseAccountTokenParams{TokenHash: security.HashOneTimeToken("resettoken"), Purpose: tokenPurposeResetPass}).
+					Return(db.UseAccountTokenRow{AccountID: 1, Email: "valid@example.com"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(verifiedAccount, nil)
+				hasher.EXPECT().GenerateSalt().Return("newsalt")
+				hasher.EXPECT().GeneratePasswordHash([]byte("new phrase"), "newsalt").Return([]byte("newhash"), nil)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), db.UpdateAccountCredentialsParams{Phrase: []byte("newhash"), Salt: "newsalt", ID: 1}).Return(nil)
+				expires := time.Now().Add(time.Minute)
+				store.EXPECT().RevokeAccountSessions(gomock.Any(), int64(1)).
+					Return([]db.RevokeAccountSessionsRow{{AccessJti: "jti1", AccessExpires: expires}}, nil)
+				store.EXPECT().RevokeToken(gomock.Any(), db.RevokeTokenParams{Jti: "jti1", Expires: expires}).Return(nil)
+				store.EXPECT().ClearUsernameLoginFailures(gomock.Any(), "valid").Return(int64(3), nil)
+			},
+		},
+		{
+			name:          "reset phrase handler given a token sent to an address the account no longer has responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/auth/reset",
+			body:          bytes.NewBufferString(`{"token":"resettoken","phrase":"new phrase"}`),
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidAccountToken.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().UseAccountToken(gomock.Any(), gomock.Any()).Return(db.UseAccountTokenRow{AccountID: 1, Email: "old@example.com"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(verifiedAccount, nil)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "reset phrase handler given a phrase that is too short responds with status bad request without using the token",
+			method:        http.MethodPost,
+			route:         "/auth/reset",
+			body:          bytes.NewBufferString(`{"token":"resettoken","phrase":"short"}`),
+			responseCode:  http.StatusBadRequest,
+			expectedError: "phrase must be at least 8 characters long, it is 5",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().UseAccountToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "reset phrase handler given a used or expired token responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/auth/reset",
+			body:          bytes.NewBufferString(`{"token":"usedtoken","phrase":"new phrase"}`),
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidAccountToken.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().UseAccountToken(gomock.Any(), gomock.Any()).Return(db.UseAccountTokenRow{}, sql.ErrNoRows)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			server := NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			outbox := mail.NewOutbox("")
+			server.UseMailer(outbox)
+			responseRecorder := httptest.NewRecorder()
+
+			tokenHash = nil
+			request := httptest.NewRequest(test.method, test.route, test.body)
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			if test.expectedError != "" {
+				var response map[string]string
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.expectedError, response["error"])
+			}
+			if test.assertBody != nil {
+				test.assertBody(t, result.Body)
+			}
+			if test.assertOutbox != nil {
+				// reset emails are sent after the response
+				server.mailing.Wait()
+				test.assertOutbox(t, outbox)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/export"
+	"github.com/meads/firstly-api/security"
+)
+
+// exportPollAfter is how many seconds a client is asked to wait before polling an unfinished export again.
+const exportPollAfter = "5"
+
+var errExportNotFound = errors.New("export not found, or it has expired")
+
+type exportResponse struct {
+	ID        int64      `json:"id"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	Size      int64      `json:"size,omitempty"`
+	Created   time.Time  `json:"created"`
+	Completed *time.Time `json:"completed,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// DownloadURL links to the archive while the export is ready, and needs no other credentials
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func newExportResponse(accountExport db.AccountExport) (exportResponse, error) {
+	response := exportResponse{
+		ID:      accountExport.ID,
+		Status:  accountExport.Status,
+		Error:   accountExport.Error,
+		Size:    accountExport.Size,
+		Created: accountExport.Created,
+	}
+	if accountExport.Completed.Valid {
+		response.Completed = &accountExport.Completed.Time
+	}
+	if accountExport.Expires.Valid {
+		response.ExpiresAt = &accountExport.Expires.Time
+	}
+	if accountExport.Status == export.StatusReady && accountExport.Expires.Time.After(time.Now()) {
+		token, err := security.SignExportToken(accountExport.ID, accountExport.Expires.Time)
+		if err != nil {
+			return exportResponse{}, err
+		}
+		response.DownloadURL = "/account/export/" + token
+	}
+	return response, nil
+}
+
+// respondExport responds with the status of an export, asking the client to poll again while it is
+// waiting to be built.
+func respondExport(ctx *gin.Context, status int, accountExport db.AccountExport) {
+	response, err := newExportResponse(accountExport)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if accountExport.Status == export.StatusPending || accountExport.Status == export.StatusRunning {
+		ctx.Header("Retry-After", exportPollAfter)
+	}
+	ctx.Header("Location", "/account/me/export/"+strconv.FormatInt(accountExport.ID, 10))
+	ctx.JSON(status, response)
+}
+
+// requestExportHandler requests an export of the signed in account's data, which is built in the
+// background. While an earlier export is still waiting to be built it is returned rather than requesting
+// another.
+func requestExportHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	latest, err := firstly.store.GetLatestAccountExport(ctx, accountID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && (latest.Status == export.StatusPending || latest.Status == export.StatusRunning) {
+		respondExport(ctx, http.StatusAccepted, latest)
+		return
+	}
+
+	accountExport, err := firstly.store.CreateAccountExport(ctx, accountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	firstly.exporter.Kick()
+	respondExport(ctx, http.StatusAccepted, accountExport)
+}
+
+// getExportHandler responds with the status of one of the signed in account's exports, with a link to
+// download it once it is ready.
+func getExportHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("id parameter must be a valid integer"))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	accountExport, err := firstly.store.GetAccountExport(ctx, db.GetAccountExportParams{ID: id, AccountID: accountID})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, errorResponse(errExportNotFound))
+		return
+	}
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	respondExport(ctx, http.StatusOK, accountExport)
+}
+
+// downloadExportHandler serves the archive of an export through the signed link given with its status.
+// The link is the only credential, so that it can be opened in a browser, and stops working when the
+// export expires.
+func downloadExportHandler(ctx *gin.Context) {
+	id, err := security.VerifyExportToken(ctx.Param("token"), time.Now())
+	if errors.Is(err, security.ErrInvalidExportToken) {
+		ctx.JSON(http.StatusNotFound, errorResponse(errExportNotFound))
+		return
+	}
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	archive, err := firstly.store.GetAccountExportArchive(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, errorResponse(errExportNotFound))
+		return
+	}
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="firstly-export-`+strconv.FormatInt(id, 10)+`.zip"`)
+	ctx.Header("Cache-Control", "private, no-store")
+	ctx.Data(http.StatusOK, "application/zip", archive)
+}
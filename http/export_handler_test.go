@@ -0,0 +1,213 @@
+package http
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/export"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestExportHandlers(t *testing.T) {
+	t.Setenv("SECRET_KEYS", "")
+	t.Setenv("SECRET", "test")
+	created := time.Date(2022, 11, 2, 12, 0, 0, 0, time.UTC)
+	expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	pending := db.AccountExport{ID: 5, AccountID: 1, Status: export.StatusPending, Created: created}
+	ready := db.AccountExport{
+		ID:        5,
+		AccountID: 1,
+		Status:    export.StatusReady,
+		Size:      4,
+		Created:   created,
+		Completed: sql.NullTime{Time: created, Valid: true},
+		Expires:   sql.NullTime{Time: expires, Valid: true},
+	}
+	token, err := security.SignExportToken(5, expires)
+	if err != nil {
+		t.Fatalf("unexpected error signing export token: %s", err)
+	}
+	expiredToken, err := security.SignExportToken(5, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error signing export token: %s", err)
+	}
+
+	tests := []struct {
+		name              string
+		method            string
+		responseCode      int
+		route             string
+		expectedExport    *exportResponse
+		expectedLocation  string
+		expectedBody      string
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:             "request export handler requests an export and responds with where to poll its status",
+			method:           http.MethodPost,
+			responseCode:     http.StatusAccepted,
+			route:            "/account/me/export",
+			expectedExport:   &exportResponse{ID: 5, Status: export.StatusPending, Created: created},
+			expectedLocation: "/account/me/export/5",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetLatestAccountExport(gomock.Any(), int64(1)).Return(db.AccountExport{}, sql.ErrNoRows)
+				store.EXPECT().CreateAccountExport(gomock.Any(), int64(1)).Return(pending, nil)
+			},
+		},
+		{
+			name:             "request export handler responds with the export still waiting to be built rather than requesting another",
+			method:           http.MethodPost,
+			responseCode:     http.StatusAccepted,
+			route:            "/account/me/export",
+			expectedExport:   &exportResponse{ID: 5, Status: export.StatusPending, Created: created},
+			expectedLocation: "/account/me/export/5",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetLatestAccountExport(gomock.Any(), int64(1)).Return(pending, nil)
+				store.EXPECT().CreateAccountExport(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:             "request export handler requests another export once the last one is ready",
+			method:           http.MethodPost,
+			responseCode:     http.StatusAccepted,
+			route:            "/account/me/export",
+			expectedExport:   &exportResponse{ID: 6, Status: export.StatusPending, Created: created},
+			expectedLocation: "/account/me/export/6",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetLatestAccountExport(gomock.Any(), int64(1)).Return(ready, nil)
+				store.EXPECT().CreateAccountExport(gomock.Any(), int64(1)).
+					Return(db.AccountExport{ID: 6, AccountID: 1, Status: export.StatusPending, Created: created}, nil)
+			},
+		},
+		{
+			name:         "get export handler responds with a download link once the export is ready",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/account/me/export/5",
+			expectedExport: &exportResponse{
+				ID:          5,
+				Status:      export.StatusReady,
+				Size:        4,
+				Created:     created,
+				Completed:   &created,
+				ExpiresAt:   &expires,
+				DownloadURL: "/account/export/" + token,
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccountExport(gomock.Any(), db.GetAccountExportParams{ID: 5, AccountID: 1}).Return(ready, nil)
+			},
+		},
+		{
+			name:         "get export handler responds with Status Code 404 given another account's export",
+			method:       http.MethodGet,
+			responseCode: http.StatusNotFound,
+			route:        "/account/me/export/9",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccountExport(gomock.Any(), db.GetAccountExportParams{ID: 9, AccountID: 1}).Return(db.AccountExport{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:         "get export handler responds with Status Code 400 given an id that isn't a number",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me/export/latest",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			name:         "download export handler serves the archive given a signed link",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/account/export/" + token,
+			expectedBody: "PK\x03\x04",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountExportArchive(gomock.Any(), int64(5)).Return([]byte("PK\x03\x04"), nil)
+			},
+		},
+		{
+			name:         "download export handler responds with Status Code 404 given an expired link",
+			method:       http.MethodGet,
+			responseCode: http.StatusNotFound,
+			route:        "/account/export/" + expiredToken,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountExportArchive(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "download export handler responds with Status Code 404 given a forged link",
+			method:       http.MethodGet,
+			responseCode: http.StatusNotFound,
+			route:        "/account/export/" + strings.SplitN(token, ".", 2)[0] + ".forged",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountExportArchive(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "download export handler responds with Status Code 404 once the export was deleted",
+			method:       http.MethodGet,
+			responseCode: http.StatusNotFound,
+			route:        "/account/export/" + token,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountExportArchive(gomock.Any(), int64(5)).Return(nil, sql.ErrNoRows)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockStore := db.NewMockStore(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockClaimer := security.NewMockClaimer(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(test.method, test.route, nil)
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			assert.Equal(t, test.responseCode, result.StatusCode)
+			if test.expectedExport != nil {
+				var response exportResponse
+				decodeJSON(t, result.Body, &response)
+				assert.Equal(t, *test.expectedExport, response)
+			}
+			if test.expectedLocation != "" {
+				assert.Equal(t, test.expectedLocation, result.Header.Get("Location"))
+				assert.Equal(t, exportPollAfter, result.Header.Get("Retry-After"))
+			}
+			if test.expectedBody != "" {
+				body, err := io.ReadAll(result.Body)
+				if err != nil {
+					t.Fatalf("unexpected error reading archive: %s", err)
+				}
+				assert.Equal(t, []byte(test.expectedBody), body)
+				assert.Equal(t, "application/zip", result.Header.Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="firstly-export-5.zip"`, result.Header.Get("Content-Disposition"))
+				assert.Equal(t, "private, no-store", result.Header.Get("Cache-Control"))
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+)
+
+// imageFields are the fields the image listing can return, in the order they're documented.
+var imageFields = []string{"id", "memo", "created", "updated", "taken_at", "mime_type", "size", "width", "height", "variants", "data"}
+
+// imageFieldSet is the sparse fieldset requested for the image listing.
+type imageFieldSet map[string]bool
+
+// parseImageFields reads the comma separated fields param, defaulting to every field but data.
+func parseImageFields(ctx *gin.Context) (imageFieldSet, error) {
+	fields := imageFieldSet{}
+
+	param := ctx.Query("fields")
+	if param == "" {
+		for _, field := range imageFields {
+			fields[field] = field != "data"
+		}
+		return fields, nil
+	}
+
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if !isImageField(field) {
+			return nil, fmt.Errorf("fields must be a comma separated list of: %s", strings.Join(imageFields, ", "))
+		}
+		fields[field] = true
+	}
+	return fields, nil
+}
+
+func isImageField(field string) bool {
+	for _, f := range imageFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (fields imageFieldSet) includes(field string) bool {
+	return fields[field]
+}
+
+// project returns the requested fields of image.
+func (fields imageFieldSet) project(image db.Image) gin.H {
+	values := gin.H{
+		"id":        image.ID,
+		"memo":      image.Memo,
+		"created":   image.Created,
+		"updated":   image.Updated,
+		"taken_at":  image.TakenAt,
+		"mime_type": image.MimeType,
+		"size":      image.Size,
+		"width":     image.Width,
+		"height":    image.Height,
+		"variants":  imageVariants(image.ID),
+		"data":      image.Data,
+	}
+
+	summary := gin.H{}
+	for field, value := range values {
+		if fields.includes(field) {
+			summary[field] = value
+		}
+	}
+	return summary
+}
+
+// imageVariants returns the URLs the renditions of an image are served from.
+func imageVariants(id int64) gin.H {
+	return gin.H{
+		"original": fmt.Sprintf("/image/%d/data", id),
+	}
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+)
+
+var mimeTypePattern = regexp.MustCompile(`^image/[a-z0-9.+-]+$`)
+
+// parseImageFilters validates the created_after, created_before, has_memo and mime_type query params
+// of the image listing.
+func parseImageFilters(ctx *gin.Context) (db.SearchImagesParams, error) {
+	var params db.SearchImagesParams
+
+	if v := ctx.Query("created_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return params, errors.New("created_after must be an RFC 3339 timestamp")
+		}
+		params.CreatedAfter = sql.NullTime{Time: t, Valid: true}
+	}
+	if v := ctx.Query("created_before"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return params, errors.New("created_before must be an RFC 3339 timestamp")
+		}
+		params.CreatedBefore = sql.NullTime{Time: t, Valid: true}
+	}
+	if params.CreatedAfter.Valid && params.CreatedBefore.Valid && !params.CreatedAfter.Time.Before(params.CreatedBefore.Time) {
+		return params, errors.New("created_after must be before created_before")
+	}
+	if v := ctx.Query("has_memo"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return params, errors.New("has_memo must be true or false")
+		}
+		params.HasMemo = sql.NullBool{Bool: b, Valid: true}
+	}
+	if v := ctx.Query("mime_type"); v != "" {
+		if !mimeTypePattern.MatchString(v) {
+			return params, errors.New("mime_type must be an image media type such as image/png")
+		}
+		params.MimeType = sql.NullString{String: v, Valid: true}
+	}
+
+	return params, nil
+}
@@ -5,24 +5,63 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/meads/firstly-api/db"
 )
 
 type createImageRequest struct {
-	Data string `json:"data" binding:"required"`
+	Data    string `json:"data" binding:"required"`
+	TakenAt string `json:"taken_at"`
 }
 
 func createImageHandler(store db.Store) func(*gin.Context) {
 	return func(ctx *gin.Context) {
+		limits := loadImageLimits()
+		if !limitRequestBody(ctx, limits) {
+			return
+		}
+
 		var req createImageRequest
 		if err := ctx.BindJSON(&req); err != nil {
 			ctx.JSON(http.StatusBadRequest, errorResponse(err))
 			return
 		}
 
-		image, err := store.CreateImage(ctx, req.Data)
+		info, err := inspectImage(req.Data, limits)
+		if err != nil {
+			var limitErr *imageLimitError
+			if errors.As(err, &limitErr) {
+				abortImageLimit(ctx, limitErr)
+				return
+			}
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		// the capture time is optional, as not every client knows it
+		var takenAt string
+		if req.TakenAt != "" {
+			t, err := time.Parse(time.RFC3339, req.TakenAt)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("taken_at must be an RFC 3339 timestamp")))
+				return
+			}
+			takenAt = t.UTC().Format(time.RFC3339)
+		}
+
+		image, err := store.CreateAccountImage(ctx, db.CreateAccountImageParams{
+			CreateImageParams: db.CreateImageParams{
+				Data:     req.Data,
+				MimeType: "image/" + info.Format,
+				Size:     info.Size,
+				Width:    int32(info.Width),
+				Height:   int32(info.Height),
+				TakenAt:  takenAt,
+			},
+			AccountID: currentClaims(ctx).AccountID(),
+		})
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
@@ -68,7 +107,77 @@ var getLimitAndOffset = func(ctx *gin.Context) (string, string) {
 	return limit, offset
 }
 
+// listImagesHandler responds with a page of image summaries matching the filter params, sorted by the sort
+// param (created unless given) newest first unless order=asc is given. The fields param selects which
+// fields are returned, data only being included when asked for. Pages are linked with signed cursors over
+// (sort, id); limit/offset paging and the bare array of whole images are still served to older clients.
 func listImagesHandler(ctx *gin.Context) {
+	if usesOffsetPagination(ctx) {
+		listImagesByOffset(ctx)
+		return
+	}
+
+	sort := ctx.DefaultQuery("sort", "created")
+	if !db.IsImageSort(sort) {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("sort must be one of: created, updated, taken_at, size"))
+		return
+	}
+	page, err := parsePageRequest(ctx, sort)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	params, err := parseImageFilters(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	fields, err := parseImageFields(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	params.Sort = page.Sort
+	params.Desc = page.Desc
+	params.IncludeData = fields.includes("data")
+	params.CursorValue, params.CursorID = page.cursorValue()
+	params.Limit = page.Limit + 1
+
+	images, err := firstly.store.SearchImages(ctx, params)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var nextCursor string
+	if len(images) > int(page.Limit) {
+		images = images[:page.Limit]
+		last := images[len(images)-1]
+		nextCursor, err = page.nextCursor(db.ImageSortValue(last, page.Sort), last.ID)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		setNextLink(ctx, nextCursor)
+	}
+
+	items := make([]gin.H, 0, len(images))
+	for _, image := range images {
+		items = append(items, fields.project(image))
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	if !wantsPageResponse(ctx) {
+		ctx.JSON(http.StatusOK, items)
+		return
+	}
+	ctx.JSON(http.StatusOK, pageResponse{Items: items, NextCursor: nextCursor})
+}
+
+// listImagesByOffset is the deprecated limit/offset listing.
+func listImagesByOffset(ctx *gin.Context) {
 	limit, offset := getLimitAndOffset(ctx)
 	i, err := strconv.ParseInt(limit, 10, 32)
 	if err != nil {
@@ -88,10 +197,47 @@ func listImagesHandler(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("Deprecation", "true")
 	ctx.Header("Access-Control-Allow-Origin", "*")
 	ctx.JSON(http.StatusOK, images)
 }
 
+// imageDataHandler responds with the decoded bytes of an image, which is what the variant URLs in the
+// image listing point at.
+func imageDataHandler(store db.Store) func(*gin.Context) {
+	return func(ctx *gin.Context) {
+		id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, errors.New("id parameter must be a valid integer"))
+			return
+		}
+
+		image, err := store.GetImage(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		raw, err := decodeImageData(image.Data)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		mimeType := image.MimeType
+		if mimeType == "" {
+			mimeType = http.DetectContentType(raw)
+		}
+
+		ctx.Header("Cache-Control", "private, max-age=86400")
+		ctx.Data(http.StatusOK, mimeType, raw)
+	}
+}
+
 type updateImageRequest struct {
 	ID   int64  `json:"id" binding:"required"`
 	Memo string `json:"memo" binding:"required"`
@@ -8,6 +8,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,16 +23,32 @@ import (
 	"github.com/meads/firstly-api/security"
 )
 
+// A 1x1 and a 9000x1 grayscale PNG, base64 encoded.
+const (
+	testImagePNG     = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAAAAAA6fptVAAAAD0lEQVR4nAACAP3/Av8DAAEFAQJnLD7dAAAAAElFTkSuQmCC"
+	testWideImagePNG = "iVBORw0KGgoAAAANSUhEUgAAIygAAAABCAAAAACWSFqZAAAAIklEQVR4nOzAAQ0AAAgCMGf/ztCD/Z8DAAAAAAAAAIB9HQBwYAECjM3ZsQAAAABJRU5ErkJggg=="
+)
+
 func passClaimsMiddleware(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+	passClaimsMiddlewareAs(security.RoleMember, r, claimer, hasher, store)
+}
+
+// passClaimsMiddlewareAs passes claimsMiddleware with the token of an account with role.
+func passClaimsMiddlewareAs(role string, r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
 	tokenString := "mocktoken"
 	usernameClaims := security.NewUsernameClaims()
 	usernameClaims.Username = "valid"
+	usernameClaims.Role = role
+	usernameClaims.Scope = strings.Join(security.RoleScopes(role), " ")
+	usernameClaims.Subject = "1"
+	usernameClaims.Id = "mockjti"
 	claimToken := &security.ClaimToken{
 		Token: &jwt.Token{
 			Valid: true,
 		},
 	}
 	claimer.EXPECT().GetFromTokenString(tokenString).Return(claimToken, usernameClaims, nil)
+	store.EXPECT().IsTokenRevoked(gomock.Any(), usernameClaims.Id).Return(false, nil)
 
 	// Finally, we set the client cookie for "token" as the JWT we just generated
 	// we also set an expiry time which is the same as the token itself
@@ -47,20 +67,27 @@ func TestImageHandler(t *testing.T) {
 		responseCode      int
 		route             string
 		isList            bool
+		isLegacyList      bool
+		isArray           bool
+		nextCursor        bool
+		listFields        []string
+		contentType       string
+		errorCode         string
 		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
 	}{
 		{
-			body:         bytes.NewBufferString("{\"data\":\"test\"}"),
+			body:         bytes.NewBufferString("{\"data\":\"" + testImagePNG + "\"}"),
 			method:       http.MethodPost,
 			name:         "create handler responds with Status Code 200 when valid data supplied",
 			responseCode: http.StatusOK,
 			route:        "/image/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
 				passClaimsMiddleware(r, claimer, hasher, store)
-				store.EXPECT().CreateImage(gomock.Any(), "test").Return(
+				params := db.CreateAccountImageParams{CreateImageParams: db.CreateImageParams{Data: testImagePNG, MimeType: "image/png", Size: 72, Width: 1, Height: 1}, AccountID: 1}
+				store.EXPECT().CreateAccountImage(gomock.Any(), params).Return(
 					db.Image{
 						ID:      1,
-						Data:    "test",
+						Data:    testImagePNG,
 						Created: time.Now().String(),
 						Deleted: false,
 					}, nil)
@@ -78,13 +105,71 @@ func TestImageHandler(t *testing.T) {
 		},
 		{
 			name:         "create handler responds with Status Code 500 given there is some server error",
-			body:         bytes.NewBufferString("{\"data\":\"server error\"}"),
+			body:         bytes.NewBufferString("{\"data\":\"data:image/png;base64," + testImagePNG + "\"}"),
 			method:       http.MethodPost,
 			responseCode: http.StatusInternalServerError,
 			route:        "/image/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
 				passClaimsMiddleware(r, claimer, hasher, store)
-				store.EXPECT().CreateImage(gomock.Any(), "server error").Return(db.Image{}, errors.New("oops"))
+				params := db.CreateAccountImageParams{CreateImageParams: db.CreateImageParams{Data: "data:image/png;base64," + testImagePNG, MimeType: "image/png", Size: 72, Width: 1, Height: 1}, AccountID: 1}
+				store.EXPECT().CreateAccountImage(gomock.Any(), params).Return(db.Image{}, errors.New("oops"))
+			},
+		},
+		{
+			name:         "create handler responds with Status Code 400 given data that is not base64",
+			body:         bytes.NewBufferString("{\"data\":\"not base64!\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/",
+			errorCode:    imageErrInvalid,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			name:         "create handler responds with Status Code 413 given a body larger than IMAGE_MAX_BYTES",
+			body:         bytes.NewBufferString("{\"data\":\"" + testImagePNG + "\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusRequestEntityTooLarge,
+			route:        "/image/",
+			errorCode:    imageErrBodyTooLarge,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("IMAGE_MAX_BYTES", "64")
+			},
+		},
+		{
+			name:         "create handler responds with Status Code 415 given a format missing from IMAGE_FORMATS",
+			body:         bytes.NewBufferString("{\"data\":\"" + testImagePNG + "\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusUnsupportedMediaType,
+			route:        "/image/",
+			errorCode:    imageErrFormatUnsupported,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("IMAGE_FORMATS", "jpeg")
+			},
+		},
+		{
+			name:         "create handler responds with Status Code 415 given data that is not a known image format",
+			body:         bytes.NewBufferString("{\"data\":\"Qk0gbm90IHJlYWxseSBhIGJpdG1hcA==\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusUnsupportedMediaType,
+			route:        "/image/",
+			errorCode:    imageErrFormatUnsupported,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			name:         "create handler responds with Status Code 422 given an image wider than IMAGE_MAX_WIDTH",
+			body:         bytes.NewBufferString("{\"data\":\"" + testWideImagePNG + "\"}"),
+			method:       http.MethodPost,
+			responseCode: http.StatusUnprocessableEntity,
+			route:        "/image/",
+			errorCode:    imageErrDimensionsExceeded,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
 			},
 		},
 		{
@@ -186,7 +271,7 @@ func TestImageHandler(t *testing.T) {
 			body:         bytes.NewBufferString("{\"data\":\"test\"}"),
 			name:         "list handler responds with Status Code 401 given the token is not valid",
 			method:       http.MethodGet,
-			responseCode: http.StatusBadRequest,
+			responseCode: http.StatusUnauthorized,
 			route:        "/image/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
 				tokenString := "invalid"
@@ -228,21 +313,22 @@ func TestImageHandler(t *testing.T) {
 			route:        "/image/",
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
 				passClaimsMiddleware(r, claimer, hasher, store)
-				params := db.ListImagesParams{Limit: 50, Offset: 0}
-				store.EXPECT().ListImages(gomock.Any(), params).Return([]db.Image{}, errors.New("oops."))
+				params := db.SearchImagesParams{Sort: "created", Desc: true, Limit: 51}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{}, errors.New("oops."))
 			},
 		},
 		{
 			body:         bytes.NewBufferString(""),
-			name:         "list handler responds with Status Code 200 given a valid request",
+			name:         "list handler responds with a bare array of image summaries given no cursor",
 			method:       http.MethodGet,
 			responseCode: http.StatusOK,
 			route:        "/image/",
-			isList:       true,
+			isArray:      true,
+			listFields:   []string{"created", "height", "id", "memo", "mime_type", "size", "taken_at", "updated", "variants", "width"},
 			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
 				passClaimsMiddleware(r, claimer, hasher, store)
-				params := db.ListImagesParams{Limit: 50, Offset: 0}
-				store.EXPECT().ListImages(gomock.Any(), params).Return([]db.Image{
+				params := db.SearchImagesParams{Sort: "created", Desc: true, Limit: 51}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{
 					{
 						ID:      69,
 						Data:    "foo",
@@ -252,6 +338,306 @@ func TestImageHandler(t *testing.T) {
 				}, nil)
 			},
 		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with a page of image summaries given pagination cursor",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/?pagination=cursor",
+			isList:       true,
+			listFields:   []string{"created", "height", "id", "memo", "mime_type", "size", "taken_at", "updated", "variants", "width"},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				params := db.SearchImagesParams{Sort: "created", Desc: true, Limit: 51}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{{ID: 69, Data: "foo"}}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler links the next page in the Link header given no cursor and more rows than the limit",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/?limit=1",
+			isArray:      true,
+			nextCursor:   true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				params := db.SearchImagesParams{Sort: "created", Desc: true, Limit: 2}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{
+					{ID: 70, Created: "2022-10-30 12:00:01+00"},
+					{ID: 69, Created: "2022-10-30 12:00:00+00"},
+				}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with a next cursor and Link header given more rows than the limit",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/?limit=1&pagination=cursor",
+			isList:       true,
+			nextCursor:   true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				params := db.SearchImagesParams{Sort: "created", Desc: true, Limit: 2}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{
+					{ID: 70, Created: "2022-10-30 12:00:01+00"},
+					{ID: 69, Created: "2022-10-30 12:00:00+00"},
+				}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler continues after the row in the cursor given a valid cursor",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/",
+			isList:       true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				cursor, _ := pageRequest{Sort: "created", Desc: false}.nextCursor("2022-10-30 12:00:00+00", 69)
+				r.URL.RawQuery = url.Values{"cursor": {cursor}, "order": {"asc"}}.Encode()
+				params := db.SearchImagesParams{
+					Sort:        "created",
+					CursorValue: sql.NullString{String: "2022-10-30 12:00:00+00", Valid: true},
+					CursorID:    sql.NullInt64{Int64: 69, Valid: true},
+					Limit:       51,
+				}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{{ID: 70}}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given a cursor for a different order",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				cursor, _ := pageRequest{Sort: "created", Desc: false}.nextCursor("2022-10-30 12:00:00+00", 69)
+				r.URL.RawQuery = url.Values{"cursor": {cursor}}.Encode()
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given a tampered cursor",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/?cursor=eyJzIjoiY3JlYXRlZCJ9.invalid",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given an invalid order",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/?order=sideways",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"data\":\"" + testImagePNG + "\",\"taken_at\":\"2022-10-30T08:00:00-04:00\"}"),
+			method:       http.MethodPost,
+			name:         "create handler stores the capture time in UTC given taken_at",
+			responseCode: http.StatusOK,
+			route:        "/image/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				params := db.CreateAccountImageParams{CreateImageParams: db.CreateImageParams{Data: testImagePNG, MimeType: "image/png", Size: 72, Width: 1, Height: 1, TakenAt: "2022-10-30T12:00:00Z"}, AccountID: 1}
+				store.EXPECT().CreateAccountImage(gomock.Any(), params).Return(db.Image{ID: 1}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"data\":\"" + testImagePNG + "\",\"taken_at\":\"yesterday\"}"),
+			method:       http.MethodPost,
+			name:         "create handler responds with Status Code 400 given taken_at is not a timestamp",
+			responseCode: http.StatusBadRequest,
+			route:        "/image/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler sorts and filters given sort, order and filter params",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/?sort=size&order=asc&created_after=2022-10-01T00:00:00Z&created_before=2022-11-01T00:00:00Z&has_memo=true&mime_type=image/png&pagination=cursor",
+			isList:       true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				params := db.SearchImagesParams{
+					Sort:          "size",
+					CreatedAfter:  sql.NullTime{Time: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+					CreatedBefore: sql.NullTime{Time: time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+					HasMemo:       sql.NullBool{Bool: true, Valid: true},
+					MimeType:      sql.NullString{String: "image/png", Valid: true},
+					Limit:         51,
+				}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{{ID: 69, Size: 72}}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with a cursor on the sort value given sort taken_at",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/?sort=taken_at&limit=1&pagination=cursor",
+			isList:       true,
+			nextCursor:   true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				params := db.SearchImagesParams{Sort: "taken_at", Desc: true, Limit: 2}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{
+					{ID: 70, Created: "2022-10-30 12:00:00+00", TakenAt: "2022-10-29T08:00:00Z"},
+					{ID: 69, Created: "2022-10-30 11:00:00+00"},
+				}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given an unknown sort",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/?sort=data",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given a cursor for a different sort",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				cursor, _ := pageRequest{Sort: "size", Desc: true}.nextCursor("72", 69)
+				r.URL.RawQuery = url.Values{"cursor": {cursor}}.Encode()
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given created_after is not a timestamp",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/?created_after=yesterday",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given created_after is not before created_before",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/?created_after=2022-11-01T00:00:00Z&created_before=2022-10-01T00:00:00Z",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given has_memo is not a bool",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/?has_memo=maybe",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given an invalid mime_type",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/?mime_type=image/png%27%20OR%201=1",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with only the requested fields given the fields param",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/?fields=id,data&pagination=cursor",
+			isList:       true,
+			listFields:   []string{"data", "id"},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				params := db.SearchImagesParams{Sort: "created", Desc: true, IncludeData: true, Limit: 51}
+				store.EXPECT().SearchImages(gomock.Any(), params).Return([]db.Image{{ID: 69, Data: testImagePNG}}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler responds with Status Code 400 given an unknown field",
+			method:       http.MethodGet,
+			responseCode: http.StatusBadRequest,
+			route:        "/image/?fields=id,phrase",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "data handler responds with the decoded image given a valid id",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/69/data",
+			contentType:  "image/png",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetImage(gomock.Any(), int64(69)).Return(db.Image{ID: 69, Data: testImagePNG, MimeType: "image/png"}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "data handler detects the content type given an image uploaded without one",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/69/data",
+			contentType:  "image/png",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetImage(gomock.Any(), int64(69)).Return(db.Image{ID: 69, Data: testImagePNG}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "data handler responds with Status Code 404 given no image with the id",
+			method:       http.MethodGet,
+			responseCode: http.StatusNotFound,
+			route:        "/image/68/data",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetImage(gomock.Any(), int64(68)).Return(db.Image{}, sql.ErrNoRows)
+			},
+		},
+		{
+			body:         bytes.NewBufferString(""),
+			name:         "list handler still pages with limit and offset given an offset param",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/image/?limit=10&offset=20",
+			isLegacyList: true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				params := db.ListImagesParams{Limit: 10, Offset: 20}
+				store.EXPECT().ListImages(gomock.Any(), params).Return([]db.Image{{ID: 69}}, nil)
+			},
+		},
 		{
 			body:         bytes.NewBufferString("{\"id\":69, \"memo\": \"memo test\"}"),
 			method:       http.MethodPatch,
@@ -322,6 +708,8 @@ func TestImageHandler(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			// Arrange
+			os.Unsetenv("IMAGE_MAX_BYTES")
+			os.Unsetenv("IMAGE_FORMATS")
 			router := gin.Default()
 			gin.SetMode(gin.TestMode)
 			ctrl := gomock.NewController(t)
@@ -346,19 +734,55 @@ func TestImageHandler(t *testing.T) {
 			// Assert
 			assert.Equal(t, test.responseCode, result.StatusCode)
 
-			if !test.isList {
-				response := db.Image{}
-
-				if result.Body != http.NoBody {
-					if err := json.NewDecoder(result.Body).Decode(&response); err != nil && !errors.Is(err, io.EOF) {
-						t.Errorf("Error decoding response body: %v", err)
-						t.Log()
-						t.Log(responseRecorder.Body)
-						t.Log()
+			if test.errorCode != "" {
+				response := map[string]string{}
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.errorCode, response["code"])
+			} else if test.contentType != "" {
+				assert.Equal(t, test.contentType, result.Header.Get("Content-Type"))
+			} else if test.isList {
+				response := struct {
+					Items      []map[string]interface{} `json:"items"`
+					NextCursor string                   `json:"next_cursor"`
+				}{}
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.nextCursor, response.NextCursor != "")
+				assert.Equal(t, test.nextCursor, result.Header.Get("Link") != "")
+				if test.listFields != nil {
+					fields := []string{}
+					for field := range response.Items[0] {
+						fields = append(fields, field)
 					}
+					sort.Strings(fields)
+					assert.Equal(t, test.listFields, fields)
 				}
-			} else {
+			} else if test.isLegacyList {
 				response := []db.Image{}
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, "true", result.Header.Get("Deprecation"))
+			} else if test.isArray {
+				response := []map[string]interface{}{}
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.nextCursor, strings.Contains(result.Header.Get("Link"), "cursor="))
+				if test.listFields != nil {
+					fields := []string{}
+					for field := range response[0] {
+						fields = append(fields, field)
+					}
+					sort.Strings(fields)
+					assert.Equal(t, test.listFields, fields)
+				}
+			} else {
+				response := db.Image{}
+
 				if result.Body != http.NoBody {
 					if err := json.NewDecoder(result.Body).Decode(&response); err != nil && !errors.Is(err, io.EOF) {
 						t.Errorf("Error decoding response body: %v", err)
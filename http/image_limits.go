@@ -0,0 +1,205 @@
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	// Register the decoders image.DecodeConfig can recognise.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes returned alongside the error message when an upload breaks one of the image limits,
+// so the native apps can tell the user which limit they hit.
+const (
+	imageErrBodyTooLarge       = "image_body_too_large"
+	imageErrDimensionsExceeded = "image_dimensions_exceeded"
+	imageErrFormatUnsupported  = "image_format_unsupported"
+	imageErrInvalid            = "image_invalid"
+)
+
+const (
+	defaultImageMaxBytes  = 10 << 20
+	defaultImageMaxWidth  = 8192
+	defaultImageMaxHeight = 8192
+	defaultImageFormats   = "jpeg,png,gif"
+)
+
+// imageLimits holds the upload restrictions enforced before an image is stored.
+type imageLimits struct {
+	MaxBytes  int64
+	MaxWidth  int
+	MaxHeight int
+	Formats   []string
+}
+
+// imageInfo describes an uploaded image as read from its header, without decoding the pixel data.
+type imageInfo struct {
+	Format string
+	Size   int64
+	Width  int
+	Height int
+}
+
+// imageLimitError is returned by inspectImage when an upload breaks a limit.
+type imageLimitError struct {
+	Code   string
+	Status int
+	Err    error
+}
+
+func (e *imageLimitError) Error() string {
+	return e.Err.Error()
+}
+
+// loadImageLimits reads the upload limits from the IMAGE_MAX_BYTES, IMAGE_MAX_WIDTH, IMAGE_MAX_HEIGHT and
+// IMAGE_FORMATS env variables, falling back to the defaults for any that are unset or invalid.
+func loadImageLimits() imageLimits {
+	limits := imageLimits{
+		MaxBytes:  defaultImageMaxBytes,
+		MaxWidth:  defaultImageMaxWidth,
+		MaxHeight: defaultImageMaxHeight,
+	}
+	if v, err := strconv.ParseInt(os.Getenv("IMAGE_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		limits.MaxBytes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("IMAGE_MAX_WIDTH")); err == nil && v > 0 {
+		limits.MaxWidth = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("IMAGE_MAX_HEIGHT")); err == nil && v > 0 {
+		limits.MaxHeight = v
+	}
+
+	formats := os.Getenv("IMAGE_FORMATS")
+	if formats == "" {
+		formats = defaultImageFormats
+	}
+	for _, format := range strings.Split(formats, ",") {
+		if format = strings.ToLower(strings.TrimSpace(format)); format != "" {
+			limits.Formats = append(limits.Formats, format)
+		}
+	}
+
+	return limits
+}
+
+func (limits imageLimits) allowsFormat(format string) bool {
+	for _, allowed := range limits.Formats {
+		if allowed == format {
+			return true
+		}
+	}
+	return false
+}
+
+// limitRequestBody reads at most limits.MaxBytes of the request body so that gin never buffers an
+// oversized upload, responding with 413 when the body is larger than that.
+func limitRequestBody(ctx *gin.Context, limits imageLimits) bool {
+	if ctx.Request.ContentLength > limits.MaxBytes {
+		abortImageLimit(ctx, errBodyTooLarge(limits))
+		return false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, limits.MaxBytes+1))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return false
+	}
+	if int64(len(body)) > limits.MaxBytes {
+		abortImageLimit(ctx, errBodyTooLarge(limits))
+		return false
+	}
+
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return true
+}
+
+// inspectImage decodes the base64 (or data URI) encoded upload and checks its format and dimensions
+// against the limits using only the image header.
+func inspectImage(data string, limits imageLimits) (imageInfo, error) {
+	raw, err := decodeImageData(data)
+	if err != nil {
+		return imageInfo{}, &imageLimitError{
+			Code:   imageErrInvalid,
+			Status: http.StatusBadRequest,
+			Err:    errors.New("image data must be base64 encoded"),
+		}
+	}
+	if int64(len(raw)) > limits.MaxBytes {
+		return imageInfo{}, errBodyTooLarge(limits)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return imageInfo{}, &imageLimitError{
+				Code:   imageErrFormatUnsupported,
+				Status: http.StatusUnsupportedMediaType,
+				Err:    fmt.Errorf("image format must be one of: %s", strings.Join(limits.Formats, ", ")),
+			}
+		}
+		return imageInfo{}, &imageLimitError{
+			Code:   imageErrInvalid,
+			Status: http.StatusBadRequest,
+			Err:    fmt.Errorf("image data could not be read: %s", err),
+		}
+	}
+	if !limits.allowsFormat(format) {
+		return imageInfo{}, &imageLimitError{
+			Code:   imageErrFormatUnsupported,
+			Status: http.StatusUnsupportedMediaType,
+			Err:    fmt.Errorf("image format %s is not allowed, must be one of: %s", format, strings.Join(limits.Formats, ", ")),
+		}
+	}
+	if config.Width > limits.MaxWidth || config.Height > limits.MaxHeight {
+		return imageInfo{}, &imageLimitError{
+			Code:   imageErrDimensionsExceeded,
+			Status: http.StatusUnprocessableEntity,
+			Err: fmt.Errorf("image is %dx%d pixels, the maximum is %dx%d",
+				config.Width, config.Height, limits.MaxWidth, limits.MaxHeight),
+		}
+	}
+
+	return imageInfo{
+		Format: format,
+		Size:   int64(len(raw)),
+		Width:  config.Width,
+		Height: config.Height,
+	}, nil
+}
+
+// decodeImageData returns the bytes of base64 image data, which may be given as a data URI.
+func decodeImageData(data string) ([]byte, error) {
+	if i := strings.Index(data, ","); strings.HasPrefix(data, "data:") && i > 0 {
+		data = data[i+1:]
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		raw, err = base64.RawStdEncoding.DecodeString(data)
+	}
+	return raw, err
+}
+
+func errBodyTooLarge(limits imageLimits) *imageLimitError {
+	return &imageLimitError{
+		Code:   imageErrBodyTooLarge,
+		Status: http.StatusRequestEntityTooLarge,
+		Err:    fmt.Errorf("image must not be larger than %d bytes", limits.MaxBytes),
+	}
+}
+
+func abortImageLimit(ctx *gin.Context, err *imageLimitError) {
+	ctx.AbortWithStatusJSON(err.Status, errorCodeResponse(err.Code, err))
+}
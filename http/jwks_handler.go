@@ -0,0 +1,15 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksHandler publishes the public keys tokens are signed with, so that other services can verify them
+// without holding a secret.
+func jwksHandler(ctx *gin.Context) {
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.JSON(http.StatusOK, firstly.claimer.JWKS())
+}
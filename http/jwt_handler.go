@@ -1,48 +1,97 @@
 package http
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
 )
 
 // Create a struct that models the structure of a user, both in the request body, and in the DB
 type signInRequest struct {
 	Phrase   string `json:"phrase" binding:"required"`
 	Username string `json:"username" binding:"required"`
+	// TokenInBody asks for the tokens in the response body, for clients that can't use cookies
+	TokenInBody bool `json:"token_in_body"`
 }
 
+var (
+	errInvalidAccessToken = errors.New("access token is invalid or has expired")
+	errRevokedAccessToken = errors.New("access token has been revoked")
+)
+
 func claimsMiddleware(h gin.HandlerFunc) gin.HandlerFunc {
 	return gin.HandlerFunc(func(ctx *gin.Context) {
-		// We can obtain the session token from the requests cookies, which come with every request
-		c, err := ctx.Request.Cookie("token")
+		// The access token, or an API key, comes from the Authorization header or the token cookie
+		tokenString, err := accessToken(ctx.Request)
 		if err != nil {
-			ctx.Writer.WriteHeader(http.StatusUnauthorized)
+			abortWithBearerChallenge(ctx, http.StatusBadRequest, bearerInvalidRequest, err)
+			return
+		}
+		if tokenString == "" {
+			abortWithBearerChallenge(ctx, http.StatusUnauthorized, "", nil)
 			return
 		}
 
-		// Get the JWT string from the cookie
-		claimToken, _, err := firstly.claimer.GetFromTokenString(c.Value)
-		if err != nil {
-			if err == jwt.ErrSignatureInvalid {
-				ctx.Writer.WriteHeader(http.StatusUnauthorized)
+		if security.IsAPIKey(tokenString) {
+			usernameClaims, err := apiKeyClaims(ctx, tokenString)
+			if err != nil {
+				if errors.Is(err, errInvalidAPIKey) {
+					abortWithBearerChallenge(ctx, http.StatusUnauthorized, bearerInvalidToken, err)
+					return
+				}
+				ctx.Writer.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			ctx.Set(claimsKey, usernameClaims)
+
+			h(ctx)
 			return
 		}
-		if !claimToken.Valid {
-			ctx.Writer.WriteHeader(http.StatusUnauthorized)
+
+		// Tokens waiting for the second factor of a sign in aren't access tokens
+		claimToken, usernameClaims, err := firstly.claimer.GetFromTokenString(tokenString)
+		if err != nil || !claimToken.Valid || usernameClaims.IsMFAPending() {
+			abortWithBearerChallenge(ctx, http.StatusUnauthorized, bearerInvalidToken, errInvalidAccessToken)
 			return
 		}
 
+		// Tokens that were signed out of, or whose session was revoked, are rejected until they expire
+		if usernameClaims.Id != "" {
+			revoked, err := firstly.store.IsTokenRevoked(ctx, usernameClaims.Id)
+			if err != nil {
+				ctx.Writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				abortWithBearerChallenge(ctx, http.StatusUnauthorized, bearerInvalidToken, errRevokedAccessToken)
+				return
+			}
+		}
+		ctx.Set(claimsKey, usernameClaims)
+
 		h(ctx)
 	})
 }
 
+// optionalClaimsMiddleware lets requests without an access token through to h as nobody, and otherwise
+// verifies the token like claimsMiddleware.
+func optionalClaimsMiddleware(h gin.HandlerFunc) gin.HandlerFunc {
+	withClaims := claimsMiddleware(h)
+	return gin.HandlerFunc(func(ctx *gin.Context) {
+		if tokenString, err := accessToken(ctx.Request); err == nil && tokenString == "" {
+			h(ctx)
+			return
+		}
+		withClaims(ctx)
+	})
+}
+
 func signinHandler(ctx *gin.Context) {
 	var req signInRequest
 
@@ -51,9 +100,26 @@ func signinHandler(ctx *gin.Context) {
 		return
 	}
 
+	wait, err := loginRetryAfter(ctx, req.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if wait > 0 {
+		recordLoginAttempt(ctx, req.Username, loginThrottled)
+		abortTooManyAttempts(ctx, wait)
+		return
+	}
+
 	account, err := firstly.store.GetAccountByUsername(ctx, req.Username)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		if errors.Is(err, sql.ErrNoRows) {
+			verifyDummyPhrase(req.Phrase)
+			recordLoginAttempt(ctx, req.Username, loginUnknownUsername)
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
@@ -67,11 +133,39 @@ func signinHandler(ctx *gin.Context) {
 	// AND, if it is the same as the password we received, the we can move ahead
 	// if NOT, then we return an "Unauthorized" status
 	if !valid {
-		ctx.Writer.WriteHeader(http.StatusUnauthorized)
+		recordLoginAttempt(ctx, req.Username, loginWrongPhrase)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
 		return
 	}
 
-	tokenString, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(account.Username)
+	// Now that we have the phrase, upgrade a hash made with an older scheme or parameters. The user is
+	// signed in regardless of whether this succeeds, and it is tried again on their next sign in.
+	if firstly.hasher.NeedsRehash(account.Phrase) {
+		if err := rehashPhrase(ctx, account, req.Phrase); err != nil {
+			log.Printf("error upgrading the phrase hash for account %d: %s", account.ID, err)
+		}
+	}
+
+	// With two-factor authentication the phrase alone isn't enough, the sign in only succeeds once the
+	// code is verified
+	if account.TotpEnabled {
+		startMFA(ctx, account)
+		return
+	}
+	recordLoginAttempt(ctx, req.Username, loginSucceeded)
+
+	completeSignin(ctx, account, req.TokenInBody)
+}
+
+// completeSignin issues an access token and starts a session for the account, setting both cookies and,
+// when tokenInBody is set, responding with the tokens.
+func completeSignin(ctx *gin.Context, account db.Account, tokenInBody bool) {
+	claims, err := security.NewAccessClaims(account.ID, account.Username, account.Role)
+	if err != nil {
+		ctx.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	tokenString, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(claims)
 	if err != nil {
 		// If there is an error in creating the JWT return an internal server error
 		ctx.Writer.WriteHeader(http.StatusInternalServerError)
@@ -81,85 +175,60 @@ func signinHandler(ctx *gin.Context) {
 	// Finally, we set the client cookie for "token" as the JWT we just generated
 	// we also set an expiry time which is the same as the token itself
 	http.SetCookie(ctx.Writer, &http.Cookie{
-		Name:    "token",
+		Name:    accessTokenCookie,
 		Value:   tokenString,
 		Expires: expirationTime,
 	})
 
-	ctx.Status(http.StatusOK)
-}
-
-func welcomeHandler(ctx *gin.Context) {
-	// We can obtain the session token from the requests cookies, which come with every request
-	c, err := ctx.Request.Cookie("token")
+	refreshToken, refreshExpires, err := startSession(ctx, account.ID, claims.Id, expirationTime)
 	if err != nil {
-		ctx.Writer.WriteHeader(http.StatusUnauthorized)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	token, usernameClaims, err := firstly.claimer.GetFromTokenString(c.Value)
 
-	if err != nil {
-		if err == jwt.ErrSignatureInvalid {
-			ctx.Writer.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-		ctx.Writer.WriteHeader(http.StatusBadRequest)
+	if tokenInBody {
+		ctx.JSON(http.StatusOK, tokenResponse{
+			AccessToken:      tokenString,
+			TokenType:        "Bearer",
+			ExpiresAt:        expirationTime,
+			RefreshToken:     refreshToken,
+			RefreshExpiresAt: refreshExpires,
+		})
 		return
 	}
-	if !token.Valid {
-		ctx.Writer.WriteHeader(http.StatusUnauthorized)
-		return
-	}
-	// Return the welcome message to the user, along with their username
-	ctx.Writer.Write([]byte(fmt.Sprintf("Welcome %s!", usernameClaims.Username)))
+	ctx.Status(http.StatusOK)
 }
 
-func refreshHandler(ctx *gin.Context) {
-	// (BEGIN) The code uptil this point is the same as the first part of the `Welcome` route
-	c, err := ctx.Request.Cookie("token")
+// rehashPhrase stores phrase hashed with the current scheme and a new salt.
+func rehashPhrase(ctx *gin.Context, account db.Account, phrase string) error {
+	salt := firstly.hasher.GenerateSalt()
+	hash, err := firstly.hasher.GeneratePasswordHash([]byte(phrase), salt)
 	if err != nil {
-		if err == http.ErrNoCookie {
-			ctx.Writer.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-		ctx.Writer.WriteHeader(http.StatusBadRequest)
-		return
+		return err
 	}
+	return firstly.store.UpdateAccountCredentials(ctx, db.UpdateAccountCredentialsParams{
+		Phrase: hash,
+		Salt:   salt,
+		ID:     account.ID,
+	})
+}
 
-	claimToken, usernameClaims, err := firstly.claimer.GetFromTokenString(c.Value)
-	if !claimToken.Valid {
-		ctx.Writer.WriteHeader(http.StatusUnauthorized)
-		return
-	}
+func welcomeHandler(ctx *gin.Context) {
+	// The access token comes from the Authorization header or the token cookie
+	tokenString, err := accessToken(ctx.Request)
 	if err != nil {
-		if err == jwt.ErrSignatureInvalid {
-			ctx.Writer.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-		ctx.Writer.WriteHeader(http.StatusBadRequest)
+		abortWithBearerChallenge(ctx, http.StatusBadRequest, bearerInvalidRequest, err)
 		return
 	}
-	// (END) The code uptil this point is the same as the first part of the `Welcome` route
-
-	// We ensure that a new token is not issued until enough time has elapsed
-	// In this case, a new token will only be issued if the old token is within
-	// 30 seconds of expiry. Otherwise, return a bad request status
-	if time.Unix(usernameClaims.ExpiresAt, 0).Sub(time.Now()) > 30*time.Second {
-		ctx.Writer.WriteHeader(http.StatusBadRequest)
+	if tokenString == "" {
+		abortWithBearerChallenge(ctx, http.StatusUnauthorized, "", nil)
 		return
 	}
-
-	// Now, create a new token for the current use, with a renewed expiration time
-	tokenString, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(usernameClaims.Username)
-	if err != nil {
-		ctx.Writer.WriteHeader(http.StatusInternalServerError)
+	token, usernameClaims, err := firstly.claimer.GetFromTokenString(tokenString)
+	if err != nil || !token.Valid || usernameClaims.IsMFAPending() {
+		abortWithBearerChallenge(ctx, http.StatusUnauthorized, bearerInvalidToken, errInvalidAccessToken)
 		return
 	}
-
-	// Set the new token as the users `session_token` cookie
-	http.SetCookie(ctx.Writer, &http.Cookie{
-		Name:    "session_token",
-		Value:   tokenString,
-		Expires: expirationTime,
-	})
+	// Return the welcome message to the user, along with their username
+	ctx.Writer.Write([]byte(fmt.Sprintf("Welcome %s!", usernameClaims.Username)))
 }
@@ -2,7 +2,10 @@ package http
 
 import (
 	"bytes"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -17,6 +20,24 @@ import (
 	"github.com/meads/firstly-api/security"
 )
 
+// claimsMatcher matches the access token claims passed to GetFiveMinuteExpirationToken by username.
+type claimsMatcher struct {
+	username string
+}
+
+func claimsFor(username string) gomock.Matcher {
+	return claimsMatcher{username: username}
+}
+
+func (m claimsMatcher) Matches(x interface{}) bool {
+	claims, ok := x.(*security.UsernameClaims)
+	return ok && claims.Username == m.username && claims.Id != ""
+}
+
+func (m claimsMatcher) String() string {
+	return fmt.Sprintf("has username %s and a jti", m.username)
+}
+
 func TestJWTSignInHandler(t *testing.T) {
 	tests := []struct {
 		body              *bytes.Buffer
@@ -24,6 +45,8 @@ func TestJWTSignInHandler(t *testing.T) {
 		name              string
 		responseCode      int
 		route             string
+		expectedToken     string
+		expectedError     string
 		setupExpectations func(store *db.MockStore, hasher *security.MockHasher, claimer *security.MockClaimer, rr *httptest.ResponseRecorder, r *http.Request)
 	}{
 		{
@@ -40,11 +63,29 @@ func TestJWTSignInHandler(t *testing.T) {
 		{
 			body:         bytes.NewBufferString("{\"phrase\":\"valid\",\"username\":\"invalid\"}"),
 			method:       http.MethodPost,
-			name:         "signin returns status code bad request when invalid username supplied",
-			responseCode: http.StatusBadRequest,
+			name:         "signin returns status code unauthorized when an unknown username is supplied",
+			responseCode: http.StatusUnauthorized,
+			route:        "/signin/",
+			setupExpectations: func(store *db.MockStore, hasher *security.MockHasher, claimer *security.MockClaimer, rr *httptest.ResponseRecorder, r *http.Request) {
+				allowLoginAttempt(store, "invalid")
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "invalid").Return(db.Account{}, sql.ErrNoRows)
+				// the phrase is still checked, against a dummy hash, so that the response takes as long
+				hasher.EXPECT().GenerateSalt().Return("dummysalt")
+				hasher.EXPECT().GeneratePasswordHash(gomock.Any(), "dummysalt").Return([]byte("dummyhash"), nil)
+				hasher.EXPECT().IsValidPassword([]byte("dummyhash"), "dummysalt", "valid").Return(false, nil)
+				expectLoginAttempt(store, "invalid", loginUnknownUsername)
+			},
+			expectedError: errInvalidCredentials.Error(),
+		},
+		{
+			body:         bytes.NewBufferString("{\"phrase\":\"valid\",\"username\":\"valid\"}"),
+			method:       http.MethodPost,
+			name:         "signin returns status code internal server error when looking up the username fails",
+			responseCode: http.StatusInternalServerError,
 			route:        "/signin/",
 			setupExpectations: func(store *db.MockStore, hasher *security.MockHasher, claimer *security.MockClaimer, rr *httptest.ResponseRecorder, r *http.Request) {
-				store.EXPECT().GetAccountByUsername(gomock.Any(), "invalid").Return(db.Account{}, errors.New("oops"))
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(db.Account{}, errors.New("oops"))
 				hasher.EXPECT().IsValidPassword(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 			},
 		},
@@ -59,6 +100,7 @@ func TestJWTSignInHandler(t *testing.T) {
 					Phrase: []byte("invalid"),
 					Salt:   "salt",
 				}
+				allowLoginAttempt(store, "valid")
 				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(expectedAccount, nil)
 				hasher.EXPECT().IsValidPassword(expectedAccount.Phrase, expectedAccount.Salt, "valid").Return(
 					false,
@@ -77,9 +119,12 @@ func TestJWTSignInHandler(t *testing.T) {
 					Phrase: []byte("valid"),
 					Salt:   "salt",
 				}
+				allowLoginAttempt(store, "valid")
 				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(expectedAccount, nil)
 				hasher.EXPECT().IsValidPassword(expectedAccount.Phrase, expectedAccount.Salt, "invalid").Return(false, nil)
+				expectLoginAttempt(store, "valid", loginWrongPhrase)
 			},
+			expectedError: errInvalidCredentials.Error(),
 		},
 		{
 			body:         bytes.NewBufferString("{\"phrase\":\"valid\",\"username\":\"valid\"}"),
@@ -93,16 +138,20 @@ func TestJWTSignInHandler(t *testing.T) {
 					Phrase:   []byte("valid"),
 					Salt:     "salt",
 				}
+				allowLoginAttempt(store, "valid")
 				store.EXPECT().GetAccountByUsername(gomock.Any(), expectedAccount.Username).
 					Return(expectedAccount, nil)
 				hasher.EXPECT().IsValidPassword(expectedAccount.Phrase, expectedAccount.Salt, "valid").
 					Return(true, nil)
+				expectLoginAttempt(store, "valid", loginSucceeded)
+				hasher.EXPECT().NeedsRehash(expectedAccount.Phrase).Return(false)
 
 				// Create the JWT claims, which includes the username and expiry time
 				tokenString := "mocktoken"
 				expirationTime := time.Now().Add(5 * time.Minute)
-				claimer.EXPECT().GetFiveMinuteExpirationToken(expectedAccount.Username).
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor(expectedAccount.Username)).
 					Return(tokenString, expirationTime, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Return(db.Session{ID: 1}, nil)
 
 				// Finally, we set the client cookie for "token" as the JWT we just generated
 				// we also set an expiry time which is the same as the token itself
@@ -113,6 +162,32 @@ func TestJWTSignInHandler(t *testing.T) {
 				})
 			},
 		},
+		{
+			body:          bytes.NewBufferString("{\"phrase\":\"valid\",\"username\":\"valid\",\"token_in_body\":true}"),
+			method:        http.MethodPost,
+			name:          "signin handler given token_in_body responds with the tokens in the body",
+			responseCode:  http.StatusOK,
+			route:         "/signin/",
+			expectedToken: "mocktoken",
+			setupExpectations: func(store *db.MockStore, hasher *security.MockHasher, claimer *security.MockClaimer, rr *httptest.ResponseRecorder, r *http.Request) {
+				expectedAccount := db.Account{
+					Username: "valid",
+					Phrase:   []byte("valid"),
+					Salt:     "salt",
+				}
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccountByUsername(gomock.Any(), expectedAccount.Username).
+					Return(expectedAccount, nil)
+				hasher.EXPECT().IsValidPassword(expectedAccount.Phrase, expectedAccount.Salt, "valid").
+					Return(true, nil)
+				expectLoginAttempt(store, "valid", loginSucceeded)
+				hasher.EXPECT().NeedsRehash(expectedAccount.Phrase).Return(false)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor(expectedAccount.Username)).
+					Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Return(db.Session{ID: 1, Expires: time.Now().Add(time.Hour)}, nil)
+			},
+		},
 		{
 			body:         bytes.NewBufferString("{\"phrase\":\"valid\",\"username\":\"valid\"}"),
 			method:       http.MethodPost,
@@ -125,15 +200,18 @@ func TestJWTSignInHandler(t *testing.T) {
 					Phrase:   []byte("valid"),
 					Salt:     "salt",
 				}
+				allowLoginAttempt(store, "valid")
 				store.EXPECT().GetAccountByUsername(gomock.Any(), expectedAccount.Username).
 					Return(expectedAccount, nil)
 				hasher.EXPECT().IsValidPassword(expectedAccount.Phrase, expectedAccount.Salt, "valid").
 					Return(true, nil)
+				expectLoginAttempt(store, "valid", loginSucceeded)
+				hasher.EXPECT().NeedsRehash(expectedAccount.Phrase).Return(false)
 
 				// Create the JWT claims, which includes the username and expiry time
 				tokenString := "mocktoken"
 				expirationTime := time.Now().Add(5 * time.Minute)
-				claimer.EXPECT().GetFiveMinuteExpirationToken(expectedAccount.Username).
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor(expectedAccount.Username)).
 					Return("", time.Time{}, errors.New("oops"))
 
 				// Finally, we set the client cookie for "token" as the JWT we just generated
@@ -145,6 +223,66 @@ func TestJWTSignInHandler(t *testing.T) {
 				})
 			},
 		},
+		{
+			body:         bytes.NewBufferString("{\"phrase\":\"valid\",\"username\":\"valid\"}"),
+			method:       http.MethodPost,
+			name:         "signin handler given a phrase hashed with an older scheme stores a new hash and signs in",
+			responseCode: http.StatusOK,
+			route:        "/signin/",
+			setupExpectations: func(store *db.MockStore, hasher *security.MockHasher, claimer *security.MockClaimer, rr *httptest.ResponseRecorder, r *http.Request) {
+				expectedAccount := db.Account{
+					ID:       1,
+					Username: "valid",
+					Phrase:   []byte("legacy"),
+					Salt:     "salt",
+				}
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccountByUsername(gomock.Any(), expectedAccount.Username).
+					Return(expectedAccount, nil)
+				hasher.EXPECT().IsValidPassword(expectedAccount.Phrase, expectedAccount.Salt, "valid").
+					Return(true, nil)
+				expectLoginAttempt(store, "valid", loginSucceeded)
+				hasher.EXPECT().NeedsRehash(expectedAccount.Phrase).Return(true)
+				hasher.EXPECT().GenerateSalt().Return("")
+				hasher.EXPECT().GeneratePasswordHash([]byte("valid"), "").Return([]byte("$argon2id$"), nil)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), db.UpdateAccountCredentialsParams{
+					Phrase: []byte("$argon2id$"),
+					Salt:   "",
+					ID:     expectedAccount.ID,
+				}).Return(nil)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor(expectedAccount.Username)).
+					Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Return(db.Session{ID: 1}, nil)
+			},
+		},
+		{
+			body:         bytes.NewBufferString("{\"phrase\":\"valid\",\"username\":\"valid\"}"),
+			method:       http.MethodPost,
+			name:         "signin handler still signs in when storing the upgraded hash fails",
+			responseCode: http.StatusOK,
+			route:        "/signin/",
+			setupExpectations: func(store *db.MockStore, hasher *security.MockHasher, claimer *security.MockClaimer, rr *httptest.ResponseRecorder, r *http.Request) {
+				expectedAccount := db.Account{
+					ID:       1,
+					Username: "valid",
+					Phrase:   []byte("legacy"),
+					Salt:     "salt",
+				}
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccountByUsername(gomock.Any(), expectedAccount.Username).
+					Return(expectedAccount, nil)
+				hasher.EXPECT().IsValidPassword(expectedAccount.Phrase, expectedAccount.Salt, "valid").
+					Return(true, nil)
+				expectLoginAttempt(store, "valid", loginSucceeded)
+				hasher.EXPECT().NeedsRehash(expectedAccount.Phrase).Return(true)
+				hasher.EXPECT().GenerateSalt().Return("")
+				hasher.EXPECT().GeneratePasswordHash([]byte("valid"), "").Return([]byte("$argon2id$"), nil)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), gomock.Any()).Return(errors.New("oops"))
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor(expectedAccount.Username)).
+					Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Return(db.Session{ID: 1}, nil)
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -171,6 +309,23 @@ func TestJWTSignInHandler(t *testing.T) {
 
 			// Assert
 			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			if test.expectedError != "" {
+				var response map[string]string
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.expectedError, response["error"])
+			}
+			if test.expectedToken != "" {
+				var response tokenResponse
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.expectedToken, response.AccessToken)
+				assert.Equal(t, "Bearer", response.TokenType)
+				assert.NotEqual(t, "", response.RefreshToken)
+			}
 		})
 	}
 }
@@ -251,3 +406,66 @@ func TestJWTWelcomeHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestJWKSHandler(t *testing.T) {
+	tests := []struct {
+		name              string
+		responseCode      int
+		expectedBody      string
+		setupExpectations func(claimer *security.MockClaimer)
+	}{
+		{
+			name:         "jwks handler responds with the public signing keys",
+			responseCode: http.StatusOK,
+			expectedBody: `{"keys":[{"kid":"k1","kty":"OKP","alg":"EdDSA","use":"sig","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`,
+			setupExpectations: func(claimer *security.MockClaimer) {
+				claimer.EXPECT().JWKS().Return(security.JSONWebKeySet{Keys: []security.JSONWebKey{{
+					KeyID:     "k1",
+					KeyType:   "OKP",
+					Algorithm: "EdDSA",
+					Use:       "sig",
+					Curve:     "Ed25519",
+					X:         "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+				}}})
+			},
+		},
+		{
+			name:         "jwks handler responds with an empty key set when tokens are signed with HMAC",
+			responseCode: http.StatusOK,
+			expectedBody: `{"keys":[]}`,
+			setupExpectations: func(claimer *security.MockClaimer) {
+				claimer.EXPECT().JWKS().Return(security.JSONWebKeySet{Keys: []security.JSONWebKey{}})
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockStore := db.NewMockStore(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockClaimer := security.NewMockClaimer(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			test.setupExpectations(mockClaimer)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			responseBody, _ := io.ReadAll(result.Body)
+			assert.Equal(t, test.expectedBody, string(responseBody))
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+)
+
+const (
+	defaultLoginMaxFailures   = 5
+	defaultLoginMaxIPFailures = 20
+	defaultLoginLockout       = 15 * time.Minute
+
+	// reasons sign in attempts are recorded with
+	loginSucceeded       = "success"
+	loginUnknownUsername = "unknown_username"
+	loginWrongPhrase     = "wrong_phrase"
+	loginUnknownPasskey  = "unknown_passkey"
+	loginWrongPasskey    = "wrong_passkey"
+	loginThrottled       = "throttled"
+)
+
+var (
+	// errInvalidCredentials is the response to both an unknown username and a wrong phrase, so that
+	// signing in doesn't reveal which usernames exist
+	errInvalidCredentials = errors.New("username or phrase is incorrect")
+	errTooManyAttempts    = errors.New("too many sign in attempts, try again later")
+)
+
+type unlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+type unlockLoginResponse struct {
+	Cleared int64 `json:"cleared"`
+}
+
+// loginPolicy is how many failed sign ins are allowed before signing in is locked, and for how long.
+type loginPolicy struct {
+	MaxFailures   int64
+	MaxIPFailures int64
+	Lockout       time.Duration
+}
+
+// loadLoginPolicy reads the policy from the LOGIN_MAX_FAILURES, LOGIN_MAX_IP_FAILURES and LOGIN_LOCKOUT
+// env variables.
+func loadLoginPolicy() loginPolicy {
+	policy := loginPolicy{
+		MaxFailures:   defaultLoginMaxFailures,
+		MaxIPFailures: defaultLoginMaxIPFailures,
+		Lockout:       defaultLoginLockout,
+	}
+	if max, err := strconv.ParseInt(os.Getenv("LOGIN_MAX_FAILURES"), 10, 64); err == nil && max > 0 {
+		policy.MaxFailures = max
+	}
+	if max, err := strconv.ParseInt(os.Getenv("LOGIN_MAX_IP_FAILURES"), 10, 64); err == nil && max > 0 {
+		policy.MaxIPFailures = max
+	}
+	if lockout, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT")); err == nil && lockout > 0 {
+		policy.Lockout = lockout
+	}
+	return policy
+}
+
+// usernameDelay is how long to wait after the last of failures for a username. Each failure doubles
+// the delay, starting at a second, until the limit locks the username out.
+func (policy loginPolicy) usernameDelay(failures int64) time.Duration {
+	if failures == 0 {
+		return 0
+	}
+	if failures >= policy.MaxFailures || failures > 30 {
+		return policy.Lockout
+	}
+	if delay := time.Second << uint(failures-1); delay < policy.Lockout {
+		return delay
+	}
+	return policy.Lockout
+}
+
+// ipDelay is how long to wait after the last of failures from an IP. Many people can share an IP, so it
+// isn't slowed down, only locked out once the limit is reached.
+func (policy loginPolicy) ipDelay(failures int64) time.Duration {
+	if failures >= policy.MaxIPFailures {
+		return policy.Lockout
+	}
+	return 0
+}
+
+// remaining returns how much of delay is left since last.
+func remaining(last time.Time, delay time.Duration, now time.Time) time.Duration {
+	if wait := last.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// loginRetryAfter returns how long the client has to wait before it can try signing in as username again,
+// going by the recent failures for the username and from the client's IP.
+func loginRetryAfter(ctx *gin.Context, username string) (time.Duration, error) {
+	policy := loadLoginPolicy()
+	now := time.Now()
+	since := now.Add(-policy.Lockout)
+
+	byUsername, err := firstly.store.CountUsernameLoginFailures(ctx, db.CountUsernameLoginFailuresParams{Username: username, Created: since})
+	if err != nil {
+		return 0, err
+	}
+	byIP, err := firstly.store.CountIPLoginFailures(ctx, db.CountIPLoginFailuresParams{IP: ctx.ClientIP(), Created: since})
+	if err != nil {
+		return 0, err
+	}
+
+	wait := remaining(byUsername.LastFailure.Time, policy.usernameDelay(byUsername.Failures), now)
+	if ipWait := remaining(byIP.LastFailure.Time, policy.ipDelay(byIP.Failures), now); ipWait > wait {
+		wait = ipWait
+	}
+	return wait, nil
+}
+
+// recordLoginAttempt records a sign in attempt. A successful sign in clears the username's failures.
+// Recording is best effort, failures are only logged.
+func recordLoginAttempt(ctx *gin.Context, username string, reason string) {
+	success := reason == loginSucceeded
+	err := firstly.store.CreateLoginAttempt(ctx, db.CreateLoginAttemptParams{
+		Username:  username,
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Success:   success,
+		Reason:    reason,
+	})
+	if err != nil {
+		log.Printf("error recording sign in attempt for %q: %s", username, err)
+	}
+	if success {
+		if _, err := firstly.store.ClearUsernameLoginFailures(ctx, username); err != nil {
+			log.Printf("error clearing sign in failures for %q: %s", username, err)
+		}
+	}
+}
+
+// abortTooManyAttempts responds with status too many requests and when to try again.
+func abortTooManyAttempts(ctx *gin.Context, wait time.Duration) {
+	ctx.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10))
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(errTooManyAttempts))
+}
+
+// dummyPhrase is a hash checked against when the username doesn't exist, so that an unknown username
+// takes as long to reject as a wrong phrase.
+type dummyPhrase struct {
+	once sync.Once
+	hash []byte
+	salt string
+}
+
+func verifyDummyPhrase(phrase string) {
+	dummy := firstly.dummyPhrase
+	dummy.once.Do(func() {
+		dummy.salt = firstly.hasher.GenerateSalt()
+		hash, err := firstly.hasher.GeneratePasswordHash([]byte("not a phrase anyone has"), dummy.salt)
+		if err != nil {
+			log.Printf("error hashing the dummy phrase: %s", err)
+			return
+		}
+		dummy.hash = hash
+	})
+	if dummy.hash != nil {
+		firstly.hasher.IsValidPassword(dummy.hash, dummy.salt, phrase)
+	}
+}
+
+// unlockLoginHandler clears the recent sign in failures of a username and/or an IP, lifting any lockout.
+func unlockLoginHandler(ctx *gin.Context) {
+	var req unlockLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Username == "" && req.IP == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("username or ip is required")))
+		return
+	}
+
+	var cleared int64
+	if req.Username != "" {
+		n, err := firstly.store.ClearUsernameLoginFailures(ctx, req.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		cleared += n
+	}
+	if req.IP != "" {
+		n, err := firstly.store.ClearIPLoginFailures(ctx, req.IP)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		cleared += n
+	}
+	ctx.JSON(http.StatusOK, unlockLoginResponse{Cleared: cleared})
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+// allowLoginAttempt expects signing in as username to be checked against no recent failures.
+func allowLoginAttempt(store *db.MockStore, username string) {
+	store.EXPECT().CountUsernameLoginFailures(gomock.Any(), usernameFailuresSince(username)).Return(db.CountUsernameLoginFailuresRow{}, nil)
+	store.EXPECT().CountIPLoginFailures(gomock.Any(), gomock.Any()).Return(db.CountIPLoginFailuresRow{}, nil)
+}
+
+// expectLoginAttempt expects a sign in attempt as username to be recorded with reason.
+func expectLoginAttempt(store *db.MockStore, username string, reason string) {
+	store.EXPECT().CreateLoginAttempt(gomock.Any(), loginAttempt(username, reason)).Return(nil)
+	if reason == loginSucceeded {
+		store.EXPECT().ClearUsernameLoginFailures(gomock.Any(), username).Return(int64(0), nil)
+	}
+}
+
+func usernameFailuresSince(username string) gomock.Matcher {
+	return gomock.AssignableToTypeOf(db.CountUsernameLoginFailuresParams{Username: username})
+}
+
+type loginAttemptMatcher struct {
+	username string
+	reason   string
+}
+
+func loginAttempt(username string, reason string) gomock.Matcher {
+	return loginAttemptMatcher{username: username, reason: reason}
+}
+
+func (m loginAttemptMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateLoginAttemptParams)
+	return ok && arg.Username == m.username && arg.Reason == m.reason && arg.Success == (m.reason == loginSucceeded)
+}
+
+func (m loginAttemptMatcher) String() string {
+	return "is a sign in attempt by " + m.username + " with reason " + m.reason
+}
+
+func TestLoginPolicyUsernameDelay(t *testing.T) {
+	policy := loginPolicy{MaxFailures: 5, MaxIPFailures: 20, Lockout: 15 * time.Minute}
+	tests := []struct {
+		failures int64
+		expected time.Duration
+	}{
+		{failures: 0, expected: 0},
+		{failures: 1, expected: time.Second},
+		{failures: 2, expected: 2 * time.Second},
+		{failures: 4, expected: 8 * time.Second},
+		{failures: 5, expected: 15 * time.Minute},
+		{failures: 100, expected: 15 * time.Minute},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, policy.usernameDelay(test.failures))
+	}
+	assert.Equal(t, time.Duration(0), policy.ipDelay(19))
+	assert.Equal(t, 15*time.Minute, policy.ipDelay(20))
+}
+
+func TestLoginThrottling(t *testing.T) {
+	tests := []struct {
+		name              string
+		route             string
+		body              io.Reader
+		responseCode      int
+		retryAfter        bool
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:         "signin handler after repeated failures for the username responds with too many requests",
+			route:        "/signin/",
+			body:         bytes.NewBufferString(`{"username":"valid","phrase":"guess"}`),
+			responseCode: http.StatusTooManyRequests,
+			retryAfter:   true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().CountUsernameLoginFailures(gomock.Any(), usernameFailuresSince("valid")).
+					Return(db.CountUsernameLoginFailuresRow{Failures: 5, LastFailure: sql.NullTime{Time: time.Now(), Valid: true}}, nil)
+				store.EXPECT().CountIPLoginFailures(gomock.Any(), gomock.Any()).Return(db.CountIPLoginFailuresRow{}, nil)
+				expectLoginAttempt(store, "valid", loginThrottled)
+				store.EXPECT().GetAccountByUsername(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "signin handler after repeated failures from the ip responds with too many requests",
+			route:        "/signin/",
+			body:         bytes.NewBufferString(`{"username":"other","phrase":"guess"}`),
+			responseCode: http.StatusTooManyRequests,
+			retryAfter:   true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().CountUsernameLoginFailures(gomock.Any(), usernameFailuresSince("other")).Return(db.CountUsernameLoginFailuresRow{}, nil)
+				store.EXPECT().CountIPLoginFailures(gomock.Any(), gomock.Any()).
+					Return(db.CountIPLoginFailuresRow{Failures: 20, LastFailure: sql.NullTime{Time: time.Now(), Valid: true}}, nil)
+				expectLoginAttempt(store, "other", loginThrottled)
+				store.EXPECT().GetAccountByUsername(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "signin handler once the delay after a failure has passed checks the phrase",
+			route:        "/signin/",
+			body:         bytes.NewBufferString(`{"username":"valid","phrase":"guess"}`),
+			responseCode: http.StatusUnauthorized,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().CountUsernameLoginFailures(gomock.Any(), usernameFailuresSince("valid")).
+					Return(db.CountUsernameLoginFailuresRow{Failures: 2, LastFailure: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}}, nil)
+				store.EXPECT().CountIPLoginFailures(gomock.Any(), gomock.Any()).Return(db.CountIPLoginFailuresRow{}, nil)
+				account := db.Account{ID: 1, Username: "valid", Phrase: []byte("hash"), Salt: "salt"}
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "guess").Return(false, nil)
+				expectLoginAttempt(store, "valid", loginWrongPhrase)
+			},
+		},
+		{
+			name:         "unlock handler clears the failures of a username and an ip",
+			route:        "/auth/unlock",
+			body:         bytes.NewBufferString(`{"username":"valid","ip":"10.0.0.1"}`),
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+				store.EXPECT().ClearUsernameLoginFailures(gomock.Any(), "valid").Return(int64(5), nil)
+				store.EXPECT().ClearIPLoginFailures(gomock.Any(), "10.0.0.1").Return(int64(2), nil)
+			},
+		},
+		{
+			name:         "unlock handler given neither a username nor an ip responds with status bad request",
+			route:        "/auth/unlock",
+			body:         bytes.NewBufferString(`{}`),
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+			},
+		},
+		{
+			name:         "unlock handler responds with status forbidden given the account isn't an admin",
+			route:        "/auth/unlock",
+			body:         bytes.NewBufferString(`{"username":"valid"}`),
+			responseCode: http.StatusForbidden,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ClearUsernameLoginFailures(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodPost, test.route, test.body)
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+			if test.retryAfter {
+				assert.NotEqual(t, "", result.Header.Get("Retry-After"))
+				var response map[string]string
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, errTooManyAttempts.Error(), response["error"])
+			}
+		})
+	}
+}
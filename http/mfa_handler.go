@@ -0,0 +1,324 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+// reason sign in attempts are recorded with when the second factor is wrong
+const loginWrongCode = "wrong_code"
+
+var (
+	errInvalidMFAToken   = errors.New("mfa token is invalid or has expired, sign in again")
+	errInvalidMFACode    = errors.New("code is incorrect")
+	errTotpEnabled       = errors.New("two-factor authentication is already enabled")
+	errTotpNotEnrolled   = errors.New("two-factor authentication hasn't been enrolled in")
+	errMFACodeIsRequired = errors.New("code or recovery_code is required")
+)
+
+// mfaPendingResponse is the response to signing in to an account with two-factor authentication. The
+// token is exchanged for an access token at /auth/mfa along with a code.
+type mfaPendingResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type verifyMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	// TokenInBody asks for the tokens in the response body, for clients that can't use cookies
+	TokenInBody bool `json:"token_in_body"`
+}
+
+type enrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+	// QRCode is the URI as a PNG, base64 encoded
+	QRCode []byte `json:"qr_png"`
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type recoveryCodesResponse struct {
+	// RecoveryCodes are only returned when two-factor authentication is enabled, they can't be retrieved
+	// afterwards
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type disableTOTPRequest struct {
+	CurrentPhrase string `json:"current_phrase" binding:"required"`
+}
+
+// startMFA responds with a short lived token proving the account's phrase was given, which can only be
+// exchanged for an access token along with a code.
+func startMFA(ctx *gin.Context, account db.Account) {
+	claims, err := security.NewMFAPendingClaims(account.ID, account.Username)
+	if err != nil {
+		ctx.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	tokenString, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(claims)
+	if err != nil {
+		ctx.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ctx.JSON(http.StatusOK, mfaPendingResponse{
+		MFARequired: true,
+		MFAToken:    tokenString,
+		ExpiresAt:   expirationTime,
+	})
+}
+
+// verifyMFAHandler completes a sign in started at /signin/ given the mfa token along with either the
+// current TOTP code or an unused recovery code. Wrong codes count towards the sign in lockout like wrong
+// phrases do, and each mfa token can only be used once.
+func verifyMFAHandler(ctx *gin.Context) {
+	var req verifyMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errMFACodeIsRequired))
+		return
+	}
+
+	token, claims, err := firstly.claimer.GetFromTokenString(req.MFAToken)
+	if err != nil || !token.Valid || !claims.IsMFAPending() || claims.AccountID() == 0 {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFAToken))
+		return
+	}
+	revoked, err := firstly.store.IsTokenRevoked(ctx, claims.Id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFAToken))
+		return
+	}
+
+	wait, err := loginRetryAfter(ctx, claims.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if wait > 0 {
+		recordLoginAttempt(ctx, claims.Username, loginThrottled)
+		abortTooManyAttempts(ctx, wait)
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, claims.AccountID())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFAToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// two-factor authentication was turned off since signing in, so the token is no longer any use
+	if !account.TotpEnabled {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFAToken))
+		return
+	}
+
+	var valid bool
+	if req.Code != "" {
+		valid, err = useTOTPCode(ctx, account, req.Code)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	} else {
+		used, err := firstly.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			AccountID: account.ID,
+			CodeHash:  security.HashRecoveryCode(req.RecoveryCode),
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		valid = used > 0
+	}
+	if !valid {
+		recordLoginAttempt(ctx, account.Username, loginWrongCode)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	if err := revokeAccessToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	recordLoginAttempt(ctx, account.Username, loginSucceeded)
+
+	completeSignin(ctx, account, req.TokenInBody)
+}
+
+// enrollTOTPHandler generates a TOTP secret for the signed in account, responding with it as an otpauth
+// URI and QR code for an authenticator app. Two-factor authentication isn't enabled until a code from the
+// app is confirmed, and enrolling again replaces the unconfirmed secret.
+func enrollTOTPHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if account.TotpEnabled {
+		ctx.JSON(http.StatusConflict, errorResponse(errTotpEnabled))
+		return
+	}
+
+	secret, uri, qr, err := security.NewTOTPKey(account.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	updated, err := firstly.store.SetAccountTotpSecret(ctx, db.SetAccountTotpSecretParams{TotpSecret: secret, ID: account.ID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if updated == 0 {
+		ctx.JSON(http.StatusConflict, errorResponse(errTotpEnabled))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, enrollTOTPResponse{Secret: secret, URI: uri, QRCode: qr})
+}
+
+// confirmTOTPHandler enables two-factor authentication once given a code for the enrolled secret,
+// responding with the recovery codes that can be used in place of a code.
+func confirmTOTPHandler(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if account.TotpEnabled {
+		ctx.JSON(http.StatusConflict, errorResponse(errTotpEnabled))
+		return
+	}
+	if account.TotpSecret == "" {
+		ctx.JSON(http.StatusConflict, errorResponse(errTotpNotEnrolled))
+		return
+	}
+	valid, err := useTOTPCode(ctx, account, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	codes, hashes, err := security.NewRecoveryCodes(security.RecoveryCodeCount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err := firstly.store.EnableTotp(ctx, db.EnableTotpParams{AccountID: account.ID, RecoveryCodeHashes: hashes}); err != nil {
+		if errors.Is(err, db.ErrTotpNotPending) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// disableTOTPHandler turns two-factor authentication off for the signed in account, which needs the
+// current phrase so that a stolen token can't be used to remove the second factor.
+func disableTOTPHandler(ctx *gin.Context) {
+	var req disableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	valid, err := firstly.hasher.IsValidPassword(account.Phrase, account.Salt, req.CurrentPhrase)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("current phrase is incorrect")))
+		return
+	}
+
+	if err := firstly.store.DisableTotp(ctx, account.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// useTOTPCode reports whether code is valid for the account's secret, recording its time step so that
+// neither it nor a code for an earlier step is accepted again. A code seen twice within its validity is
+// treated as wrong, since it may have been read over the account holder's shoulder.
+func useTOTPCode(ctx context.Context, account db.Account, code string) (bool, error) {
+	step, ok := security.ValidateTOTP(code, account.TotpSecret)
+	if !ok {
+		return false, nil
+	}
+	used, err := firstly.store.UseTotpStep(ctx, db.UseTotpStepParams{TotpLastStep: step, ID: account.ID})
+	if err != nil {
+		return false, err
+	}
+	return used > 0, nil
+}
@@ -0,0 +1,500 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+	"github.com/pquerna/otp/totp"
+)
+
+// mfaPendingMatcher matches the claims of an mfa token passed to GetFiveMinuteExpirationToken by username.
+type mfaPendingMatcher struct {
+	username string
+}
+
+func (m mfaPendingMatcher) Matches(x interface{}) bool {
+	claims, ok := x.(*security.UsernameClaims)
+	return ok && claims.Username == m.username && claims.IsMFAPending() && claims.Id != ""
+}
+
+func (m mfaPendingMatcher) String() string {
+	return fmt.Sprintf("is an mfa token for %s", m.username)
+}
+
+// expectMFAToken expects the mfa token "mfatoken" to be verified, returning claims for account 1 with the
+// given scope.
+func expectMFAToken(claimer *security.MockClaimer, scope string) *security.UsernameClaims {
+	claims := security.NewUsernameClaims()
+	claims.Username = "valid"
+	claims.Subject = "1"
+	claims.Id = "mfajti"
+	claims.Scope = scope
+	claims.ExpiresAt = time.Now().Add(5 * time.Minute).Unix()
+	claimer.EXPECT().GetFromTokenString("mfatoken").Return(&security.ClaimToken{Token: &jwt.Token{Valid: true}}, claims, nil)
+	return claims
+}
+
+func TestMFAHandlers(t *testing.T) {
+	secret, _, _, err := security.NewTOTPKey("valid")
+	if err != nil {
+		t.Fatalf("Error generating totp key: %v", err)
+	}
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("Error generating totp code: %v", err)
+	}
+	wrongCode := "000000"
+	if wrongCode == code {
+		wrongCode = "111111"
+	}
+	enabledAccount := db.Account{ID: 1, Username: "valid", Phrase: []byte("valid"), Salt: "salt", Role: security.RoleMember, TotpSecret: secret, TotpEnabled: true}
+	enrolledAccount := db.Account{ID: 1, Username: "valid", Phrase: []byte("valid"), Salt: "salt", Role: security.RoleMember, TotpSecret: secret}
+
+	tests := []struct {
+		name              string
+		method            string
+		route             string
+		body              io.Reader
+		responseCode      int
+		expectedError     string
+		assertBody        func(t *testing.T, body io.Reader)
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:         "signin handler given an account with two-factor authentication responds with an mfa token instead of signing in",
+			method:       http.MethodPost,
+			route:        "/signin/",
+			body:         bytes.NewBufferString(`{"phrase":"valid","username":"valid"}`),
+			responseCode: http.StatusOK,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response mfaPendingResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, true, response.MFARequired)
+				assert.Equal(t, "mfatoken", response.MFAToken)
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(enabledAccount, nil)
+				hasher.EXPECT().IsValidPassword(enabledAccount.Phrase, enabledAccount.Salt, "valid").Return(true, nil)
+				hasher.EXPECT().NeedsRehash(enabledAccount.Phrase).Return(false)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(mfaPendingMatcher{username: "valid"}).
+					Return("mfatoken", time.Now().Add(5*time.Minute), nil)
+				// the sign in hasn't succeeded yet, so neither is it recorded nor is a session started
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "verify mfa handler given the current code signs in",
+			method:       http.MethodPost,
+			route:        "/auth/mfa",
+			body:         bytes.NewBufferString(fmt.Sprintf(`{"mfa_token":"mfatoken","code":%q,"token_in_body":true}`, code)),
+			responseCode: http.StatusOK,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response tokenResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, "mocktoken", response.AccessToken)
+				assert.NotEqual(t, "", response.RefreshToken)
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectMFAToken(claimer, security.ScopeMFAPending)
+				store.EXPECT().IsTokenRevoked(gomock.Any(), "mfajti").Return(false, nil)
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enabledAccount, nil)
+				// the code can't be used again
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.AssignableToTypeOf(db.UseTotpStepParams{})).
+					DoAndReturn(func(_ interface{}, arg db.UseTotpStepParams) (int64, error) {
+						assert.Equal(t, int64(1), arg.ID)
+						if now := time.Now().Unix() / 30; arg.TotpLastStep < now-1 || arg.TotpLastStep > now+1 {
+							t.Fatalf("expected the step of the current code to be within one of %d, got %d", now, arg.TotpLastStep)
+						}
+						return 1, nil
+					})
+				// the mfa token can't be used again
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.AssignableToTypeOf(db.RevokeTokenParams{})).
+					DoAndReturn(func(_ interface{}, arg db.RevokeTokenParams) error {
+						assert.Equal(t, "mfajti", arg.Jti)
+						return nil
+					})
+				expectLoginAttempt(store, "valid", loginSucceeded)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("valid")).
+					Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Return(db.Session{ID: 1, Expires: time.Now().Add(time.Hour)}, nil)
+			},
+		},
+		{
+			name:          "verify mfa handler given a wrong code responds with status unauthorized and records the failure",
+			method:        http.MethodPost,
+			route:         "/auth/mfa",
+			body:          bytes.NewBufferString(fmt.Sprintf(`{"mfa_token":"mfatoken","code":%q}`, wrongCode)),
+			responseCode:  http.StatusUnauthorized,
+			expectedError: errInvalidMFACode.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectMFAToken(claimer, security.ScopeMFAPending)
+				store.EXPECT().IsTokenRevoked(gomock.Any(), "mfajti").Return(false, nil)
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enabledAccount, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginAttempt(store, "valid", loginWrongCode)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "verify mfa handler given a code that was already used responds with status unauthorized and records the failure",
+			method:        http.MethodPost,
+			route:         "/auth/mfa",
+			body:          bytes.NewBufferString(fmt.Sprintf(`{"mfa_token":"mfatoken","code":%q}`, code)),
+			responseCode:  http.StatusUnauthorized,
+			expectedError: errInvalidMFACode.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectMFAToken(claimer, security.ScopeMFAPending)
+				store.EXPECT().IsTokenRevoked(gomock.Any(), "mfajti").Return(false, nil)
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enabledAccount, nil)
+				// the step, or a later one, was already accepted
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+				expectLoginAttempt(store, "valid", loginWrongCode)
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "verify mfa handler given an unused recovery code signs in",
+			method:       http.MethodPost,
+			route:        "/auth/mfa",
+			body:         bytes.NewBufferString(`{"mfa_token":"mfatoken","recovery_code":"ABCDE-FGHIJ"}`),
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectMFAToken(claimer, security.ScopeMFAPending)
+				store.EXPECT().IsTokenRevoked(gomock.Any(), "mfajti").Return(false, nil)
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enabledAccount, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), db.UseRecoveryCodeParams{
+					AccountID: 1,
+					CodeHash:  security.HashRecoveryCode("abcde-fghij"),
+				}).Return(int64(1), nil)
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Return(nil)
+				expectLoginAttempt(store, "valid", loginSucceeded)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("valid")).
+					Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Return(db.Session{ID: 1}, nil)
+			},
+		},
+		{
+			name:          "verify mfa handler given a used recovery code responds with status unauthorized",
+			method:        http.MethodPost,
+			route:         "/auth/mfa",
+			body:          bytes.NewBufferString(`{"mfa_token":"mfatoken","recovery_code":"abcde-fghij"}`),
+			responseCode:  http.StatusUnauthorized,
+			expectedError: errInvalidMFACode.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectMFAToken(claimer, security.ScopeMFAPending)
+				store.EXPECT().IsTokenRevoked(gomock.Any(), "mfajti").Return(false, nil)
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enabledAccount, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+				expectLoginAttempt(store, "valid", loginWrongCode)
+			},
+		},
+		{
+			name:          "verify mfa handler given an access token in place of an mfa token responds with status unauthorized",
+			method:        http.MethodPost,
+			route:         "/auth/mfa",
+			body:          bytes.NewBufferString(fmt.Sprintf(`{"mfa_token":"mfatoken","code":%q}`, code)),
+			responseCode:  http.StatusUnauthorized,
+			expectedError: errInvalidMFAToken.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectMFAToken(claimer, "account:read account:write")
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "verify mfa handler given an mfa token that was already used responds with status unauthorized",
+			method:        http.MethodPost,
+			route:         "/auth/mfa",
+			body:          bytes.NewBufferString(fmt.Sprintf(`{"mfa_token":"mfatoken","code":%q}`, code)),
+			responseCode:  http.StatusUnauthorized,
+			expectedError: errInvalidMFAToken.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectMFAToken(claimer, security.ScopeMFAPending)
+				store.EXPECT().IsTokenRevoked(gomock.Any(), "mfajti").Return(true, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "verify mfa handler without a code responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/auth/mfa",
+			body:          bytes.NewBufferString(`{"mfa_token":"mfatoken"}`),
+			responseCode:  http.StatusBadRequest,
+			expectedError: errMFACodeIsRequired.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				claimer.EXPECT().GetFromTokenString(gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "claims middleware rejects an mfa token used as an access token",
+			method:       http.MethodGet,
+			route:        "/account/me",
+			responseCode: http.StatusUnauthorized,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectMFAToken(claimer, security.ScopeMFAPending)
+				r.Header.Set("Authorization", "Bearer mfatoken")
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "enroll totp handler responds with a secret, otpauth uri and qr code",
+			method:       http.MethodPost,
+			route:        "/account/me/mfa/totp",
+			responseCode: http.StatusCreated,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response enrollTOTPResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.NotEqual(t, "", response.Secret)
+				assert.Equal(t, true, bytes.HasPrefix(response.QRCode, []byte("\x89PNG")))
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Username: "valid"}, nil)
+				store.EXPECT().SetAccountTotpSecret(gomock.Any(), gomock.AssignableToTypeOf(db.SetAccountTotpSecretParams{})).
+					DoAndReturn(func(_ interface{}, arg db.SetAccountTotpSecretParams) (int64, error) {
+						assert.Equal(t, int64(1), arg.ID)
+						assert.NotEqual(t, "", arg.TotpSecret)
+						return 1, nil
+					})
+			},
+		},
+		{
+			name:          "enroll totp handler given an account with two-factor authentication responds with status conflict",
+			method:        http.MethodPost,
+			route:         "/account/me/mfa/totp",
+			responseCode:  http.StatusConflict,
+			expectedError: errTotpEnabled.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enabledAccount, nil)
+				store.EXPECT().SetAccountTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "confirm totp handler given the current code enables two-factor authentication and responds with recovery codes",
+			method:       http.MethodPost,
+			route:        "/account/me/mfa/totp/confirm",
+			body:         bytes.NewBufferString(fmt.Sprintf(`{"code":%q}`, code)),
+			responseCode: http.StatusOK,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response recoveryCodesResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, security.RecoveryCodeCount, len(response.RecoveryCodes))
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enrolledAccount, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.AssignableToTypeOf(db.UseTotpStepParams{})).Return(int64(1), nil)
+				store.EXPECT().EnableTotp(gomock.Any(), gomock.AssignableToTypeOf(db.EnableTotpParams{})).
+					DoAndReturn(func(_ interface{}, arg db.EnableTotpParams) error {
+						assert.Equal(t, int64(1), arg.AccountID)
+						assert.Equal(t, security.RecoveryCodeCount, len(arg.RecoveryCodeHashes))
+						return nil
+					})
+			},
+		},
+		{
+			name:          "confirm totp handler given a wrong code responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/account/me/mfa/totp/confirm",
+			body:          bytes.NewBufferString(fmt.Sprintf(`{"code":%q}`, wrongCode)),
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidMFACode.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enrolledAccount, nil)
+				store.EXPECT().EnableTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "confirm totp handler given a code that was already used responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/account/me/mfa/totp/confirm",
+			body:          bytes.NewBufferString(fmt.Sprintf(`{"code":%q}`, code)),
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidMFACode.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enrolledAccount, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+				store.EXPECT().EnableTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "confirm totp handler before enrolling responds with status conflict",
+			method:        http.MethodPost,
+			route:         "/account/me/mfa/totp/confirm",
+			body:          bytes.NewBufferString(fmt.Sprintf(`{"code":%q}`, code)),
+			responseCode:  http.StatusConflict,
+			expectedError: errTotpNotEnrolled.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Username: "valid"}, nil)
+				store.EXPECT().EnableTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "disable totp handler given the current phrase turns two-factor authentication off",
+			method:       http.MethodDelete,
+			route:        "/account/me/mfa/totp",
+			body:         bytes.NewBufferString(`{"current_phrase":"valid"}`),
+			responseCode: http.StatusNoContent,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enabledAccount, nil)
+				hasher.EXPECT().IsValidPassword(enabledAccount.Phrase, enabledAccount.Salt, "valid").Return(true, nil)
+				store.EXPECT().DisableTotp(gomock.Any(), int64(1)).Return(nil)
+			},
+		},
+		{
+			name:         "disable totp handler given a wrong phrase responds with status forbidden",
+			method:       http.MethodDelete,
+			route:        "/account/me/mfa/totp",
+			body:         bytes.NewBufferString(`{"current_phrase":"invalid"}`),
+			responseCode: http.StatusForbidden,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(enabledAccount, nil)
+				hasher.EXPECT().IsValidPassword(enabledAccount.Phrase, enabledAccount.Salt, "invalid").Return(false, nil)
+				store.EXPECT().DisableTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			body := test.body
+			if body == nil {
+				body = http.NoBody
+			}
+			request := httptest.NewRequest(test.method, test.route, body)
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			if test.expectedError != "" {
+				var response map[string]string
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.expectedError, response["error"])
+			}
+			if test.assertBody != nil {
+				test.assertBody(t, result.Body)
+			}
+		})
+	}
+}
+
+// TestMFAWithRealClaimer signs in to an account with two-factor authentication using the real claimer, so
+// that the claims of the mfa and access tokens are those that survive signing and parsing.
+func TestMFAWithRealClaimer(t *testing.T) {
+	t.Setenv("SECRET_KEYS", "")
+	t.Setenv("SECRET", "test")
+	secret, _, _, err := security.NewTOTPKey("valid")
+	if err != nil {
+		t.Fatalf("Error generating totp key: %v", err)
+	}
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("Error generating totp code: %v", err)
+	}
+	account := db.Account{ID: 1, Username: "valid", Phrase: []byte("valid"), Salt: "salt", Role: security.RoleMember, TotpSecret: secret, TotpEnabled: true}
+
+	router := gin.Default()
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	store := db.NewMockStore(ctrl)
+	hasher := security.NewMockHasher(ctrl)
+	NewFirstlyServer(security.NewClaimsValidator(), hasher, router, store)
+	serve := func(method string, route string, body string, token string) *http.Response {
+		request := httptest.NewRequest(method, route, bytes.NewBufferString(body))
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		return responseRecorder.Result()
+	}
+
+	// the phrase is right, so an mfa token is given instead of signing in
+	allowLoginAttempt(store, "valid")
+	store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(account, nil)
+	hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "valid").Return(true, nil)
+	hasher.EXPECT().NeedsRehash(account.Phrase).Return(false)
+	result := serve(http.MethodPost, "/signin/", `{"phrase":"valid","username":"valid"}`, "")
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	var pending mfaPendingResponse
+	decodeJSON(t, result.Body, &pending)
+	assert.Equal(t, true, pending.MFARequired)
+
+	// the mfa token isn't an access token
+	store.EXPECT().ListAccountSessions(gomock.Any(), gomock.Any()).Times(0)
+	result = serve(http.MethodGet, "/account/me/sessions", "", pending.MFAToken)
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+
+	// along with the code it signs in
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil)
+	allowLoginAttempt(store, "valid")
+	store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+	store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+	store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Return(nil)
+	expectLoginAttempt(store, "valid", loginSucceeded)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Return(db.Session{ID: 1, Expires: time.Now().Add(time.Hour)}, nil)
+	result = serve(http.MethodPost, "/auth/mfa", fmt.Sprintf(`{"mfa_token":%q,"code":%q,"token_in_body":true}`, pending.MFAToken, code), "")
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	var signedIn tokenResponse
+	decodeJSON(t, result.Body, &signedIn)
+
+	// and the access token it responds with is accepted
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil)
+	store.EXPECT().ListAccountSessions(gomock.Any(), int64(1)).Return([]db.Session{}, nil)
+	result = serve(http.MethodGet, "/account/me/sessions", "", signedIn.AccessToken)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+}
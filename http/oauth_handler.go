@@ -0,0 +1,828 @@
+package http
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+const (
+	// oauthTicketTTL is how long the user has to give their consent
+	oauthTicketTTL = 10 * time.Minute
+	// oauthCodeTTL is how long an app has to exchange an authorization code for tokens
+	oauthCodeTTL = time.Minute
+
+	// error codes from RFC 6749 sections 4.1.2.1 and 5.2
+	oauthInvalidRequest          = "invalid_request"
+	oauthInvalidClient           = "invalid_client"
+	oauthInvalidGrant            = "invalid_grant"
+	oauthInvalidScope            = "invalid_scope"
+	oauthAccessDenied            = "access_denied"
+	oauthUnsupportedGrantType    = "unsupported_grant_type"
+	oauthUnsupportedResponseType = "unsupported_response_type"
+)
+
+var (
+	errInvalidOAuthClient = errors.New("client is unknown or its credentials are wrong")
+	errInvalidRedirectURI = errors.New("redirect_uri isn't registered for the client")
+	errInvalidOAuthTicket = errors.New("consent ticket is invalid or has expired")
+	errFirstPartyOnly     = errors.New("apps can only be managed and given access by signing in")
+)
+
+// scopeDescriptions explain the scopes an app asks for on the consent screen.
+var scopeDescriptions = map[string]string{
+	security.ScopeAccountRead: "See your account details",
+	security.ScopeImagesRead:  "See your images",
+	security.ScopeImagesWrite: "Add, change and delete your images",
+}
+
+var consentPage = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Allow {{.ClientName}} access to your account?</title>
+</head>
+<body>
+<h1>Allow {{.ClientName}} access to your account?</h1>
+<p>Signed in as {{.Username}}. {{.ClientName}} is asking to:</p>
+<ul>
+{{range .Scopes}}<li>{{.}}</li>
+{{end}}</ul>
+<p>You will be returned to {{.RedirectURI}}</p>
+<form method="post" action="/oauth/authorize">
+<input type="hidden" name="ticket" value="{{.Ticket}}">
+<button type="submit" name="approve" value="true">Allow</button>
+<button type="submit" name="approve" value="false">Deny</button>
+</form>
+</body>
+</html>
+`))
+
+type createOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required"`
+	Scopes       []string `json:"scopes" binding:"required"`
+	// Public apps, such as mobile and single page apps, can't keep a secret and are only given a client id
+	Public bool `json:"public"`
+}
+
+type oauthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Public       bool      `json:"public"`
+	Created      time.Time `json:"created"`
+	// ClientSecret is only returned when a confidential client is created, it can't be retrieved afterwards
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: strings.Fields(client.RedirectUris),
+		Scopes:       strings.Fields(client.Scopes),
+		Public:       len(client.SecretHash) == 0,
+		Created:      client.Created,
+	}
+}
+
+type authorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+type consentResponse struct {
+	Ticket      string   `json:"ticket"`
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	RedirectURI string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+}
+
+type consentRequest struct {
+	Ticket  string `form:"ticket" json:"ticket" binding:"required"`
+	Approve bool   `form:"approve" json:"approve"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// introspectionResponse describes a token as in RFC 7662 section 2.2. Inactive tokens are only described
+// as inactive.
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Expires   int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+}
+
+// oauthError responds with an error of the token, introspection and revocation endpoints, which is shaped
+// as in RFC 6749 section 5.2 rather than like the rest of the API's errors.
+func oauthError(ctx *gin.Context, status int, code string, description string) {
+	ctx.AbortWithStatusJSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// oauthServerError logs err and responds with a server_error that doesn't describe it, as the error can
+// name internals such as the database that aren't the client's business.
+func oauthServerError(ctx *gin.Context, err error) {
+	log.Printf("error handling %s %s: %s", ctx.Request.Method, ctx.Request.URL.Path, err)
+	oauthError(ctx, http.StatusInternalServerError, "server_error", "the server couldn't handle the request, try again later")
+}
+
+// parseRedirectURI checks an app's redirect URI is absolute, without a fragment, and uses https unless
+// it is on the user's own machine.
+func parseRedirectURI(raw string) error {
+	uri, err := url.Parse(raw)
+	if err != nil || !uri.IsAbs() || uri.Host == "" || uri.Fragment != "" {
+		return fmt.Errorf("redirect uri %q must be an absolute url without a fragment", raw)
+	}
+	switch uri.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		if uri.Scheme == "http" || uri.Scheme == "https" {
+			return nil
+		}
+	default:
+		if uri.Scheme == "https" {
+			return nil
+		}
+	}
+	return fmt.Errorf("redirect uri %q must use https unless it is on localhost", raw)
+}
+
+// redirectWithParams sends the user back to the app with params, or responds with where they are sent
+// when the consent screen is used from JSON rather than HTML.
+func redirectWithParams(ctx *gin.Context, redirectURI string, params url.Values) {
+	uri, err := url.Parse(redirectURI)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	query := uri.Query()
+	for name, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(name, value)
+			}
+		}
+	}
+	uri.RawQuery = query.Encode()
+
+	if ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		ctx.Redirect(http.StatusFound, uri.String())
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"redirect_uri": uri.String()})
+}
+
+func redirectWithOAuthError(ctx *gin.Context, redirectURI string, state string, code string, description string) {
+	redirectWithParams(ctx, redirectURI, url.Values{
+		"error":             {code},
+		"error_description": {description},
+		"state":             {state},
+	})
+}
+
+// createOAuthClientHandler registers an app owned by the signed in account, responding with its client id
+// and, for confidential apps, its secret.
+func createOAuthClientHandler(ctx *gin.Context) {
+	var req createOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+	if !claims.IsFirstParty() {
+		ctx.JSON(http.StatusForbidden, errorResponse(errFirstPartyOnly))
+		return
+	}
+
+	if len(req.RedirectURIs) == 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("at least one redirect uri is required")))
+		return
+	}
+	for _, uri := range req.RedirectURIs {
+		if err := parseRedirectURI(uri); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	scopes, err := security.ParseOAuthScopes(req.Scopes)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	clientID, err := security.NewOAuthClientID()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	var secret string
+	secretHash := []byte{}
+	if !req.Public {
+		if secret, secretHash, err = security.NewOAuthClientSecret(); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+	client, err := firstly.store.CreateOauthClient(ctx, db.CreateOauthClientParams{
+		ClientID:     clientID,
+		AccountID:    accountID,
+		Name:         req.Name,
+		RedirectUris: strings.Join(req.RedirectURIs, " "),
+		Scopes:       scopes,
+		SecretHash:   secretHash,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := newOAuthClientResponse(client)
+	response.ClientSecret = secret
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// listOAuthClientsHandler responds with the apps registered by the signed in account, newest first.
+func listOAuthClientsHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	clients, err := firstly.store.ListAccountOauthClients(ctx, accountID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	items := make([]oauthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		items = append(items, newOAuthClientResponse(client))
+	}
+	ctx.JSON(http.StatusOK, items)
+}
+
+// deleteOAuthClientHandler deletes one of the signed in account's apps along with the refresh tokens
+// issued to it. Its access tokens are rejected by introspection straight away and expire within minutes.
+func deleteOAuthClientHandler(ctx *gin.Context) {
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+	if !claims.IsFirstParty() {
+		ctx.JSON(http.StatusForbidden, errorResponse(errFirstPartyOnly))
+		return
+	}
+
+	deleted, err := firstly.store.DeleteAccountOauthClient(ctx, db.DeleteAccountOauthClientParams{
+		ClientID:  ctx.Param("client_id"),
+		AccountID: accountID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if deleted == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// authorizeHandler starts an authorization code grant for the signed in user, responding with the consent
+// screen, or with its details as JSON for clients rendering their own. Until the client and redirect uri
+// are known to be registered errors are responded with, after which the user is sent back to the app with
+// them.
+func authorizeHandler(ctx *gin.Context) {
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+	if !claims.IsFirstParty() {
+		ctx.JSON(http.StatusForbidden, errorResponse(errFirstPartyOnly))
+		return
+	}
+
+	var req authorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	client, err := firstly.store.GetOauthClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidOAuthClient))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	redirectURIs := strings.Fields(client.RedirectUris)
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(redirectURIs) == 1 {
+		redirectURI = redirectURIs[0]
+	}
+	if !hasField(redirectURIs, redirectURI) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidRedirectURI))
+		return
+	}
+
+	if req.ResponseType != "code" {
+		redirectWithOAuthError(ctx, redirectURI, req.State, oauthUnsupportedResponseType, "response_type must be code")
+		return
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		redirectWithOAuthError(ctx, redirectURI, req.State, oauthInvalidRequest, "an S256 code_challenge is required")
+		return
+	}
+
+	// apps are given the scopes they ask for, or all of their scopes when they don't ask, as long as they
+	// were registered with them and the user's role allows them
+	requested := strings.Fields(req.Scope)
+	if len(requested) == 0 {
+		requested = strings.Fields(client.Scopes)
+	}
+	for _, scope := range requested {
+		if !hasField(strings.Fields(client.Scopes), scope) {
+			redirectWithOAuthError(ctx, redirectURI, req.State, oauthInvalidScope, fmt.Sprintf("the app can't ask for the %s scope", scope))
+			return
+		}
+	}
+	scope := security.LimitScopes(strings.Join(requested, " "), claims.Role)
+	if scope == "" {
+		redirectWithOAuthError(ctx, redirectURI, req.State, oauthInvalidScope, "the account's role doesn't allow any of the scopes")
+		return
+	}
+
+	ticket, err := security.NewOAuthTicket()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	err = firstly.store.CreateOauthAuthorization(ctx, db.CreateOauthAuthorizationParams{
+		Ticket:        ticket,
+		ClientID:      client.ClientID,
+		AccountID:     accountID,
+		RedirectUri:   redirectURI,
+		Scope:         scope,
+		State:         req.State,
+		CodeChallenge: req.CodeChallenge,
+		Expires:       time.Now().Add(oauthTicketTTL),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := consentResponse{
+		Ticket:      ticket,
+		ClientID:    client.ClientID,
+		ClientName:  client.Name,
+		RedirectURI: redirectURI,
+		Scopes:      strings.Fields(scope),
+	}
+	if ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEJSON {
+		ctx.JSON(http.StatusOK, response)
+		return
+	}
+
+	descriptions := make([]string, 0, len(response.Scopes))
+	for _, s := range response.Scopes {
+		descriptions = append(descriptions, scopeDescriptions[s])
+	}
+	// the consent screen mustn't be framed by another site, which could trick the user into allowing access
+	ctx.Header("X-Frame-Options", "DENY")
+	ctx.Header("Content-Security-Policy", "frame-ancestors 'none'")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(http.StatusOK)
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	consentPage.Execute(ctx.Writer, gin.H{
+		"ClientName":  client.Name,
+		"Username":    claims.Username,
+		"Scopes":      descriptions,
+		"RedirectURI": redirectURI,
+		"Ticket":      ticket,
+	})
+}
+
+// consentHandler allows or denies the authorization of a consent ticket, sending the user back to the app
+// with an authorization code or an access_denied error. Tickets can only be used by the account they were
+// issued to, so another site can't give consent on the user's behalf.
+func consentHandler(ctx *gin.Context) {
+	var req consentRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+	if !claims.IsFirstParty() {
+		ctx.JSON(http.StatusForbidden, errorResponse(errFirstPartyOnly))
+		return
+	}
+
+	if !req.Approve {
+		denied, err := firstly.store.DenyOauthAuthorization(ctx, db.DenyOauthAuthorizationParams{Ticket: req.Ticket, AccountID: accountID})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidOAuthTicket))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		redirectWithOAuthError(ctx, denied.RedirectUri, denied.State, oauthAccessDenied, "the user denied access")
+		return
+	}
+
+	code, hash, err := security.NewOAuthCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	approved, err := firstly.store.ApproveOauthAuthorization(ctx, db.ApproveOauthAuthorizationParams{
+		CodeHash:  hash,
+		Expires:   time.Now().Add(oauthCodeTTL),
+		Ticket:    req.Ticket,
+		AccountID: accountID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidOAuthTicket))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	redirectWithParams(ctx, approved.RedirectUri, url.Values{"code": {code}, "state": {approved.State}})
+}
+
+// authenticateOAuthClient authenticates the app making a request with HTTP basic authentication or with
+// client_id and client_secret form parameters. Public apps only send their client id, and are refused when
+// requireSecret is set. An invalid_client error has been responded with when false is returned.
+func authenticateOAuthClient(ctx *gin.Context, requireSecret bool) (db.OauthClient, bool) {
+	clientID, secret, basic := ctx.Request.BasicAuth()
+	if basic {
+		// credentials in the header are form encoded, as described in RFC 6749 section 2.3.1
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+	refuse := func() (db.OauthClient, bool) {
+		if basic {
+			ctx.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", bearerRealm))
+		}
+		oauthError(ctx, http.StatusUnauthorized, oauthInvalidClient, errInvalidOAuthClient.Error())
+		return db.OauthClient{}, false
+	}
+
+	client, err := firstly.store.GetOauthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return refuse()
+		}
+		oauthServerError(ctx, err)
+		return db.OauthClient{}, false
+	}
+	if len(client.SecretHash) == 0 {
+		if requireSecret || secret != "" {
+			return refuse()
+		}
+		return client, true
+	}
+	if subtle.ConstantTimeCompare(client.SecretHash, security.HashOAuthClientSecret(secret)) != 1 {
+		return refuse()
+	}
+	return client, true
+}
+
+// oauthTokenHandler exchanges an authorization code, or a refresh token, for an access token and a new
+// refresh token, as described in RFC 6749 sections 4.1.3 and 6.
+func oauthTokenHandler(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	client, ok := authenticateOAuthClient(ctx, false)
+	if !ok {
+		return
+	}
+
+	switch ctx.PostForm("grant_type") {
+	case "authorization_code":
+		exchangeOAuthCode(ctx, client)
+	case "refresh_token":
+		refreshOAuthToken(ctx, client)
+	default:
+		oauthError(ctx, http.StatusBadRequest, oauthUnsupportedGrantType, "grant_type must be authorization_code or refresh_token")
+	}
+}
+
+func exchangeOAuthCode(ctx *gin.Context, client db.OauthClient) {
+	// codes are deleted as they are used, so each can only be exchanged once
+	grant, err := firstly.store.ConsumeOauthCode(ctx, security.HashOAuthCode(ctx.PostForm("code")))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, "code is invalid or has expired")
+			return
+		}
+		oauthServerError(ctx, err)
+		return
+	}
+	redirectURI := ctx.PostForm("redirect_uri")
+	if grant.ClientID != client.ClientID || (redirectURI != "" && redirectURI != grant.RedirectUri) {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, "code was issued to another client or redirect_uri")
+		return
+	}
+	if !security.VerifyCodeChallenge(ctx.PostForm("code_verifier"), grant.CodeChallenge) {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, "code_verifier doesn't match the code_challenge")
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, grant.AccountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, "the account no longer exists")
+			return
+		}
+		oauthServerError(ctx, err)
+		return
+	}
+	issueOAuthTokens(ctx, client.ClientID, account.ID, account.Username, account.Role, grant.Scope)
+}
+
+// refreshOAuthToken rotates a refresh token. A refresh token that was already rotated being used again
+// means it has leaked, so every token issued to the app for the account is revoked.
+func refreshOAuthToken(ctx *gin.Context, client db.OauthClient) {
+	token, err := firstly.store.GetOauthRefreshToken(ctx, security.HashRefreshToken(ctx.PostForm("refresh_token")))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, errInvalidRefreshToken.Error())
+			return
+		}
+		oauthServerError(ctx, err)
+		return
+	}
+	if token.ClientID != client.ClientID || !token.Expires.After(time.Now()) {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, errInvalidRefreshToken.Error())
+		return
+	}
+
+	rotated := int64(0)
+	if !token.Revoked {
+		if rotated, err = firstly.store.RevokeOauthRefreshToken(ctx, token.ID); err != nil {
+			oauthServerError(ctx, err)
+			return
+		}
+	}
+	if rotated == 0 {
+		if err := revokeOAuthGrant(ctx, token.ClientID, token.AccountID); err != nil {
+			oauthServerError(ctx, err)
+			return
+		}
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, errInvalidRefreshToken.Error())
+		return
+	}
+
+	// an app can narrow the scope of the new access token, but not widen it
+	scope := token.Scope
+	if requested := strings.Fields(ctx.PostForm("scope")); len(requested) > 0 {
+		for _, s := range requested {
+			if !hasField(strings.Fields(token.Scope), s) {
+				oauthError(ctx, http.StatusBadRequest, oauthInvalidScope, fmt.Sprintf("the %s scope wasn't granted", s))
+				return
+			}
+		}
+		scope = strings.Join(requested, " ")
+	}
+	issueOAuthTokens(ctx, client.ClientID, token.AccountID, token.Username, token.Role, scope)
+}
+
+// issueOAuthTokens responds with an access token for the app limited to scope, along with a refresh token
+// for it. The access token is an ordinary access token with the client id added, so claimsMiddleware
+// accepts it for the routes its scopes allow.
+func issueOAuthTokens(ctx *gin.Context, clientID string, accountID int64, username string, role string, scope string) {
+	// the app can't do more than the account's current role allows, whatever it was granted
+	limited := security.LimitScopes(scope, role)
+	if limited == "" {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, "the account's role no longer allows any of the granted scopes")
+		return
+	}
+	claims, err := security.NewAccessClaims(accountID, username, role)
+	if err != nil {
+		oauthServerError(ctx, err)
+		return
+	}
+	claims.Scope = limited
+	claims.ClientID = clientID
+	accessToken, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(claims)
+	if err != nil {
+		oauthServerError(ctx, err)
+		return
+	}
+
+	refreshToken, hash, err := security.NewRefreshToken()
+	if err != nil {
+		oauthServerError(ctx, err)
+		return
+	}
+	err = firstly.store.CreateOauthRefreshToken(ctx, db.CreateOauthRefreshTokenParams{
+		TokenHash:     hash,
+		ClientID:      clientID,
+		AccountID:     accountID,
+		Scope:         scope,
+		AccessJti:     claims.Id,
+		AccessExpires: expirationTime,
+		Expires:       time.Now().Add(refreshTokenTTL()),
+	})
+	if err != nil {
+		oauthServerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, oauthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(expirationTime).Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        limited,
+	})
+}
+
+// revokeOAuthGrant revokes every refresh token issued to the app for the account and the access tokens
+// issued with them.
+func revokeOAuthGrant(ctx *gin.Context, clientID string, accountID int64) error {
+	revoked, err := firstly.store.RevokeOauthGrant(ctx, db.RevokeOauthGrantParams{ClientID: clientID, AccountID: accountID})
+	if err != nil {
+		return err
+	}
+	for _, token := range revoked {
+		if err := revokeAccessToken(ctx, token.AccessJti, token.AccessExpires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// oauthAccessClaims returns the claims of token when it is a valid access token issued to the client.
+func oauthAccessClaims(token string, clientID string) (*security.UsernameClaims, bool) {
+	if strings.Count(token, ".") != 2 {
+		return nil, false
+	}
+	claimToken, claims, err := firstly.claimer.GetFromTokenString(token)
+	if err != nil || !claimToken.Valid || claims.IsMFAPending() || claims.ClientID != clientID {
+		return nil, false
+	}
+	return claims, true
+}
+
+// introspectHandler describes a token issued to the authenticated app, as described in RFC 7662. Tokens
+// issued to other apps, or to no app, are described as inactive.
+func introspectHandler(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	client, ok := authenticateOAuthClient(ctx, true)
+	if !ok {
+		return
+	}
+	token := ctx.PostForm("token")
+	if token == "" {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, "token is required")
+		return
+	}
+
+	if claims, ok := oauthAccessClaims(token, client.ClientID); ok {
+		revoked, err := firstly.store.IsTokenRevoked(ctx, claims.Id)
+		if err != nil {
+			oauthServerError(ctx, err)
+			return
+		}
+		if revoked {
+			ctx.JSON(http.StatusOK, introspectionResponse{})
+			return
+		}
+		ctx.JSON(http.StatusOK, introspectionResponse{
+			Active:    true,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			Username:  claims.Username,
+			TokenType: "Bearer",
+			Expires:   claims.ExpiresAt,
+			IssuedAt:  claims.IssuedAt,
+			Subject:   claims.Subject,
+			JTI:       claims.Id,
+		})
+		return
+	}
+
+	refresh, err := firstly.store.GetOauthRefreshToken(ctx, security.HashRefreshToken(token))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		oauthServerError(ctx, err)
+		return
+	}
+	if err != nil || refresh.ClientID != client.ClientID || refresh.Revoked || !refresh.Expires.After(time.Now()) {
+		ctx.JSON(http.StatusOK, introspectionResponse{})
+		return
+	}
+	ctx.JSON(http.StatusOK, introspectionResponse{
+		Active:    true,
+		Scope:     security.LimitScopes(refresh.Scope, refresh.Role),
+		ClientID:  refresh.ClientID,
+		Username:  refresh.Username,
+		TokenType: "refresh_token",
+		Expires:   refresh.Expires.Unix(),
+		IssuedAt:  refresh.Created.Unix(),
+		Subject:   strconv.FormatInt(refresh.AccountID, 10),
+	})
+}
+
+// revokeHandler revokes an access or refresh token issued to the authenticated app, as described in RFC
+// 7009. Revoking a refresh token revokes the access token issued with it too. Unknown tokens are ignored,
+// so the response doesn't tell whether the token was valid.
+func revokeHandler(ctx *gin.Context) {
+	client, ok := authenticateOAuthClient(ctx, false)
+	if !ok {
+		return
+	}
+	token := ctx.PostForm("token")
+	if token == "" {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, "token is required")
+		return
+	}
+
+	if claims, ok := oauthAccessClaims(token, client.ClientID); ok {
+		if err := revokeAccessToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+			oauthServerError(ctx, err)
+			return
+		}
+		ctx.Status(http.StatusOK)
+		return
+	}
+
+	refresh, err := firstly.store.GetOauthRefreshToken(ctx, security.HashRefreshToken(token))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		oauthServerError(ctx, err)
+		return
+	}
+	if err == nil && refresh.ClientID == client.ClientID {
+		if _, err := firstly.store.RevokeOauthRefreshToken(ctx, refresh.ID); err != nil {
+			oauthServerError(ctx, err)
+			return
+		}
+		if err := revokeAccessToken(ctx, refresh.AccessJti, refresh.AccessExpires); err != nil {
+			oauthServerError(ctx, err)
+			return
+		}
+	}
+	ctx.Status(http.StatusOK)
+}
+
+func hasField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
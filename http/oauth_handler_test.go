@@ -0,0 +1,690 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+// passClaimsMiddlewareForApp passes claimsMiddleware with a token issued to the app client id through
+// OAuth, limited to scope.
+func passClaimsMiddlewareForApp(clientID string, scope string, r *http.Request, claimer *security.MockClaimer, store *db.MockStore) {
+	claims := security.NewUsernameClaims()
+	claims.Username = "valid"
+	claims.Role = security.RoleMember
+	claims.Scope = scope
+	claims.ClientID = clientID
+	claims.Subject = "1"
+	claims.Id = "appjti"
+	claimer.EXPECT().GetFromTokenString("apptoken").Return(&security.ClaimToken{Token: &jwt.Token{Valid: true}}, claims, nil)
+	store.EXPECT().IsTokenRevoked(gomock.Any(), "appjti").Return(false, nil)
+	r.Header.Set("Authorization", "Bearer apptoken")
+}
+
+func decodeJSON(t *testing.T, body io.Reader, v interface{}) {
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		t.Fatalf("Error decoding response body: %v", err)
+	}
+}
+
+func TestOAuthHandlers(t *testing.T) {
+	secret, secretHash, err := security.NewOAuthClientSecret()
+	if err != nil {
+		t.Fatalf("Error generating client secret: %v", err)
+	}
+	confidential := db.OauthClient{
+		ID:           3,
+		ClientID:     "printer",
+		AccountID:    2,
+		Name:         "Photo Printer",
+		RedirectUris: "https://printer.example.com/callback",
+		Scopes:       "account:read images:read",
+		SecretHash:   secretHash,
+	}
+	public := db.OauthClient{
+		ID:           4,
+		ClientID:     "mobile",
+		AccountID:    2,
+		Name:         "Mobile",
+		RedirectUris: "http://localhost:8080/callback com.example.mobile://callback",
+		Scopes:       "images:read images:write",
+		SecretHash:   []byte{},
+	}
+	// the example from RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	form := func(values url.Values) string { return values.Encode() }
+
+	expectClient := func(store *db.MockStore, client db.OauthClient) {
+		store.EXPECT().GetOauthClient(gomock.Any(), client.ClientID).Return(client, nil)
+	}
+	// expectTokens expects an access token for the client limited to scope, and a refresh token for it
+	expectTokens := func(claimer *security.MockClaimer, store *db.MockStore, clientID string, scope string) {
+		claimer.EXPECT().GetFiveMinuteExpirationToken(gomock.Any()).
+			DoAndReturn(func(claims *security.UsernameClaims) (string, time.Time, error) {
+				assert.Equal(t, clientID, claims.ClientID)
+				assert.Equal(t, scope, claims.Scope)
+				assert.Equal(t, "1", claims.Subject)
+				return "accesstoken", time.Now().Add(5 * time.Minute), nil
+			})
+		store.EXPECT().CreateOauthRefreshToken(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, arg db.CreateOauthRefreshTokenParams) error {
+				assert.Equal(t, clientID, arg.ClientID)
+				assert.Equal(t, int64(1), arg.AccountID)
+				assert.Equal(t, true, arg.Expires.After(time.Now()))
+				return nil
+			})
+	}
+	assertOAuthError := func(code string) func(t *testing.T, result *http.Response) {
+		return func(t *testing.T, result *http.Response) {
+			var response map[string]string
+			decodeJSON(t, result.Body, &response)
+			assert.Equal(t, code, response["error"])
+		}
+	}
+	assertRedirect := func(params url.Values) func(t *testing.T, result *http.Response) {
+		return func(t *testing.T, result *http.Response) {
+			location, err := url.Parse(result.Header.Get("Location"))
+			if err != nil {
+				t.Fatalf("Error parsing redirect: %v", err)
+			}
+			assert.Equal(t, "printer.example.com", location.Host)
+			for name := range params {
+				assert.Equal(t, params.Get(name), location.Query().Get(name))
+			}
+		}
+	}
+
+	tests := []struct {
+		name              string
+		method            string
+		route             string
+		body              string
+		form              bool
+		accept            string
+		responseCode      int
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+		assertResponse    func(t *testing.T, result *http.Response)
+	}{
+		{
+			name:         "create oauth client handler registers an app and responds with its secret once",
+			method:       http.MethodPost,
+			route:        "/oauth/clients",
+			body:         `{"name":"Photo Printer","redirect_uris":["https://printer.example.com/callback"],"scopes":["account:read","images:read"]}`,
+			responseCode: http.StatusCreated,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().CreateOauthClient(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.CreateOauthClientParams) (db.OauthClient, error) {
+						assert.Equal(t, int64(1), arg.AccountID)
+						assert.Equal(t, "https://printer.example.com/callback", arg.RedirectUris)
+						assert.Equal(t, "account:read images:read", arg.Scopes)
+						return db.OauthClient{ClientID: arg.ClientID, Name: arg.Name, RedirectUris: arg.RedirectUris, Scopes: arg.Scopes, SecretHash: arg.SecretHash}, nil
+					})
+			},
+			assertResponse: func(t *testing.T, result *http.Response) {
+				var response oauthClientResponse
+				decodeJSON(t, result.Body, &response)
+				assert.NotEqual(t, "", response.ClientID)
+				assert.NotEqual(t, "", response.ClientSecret)
+				assert.Equal(t, false, response.Public)
+			},
+		},
+		{
+			name:         "create oauth client handler given a scope apps can't have responds with status bad request",
+			method:       http.MethodPost,
+			route:        "/oauth/clients",
+			body:         `{"name":"Takeover","redirect_uris":["https://evil.example.com/callback"],"scopes":["account:write"]}`,
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().CreateOauthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "create oauth client handler given an http redirect uri off localhost responds with status bad request",
+			method:       http.MethodPost,
+			route:        "/oauth/clients",
+			body:         `{"name":"Photo Printer","redirect_uris":["http://printer.example.com/callback"],"scopes":["images:read"]}`,
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().CreateOauthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "delete oauth client handler given another account's app responds with status not found",
+			method:       http.MethodDelete,
+			route:        "/oauth/clients/printer",
+			responseCode: http.StatusNotFound,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().DeleteAccountOauthClient(gomock.Any(), db.DeleteAccountOauthClientParams{ClientID: "printer", AccountID: 1}).Return(int64(0), nil)
+			},
+		},
+		{
+			name:         "authorize handler responds with a consent ticket for the scopes the app asked for",
+			method:       http.MethodGet,
+			route:        "/oauth/authorize?response_type=code&client_id=printer&scope=images:read&state=xyz&code_challenge=" + challenge + "&code_challenge_method=S256",
+			accept:       gin.MIMEJSON,
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				expectClient(store, confidential)
+				store.EXPECT().CreateOauthAuthorization(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.CreateOauthAuthorizationParams) error {
+						assert.Equal(t, int64(1), arg.AccountID)
+						assert.Equal(t, "https://printer.example.com/callback", arg.RedirectUri)
+						assert.Equal(t, "images:read", arg.Scope)
+						assert.Equal(t, "xyz", arg.State)
+						assert.Equal(t, challenge, arg.CodeChallenge)
+						return nil
+					})
+			},
+			assertResponse: func(t *testing.T, result *http.Response) {
+				var response consentResponse
+				decodeJSON(t, result.Body, &response)
+				assert.NotEqual(t, "", response.Ticket)
+				assert.Equal(t, "Photo Printer", response.ClientName)
+				assert.Equal(t, []string{"images:read"}, response.Scopes)
+			},
+		},
+		{
+			name:         "authorize handler renders a consent screen that can't be framed",
+			method:       http.MethodGet,
+			route:        "/oauth/authorize?response_type=code&client_id=printer&code_challenge=" + challenge + "&code_challenge_method=S256",
+			accept:       "text/html,application/xhtml+xml",
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				expectClient(store, confidential)
+				store.EXPECT().CreateOauthAuthorization(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.CreateOauthAuthorizationParams) error {
+						assert.Equal(t, "account:read images:read", arg.Scope)
+						return nil
+					})
+			},
+			assertResponse: func(t *testing.T, result *http.Response) {
+				body, _ := io.ReadAll(result.Body)
+				assert.Equal(t, "DENY", result.Header.Get("X-Frame-Options"))
+				assert.Equal(t, true, strings.Contains(string(body), "Allow Photo Printer access to your account?"))
+				assert.Equal(t, true, strings.Contains(string(body), `name="ticket"`))
+			},
+		},
+		{
+			name:         "authorize handler given an unregistered redirect uri responds with status bad request instead of redirecting",
+			method:       http.MethodGet,
+			route:        "/oauth/authorize?response_type=code&client_id=printer&redirect_uri=https://evil.example.com/callback&code_challenge=" + challenge + "&code_challenge_method=S256",
+			accept:       gin.MIMEHTML,
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				expectClient(store, confidential)
+				store.EXPECT().CreateOauthAuthorization(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "authorize handler given an unknown client responds with status bad request",
+			method:       http.MethodGet,
+			route:        "/oauth/authorize?response_type=code&client_id=unknown",
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetOauthClient(gomock.Any(), "unknown").Return(db.OauthClient{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:         "authorize handler without a code challenge sends the user back with invalid_request",
+			method:       http.MethodGet,
+			route:        "/oauth/authorize?response_type=code&client_id=printer&state=xyz",
+			accept:       gin.MIMEHTML,
+			responseCode: http.StatusFound,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				expectClient(store, confidential)
+				store.EXPECT().CreateOauthAuthorization(gomock.Any(), gomock.Any()).Times(0)
+			},
+			assertResponse: assertRedirect(url.Values{"error": {"invalid_request"}, "state": {"xyz"}}),
+		},
+		{
+			name:         "authorize handler given a scope the app wasn't registered with sends the user back with invalid_scope",
+			method:       http.MethodGet,
+			route:        "/oauth/authorize?response_type=code&client_id=printer&scope=images:write&code_challenge=" + challenge + "&code_challenge_method=S256",
+			accept:       gin.MIMEHTML,
+			responseCode: http.StatusFound,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				expectClient(store, confidential)
+			},
+			assertResponse: assertRedirect(url.Values{"error": {"invalid_scope"}}),
+		},
+		{
+			name:         "authorize handler given a token issued to an app responds with status forbidden",
+			method:       http.MethodGet,
+			route:        "/oauth/authorize?response_type=code&client_id=printer&code_challenge=" + challenge + "&code_challenge_method=S256",
+			responseCode: http.StatusForbidden,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareForApp("printer", "account:read images:read", r, claimer, store)
+				store.EXPECT().GetOauthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "consent handler given approval sends the user back with a code and the state",
+			method:       http.MethodPost,
+			route:        "/oauth/authorize",
+			body:         form(url.Values{"ticket": {"ticket"}, "approve": {"true"}}),
+			form:         true,
+			accept:       gin.MIMEHTML,
+			responseCode: http.StatusFound,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ApproveOauthAuthorization(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.ApproveOauthAuthorizationParams) (db.ApproveOauthAuthorizationRow, error) {
+						assert.Equal(t, "ticket", arg.Ticket)
+						assert.Equal(t, int64(1), arg.AccountID)
+						assert.Equal(t, true, arg.Expires.Before(time.Now().Add(oauthCodeTTL+time.Second)))
+						return db.ApproveOauthAuthorizationRow{RedirectUri: "https://printer.example.com/callback", State: "xyz"}, nil
+					})
+			},
+			assertResponse: func(t *testing.T, result *http.Response) {
+				assertRedirect(url.Values{"state": {"xyz"}})(t, result)
+				location, _ := url.Parse(result.Header.Get("Location"))
+				assert.NotEqual(t, "", location.Query().Get("code"))
+			},
+		},
+		{
+			name:         "consent handler given a denial sends the user back with access_denied",
+			method:       http.MethodPost,
+			route:        "/oauth/authorize",
+			body:         form(url.Values{"ticket": {"ticket"}, "approve": {"false"}}),
+			form:         true,
+			accept:       gin.MIMEHTML,
+			responseCode: http.StatusFound,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().DenyOauthAuthorization(gomock.Any(), db.DenyOauthAuthorizationParams{Ticket: "ticket", AccountID: 1}).
+					Return(db.DenyOauthAuthorizationRow{RedirectUri: "https://printer.example.com/callback", State: "xyz"}, nil)
+				store.EXPECT().ApproveOauthAuthorization(gomock.Any(), gomock.Any()).Times(0)
+			},
+			assertResponse: assertRedirect(url.Values{"error": {"access_denied"}, "state": {"xyz"}}),
+		},
+		{
+			name:         "consent handler given another account's ticket responds with status bad request",
+			method:       http.MethodPost,
+			route:        "/oauth/authorize",
+			body:         `{"ticket":"ticket","approve":true}`,
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ApproveOauthAuthorization(gomock.Any(), gomock.Any()).Return(db.ApproveOauthAuthorizationRow{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:   "token handler exchanges a code and its verifier for tokens",
+			method: http.MethodPost,
+			route:  "/oauth/token",
+			body: form(url.Values{"grant_type": {"authorization_code"}, "code": {"code"}, "code_verifier": {verifier},
+				"redirect_uri": {"https://printer.example.com/callback"}, "client_id": {"printer"}, "client_secret": {secret}}),
+			form:         true,
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, confidential)
+				store.EXPECT().ConsumeOauthCode(gomock.Any(), security.HashOAuthCode("code")).Return(db.ConsumeOauthCodeRow{
+					ClientID:      "printer",
+					AccountID:     1,
+					RedirectUri:   "https://printer.example.com/callback",
+					Scope:         "account:read images:read",
+					CodeChallenge: challenge,
+				}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Username: "valid", Role: security.RoleReadOnly}, nil)
+				expectTokens(claimer, store, "printer", "account:read images:read")
+			},
+			assertResponse: func(t *testing.T, result *http.Response) {
+				var response oauthTokenResponse
+				decodeJSON(t, result.Body, &response)
+				assert.Equal(t, "accesstoken", response.AccessToken)
+				assert.Equal(t, "Bearer", response.TokenType)
+				assert.NotEqual(t, "", response.RefreshToken)
+				assert.Equal(t, "no-store", result.Header.Get("Cache-Control"))
+			},
+		},
+		{
+			name:   "token handler given a store error responds with a server_error that doesn't describe it",
+			method: http.MethodPost,
+			route:  "/oauth/token",
+			body: form(url.Values{"grant_type": {"authorization_code"}, "code": {"code"}, "code_verifier": {verifier},
+				"client_id": {"printer"}, "client_secret": {secret}}),
+			form:         true,
+			responseCode: http.StatusInternalServerError,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, confidential)
+				store.EXPECT().ConsumeOauthCode(gomock.Any(), gomock.Any()).Return(db.ConsumeOauthCodeRow{}, errors.New("pq: relation \"oauth_authorization\" does not exist"))
+			},
+			assertResponse: func(t *testing.T, result *http.Response) {
+				var response map[string]string
+				decodeJSON(t, result.Body, &response)
+				assert.Equal(t, "server_error", response["error"])
+				assert.Equal(t, false, strings.Contains(response["error_description"], "oauth_authorization"))
+			},
+		},
+		{
+			name:         "token handler given the wrong code verifier responds with invalid_grant",
+			method:       http.MethodPost,
+			route:        "/oauth/token",
+			body:         form(url.Values{"grant_type": {"authorization_code"}, "code": {"code"}, "code_verifier": {strings.Repeat("v", 43)}, "client_id": {"mobile"}}),
+			form:         true,
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, public)
+				store.EXPECT().ConsumeOauthCode(gomock.Any(), gomock.Any()).Return(db.ConsumeOauthCodeRow{
+					ClientID: "mobile", AccountID: 1, RedirectUri: "http://localhost:8080/callback", Scope: "images:read", CodeChallenge: challenge,
+				}, nil)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(gomock.Any()).Times(0)
+			},
+			assertResponse: assertOAuthError(oauthInvalidGrant),
+		},
+		{
+			name:         "token handler given a code issued to another app responds with invalid_grant",
+			method:       http.MethodPost,
+			route:        "/oauth/token",
+			body:         form(url.Values{"grant_type": {"authorization_code"}, "code": {"code"}, "code_verifier": {verifier}, "client_id": {"mobile"}}),
+			form:         true,
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, public)
+				store.EXPECT().ConsumeOauthCode(gomock.Any(), gomock.Any()).Return(db.ConsumeOauthCodeRow{
+					ClientID: "printer", AccountID: 1, RedirectUri: "https://printer.example.com/callback", Scope: "images:read", CodeChallenge: challenge,
+				}, nil)
+			},
+			assertResponse: assertOAuthError(oauthInvalidGrant),
+		},
+		{
+			name:         "token handler given the wrong client secret responds with invalid_client",
+			method:       http.MethodPost,
+			route:        "/oauth/token",
+			body:         form(url.Values{"grant_type": {"authorization_code"}, "code": {"code"}, "client_id": {"printer"}, "client_secret": {"guessed"}}),
+			form:         true,
+			responseCode: http.StatusUnauthorized,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, confidential)
+				store.EXPECT().ConsumeOauthCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			assertResponse: assertOAuthError(oauthInvalidClient),
+		},
+		{
+			name:         "token handler rotates a refresh token authenticated with basic auth",
+			method:       http.MethodPost,
+			route:        "/oauth/token",
+			body:         form(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"refresh"}, "scope": {"images:read"}}),
+			form:         true,
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				r.SetBasicAuth("printer", secret)
+				expectClient(store, confidential)
+				store.EXPECT().GetOauthRefreshToken(gomock.Any(), security.HashRefreshToken("refresh")).Return(db.GetOauthRefreshTokenRow{
+					ID: 9, ClientID: "printer", AccountID: 1, Username: "valid", Role: security.RoleMember,
+					Scope: "account:read images:read", Expires: time.Now().Add(time.Hour),
+				}, nil)
+				store.EXPECT().RevokeOauthRefreshToken(gomock.Any(), int64(9)).Return(int64(1), nil)
+				expectTokens(claimer, store, "printer", "images:read")
+			},
+		},
+		{
+			name:         "token handler given a refresh token that was already rotated revokes the grant",
+			method:       http.MethodPost,
+			route:        "/oauth/token",
+			body:         form(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"refresh"}, "client_id": {"mobile"}}),
+			form:         true,
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, public)
+				store.EXPECT().GetOauthRefreshToken(gomock.Any(), gomock.Any()).Return(db.GetOauthRefreshTokenRow{
+					ID: 9, ClientID: "mobile", AccountID: 1, Scope: "images:read", Expires: time.Now().Add(time.Hour), Revoked: true,
+				}, nil)
+				store.EXPECT().RevokeOauthGrant(gomock.Any(), db.RevokeOauthGrantParams{ClientID: "mobile", AccountID: 1}).
+					Return([]db.RevokeOauthGrantRow{{AccessJti: "jti", AccessExpires: time.Now().Add(time.Minute)}}, nil)
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Return(nil)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(gomock.Any()).Times(0)
+			},
+			assertResponse: assertOAuthError(oauthInvalidGrant),
+		},
+		{
+			name:         "introspect handler describes an access token issued to the app",
+			method:       http.MethodPost,
+			route:        "/oauth/introspect",
+			body:         form(url.Values{"token": {"header.payload.signature"}, "client_id": {"printer"}, "client_secret": {secret}}),
+			form:         true,
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, confidential)
+				claims := security.NewUsernameClaims()
+				claims.Username = "valid"
+				claims.Subject = "1"
+				claims.Id = "appjti"
+				claims.Scope = "images:read"
+				claims.ClientID = "printer"
+				claimer.EXPECT().GetFromTokenString("header.payload.signature").Return(&security.ClaimToken{Token: &jwt.Token{Valid: true}}, claims, nil)
+				store.EXPECT().IsTokenRevoked(gomock.Any(), "appjti").Return(false, nil)
+			},
+			assertResponse: func(t *testing.T, result *http.Response) {
+				var response introspectionResponse
+				decodeJSON(t, result.Body, &response)
+				assert.Equal(t, true, response.Active)
+				assert.Equal(t, "images:read", response.Scope)
+				assert.Equal(t, "valid", response.Username)
+			},
+		},
+		{
+			name:         "introspect handler describes a signed in user's token as inactive",
+			method:       http.MethodPost,
+			route:        "/oauth/introspect",
+			body:         form(url.Values{"token": {"header.payload.signature"}, "client_id": {"printer"}, "client_secret": {secret}}),
+			form:         true,
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, confidential)
+				claims, _ := security.NewAccessClaims(1, "valid", security.RoleMember)
+				claimer.EXPECT().GetFromTokenString("header.payload.signature").Return(&security.ClaimToken{Token: &jwt.Token{Valid: true}}, claims, nil)
+				store.EXPECT().GetOauthRefreshToken(gomock.Any(), gomock.Any()).Return(db.GetOauthRefreshTokenRow{}, sql.ErrNoRows)
+			},
+			assertResponse: func(t *testing.T, result *http.Response) {
+				body, _ := io.ReadAll(result.Body)
+				assert.Equal(t, `{"active":false}`, string(body))
+			},
+		},
+		{
+			name:         "introspect handler given a public app responds with invalid_client",
+			method:       http.MethodPost,
+			route:        "/oauth/introspect",
+			body:         form(url.Values{"token": {"refresh"}, "client_id": {"mobile"}}),
+			form:         true,
+			responseCode: http.StatusUnauthorized,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, public)
+			},
+			assertResponse: assertOAuthError(oauthInvalidClient),
+		},
+		{
+			name:         "revoke handler revokes a refresh token and the access token issued with it",
+			method:       http.MethodPost,
+			route:        "/oauth/revoke",
+			body:         form(url.Values{"token": {"refresh"}, "token_type_hint": {"refresh_token"}, "client_id": {"mobile"}}),
+			form:         true,
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, public)
+				store.EXPECT().GetOauthRefreshToken(gomock.Any(), security.HashRefreshToken("refresh")).Return(db.GetOauthRefreshTokenRow{
+					ID: 9, ClientID: "mobile", AccountID: 1, AccessJti: "appjti", AccessExpires: time.Now().Add(time.Minute), Expires: time.Now().Add(time.Hour),
+				}, nil)
+				store.EXPECT().RevokeOauthRefreshToken(gomock.Any(), int64(9)).Return(int64(1), nil)
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.RevokeTokenParams) error {
+						assert.Equal(t, "appjti", arg.Jti)
+						return nil
+					})
+			},
+		},
+		{
+			name:         "revoke handler given another app's token leaves it alone",
+			method:       http.MethodPost,
+			route:        "/oauth/revoke",
+			body:         form(url.Values{"token": {"refresh"}, "client_id": {"mobile"}}),
+			form:         true,
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				expectClient(store, public)
+				store.EXPECT().GetOauthRefreshToken(gomock.Any(), gomock.Any()).Return(db.GetOauthRefreshTokenRow{ID: 9, ClientID: "printer"}, nil)
+				store.EXPECT().RevokeOauthRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "claims middleware accepts a token issued to an app for the routes its scopes allow",
+			method:       http.MethodGet,
+			route:        "/oauth/clients",
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareForApp("printer", "account:read images:read", r, claimer, store)
+				store.EXPECT().ListAccountOauthClients(gomock.Any(), int64(1)).Return(nil, nil)
+			},
+		},
+		{
+			name:         "claims middleware refuses a token issued to an app for routes outside its scopes",
+			method:       http.MethodDelete,
+			route:        "/account/me/sessions/1",
+			responseCode: http.StatusForbidden,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareForApp("printer", "account:read images:read", r, claimer, store)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(test.method, test.route, strings.NewReader(test.body))
+			if test.form {
+				request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else if test.body != "" {
+				request.Header.Set("Content-Type", gin.MIMEJSON)
+			}
+			if test.accept != "" {
+				request.Header.Set("Accept", test.accept)
+			}
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+			if test.assertResponse != nil {
+				test.assertResponse(t, result)
+			}
+		})
+	}
+}
+
+// TestOAuthWithRealClaimer exchanges a code for an access token with the real claimer, so that the scope
+// and client id the app's token is limited to are those that survive signing and parsing.
+func TestOAuthWithRealClaimer(t *testing.T) {
+	t.Setenv("SECRET_KEYS", "")
+	t.Setenv("SECRET", "test")
+	secret, secretHash, err := security.NewOAuthClientSecret()
+	if err != nil {
+		t.Fatalf("Error generating client secret: %v", err)
+	}
+	printer := db.OauthClient{ID: 3, ClientID: "printer", AccountID: 2, RedirectUris: "https://printer.example.com/callback", Scopes: "account:read", SecretHash: secretHash}
+	other := db.OauthClient{ID: 5, ClientID: "other", AccountID: 2, RedirectUris: "https://other.example.com/callback", Scopes: "account:read", SecretHash: secretHash}
+
+	router := gin.Default()
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	store := db.NewMockStore(ctrl)
+	NewFirstlyServer(security.NewClaimsValidator(), security.NewMockHasher(ctrl), router, store)
+	serve := func(method string, route string, values url.Values, token string) *http.Response {
+		request := httptest.NewRequest(method, route, strings.NewReader(values.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		return responseRecorder.Result()
+	}
+
+	// the app is granted only account:read
+	store.EXPECT().GetOauthClient(gomock.Any(), "printer").Return(printer, nil)
+	store.EXPECT().ConsumeOauthCode(gomock.Any(), security.HashOAuthCode("code")).Return(db.ConsumeOauthCodeRow{
+		ClientID:      "printer",
+		AccountID:     1,
+		RedirectUri:   "https://printer.example.com/callback",
+		Scope:         security.ScopeAccountRead,
+		CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+	}, nil)
+	store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Username: "valid", Role: security.RoleAdmin}, nil)
+	store.EXPECT().CreateOauthRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+	result := serve(http.MethodPost, "/oauth/token", url.Values{"grant_type": {"authorization_code"}, "code": {"code"},
+		"code_verifier": {"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"}, "client_id": {"printer"}, "client_secret": {secret}}, "")
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	var tokens oauthTokenResponse
+	decodeJSON(t, result.Body, &tokens)
+
+	// the token allows the granted scope, but not the others the account's role has
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil)
+	store.EXPECT().ListAccountSessions(gomock.Any(), int64(1)).Return([]db.Session{}, nil)
+	result = serve(http.MethodGet, "/account/me/sessions", nil, tokens.AccessToken)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil)
+	result = serve(http.MethodDelete, "/account/me/sessions/1", nil, tokens.AccessToken)
+	assert.Equal(t, http.StatusForbidden, result.StatusCode)
+
+	// nor the account's admin role, even for a route within the granted scope
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil)
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+	result = serve(http.MethodGet, "/account/", nil, tokens.AccessToken)
+	assert.Equal(t, http.StatusForbidden, result.StatusCode)
+
+	// the app it was issued to can introspect it, another app can't
+	store.EXPECT().GetOauthClient(gomock.Any(), "printer").Return(printer, nil)
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil)
+	result = serve(http.MethodPost, "/oauth/introspect", url.Values{"token": {tokens.AccessToken}, "client_id": {"printer"}, "client_secret": {secret}}, "")
+	var introspection introspectionResponse
+	decodeJSON(t, result.Body, &introspection)
+	assert.Equal(t, true, introspection.Active)
+	assert.Equal(t, "printer", introspection.ClientID)
+	assert.Equal(t, security.ScopeAccountRead, introspection.Scope)
+
+	store.EXPECT().GetOauthClient(gomock.Any(), "other").Return(other, nil)
+	store.EXPECT().GetOauthRefreshToken(gomock.Any(), gomock.Any()).Return(db.GetOauthRefreshTokenRow{}, sql.ErrNoRows)
+	result = serve(http.MethodPost, "/oauth/introspect", url.Values{"token": {tokens.AccessToken}, "client_id": {"other"}, "client_secret": {secret}}, "")
+	introspection = introspectionResponse{}
+	decodeJSON(t, result.Body, &introspection)
+	assert.Equal(t, false, introspection.Active)
+}
@@ -0,0 +1,474 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/oidc"
+)
+
+const (
+	// oidcTimeout is how long the user has to sign in with the provider
+	oidcTimeout = 10 * time.Minute
+
+	// oidcStateCookie holds the hash of the state of the sign in started by the browser
+	oidcStateCookie = "oidc_state"
+
+	// maxOIDCUsernameLength limits the usernames made for accounts signed up with a provider, leaving room
+	// for the number freeUsername appends when one is taken
+	maxOIDCUsernameLength = maxUsernameLength - len("-0000")
+)
+
+var (
+	errInvalidOIDCState  = errors.New("sign in is unknown or has expired, start again")
+	errOIDCStateMismatch = errors.New("sign in was started in another browser, start again")
+	errOIDCCodeRequired  = errors.New("code is required")
+	errIdentityNotLinked = errors.New("identity isn't linked to an account, sign in and link it first")
+	errIdentityLinked    = errors.New("identity is linked to another account")
+
+	usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+)
+
+type oidcProviderResponse struct {
+	Name     string `json:"name"`
+	LoginURL string `json:"login_url"`
+}
+
+// oidcCallbackRequest is the provider's redirect back, either as the query of a GET or posted as JSON by
+// an app that received the redirect itself.
+type oidcCallbackRequest struct {
+	Code             string `form:"code" json:"code"`
+	State            string `form:"state" json:"state" binding:"required"`
+	Error            string `form:"error" json:"error"`
+	ErrorDescription string `form:"error_description" json:"error_description"`
+	// TokenInBody asks for the tokens in the response body, for clients that can't use cookies
+	TokenInBody bool `form:"token_in_body" json:"token_in_body"`
+}
+
+type linkIdentityRequest struct {
+	CurrentPhrase string `json:"current_phrase" binding:"required"`
+}
+
+type authorizationURLResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type identityResponse struct {
+	ID       int64      `json:"id"`
+	Provider string     `json:"provider"`
+	Email    string     `json:"email,omitempty"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+func newIdentityResponse(identity db.AccountIdentity) identityResponse {
+	response := identityResponse{ID: identity.ID, Provider: identity.Provider, Email: identity.Email, Created: identity.Created}
+	if identity.LastUsed.Valid {
+		response.LastUsed = &identity.LastUsed.Time
+	}
+	return response
+}
+
+// loadOIDCProvider loads the provider named by the route, responding with status not found when it isn't
+// configured.
+func loadOIDCProvider(ctx *gin.Context) (oidc.Provider, bool) {
+	provider, err := oidc.LoadProvider(ctx.Param("provider"))
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return provider, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return provider, false
+	}
+	return provider, true
+}
+
+// startOIDC stores the state of a new sign in with the provider, for linking to accountID when it isn't
+// 0, returning the authorization URL to send the user to. The browser is given a cookie with the state's
+// hash, so that the callback only completes the sign in for the browser that started it.
+func startOIDC(ctx *gin.Context, provider oidc.Provider, accountID int64) (string, error) {
+	state, err := oidc.NewRandomValue()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.NewRandomValue()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewRandomValue()
+	if err != nil {
+		return "", err
+	}
+	authURL, err := firstly.oidc.AuthCodeURL(ctx, provider, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+	err = firstly.store.CreateOidcState(ctx, db.CreateOidcStateParams{
+		State:        state,
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		AccountID:    sql.NullInt64{Int64: accountID, Valid: accountID != 0},
+		Expires:      time.Now().Add(oidcTimeout),
+	})
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    oidc.StateHash(state),
+		Path:     "/auth/oidc",
+		MaxAge:   int(oidcTimeout / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return authURL, nil
+}
+
+// checkOIDCStateCookie reports whether the state is the one of the sign in the browser started, clearing
+// the cookie either way.
+func checkOIDCStateCookie(ctx *gin.Context, state string) bool {
+	cookie, err := ctx.Cookie(oidcStateCookie)
+	http.SetCookie(ctx.Writer, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/auth/oidc", MaxAge: -1, HttpOnly: true})
+	return err == nil && subtle.ConstantTimeCompare([]byte(cookie), []byte(oidc.StateHash(state))) == 1
+}
+
+// listOIDCProvidersHandler responds with the providers that can be signed in with.
+func listOIDCProvidersHandler(ctx *gin.Context) {
+	names := oidc.ProviderNames()
+	providers := make([]oidcProviderResponse, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, oidcProviderResponse{Name: name, LoginURL: "/auth/oidc/" + name})
+	}
+	ctx.JSON(http.StatusOK, providers)
+}
+
+// beginOIDCLoginHandler redirects to the provider to sign in there.
+func beginOIDCLoginHandler(ctx *gin.Context) {
+	provider, ok := loadOIDCProvider(ctx)
+	if !ok {
+		return
+	}
+	authURL, err := startOIDC(ctx, provider, 0)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// beginLinkIdentityHandler responds with the URL to sign in with the provider at, to link the identity
+// signed in as to the signed in account. It needs the current phrase so that a stolen token can't be used
+// to add a way into the account.
+func beginLinkIdentityHandler(ctx *gin.Context) {
+	var req linkIdentityRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	provider, ok := loadOIDCProvider(ctx)
+	if !ok {
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	valid, err := firstly.hasher.IsValidPassword(account.Phrase, account.Salt, req.CurrentPhrase)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("current phrase is incorrect")))
+		return
+	}
+
+	authURL, err := startOIDC(ctx, provider, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, authorizationURLResponse{AuthorizationURL: authURL})
+}
+
+// oidcCallbackHandler completes a sign in with the provider, exchanging the code for the id token. The
+// identity is linked to the account that started the sign in when one did, and otherwise signs in to the
+// account it is linked to, or to a new account when the provider allows sign ups.
+func oidcCallbackHandler(ctx *gin.Context) {
+	var req oidcCallbackRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	provider, ok := loadOIDCProvider(ctx)
+	if !ok {
+		return
+	}
+
+	// a state brought back to another browser would sign it in as, or link, the identity of whoever
+	// started the sign in
+	if !checkOIDCStateCookie(ctx, req.State) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errOIDCStateMismatch))
+		return
+	}
+
+	// the state is used up whether or not the sign in succeeds
+	state, err := firstly.store.ConsumeOidcState(ctx, db.ConsumeOidcStateParams{State: req.State, Provider: provider.Name})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidOIDCState))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if req.Error != "" {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(fmt.Errorf("%s didn't sign in: %s %s", provider.Name, req.Error, req.ErrorDescription)))
+		return
+	}
+	if req.Code == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errOIDCCodeRequired))
+		return
+	}
+
+	idToken, err := firstly.oidc.Exchange(ctx, provider, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrExchange) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+	email := ""
+	if idToken.EmailVerified {
+		email = idToken.Email
+	}
+
+	identity, err := firstly.store.GetAccountIdentity(ctx, db.GetAccountIdentityParams{Provider: provider.Name, Subject: idToken.Subject})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	linked := err == nil
+
+	if state.AccountID.Valid {
+		if linked {
+			if identity.AccountID != state.AccountID.Int64 {
+				ctx.JSON(http.StatusConflict, errorResponse(errIdentityLinked))
+				return
+			}
+			ctx.JSON(http.StatusOK, newIdentityResponse(identity))
+			return
+		}
+		identity, err = firstly.store.CreateAccountIdentity(ctx, db.CreateAccountIdentityParams{
+			AccountID: state.AccountID.Int64,
+			Provider:  provider.Name,
+			Subject:   idToken.Subject,
+			Email:     email,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusCreated, newIdentityResponse(identity))
+		return
+	}
+
+	var account db.Account
+	switch {
+	case linked:
+		if err := firstly.store.TouchAccountIdentity(ctx, db.TouchAccountIdentityParams{Email: email, ID: identity.ID}); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		account, err = firstly.store.GetAccount(ctx, identity.AccountID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	case provider.AllowSignup:
+		account, err = signUpWithIdentity(ctx, provider, idToken, email)
+		if errors.Is(err, db.ErrUsernameHeld) || db.IsUniqueViolation(err, db.AccountUsernameIndex) {
+			ctx.JSON(http.StatusConflict, errorResponse(errUsernameTaken))
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	default:
+		ctx.JSON(http.StatusForbidden, errorResponse(errIdentityNotLinked))
+		return
+	}
+
+	// the provider stands in for the phrase, not for two-factor authentication
+	if account.TotpEnabled {
+		startMFA(ctx, account)
+		return
+	}
+	recordLoginAttempt(ctx, account.Username, loginSucceeded)
+
+	completeSignin(ctx, account, req.TokenInBody)
+}
+
+// signUpWithIdentity creates an account linked to the identity, named after it. The account's phrase is
+// random, so until it is reset the account can only be signed in to with the provider. A verified email
+// is given to the account unless another account already has it, since it isn't safe to assume the two
+// are the same person.
+func signUpWithIdentity(ctx *gin.Context, provider oidc.Provider, idToken *oidc.IDToken, email string) (db.Account, error) {
+	username, err := freeUsername(ctx, oidcUsername(idToken))
+	if err != nil {
+		return db.Account{}, err
+	}
+	if email != "" {
+		_, err := firstly.store.GetAccountByEmail(ctx, email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return db.Account{}, err
+		}
+		if err == nil {
+			email = ""
+		}
+	}
+
+	phrase, err := oidc.NewRandomValue()
+	if err != nil {
+		return db.Account{}, err
+	}
+	salt := firstly.hasher.GenerateSalt()
+	hash, err := firstly.hasher.GeneratePasswordHash([]byte(phrase), salt)
+	if err != nil {
+		return db.Account{}, err
+	}
+	return firstly.store.CreateAccountWithIdentity(ctx, db.CreateAccountWithIdentityParams{
+		Username: username,
+		Phrase:   hash,
+		Salt:     salt,
+		Provider: provider.Name,
+		Subject:  idToken.Subject,
+		Email:    email,
+	})
+}
+
+// oidcUsername returns the username an identity would like, from its preferred username or else its
+// email address.
+func oidcUsername(idToken *oidc.IDToken) string {
+	username := idToken.PreferredUsername
+	if username == "" {
+		username = strings.SplitN(idToken.Email, "@", 2)[0]
+	}
+	username = usernameInvalidChars.ReplaceAllString(strings.ToLower(username), "-")
+	if len(username) > maxOIDCUsernameLength {
+		username = username[:maxOIDCUsernameLength]
+	}
+	username = strings.Trim(username, "-._")
+	if len(username) < minUsernameLength {
+		username = "user"
+	}
+	return username
+}
+
+// freeUsername returns username, or when it is taken or held by an account that gave it up username with a
+// random number appended.
+func freeUsername(ctx *gin.Context, username string) (string, error) {
+	candidate := username
+	for i := 0; i < 5; i++ {
+		free, err := usernameFree(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if free {
+			return candidate, nil
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s-%04d", username, n.Int64())
+	}
+	return "", errors.New("couldn't find a free username, try again")
+}
+
+// usernameFree reports whether no account has the username or still holds it after giving it up.
+func usernameFree(ctx *gin.Context, username string) (bool, error) {
+	_, err := firstly.store.GetAccountByUsername(ctx, username)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	_, err = firstly.store.GetUsernameHistory(ctx, username)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	return true, nil
+}
+
+// listIdentitiesHandler responds with the identities linked to the signed in account, newest first.
+func listIdentitiesHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	identities, err := firstly.store.ListAccountIdentities(ctx, accountID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	items := make([]identityResponse, 0, len(identities))
+	for _, identity := range identities {
+		items = append(items, newIdentityResponse(identity))
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.JSON(http.StatusOK, items)
+}
+
+// unlinkIdentityHandler unlinks one of the signed in account's identities, after which it can't sign in.
+func unlinkIdentityHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("id parameter must be a valid integer"))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	deleted, err := firstly.store.DeleteAccountIdentity(ctx, db.DeleteAccountIdentityParams{ID: id, AccountID: accountID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if deleted == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
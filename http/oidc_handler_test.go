@@ -0,0 +1,355 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/oidc"
+	"github.com/meads/firstly-api/security"
+)
+
+// oidcTest is a server and mocks with the provider "mock" configured to be a MockProvider.
+type oidcTest struct {
+	router   *gin.Engine
+	claimer  *security.MockClaimer
+	hasher   *security.MockHasher
+	store    *db.MockStore
+	provider *oidc.MockProvider
+}
+
+func newOIDCTest(t *testing.T, allowSignup bool) *oidcTest {
+	provider, err := oidc.NewMockProvider("firstly", "secret")
+	if err != nil {
+		t.Fatalf("Error starting mock provider: %v", err)
+	}
+	t.Cleanup(provider.Close)
+	provider.Subject = "subject-1"
+	provider.Email = "bob@example.com"
+	provider.EmailVerified = true
+	provider.PreferredUsername = "Bob"
+
+	t.Setenv("OIDC_PROVIDERS", "mock")
+	t.Setenv("OIDC_MOCK_ISSUER", provider.URL)
+	t.Setenv("OIDC_MOCK_CLIENT_ID", "firstly")
+	t.Setenv("OIDC_MOCK_CLIENT_SECRET", "secret")
+	if allowSignup {
+		t.Setenv("OIDC_MOCK_ALLOW_SIGNUP", "true")
+	}
+
+	router := gin.Default()
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	test := &oidcTest{
+		router:   router,
+		claimer:  security.NewMockClaimer(ctrl),
+		hasher:   security.NewMockHasher(ctrl),
+		store:    db.NewMockStore(ctrl),
+		provider: provider,
+	}
+	NewFirstlyServer(test.claimer, test.hasher, router, test.store)
+	return test
+}
+
+func (test *oidcTest) serve(request *http.Request) *http.Response {
+	responseRecorder := httptest.NewRecorder()
+	test.router.ServeHTTP(responseRecorder, request)
+	return responseRecorder.Result()
+}
+
+// authorize signs in at the provider with the authorization URL, returning the callback request the
+// provider redirects back with, carrying the cookies begin set, and expecting the state stored for it to be
+// consumed.
+func (test *oidcTest) authorize(t *testing.T, begin *http.Response, authURL string, state *db.CreateOidcStateParams) *http.Request {
+	callback, err := test.provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Error authorizing at the mock provider: %v", err)
+	}
+	assert.Equal(t, state.State, callback.Query().Get("state"))
+	test.store.EXPECT().ConsumeOidcState(gomock.Any(), db.ConsumeOidcStateParams{State: state.State, Provider: "mock"}).
+		Return(db.ConsumeOidcStateRow{Nonce: state.Nonce, CodeVerifier: state.CodeVerifier, AccountID: state.AccountID}, nil)
+	request := httptest.NewRequest(http.MethodGet, callback.Path+"?"+callback.RawQuery, nil)
+	for _, cookie := range begin.Cookies() {
+		request.AddCookie(cookie)
+	}
+	return request
+}
+
+// beginLogin starts signing in with the provider, returning the callback request.
+func (test *oidcTest) beginLogin(t *testing.T) *http.Request {
+	var state db.CreateOidcStateParams
+	test.store.EXPECT().CreateOidcState(gomock.Any(), gomock.AssignableToTypeOf(db.CreateOidcStateParams{})).
+		DoAndReturn(func(_ interface{}, arg db.CreateOidcStateParams) error {
+			state = arg
+			return nil
+		})
+	result := test.serve(httptest.NewRequest(http.MethodGet, "/auth/oidc/mock", nil))
+	defer result.Body.Close()
+	assert.Equal(t, http.StatusFound, result.StatusCode)
+	assert.Equal(t, false, state.AccountID.Valid)
+	assert.Equal(t, true, hasCookie(result, oidcStateCookie, oidc.StateHash(state.State)))
+	return test.authorize(t, result, result.Header.Get("Location"), &state)
+}
+
+// expectSignin expects the account to be signed in to.
+func (test *oidcTest) expectSignin(account db.Account) {
+	expectLoginAttempt(test.store, account.Username, loginSucceeded)
+	test.claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor(account.Username)).
+		Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+	test.store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+		Return(db.Session{ID: 1, Expires: time.Now().Add(time.Hour)}, nil)
+}
+
+func hasCookie(result *http.Response, name string, value string) bool {
+	for _, cookie := range result.Cookies() {
+		if cookie.Name == name && cookie.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOIDCLogin(t *testing.T) {
+	account := db.Account{ID: 1, Username: "valid", Phrase: []byte("valid"), Salt: "salt", Role: security.RoleMember}
+
+	t.Run("callback given an identity linked to an account signs in to it", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		callback := test.beginLogin(t)
+		test.store.EXPECT().GetAccountIdentity(gomock.Any(), db.GetAccountIdentityParams{Provider: "mock", Subject: "subject-1"}).
+			Return(db.AccountIdentity{ID: 3, AccountID: 1, Provider: "mock", Subject: "subject-1"}, nil)
+		test.store.EXPECT().TouchAccountIdentity(gomock.Any(), db.TouchAccountIdentityParams{Email: "bob@example.com", ID: 3}).Return(nil)
+		test.store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+		test.expectSignin(account)
+
+		result := test.serve(callback)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		assert.Equal(t, true, hasCookie(result, accessTokenCookie, "mocktoken"))
+	})
+
+	t.Run("callback given an identity linked to an account with two-factor authentication asks for a code", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		callback := test.beginLogin(t)
+		mfaAccount := account
+		mfaAccount.TotpEnabled = true
+		test.store.EXPECT().GetAccountIdentity(gomock.Any(), gomock.Any()).
+			Return(db.AccountIdentity{ID: 3, AccountID: 1, Provider: "mock", Subject: "subject-1"}, nil)
+		test.store.EXPECT().TouchAccountIdentity(gomock.Any(), gomock.Any()).Return(nil)
+		test.store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(mfaAccount, nil)
+		test.claimer.EXPECT().GetFiveMinuteExpirationToken(mfaPendingMatcher{username: "valid"}).
+			Return("mfatoken", time.Now().Add(5*time.Minute), nil)
+		test.store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+		result := test.serve(callback)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		var response mfaPendingResponse
+		if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		assert.Equal(t, true, response.MFARequired)
+	})
+
+	t.Run("callback given an unlinked identity responds with status forbidden when sign ups aren't allowed", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		callback := test.beginLogin(t)
+		test.store.EXPECT().GetAccountIdentity(gomock.Any(), gomock.Any()).Return(db.AccountIdentity{}, sql.ErrNoRows)
+		test.store.EXPECT().CreateAccountWithIdentity(gomock.Any(), gomock.Any()).Times(0)
+
+		result := test.serve(callback)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusForbidden, result.StatusCode)
+	})
+
+	t.Run("callback given an unlinked identity signs up a new account when sign ups are allowed", func(t *testing.T) {
+		test := newOIDCTest(t, true)
+		callback := test.beginLogin(t)
+		test.store.EXPECT().GetAccountIdentity(gomock.Any(), gomock.Any()).Return(db.AccountIdentity{}, sql.ErrNoRows)
+		// bob is held by the account that gave it up, so a number is added
+		test.store.EXPECT().GetAccountByUsername(gomock.Any(), gomock.Any()).Times(2).Return(db.Account{}, sql.ErrNoRows)
+		test.store.EXPECT().GetUsernameHistory(gomock.Any(), "bob").Return(db.UsernameHistory{AccountID: 2, Username: "bob"}, nil)
+		test.store.EXPECT().GetUsernameHistory(gomock.Any(), gomock.Any()).Return(db.UsernameHistory{}, sql.ErrNoRows)
+		test.store.EXPECT().GetAccountByEmail(gomock.Any(), "bob@example.com").Return(db.Account{}, sql.ErrNoRows)
+		test.hasher.EXPECT().GenerateSalt().Return("newsalt")
+		test.hasher.EXPECT().GeneratePasswordHash(gomock.Any(), "newsalt").Return([]byte("randomhash"), nil)
+		var created db.Account
+		test.store.EXPECT().CreateAccountWithIdentity(gomock.Any(), gomock.AssignableToTypeOf(db.CreateAccountWithIdentityParams{})).
+			DoAndReturn(func(_ interface{}, arg db.CreateAccountWithIdentityParams) (db.Account, error) {
+				assert.Equal(t, "mock", arg.Provider)
+				assert.Equal(t, "subject-1", arg.Subject)
+				assert.Equal(t, "bob@example.com", arg.Email)
+				assert.Equal(t, "bob-", arg.Username[:4])
+				created = db.Account{ID: 5, Username: arg.Username, Role: security.RoleMember, Email: arg.Email, EmailVerified: true}
+				return created, nil
+			})
+		test.store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Return(nil)
+		test.store.EXPECT().ClearUsernameLoginFailures(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+		test.claimer.EXPECT().GetFiveMinuteExpirationToken(gomock.Any()).Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+		test.store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Return(db.Session{ID: 1, Expires: time.Now().Add(time.Hour)}, nil)
+
+		result := test.serve(callback)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		assert.Equal(t, true, hasCookie(result, accessTokenCookie, "mocktoken"))
+	})
+
+	t.Run("callback given an unknown state responds with status bad request", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		test.store.EXPECT().ConsumeOidcState(gomock.Any(), gomock.Any()).Return(db.ConsumeOidcStateRow{}, sql.ErrNoRows)
+
+		request := httptest.NewRequest(http.MethodGet, "/auth/oidc/mock/callback?code=code&state=forged", nil)
+		request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: oidc.StateHash("forged")})
+		result := test.serve(request)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, result.StatusCode)
+	})
+
+	t.Run("callback given the provider's refusal responds with status unauthorized", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		test.store.EXPECT().ConsumeOidcState(gomock.Any(), gomock.Any()).Return(db.ConsumeOidcStateRow{Nonce: "nonce", CodeVerifier: "verifier"}, nil)
+		test.store.EXPECT().GetAccountIdentity(gomock.Any(), gomock.Any()).Times(0)
+
+		request := httptest.NewRequest(http.MethodGet, "/auth/oidc/mock/callback?error=access_denied&state=state", nil)
+		request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: oidc.StateHash("state")})
+		result := test.serve(request)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+	})
+
+	t.Run("callback in a browser that didn't start the sign in responds with status bad request", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		test.store.EXPECT().CreateOidcState(gomock.Any(), gomock.Any()).Return(nil)
+		begin := test.serve(httptest.NewRequest(http.MethodGet, "/auth/oidc/mock", nil))
+		defer begin.Body.Close()
+		callback, err := test.provider.Authorize(begin.Header.Get("Location"))
+		if err != nil {
+			t.Fatalf("Error authorizing at the mock provider: %v", err)
+		}
+		// the victim's browser is sent the callback of a sign in the attacker started
+		test.store.EXPECT().ConsumeOidcState(gomock.Any(), gomock.Any()).Times(0)
+
+		request := httptest.NewRequest(http.MethodGet, callback.Path+"?"+callback.RawQuery, nil)
+		request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: oidc.StateHash("another")})
+		result := test.serve(request)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, result.StatusCode)
+	})
+
+	t.Run("callback given a code for another sign in responds with status unauthorized", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		callback := test.beginLogin(t)
+		// a code issued for one sign in is replayed with the state of another
+		test.store.EXPECT().GetAccountIdentity(gomock.Any(), gomock.Any()).Times(0)
+		query := callback.URL.Query()
+		query.Set("code", "stolen")
+		callback.URL.RawQuery = query.Encode()
+
+		result := test.serve(callback)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+	})
+
+	t.Run("begin given an unconfigured provider responds with status not found", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		result := test.serve(httptest.NewRequest(http.MethodGet, "/auth/oidc/github", nil))
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	})
+}
+
+func TestOIDCLinkIdentity(t *testing.T) {
+	account := db.Account{ID: 1, Username: "valid", Phrase: []byte("valid"), Salt: "salt", Role: security.RoleMember}
+
+	// beginLink starts linking an identity to account 1, returning the callback request
+	beginLink := func(t *testing.T, test *oidcTest) *http.Request {
+		request := httptest.NewRequest(http.MethodPost, "/account/me/identities/mock", bytes.NewBufferString(`{"current_phrase":"valid"}`))
+		passClaimsMiddleware(request, test.claimer, test.hasher, test.store)
+		test.store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+		test.hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "valid").Return(true, nil)
+		var state db.CreateOidcStateParams
+		test.store.EXPECT().CreateOidcState(gomock.Any(), gomock.AssignableToTypeOf(db.CreateOidcStateParams{})).
+			DoAndReturn(func(_ interface{}, arg db.CreateOidcStateParams) error {
+				state = arg
+				return nil
+			})
+
+		result := test.serve(request)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		var response authorizationURLResponse
+		if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		assert.Equal(t, sql.NullInt64{Int64: 1, Valid: true}, state.AccountID)
+		authURL, err := url.Parse(response.AuthorizationURL)
+		if err != nil {
+			t.Fatalf("Error parsing authorization url: %v", err)
+		}
+		assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+		return test.authorize(t, result, response.AuthorizationURL, &state)
+	}
+
+	t.Run("callback given an unlinked identity links it to the account that started", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		callback := beginLink(t, test)
+		test.store.EXPECT().GetAccountIdentity(gomock.Any(), gomock.Any()).Return(db.AccountIdentity{}, sql.ErrNoRows)
+		test.store.EXPECT().CreateAccountIdentity(gomock.Any(), db.CreateAccountIdentityParams{AccountID: 1, Provider: "mock", Subject: "subject-1", Email: "bob@example.com"}).
+			Return(db.AccountIdentity{ID: 3, AccountID: 1, Provider: "mock", Subject: "subject-1", Email: "bob@example.com"}, nil)
+		test.store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+		result := test.serve(callback)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusCreated, result.StatusCode)
+		var response identityResponse
+		if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		assert.Equal(t, int64(3), response.ID)
+		assert.Equal(t, "mock", response.Provider)
+	})
+
+	t.Run("callback given an identity linked to another account responds with status conflict", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		callback := beginLink(t, test)
+		test.store.EXPECT().GetAccountIdentity(gomock.Any(), gomock.Any()).
+			Return(db.AccountIdentity{ID: 3, AccountID: 2, Provider: "mock", Subject: "subject-1"}, nil)
+		test.store.EXPECT().CreateAccountIdentity(gomock.Any(), gomock.Any()).Times(0)
+
+		result := test.serve(callback)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusConflict, result.StatusCode)
+	})
+
+	t.Run("begin link given the wrong current phrase responds with status forbidden", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		request := httptest.NewRequest(http.MethodPost, "/account/me/identities/mock", bytes.NewBufferString(`{"current_phrase":"wrong"}`))
+		passClaimsMiddleware(request, test.claimer, test.hasher, test.store)
+		test.store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+		test.hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "wrong").Return(false, nil)
+		test.store.EXPECT().CreateOidcState(gomock.Any(), gomock.Any()).Times(0)
+
+		result := test.serve(request)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusForbidden, result.StatusCode)
+	})
+
+	t.Run("unlink given another account's identity responds with status not found", func(t *testing.T) {
+		test := newOIDCTest(t, false)
+		request := httptest.NewRequest(http.MethodDelete, "/account/me/identities/9", nil)
+		passClaimsMiddleware(request, test.claimer, test.hasher, test.store)
+		test.store.EXPECT().DeleteAccountIdentity(gomock.Any(), db.DeleteAccountIdentityParams{ID: 9, AccountID: 1}).Return(int64(0), nil)
+
+		result := test.serve(request)
+		defer result.Body.Close()
+		assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	})
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meads/firstly-api/security"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pageCursor is the position of the last row on a page, carried between requests as an opaque signed
+// string so that the next page continues from it using keyset pagination.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+// pageRequest is a parsed request for a page of a list endpoint.
+type pageRequest struct {
+	Limit  int32
+	Sort   string
+	Desc   bool
+	Cursor *pageCursor
+}
+
+// pageResponse is the body of a list endpoint using keyset pagination.
+type pageResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// usesOffsetPagination reports whether the request is still paging with offset, which is accepted
+// while clients move over to cursors.
+func usesOffsetPagination(ctx *gin.Context) bool {
+	_, ok := ctx.GetQuery("offset")
+	return ok && ctx.Query("cursor") == ""
+}
+
+// wantsPageResponse reports whether the list should be wrapped in a pageResponse, which is when the
+// client sends a cursor or opts in with pagination=cursor. Everyone else gets the bare array they parsed
+// before cursors were added, and finds the next page in the Link header.
+func wantsPageResponse(ctx *gin.Context) bool {
+	return ctx.Query("cursor") != "" || ctx.Query("pagination") == "cursor"
+}
+
+// parsePageRequest reads the limit, order and cursor query params for a list sorted by sort.
+func parsePageRequest(ctx *gin.Context, sort string) (pageRequest, error) {
+	req := pageRequest{Limit: defaultPageLimit, Sort: sort, Desc: true}
+
+	if limit := ctx.Query("limit"); limit != "" && limit != "0" {
+		i, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil || i < 0 {
+			return req, errors.New("error parsing limit as int")
+		}
+		if i > maxPageLimit {
+			i = maxPageLimit
+		}
+		req.Limit = int32(i)
+	}
+
+	switch ctx.DefaultQuery("order", "desc") {
+	case "desc":
+		req.Desc = true
+	case "asc":
+		req.Desc = false
+	default:
+		return req, errors.New("order must be one of: asc, desc")
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		payload, err := security.VerifyCursor(cursor)
+		if err != nil {
+			return req, err
+		}
+		var c pageCursor
+		if err := json.Unmarshal(payload, &c); err != nil {
+			return req, security.ErrInvalidCursor
+		}
+		if c.Sort != req.Sort || c.Desc != req.Desc {
+			return req, errors.New("cursor does not match the requested sort order")
+		}
+		req.Cursor = &c
+	}
+
+	return req, nil
+}
+
+// cursorValue returns the nullable keyset arguments for the page's cursor.
+func (req pageRequest) cursorValue() (sql.NullString, sql.NullInt64) {
+	if req.Cursor == nil {
+		return sql.NullString{}, sql.NullInt64{}
+	}
+	return sql.NullString{String: req.Cursor.Value, Valid: true}, sql.NullInt64{Int64: req.Cursor.ID, Valid: true}
+}
+
+// nextCursor signs a cursor pointing after the row with the given sort value and id.
+func (req pageRequest) nextCursor(value string, id int64) (string, error) {
+	payload, err := json.Marshal(pageCursor{Sort: req.Sort, Desc: req.Desc, Value: value, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return security.SignCursor(payload)
+}
+
+// setNextLink sets the RFC 8288 Link header pointing at the next page.
+func setNextLink(ctx *gin.Context, nextCursor string) {
+	u := *ctx.Request.URL
+	query := u.Query()
+	query.Del("offset")
+	query.Set("cursor", nextCursor)
+	u.RawQuery = query.Encode()
+	ctx.Header("Link", fmt.Sprintf("<%s>; rel=\"next\"", u.RequestURI()))
+}
@@ -0,0 +1,372 @@
+package http
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	// Embed the timezone database so that timezones can be checked on hosts without one.
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+)
+
+// Who can see a profile beyond its username, the account itself always can.
+const (
+	profilePublic  = "public"
+	profileMembers = "members"
+	profilePrivate = "private"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxLocaleLength      = 35
+
+	// avatars are shown small, so they are held to tighter limits than the image library
+	avatarMaxBytes     = 1 << 20
+	avatarMaxDimension = 2048
+)
+
+var (
+	errNoAvatar = errors.New("account has no avatar")
+
+	// localePattern matches BCP 47 language tags such as en, en-GB or zh-Hant-TW
+	localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+)
+
+// userResponse is the profile of an account as seen by others, only the username being shown when the
+// profile isn't visible to them.
+type userResponse struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	Timezone    string `json:"timezone,omitempty"`
+}
+
+type profileResponse struct {
+	DisplayName  string `json:"display_name"`
+	Bio          string `json:"bio"`
+	AvatarURL    string `json:"avatar_url,omitempty"`
+	Locale       string `json:"locale"`
+	Timezone     string `json:"timezone"`
+	Visibility   string `json:"visibility"`
+	ShowTimezone bool   `json:"show_timezone"`
+}
+
+type updateProfileRequest struct {
+	DisplayName  *string `json:"display_name"`
+	Bio          *string `json:"bio"`
+	Locale       *string `json:"locale"`
+	Timezone     *string `json:"timezone"`
+	Visibility   *string `json:"visibility"`
+	ShowTimezone *bool   `json:"show_timezone"`
+}
+
+type updateAvatarRequest struct {
+	Data string `json:"data" binding:"required"`
+}
+
+func newProfileResponse(username string, profile db.GetProfileRow) profileResponse {
+	return profileResponse{
+		DisplayName:  profile.DisplayName,
+		Bio:          profile.Bio,
+		AvatarURL:    avatarURL(username, profile),
+		Locale:       profile.Locale,
+		Timezone:     profile.Timezone,
+		Visibility:   profile.Visibility,
+		ShowTimezone: profile.ShowTimezone,
+	}
+}
+
+// avatarURL returns where the avatar of the profile is served from, changing along with the profile so
+// that a cached avatar isn't shown after it is replaced.
+func avatarURL(username string, profile db.GetProfileRow) string {
+	if !profile.HasAvatar {
+		return ""
+	}
+	return fmt.Sprintf("/u/%s/avatar?v=%d", url.PathEscape(username), profile.Updated.Unix())
+}
+
+// loadProfile returns the account's profile, which is empty and public until the account first sets it.
+func loadProfile(ctx *gin.Context, accountID int64) (db.GetProfileRow, error) {
+	profile, err := firstly.store.GetProfile(ctx, accountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.GetProfileRow{AccountID: accountID, Visibility: profilePublic}, nil
+	}
+	return profile, err
+}
+
+// profileVisible reports whether the signed in account, if any, can see a profile with visibility
+// belonging to the account with accountID.
+func profileVisible(ctx *gin.Context, accountID int64, visibility string) bool {
+	viewer := currentClaims(ctx).AccountID()
+	switch {
+	case viewer == accountID:
+		return true
+	case visibility == profilePublic:
+		return true
+	case visibility == profileMembers:
+		return viewer != 0
+	}
+	return false
+}
+
+// validateProfile returns why the profile can't be saved.
+func validateProfile(profile db.GetProfileRow) error {
+	if utf8.RuneCountInString(profile.DisplayName) > maxDisplayNameLength {
+		return fmt.Errorf("display_name must not be longer than %d characters", maxDisplayNameLength)
+	}
+	if strings.IndexFunc(profile.DisplayName, unicode.IsControl) >= 0 {
+		return errors.New("display_name must not contain control characters")
+	}
+	if utf8.RuneCountInString(profile.Bio) > maxBioLength {
+		return fmt.Errorf("bio must not be longer than %d characters", maxBioLength)
+	}
+	if strings.IndexFunc(profile.Bio, func(r rune) bool { return unicode.IsControl(r) && r != '\n' }) >= 0 {
+		return errors.New("bio must not contain control characters other than new lines")
+	}
+	if profile.Locale != "" && (len(profile.Locale) > maxLocaleLength || !localePattern.MatchString(profile.Locale)) {
+		return errors.New("locale must be a language tag such as en or en-GB")
+	}
+	if profile.Timezone != "" {
+		if _, err := time.LoadLocation(profile.Timezone); err != nil || profile.Timezone == "Local" {
+			return errors.New("timezone must be an IANA time zone such as Europe/London")
+		}
+	}
+	switch profile.Visibility {
+	case profilePublic, profileMembers, profilePrivate:
+	default:
+		return fmt.Errorf("visibility must be one of: %s, %s, %s", profilePublic, profileMembers, profilePrivate)
+	}
+	return nil
+}
+
+// avatarLimits are the image limits held to the smaller of the avatar limits.
+func avatarLimits() imageLimits {
+	limits := loadImageLimits()
+	if limits.MaxBytes > avatarMaxBytes {
+		limits.MaxBytes = avatarMaxBytes
+	}
+	if limits.MaxWidth > avatarMaxDimension {
+		limits.MaxWidth = avatarMaxDimension
+	}
+	if limits.MaxHeight > avatarMaxDimension {
+		limits.MaxHeight = avatarMaxDimension
+	}
+	return limits
+}
+
+// getMyProfileHandler responds with the signed in account's profile and privacy settings.
+func getMyProfileHandler(ctx *gin.Context) {
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	profile, err := loadProfile(ctx, accountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.JSON(http.StatusOK, newProfileResponse(claims.Username, profile))
+}
+
+// updateMyProfileHandler changes the fields of the signed in account's profile that are given, leaving
+// the others as they are.
+func updateMyProfileHandler(ctx *gin.Context) {
+	var req updateProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	profile, err := loadProfile(ctx, accountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if req.DisplayName != nil {
+		profile.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Bio != nil {
+		profile.Bio = strings.TrimSpace(*req.Bio)
+	}
+	if req.Locale != nil {
+		profile.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		profile.Timezone = *req.Timezone
+	}
+	if req.Visibility != nil {
+		profile.Visibility = *req.Visibility
+	}
+	if req.ShowTimezone != nil {
+		profile.ShowTimezone = *req.ShowTimezone
+	}
+	if err := validateProfile(profile); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := firstly.store.UpsertProfile(ctx, db.UpsertProfileParams{
+		AccountID:    accountID,
+		DisplayName:  profile.DisplayName,
+		Bio:          profile.Bio,
+		Locale:       profile.Locale,
+		Timezone:     profile.Timezone,
+		Visibility:   profile.Visibility,
+		ShowTimezone: profile.ShowTimezone,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProfileResponse(claims.Username, profile))
+}
+
+// updateMyAvatarHandler sets the signed in account's avatar, checked against the image limits like an
+// upload to the image library.
+func updateMyAvatarHandler(ctx *gin.Context) {
+	limits := avatarLimits()
+	if !limitRequestBody(ctx, limits) {
+		return
+	}
+
+	var req updateAvatarRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	claims := currentClaims(ctx)
+	accountID := claims.AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	info, err := inspectImage(req.Data, limits)
+	if err != nil {
+		var limitErr *imageLimitError
+		if errors.As(err, &limitErr) {
+			abortImageLimit(ctx, limitErr)
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := firstly.store.UpdateProfileAvatar(ctx, db.UpdateProfileAvatarParams{
+		AccountID:      accountID,
+		Avatar:         req.Data,
+		AvatarMimeType: "image/" + info.Format,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	profile, err := loadProfile(ctx, accountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newProfileResponse(claims.Username, profile))
+}
+
+// deleteMyAvatarHandler removes the signed in account's avatar.
+func deleteMyAvatarHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	if err := firstly.store.UpdateProfileAvatar(ctx, db.UpdateProfileAvatarParams{AccountID: accountID}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// userHandler responds with the profile of the account named by the username, as far as its visibility
+// lets the signed in account, or anyone when nobody is signed in, see it.
+func userHandler(ctx *gin.Context) {
+	account, ok := resolveUsername(ctx)
+	if !ok {
+		return
+	}
+
+	profile, err := loadProfile(ctx, account.ID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	response := userResponse{Username: account.Username}
+	if profileVisible(ctx, account.ID, profile.Visibility) {
+		response.DisplayName = profile.DisplayName
+		response.Bio = profile.Bio
+		response.AvatarURL = avatarURL(account.Username, profile)
+		response.Locale = profile.Locale
+		if profile.ShowTimezone || currentClaims(ctx).AccountID() == account.ID {
+			response.Timezone = profile.Timezone
+		}
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.JSON(http.StatusOK, response)
+}
+
+// userAvatarHandler serves the avatar of the account named by the username, when its profile is visible.
+func userAvatarHandler(ctx *gin.Context) {
+	account, ok := resolveUsername(ctx)
+	if !ok {
+		return
+	}
+
+	avatar, err := firstly.store.GetProfileAvatar(ctx, account.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// an avatar that isn't visible is as good as missing, so that hiding it doesn't say it exists
+	if err != nil || !profileVisible(ctx, account.ID, avatar.Visibility) {
+		ctx.JSON(http.StatusNotFound, errorResponse(errNoAvatar))
+		return
+	}
+
+	raw, err := decodeImageData(avatar.Avatar)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if avatar.Visibility == profilePublic {
+		ctx.Header("Cache-Control", "public, max-age=86400")
+	} else {
+		ctx.Header("Cache-Control", "private, max-age=86400")
+	}
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.Data(http.StatusOK, avatar.AvatarMimeType, raw)
+}
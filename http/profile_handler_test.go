@@ -0,0 +1,323 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestProfileHandlers(t *testing.T) {
+	updated := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+	bob := db.Account{ID: 2, Username: "bob", Role: security.RoleMember}
+	profile := db.GetProfileRow{
+		AccountID:   2,
+		DisplayName: "Bob",
+		Bio:         "Takes pictures of bridges",
+		Locale:      "en-GB",
+		Timezone:    "Europe/London",
+		Visibility:  profilePublic,
+		HasAvatar:   true,
+		Updated:     updated,
+	}
+	withVisibility := func(visibility string) db.GetProfileRow {
+		p := profile
+		p.Visibility = visibility
+		return p
+	}
+	avatar := func(visibility string) db.GetProfileAvatarRow {
+		return db.GetProfileAvatarRow{Avatar: testImagePNG, AvatarMimeType: "image/png", Visibility: visibility, Updated: updated}
+	}
+
+	tests := []struct {
+		name              string
+		method            string
+		body              string
+		responseCode      int
+		route             string
+		expectedUser      *userResponse
+		expectedProfile   *profileResponse
+		expectedLocation  string
+		expectedCache     string
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:            "get my profile handler responds with an empty public profile until one is set",
+			method:          http.MethodGet,
+			responseCode:    http.StatusOK,
+			route:           "/account/me/profile",
+			expectedProfile: &profileResponse{Visibility: profilePublic},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetProfile(gomock.Any(), int64(1)).Return(db.GetProfileRow{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:         "update my profile handler changes only the fields given",
+			method:       http.MethodPatch,
+			body:         `{"display_name":"  Valid Person ","timezone":"America/New_York","show_timezone":true}`,
+			responseCode: http.StatusOK,
+			route:        "/account/me/profile",
+			expectedProfile: &profileResponse{
+				DisplayName:  "Valid Person",
+				Bio:          "hello",
+				Locale:       "fr",
+				Timezone:     "America/New_York",
+				Visibility:   profileMembers,
+				ShowTimezone: true,
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetProfile(gomock.Any(), int64(1)).
+					Return(db.GetProfileRow{AccountID: 1, DisplayName: "Old", Bio: "hello", Locale: "fr", Visibility: profileMembers}, nil)
+				store.EXPECT().UpsertProfile(gomock.Any(), db.UpsertProfileParams{
+					AccountID:    1,
+					DisplayName:  "Valid Person",
+					Bio:          "hello",
+					Locale:       "fr",
+					Timezone:     "America/New_York",
+					Visibility:   profileMembers,
+					ShowTimezone: true,
+				}).Return(nil)
+			},
+		},
+		{
+			name:         "update my profile handler responds with Status Code 400 given an unknown timezone",
+			method:       http.MethodPatch,
+			body:         `{"timezone":"Mars/Olympus_Mons"}`,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me/profile",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetProfile(gomock.Any(), int64(1)).Return(db.GetProfileRow{}, sql.ErrNoRows)
+				store.EXPECT().UpsertProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update my profile handler responds with Status Code 400 given a locale that isn't a language tag",
+			method:       http.MethodPatch,
+			body:         `{"locale":"english please"}`,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me/profile",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetProfile(gomock.Any(), int64(1)).Return(db.GetProfileRow{}, sql.ErrNoRows)
+				store.EXPECT().UpsertProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update my profile handler responds with Status Code 400 given an unknown visibility",
+			method:       http.MethodPatch,
+			body:         `{"visibility":"friends"}`,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me/profile",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetProfile(gomock.Any(), int64(1)).Return(db.GetProfileRow{}, sql.ErrNoRows)
+				store.EXPECT().UpsertProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update my avatar handler stores the image and responds with where it is served",
+			method:       http.MethodPut,
+			body:         `{"data":"` + testImagePNG + `"}`,
+			responseCode: http.StatusOK,
+			route:        "/account/me/profile/avatar",
+			expectedProfile: &profileResponse{
+				AvatarURL:  "/u/valid/avatar?v=1667131200",
+				Visibility: profilePublic,
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().UpdateProfileAvatar(gomock.Any(), db.UpdateProfileAvatarParams{
+					AccountID:      1,
+					Avatar:         testImagePNG,
+					AvatarMimeType: "image/png",
+				}).Return(nil)
+				store.EXPECT().GetProfile(gomock.Any(), int64(1)).
+					Return(db.GetProfileRow{AccountID: 1, Visibility: profilePublic, HasAvatar: true, Updated: updated}, nil)
+			},
+		},
+		{
+			name:         "update my avatar handler responds with Status Code 415 given data that isn't an image",
+			method:       http.MethodPut,
+			body:         `{"data":"` + base64.StdEncoding.EncodeToString([]byte("not an image")) + `"}`,
+			responseCode: http.StatusUnsupportedMediaType,
+			route:        "/account/me/profile/avatar",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().UpdateProfileAvatar(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "delete my avatar handler removes the avatar",
+			method:       http.MethodDelete,
+			responseCode: http.StatusNoContent,
+			route:        "/account/me/profile/avatar",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().UpdateProfileAvatar(gomock.Any(), db.UpdateProfileAvatarParams{AccountID: 1}).Return(nil)
+			},
+		},
+		{
+			name:         "user handler responds with a public profile to anyone, without the timezone unless it is shown",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/u/bob",
+			expectedUser: &userResponse{
+				Username:    "bob",
+				DisplayName: "Bob",
+				Bio:         "Takes pictures of bridges",
+				AvatarURL:   "/u/bob/avatar?v=1667131200",
+				Locale:      "en-GB",
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "bob").Return(bob, nil)
+				store.EXPECT().GetProfile(gomock.Any(), int64(2)).Return(profile, nil)
+			},
+		},
+		{
+			name:         "user handler responds with only the username of a members profile to someone not signed in",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/u/bob",
+			expectedUser: &userResponse{Username: "bob"},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "bob").Return(bob, nil)
+				store.EXPECT().GetProfile(gomock.Any(), int64(2)).Return(withVisibility(profileMembers), nil)
+			},
+		},
+		{
+			name:         "user handler responds with a members profile to a signed in account",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/u/bob",
+			expectedUser: &userResponse{
+				Username:    "bob",
+				DisplayName: "Bob",
+				Bio:         "Takes pictures of bridges",
+				AvatarURL:   "/u/bob/avatar?v=1667131200",
+				Locale:      "en-GB",
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "bob").Return(bob, nil)
+				store.EXPECT().GetProfile(gomock.Any(), int64(2)).Return(withVisibility(profileMembers), nil)
+			},
+		},
+		{
+			name:         "user handler responds with only the username of a private profile to another account",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/u/bob",
+			expectedUser: &userResponse{Username: "bob"},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "bob").Return(bob, nil)
+				store.EXPECT().GetProfile(gomock.Any(), int64(2)).Return(withVisibility(profilePrivate), nil)
+			},
+		},
+		{
+			name:         "user handler responds with a private profile, timezone and all, to its own account",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/u/valid",
+			expectedUser: &userResponse{
+				Username:    "valid",
+				DisplayName: "Bob",
+				Bio:         "Takes pictures of bridges",
+				AvatarURL:   "/u/valid/avatar?v=1667131200",
+				Locale:      "en-GB",
+				Timezone:    "Europe/London",
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(db.Account{ID: 1, Username: "valid"}, nil)
+				store.EXPECT().GetProfile(gomock.Any(), int64(1)).Return(withVisibility(profilePrivate), nil)
+			},
+		},
+		{
+			name:          "user avatar handler serves a public avatar to anyone",
+			method:        http.MethodGet,
+			responseCode:  http.StatusOK,
+			route:         "/u/bob/avatar",
+			expectedCache: "public, max-age=86400",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "bob").Return(bob, nil)
+				store.EXPECT().GetProfileAvatar(gomock.Any(), int64(2)).Return(avatar(profilePublic), nil)
+			},
+		},
+		{
+			name:         "user avatar handler responds with Status Code 404 given the avatar of a private profile",
+			method:       http.MethodGet,
+			responseCode: http.StatusNotFound,
+			route:        "/u/bob/avatar",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "bob").Return(bob, nil)
+				store.EXPECT().GetProfileAvatar(gomock.Any(), int64(2)).Return(avatar(profilePrivate), nil)
+			},
+		},
+		{
+			name:             "user avatar handler redirects a previous username to the avatar under the current one",
+			method:           http.MethodGet,
+			responseCode:     http.StatusFound,
+			route:            "/u/bobby/avatar?v=1667131200",
+			expectedLocation: "/u/bob/avatar?v=1667131200",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "bobby").Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetUsernameHistory(gomock.Any(), "bobby").Return(db.UsernameHistory{AccountID: 2, Username: "bobby"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(2)).Return(bob, nil)
+				store.EXPECT().GetProfileAvatar(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockStore := db.NewMockStore(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockClaimer := security.NewMockClaimer(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(test.method, test.route, bytes.NewBufferString(test.body))
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			assert.Equal(t, test.responseCode, result.StatusCode)
+			if test.expectedUser != nil {
+				var response userResponse
+				decodeJSON(t, result.Body, &response)
+				assert.Equal(t, *test.expectedUser, response)
+			}
+			if test.expectedProfile != nil {
+				var response profileResponse
+				decodeJSON(t, result.Body, &response)
+				assert.Equal(t, *test.expectedProfile, response)
+			}
+			if test.expectedLocation != "" {
+				assert.Equal(t, test.expectedLocation, result.Header.Get("Location"))
+			}
+			if test.expectedCache != "" {
+				assert.Equal(t, test.expectedCache, result.Header.Get("Cache-Control"))
+				assert.Equal(t, "image/png", result.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// LoadTrustedProxies returns the addresses and CIDR ranges, comma separated in TRUSTED_PROXIES, of the
+// proxies whose X-Forwarded-For and X-Real-IP headers are believed. None are when it isn't set, so that a
+// client can't choose the address its sign in attempts are throttled and recorded by.
+func LoadTrustedProxies() ([]string, error) {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES has %q, which isn't an IP address or CIDR range", proxy)
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
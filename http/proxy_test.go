@@ -0,0 +1,30 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestLoadTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		proxies []string
+		err     bool
+	}{
+		{name: "trusts no proxy given nothing", env: ""},
+		{name: "trusts the addresses and ranges given", env: "10.0.0.1, 192.168.0.0/16,::1", proxies: []string{"10.0.0.1", "192.168.0.0/16", "::1"}},
+		{name: "refuses a name", env: "proxy.internal", err: true},
+		{name: "refuses a bad range", env: "10.0.0.0/33", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", test.env)
+			proxies, err := LoadTrustedProxies()
+			assert.Equal(t, test.err, err != nil)
+			assert.Equal(t, test.proxies, proxies)
+		})
+	}
+}
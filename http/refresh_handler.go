@@ -0,0 +1,193 @@
+package http
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+const (
+	refreshTokenCookie     = "refresh_token"
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var errInvalidRefreshToken = errors.New("refresh token is invalid or has expired")
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenResponse carries the tokens in the response body, for clients sending them in the
+// Authorization header rather than as cookies.
+type tokenResponse struct {
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// refreshTokenTTL reads how long refresh tokens last from the REFRESH_TOKEN_TTL env variable, a Go
+// duration such as 720h.
+func refreshTokenTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return defaultRefreshTokenTTL
+}
+
+// startSession starts a new session family for the account and sets its refresh token cookie, returning
+// the refresh token and when it expires. The jti and expiry of the access token issued alongside it are
+// kept so that revoking the session revokes it too.
+func startSession(ctx *gin.Context, accountID int64, accessJti string, accessExpires time.Time) (string, time.Time, error) {
+	familyID, err := security.NewSessionFamilyID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token, hash, err := security.NewRefreshToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	session, err := firstly.store.CreateSession(ctx, db.CreateSessionParams{
+		AccountID:     accountID,
+		FamilyID:      familyID,
+		TokenHash:     hash,
+		Expires:       time.Now().Add(refreshTokenTTL()),
+		UserAgent:     ctx.Request.UserAgent(),
+		IP:            ctx.ClientIP(),
+		AccessJti:     accessJti,
+		AccessExpires: accessExpires,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	setRefreshCookie(ctx, token, session.Expires)
+	return token, session.Expires, nil
+}
+
+func setRefreshCookie(ctx *gin.Context, token string, expires time.Time) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    token,
+		Expires:  expires,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// refreshHandler exchanges a refresh token, from the request body or its cookie, for a new access token
+// and a new refresh token. Each refresh token can only be used once; presenting one again means it has
+// been stolen, so every session descended from the same sign in is revoked.
+func refreshHandler(ctx *gin.Context) {
+	var req refreshRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		c, err := ctx.Request.Cookie(refreshTokenCookie)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidRefreshToken))
+			return
+		}
+		req.RefreshToken = c.Value
+	}
+
+	session, err := firstly.store.GetSessionByTokenHash(ctx, security.HashRefreshToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidRefreshToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if session.Revoked || !session.Expires.After(time.Now()) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidRefreshToken))
+		return
+	}
+	if session.Rotated {
+		revokeSessionFamily(ctx, session)
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, session.AccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	claims, err := security.NewAccessClaims(account.ID, account.Username, account.Role)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	tokenString, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(claims)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	token, hash, err := security.NewRefreshToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	next, err := firstly.store.RotateSession(ctx, db.RotateSessionParams{
+		ID: session.ID,
+		Next: db.CreateSessionParams{
+			AccountID:     session.AccountID,
+			FamilyID:      session.FamilyID,
+			TokenHash:     hash,
+			Expires:       time.Now().Add(refreshTokenTTL()),
+			UserAgent:     ctx.Request.UserAgent(),
+			IP:            ctx.ClientIP(),
+			AccessJti:     claims.Id,
+			AccessExpires: expirationTime,
+		},
+	})
+	if err != nil {
+		// another request rotated the session first, so the token was used twice
+		if errors.Is(err, db.ErrSessionReused) {
+			revokeSessionFamily(ctx, session)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:    accessTokenCookie,
+		Value:   tokenString,
+		Expires: expirationTime,
+	})
+	setRefreshCookie(ctx, token, next.Expires)
+
+	ctx.JSON(http.StatusOK, tokenResponse{
+		AccessToken:      tokenString,
+		TokenType:        "Bearer",
+		ExpiresAt:        expirationTime,
+		RefreshToken:     token,
+		RefreshExpiresAt: next.Expires,
+	})
+}
+
+// revokeSessionFamily revokes every session sharing the family of a session whose refresh token was
+// reused, along with their access tokens.
+func revokeSessionFamily(ctx *gin.Context, session db.Session) {
+	log.Printf("refresh token reuse detected for account %d, revoking session family %s", session.AccountID, session.FamilyID)
+	if err := revokeSessions(ctx, session.FamilyID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidRefreshToken))
+}
@@ -0,0 +1,192 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	activeSession := db.Session{
+		ID:        1,
+		AccountID: 2,
+		FamilyID:  "family",
+		TokenHash: security.HashRefreshToken("refresh-token"),
+		Expires:   time.Now().Add(time.Hour),
+	}
+
+	tests := []struct {
+		name              string
+		responseCode      int
+		route             string
+		body              io.Reader
+		cookie            string
+		rotated           bool
+		setupExpectations func(claimer *security.MockClaimer, store *db.MockStore)
+	}{
+		{
+			name:         "refresh handler given no refresh token responds with status unauthorized",
+			responseCode: http.StatusUnauthorized,
+			route:        "/auth/refresh",
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "refresh handler given an unknown refresh token responds with status unauthorized",
+			responseCode: http.StatusUnauthorized,
+			route:        "/auth/refresh",
+			cookie:       "unknown",
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), security.HashRefreshToken("unknown")).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:         "refresh handler given a valid refresh token cookie rotates it and issues an access token",
+			responseCode: http.StatusOK,
+			route:        "/auth/refresh",
+			cookie:       "refresh-token",
+			rotated:      true,
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), activeSession.TokenHash).Return(activeSession, nil)
+				store.EXPECT().RotateSession(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.RotateSessionParams) (db.Session, error) {
+						assert.Equal(t, activeSession.ID, arg.ID)
+						assert.Equal(t, activeSession.FamilyID, arg.Next.FamilyID)
+						assert.Equal(t, activeSession.AccountID, arg.Next.AccountID)
+						assert.NotEqual(t, "", arg.Next.AccessJti)
+						return db.Session{ID: 3, FamilyID: arg.Next.FamilyID, Expires: arg.Next.Expires}, nil
+					})
+				store.EXPECT().GetAccount(gomock.Any(), activeSession.AccountID).Return(db.Account{ID: 2, Username: "valid"}, nil)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("valid")).Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+			},
+		},
+		{
+			name:         "refresh handler given a valid refresh token in the body on the legacy route rotates it",
+			responseCode: http.StatusOK,
+			route:        "/refresh/",
+			body:         bytes.NewBufferString(`{"refresh_token":"refresh-token"}`),
+			rotated:      true,
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), activeSession.TokenHash).Return(activeSession, nil)
+				store.EXPECT().RotateSession(gomock.Any(), gomock.Any()).Return(db.Session{ID: 3, Expires: time.Now().Add(time.Hour)}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), activeSession.AccountID).Return(db.Account{ID: 2, Username: "valid"}, nil)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("valid")).Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+			},
+		},
+		{
+			name:         "refresh handler given an expired refresh token responds with status unauthorized",
+			responseCode: http.StatusUnauthorized,
+			route:        "/auth/refresh",
+			cookie:       "refresh-token",
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				expired := activeSession
+				expired.Expires = time.Now().Add(-time.Minute)
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), activeSession.TokenHash).Return(expired, nil)
+				store.EXPECT().RotateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "refresh handler given a revoked refresh token responds with status unauthorized",
+			responseCode: http.StatusUnauthorized,
+			route:        "/auth/refresh",
+			cookie:       "refresh-token",
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				revoked := activeSession
+				revoked.Revoked = true
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), activeSession.TokenHash).Return(revoked, nil)
+				store.EXPECT().RotateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "refresh handler given an already rotated refresh token revokes the session family",
+			responseCode: http.StatusUnauthorized,
+			route:        "/auth/refresh",
+			cookie:       "refresh-token",
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				reused := activeSession
+				reused.Rotated = true
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), activeSession.TokenHash).Return(reused, nil)
+				store.EXPECT().RotateSession(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RevokeSessionFamily(gomock.Any(), "family").Return([]db.RevokeSessionFamilyRow{
+					{AccessJti: "expiredjti", AccessExpires: time.Now().Add(-time.Minute)},
+					{AccessJti: "activejti", AccessExpires: activeSession.Expires},
+				}, nil)
+				store.EXPECT().RevokeToken(gomock.Any(), db.RevokeTokenParams{Jti: "activejti", Expires: activeSession.Expires}).Return(nil)
+			},
+		},
+		{
+			name:         "refresh handler losing a concurrent rotation revokes the session family",
+			responseCode: http.StatusUnauthorized,
+			route:        "/auth/refresh",
+			cookie:       "refresh-token",
+			setupExpectations: func(claimer *security.MockClaimer, store *db.MockStore) {
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), activeSession.TokenHash).Return(activeSession, nil)
+				store.EXPECT().GetAccount(gomock.Any(), activeSession.AccountID).Return(db.Account{ID: 2, Username: "valid"}, nil)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("valid")).Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+				store.EXPECT().RotateSession(gomock.Any(), gomock.Any()).Return(db.Session{}, db.ErrSessionReused)
+				store.EXPECT().RevokeSessionFamily(gomock.Any(), "family").Return([]db.RevokeSessionFamilyRow{}, nil)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodPost, test.route, test.body)
+			if test.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: refreshTokenCookie, Value: test.cookie})
+			}
+			test.setupExpectations(mockClaimer, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			if test.rotated {
+				var response tokenResponse
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, "mocktoken", response.AccessToken)
+				assert.Equal(t, "Bearer", response.TokenType)
+				assert.NotEqual(t, "", response.RefreshToken)
+				assert.NotEqual(t, "refresh-token", response.RefreshToken)
+
+				cookies := map[string]string{}
+				for _, c := range result.Cookies() {
+					cookies[c.Name] = c.Value
+				}
+				assert.Equal(t, "mocktoken", cookies["token"])
+				assert.Equal(t, response.RefreshToken, cookies[refreshTokenCookie])
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+type updateAccountRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type accountRoleResponse struct {
+	ID   int64  `json:"id"`
+	Role string `json:"role"`
+}
+
+// requireRole only calls h when the verified token was issued to an account with role, responding with
+// status forbidden otherwise. Tokens issued to an OAuth app are refused whatever the account's role, since
+// an account authorizing an app doesn't hand it the account's role. It must be wrapped by claimsMiddleware.
+func requireRole(role string, h gin.HandlerFunc) gin.HandlerFunc {
+	return gin.HandlerFunc(func(ctx *gin.Context) {
+		claims := currentClaims(ctx)
+		if claims.ClientID != "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errors.New("tokens issued to apps can't do this")))
+			return
+		}
+		if claims.Role != role {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(fmt.Errorf("only accounts with the %s role can do this", role)))
+			return
+		}
+		h(ctx)
+	})
+}
+
+// updateAccountRoleHandler changes the role of an account. The account's access tokens keep the role
+// they were issued with until they are refreshed. Admins can't change their own role, so that there is
+// always an admin left to change it back.
+func updateAccountRoleHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("id parameter must be a valid integer"))
+		return
+	}
+	var req updateAccountRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	role, err := security.ParseRole(req.Role)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if id == currentClaims(ctx).AccountID() {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("admins can't change their own role")))
+		return
+	}
+
+	updated, err := firstly.store.UpdateAccountRole(ctx, db.UpdateAccountRoleParams{Role: role, ID: id})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if updated == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(errors.New("account not found")))
+		return
+	}
+	ctx.JSON(http.StatusOK, accountRoleResponse{ID: id, Role: role})
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestUpdateAccountRoleHandler(t *testing.T) {
+	tests := []struct {
+		name              string
+		route             string
+		body              io.Reader
+		responseCode      int
+		expectedRole      string
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:         "update role handler changes the role of another account",
+			route:        "/account/2/role",
+			body:         bytes.NewBufferString(`{"role":"read-only"}`),
+			responseCode: http.StatusOK,
+			expectedRole: security.RoleReadOnly,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+				store.EXPECT().UpdateAccountRole(gomock.Any(), db.UpdateAccountRoleParams{Role: security.RoleReadOnly, ID: 2}).Return(int64(1), nil)
+			},
+		},
+		{
+			name:         "update role handler given an unknown role responds with status bad request",
+			route:        "/account/2/role",
+			body:         bytes.NewBufferString(`{"role":"owner"}`),
+			responseCode: http.StatusBadRequest,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+				store.EXPECT().UpdateAccountRole(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update role handler won't change the admin's own role",
+			route:        "/account/1/role",
+			body:         bytes.NewBufferString(`{"role":"member"}`),
+			responseCode: http.StatusForbidden,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+				store.EXPECT().UpdateAccountRole(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update role handler given an unknown account responds with status not found",
+			route:        "/account/9/role",
+			body:         bytes.NewBufferString(`{"role":"admin"}`),
+			responseCode: http.StatusNotFound,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddlewareAs(security.RoleAdmin, r, claimer, hasher, store)
+				store.EXPECT().UpdateAccountRole(gomock.Any(), db.UpdateAccountRoleParams{Role: security.RoleAdmin, ID: 9}).Return(int64(0), nil)
+			},
+		},
+		{
+			name:         "update role handler responds with status forbidden given the account isn't an admin",
+			route:        "/account/2/role",
+			body:         bytes.NewBufferString(`{"role":"admin"}`),
+			responseCode: http.StatusForbidden,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().UpdateAccountRole(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodPut, test.route, test.body)
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			if test.expectedRole != "" {
+				var response accountRoleResponse
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.expectedRole, response.Role)
+			}
+		})
+	}
+}
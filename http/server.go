@@ -1,8 +1,13 @@
 package http
 
 import (
+	"sync"
+
 	"github.com/gin-gonic/gin"
 	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/export"
+	"github.com/meads/firstly-api/mail"
+	"github.com/meads/firstly-api/oidc"
 	"github.com/meads/firstly-api/security"
 )
 
@@ -11,6 +16,14 @@ type FirstlyServer struct {
 	hasher  security.Hasher
 	router  *gin.Engine
 	store   db.Store
+	mailer  mail.Mailer
+	oidc    *oidc.Client
+
+	phrasePolicy security.PhrasePolicy
+	dummyPhrase  *dummyPhrase
+	exporter     *export.Worker
+	// mailing waits for the emails being sent in the background
+	mailing sync.WaitGroup
 }
 
 var firstly = &FirstlyServer{}
@@ -21,24 +34,107 @@ func NewFirstlyServer(claimer security.Claimer, hasher security.Hasher, router *
 	firstly.hasher = hasher
 	firstly.router = router
 	firstly.store = store
+	firstly.mailer = mail.NewOutbox("")
+	firstly.oidc = oidc.NewClient(nil)
+	firstly.exporter = nil
+	firstly.phrasePolicy = security.DefaultPhrasePolicy
+	firstly.dummyPhrase = &dummyPhrase{}
+	// gin believes X-Forwarded-For from any peer unless told otherwise, see UseTrustedProxies
+	firstly.router.TrustedProxies = nil
 
 	firstly.router.POST("/signin/", signinHandler)
 	firstly.router.GET("/welcome/", welcomeHandler)
 	firstly.router.POST("/refresh/", refreshHandler)
+	firstly.router.POST("/auth/refresh", refreshHandler)
+	firstly.router.POST("/auth/logout", logoutHandler)
+	firstly.router.POST("/auth/mfa", verifyMFAHandler)
+	firstly.router.POST("/auth/webauthn/register/begin", claimsMiddleware(requireScope(security.ScopeAccountWrite, beginPasskeyRegistrationHandler)))
+	firstly.router.POST("/auth/webauthn/register/finish", claimsMiddleware(requireScope(security.ScopeAccountWrite, finishPasskeyRegistrationHandler)))
+	firstly.router.POST("/auth/webauthn/login/begin", beginPasskeyLoginHandler)
+	firstly.router.POST("/auth/webauthn/login/finish", finishPasskeyLoginHandler)
+	firstly.router.POST("/auth/verify-email", verifyEmailHandler)
+	firstly.router.POST("/auth/forgot", forgotPhraseHandler)
+	firstly.router.POST("/auth/reset", resetPhraseHandler)
+	firstly.router.GET("/auth/oidc", listOIDCProvidersHandler)
+	firstly.router.GET("/auth/oidc/:provider", beginOIDCLoginHandler)
+	firstly.router.GET("/auth/oidc/:provider/callback", oidcCallbackHandler)
+	firstly.router.POST("/auth/oidc/:provider/callback", oidcCallbackHandler)
+	firstly.router.POST("/auth/unlock", claimsMiddleware(requireRole(security.RoleAdmin, requireScope(security.ScopeAccountWrite, unlockLoginHandler))))
+	firstly.router.GET("/.well-known/jwks.json", jwksHandler)
+	firstly.router.GET("/u/:username", optionalClaimsMiddleware(userHandler))
+	firstly.router.GET("/u/:username/avatar", optionalClaimsMiddleware(userAvatarHandler))
+	firstly.router.GET("/account/export/:token", downloadExportHandler)
+
+	firstly.router.GET("/oauth/authorize", claimsMiddleware(authorizeHandler))
+	firstly.router.POST("/oauth/authorize", claimsMiddleware(consentHandler))
+	firstly.router.POST("/oauth/token", oauthTokenHandler)
+	firstly.router.POST("/oauth/introspect", introspectHandler)
+	firstly.router.POST("/oauth/revoke", revokeHandler)
+	firstly.router.GET("/oauth/clients", claimsMiddleware(requireScope(security.ScopeAccountRead, listOAuthClientsHandler)))
+	firstly.router.POST("/oauth/clients", claimsMiddleware(requireScope(security.ScopeAccountWrite, createOAuthClientHandler)))
+	firstly.router.DELETE("/oauth/clients/:client_id", claimsMiddleware(requireScope(security.ScopeAccountWrite, deleteOAuthClientHandler)))
 
 	firstly.router.POST("/account/", createAccountHandler)
-	firstly.router.GET("/account/", claimsMiddleware(listAccountsHandler))
-	firstly.router.PATCH("/account/", claimsMiddleware(updateAccountHandler))
-	firstly.router.DELETE("/account/:id/", claimsMiddleware(deleteAccountHandler))
+	firstly.router.GET("/account/", claimsMiddleware(requireRole(security.RoleAdmin, requireScope(security.ScopeAccountRead, listAccountsHandler))))
+	firstly.router.PATCH("/account/", claimsMiddleware(requireRole(security.RoleAdmin, requireScope(security.ScopeAccountWrite, updateAccountHandler))))
+	firstly.router.DELETE("/account/:id/", claimsMiddleware(requireRole(security.RoleAdmin, requireScope(security.ScopeAccountWrite, deleteAccountHandler))))
+	firstly.router.PUT("/account/:id/role", claimsMiddleware(requireRole(security.RoleAdmin, requireScope(security.ScopeAccountWrite, updateAccountRoleHandler))))
+	firstly.router.GET("/account/me", claimsMiddleware(requireScope(security.ScopeAccountRead, getMyAccountHandler)))
+	firstly.router.PATCH("/account/me", claimsMiddleware(requireScope(security.ScopeAccountWrite, updateMyAccountHandler)))
+	firstly.router.DELETE("/account/me", claimsMiddleware(requireScope(security.ScopeAccountWrite, deleteMyAccountHandler)))
+	firstly.router.PUT("/account/me/email", claimsMiddleware(requireScope(security.ScopeAccountWrite, updateMyEmailHandler)))
+	firstly.router.GET("/account/me/profile", claimsMiddleware(requireScope(security.ScopeAccountRead, getMyProfileHandler)))
+	firstly.router.PATCH("/account/me/profile", claimsMiddleware(requireScope(security.ScopeAccountWrite, updateMyProfileHandler)))
+	firstly.router.PUT("/account/me/profile/avatar", claimsMiddleware(requireScope(security.ScopeAccountWrite, updateMyAvatarHandler)))
+	firstly.router.DELETE("/account/me/profile/avatar", claimsMiddleware(requireScope(security.ScopeAccountWrite, deleteMyAvatarHandler)))
+	firstly.router.POST("/account/me/export", claimsMiddleware(requireScope(security.ScopeAccountWrite, requestExportHandler)))
+	firstly.router.GET("/account/me/export/:id", claimsMiddleware(requireScope(security.ScopeAccountRead, getExportHandler)))
+	firstly.router.GET("/account/me/identities", claimsMiddleware(requireScope(security.ScopeAccountRead, listIdentitiesHandler)))
+	firstly.router.POST("/account/me/identities/:provider", claimsMiddleware(requireScope(security.ScopeAccountWrite, beginLinkIdentityHandler)))
+	firstly.router.DELETE("/account/me/identities/:id", claimsMiddleware(requireScope(security.ScopeAccountWrite, unlinkIdentityHandler)))
+	firstly.router.POST("/account/me/mfa/totp", claimsMiddleware(requireScope(security.ScopeAccountWrite, enrollTOTPHandler)))
+	firstly.router.POST("/account/me/mfa/totp/confirm", claimsMiddleware(requireScope(security.ScopeAccountWrite, confirmTOTPHandler)))
+	firstly.router.DELETE("/account/me/mfa/totp", claimsMiddleware(requireScope(security.ScopeAccountWrite, disableTOTPHandler)))
+	firstly.router.GET("/account/me/passkeys", claimsMiddleware(requireScope(security.ScopeAccountRead, listPasskeysHandler)))
+	firstly.router.DELETE("/account/me/passkeys/:id", claimsMiddleware(requireScope(security.ScopeAccountWrite, deletePasskeyHandler)))
+	firstly.router.GET("/account/me/sessions", claimsMiddleware(requireScope(security.ScopeAccountRead, listSessionsHandler)))
+	firstly.router.DELETE("/account/me/sessions/:id", claimsMiddleware(requireScope(security.ScopeAccountWrite, deleteSessionHandler)))
+	firstly.router.GET("/account/me/tokens", claimsMiddleware(requireScope(security.ScopeAccountRead, listAPIKeysHandler)))
+	firstly.router.POST("/account/me/tokens", claimsMiddleware(requireScope(security.ScopeAccountWrite, createAPIKeyHandler)))
+	firstly.router.DELETE("/account/me/tokens/:id", claimsMiddleware(requireScope(security.ScopeAccountWrite, deleteAPIKeyHandler)))
 
-	firstly.router.GET("/image/", claimsMiddleware(listImagesHandler))
-	firstly.router.POST("/image/", claimsMiddleware(createImageHandler(store)))
-	firstly.router.DELETE("/image/:id/", claimsMiddleware(deleteImageHandler(store)))
-	firstly.router.PATCH("/image/", claimsMiddleware(updateImageHandler(store)))
+	firstly.router.GET("/image/", claimsMiddleware(requireScope(security.ScopeImagesRead, listImagesHandler)))
+	firstly.router.GET("/image/timeline", claimsMiddleware(requireScope(security.ScopeImagesRead, timelineHandler)))
+	firstly.router.GET("/image/:id/data", claimsMiddleware(requireScope(security.ScopeImagesRead, imageDataHandler(store))))
+	firstly.router.POST("/image/", claimsMiddleware(requireScope(security.ScopeImagesWrite, createImageHandler(store))))
+	firstly.router.DELETE("/image/:id/", claimsMiddleware(requireScope(security.ScopeImagesWrite, deleteImageHandler(store))))
+	firstly.router.PATCH("/image/", claimsMiddleware(requireScope(security.ScopeImagesWrite, updateImageHandler(store))))
 
 	return firstly
 }
 
+// UseMailer sets the mailer verification and phrase reset emails are sent with, in place of the outbox
+// that only keeps them in memory.
+func (server *FirstlyServer) UseMailer(mailer mail.Mailer) {
+	server.mailer = mailer
+}
+
+// UsePhrasePolicy sets the policy new phrases have to satisfy, in place of the default policy.
+func (server *FirstlyServer) UsePhrasePolicy(policy security.PhrasePolicy) {
+	server.phrasePolicy = policy
+}
+
+// UseExporter sets the worker that builds account exports, which is kicked as soon as an export is requested
+// rather than when it next polls.
+func (server *FirstlyServer) UseExporter(exporter *export.Worker) {
+	server.exporter = exporter
+}
+
+// UseTrustedProxies sets the proxies whose forwarded client addresses are believed, in place of none.
+func (server *FirstlyServer) UseTrustedProxies(proxies []string) {
+	server.router.TrustedProxies = proxies
+}
+
 // Start runs the Http server on the supplied address.
 func (server *FirstlyServer) Start(address string) error {
 	return server.router.Run(address)
@@ -47,3 +143,7 @@ func (server *FirstlyServer) Start(address string) error {
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+func errorCodeResponse(code string, err error) gin.H {
+	return gin.H{"error": err.Error(), "code": code}
+}
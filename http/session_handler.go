@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+// claimsKey is the context key claimsMiddleware stores the verified token claims under.
+const claimsKey = "claims"
+
+type sessionResponse struct {
+	ID       int64     `json:"id"`
+	Device   string    `json:"device"`
+	IP       string    `json:"ip"`
+	LastSeen time.Time `json:"last_seen"`
+	Created  string    `json:"created"`
+	Current  bool      `json:"current"`
+}
+
+// currentClaims returns the claims of the access token verified by claimsMiddleware.
+func currentClaims(ctx *gin.Context) *security.UsernameClaims {
+	if claims, ok := ctx.Get(claimsKey); ok {
+		return claims.(*security.UsernameClaims)
+	}
+	return security.NewUsernameClaims()
+}
+
+// revokeSessions revokes every session in the family and the access tokens issued with them.
+func revokeSessions(ctx *gin.Context, familyID string) error {
+	revoked, err := firstly.store.RevokeSessionFamily(ctx, familyID)
+	if err != nil {
+		return err
+	}
+	for _, session := range revoked {
+		if err := revokeAccessToken(ctx, session.AccessJti, session.AccessExpires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeAccountSessions revokes every session of the account and the access tokens issued with them.
+func revokeAccountSessions(ctx *gin.Context, accountID int64) error {
+	revoked, err := firstly.store.RevokeAccountSessions(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	for _, session := range revoked {
+		if err := revokeAccessToken(ctx, session.AccessJti, session.AccessExpires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeAccessToken adds the jti to the revocation list checked by claimsMiddleware until the token would
+// have expired anyway.
+func revokeAccessToken(ctx *gin.Context, jti string, expires time.Time) error {
+	if jti == "" || !expires.After(time.Now()) {
+		return nil
+	}
+	return firstly.store.RevokeToken(ctx, db.RevokeTokenParams{Jti: jti, Expires: expires})
+}
+
+// logoutHandler signs out of the current session, revoking the access token and the session of the
+// refresh token, from the request body or its cookie, when they are given, and clearing both cookies. It
+// doesn't require a valid access token so that a client whose token has expired can still sign out.
+func logoutHandler(ctx *gin.Context) {
+	var req refreshRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		if c, err := ctx.Request.Cookie(refreshTokenCookie); err == nil {
+			req.RefreshToken = c.Value
+		}
+	}
+
+	if tokenString, err := accessToken(ctx.Request); err == nil && tokenString != "" {
+		claimToken, claims, err := firstly.claimer.GetFromTokenString(tokenString)
+		if err == nil && claimToken.Valid {
+			if err := revokeAccessToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+		}
+	}
+
+	if req.RefreshToken != "" {
+		session, err := firstly.store.GetSessionByTokenHash(ctx, security.HashRefreshToken(req.RefreshToken))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if err == nil {
+			if err := revokeSessions(ctx, session.FamilyID); err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+		}
+	}
+
+	// revocations are only needed until the tokens expire
+	if err := firstly.store.DeleteExpiredRevokedTokens(ctx); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	clearAuthCookies(ctx)
+	ctx.Status(http.StatusNoContent)
+}
+
+// clearAuthCookies removes the access and refresh token cookies.
+func clearAuthCookies(ctx *gin.Context) {
+	http.SetCookie(ctx.Writer, &http.Cookie{Name: accessTokenCookie, Value: "", MaxAge: -1})
+	http.SetCookie(ctx.Writer, &http.Cookie{Name: refreshTokenCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+}
+
+// listSessionsHandler responds with the signed in account's active sessions, most recently used first.
+func listSessionsHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	sessions, err := firstly.store.ListAccountSessions(ctx, accountID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var currentHash []byte
+	if c, err := ctx.Request.Cookie(refreshTokenCookie); err == nil {
+		currentHash = security.HashRefreshToken(c.Value)
+	}
+
+	items := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, sessionResponse{
+			ID:       session.ID,
+			Device:   session.UserAgent,
+			IP:       session.IP,
+			LastSeen: session.LastSeen,
+			Created:  session.Created,
+			Current:  currentHash != nil && bytes.Equal(currentHash, session.TokenHash),
+		})
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.JSON(http.StatusOK, items)
+}
+
+// deleteSessionHandler revokes one of the signed in account's sessions.
+func deleteSessionHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("id parameter must be a valid integer"))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	session, err := firstly.store.GetAccountSession(ctx, db.GetAccountSessionParams{ID: id, AccountID: accountID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := revokeSessions(ctx, session.FamilyID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
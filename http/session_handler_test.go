@@ -0,0 +1,193 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestSessionHandlers(t *testing.T) {
+	lastSeen := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+	accessExpires := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name              string
+		method            string
+		responseCode      int
+		route             string
+		body              string
+		expectedSessions  []sessionResponse
+		clearsCookies     bool
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:          "logout handler revokes the access token and the session and clears the cookies",
+			method:        http.MethodPost,
+			responseCode:  http.StatusNoContent,
+			route:         "/auth/logout",
+			clearsCookies: true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				claims := security.NewUsernameClaims()
+				claims.Id = "mockjti"
+				claims.ExpiresAt = accessExpires.Unix()
+				claimer.EXPECT().GetFromTokenString("mocktoken").Return(&security.ClaimToken{Token: &jwt.Token{Valid: true}}, claims, nil)
+				store.EXPECT().RevokeToken(gomock.Any(), db.RevokeTokenParams{Jti: "mockjti", Expires: accessExpires}).Return(nil)
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), security.HashRefreshToken("refresh-token")).
+					Return(db.Session{ID: 1, FamilyID: "family"}, nil)
+				store.EXPECT().RevokeSessionFamily(gomock.Any(), "family").Return([]db.RevokeSessionFamilyRow{}, nil)
+				store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Return(nil)
+
+				r.AddCookie(&http.Cookie{Name: "token", Value: "mocktoken"})
+				r.AddCookie(&http.Cookie{Name: refreshTokenCookie, Value: "refresh-token"})
+			},
+		},
+		{
+			name:          "logout handler revokes the session of the refresh token given in the body",
+			method:        http.MethodPost,
+			responseCode:  http.StatusNoContent,
+			route:         "/auth/logout",
+			body:          `{"refresh_token":"body-token"}`,
+			clearsCookies: true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				claims := security.NewUsernameClaims()
+				claims.Id = "mockjti"
+				claims.ExpiresAt = accessExpires.Unix()
+				claimer.EXPECT().GetFromTokenString("mocktoken").Return(&security.ClaimToken{Token: &jwt.Token{Valid: true}}, claims, nil)
+				store.EXPECT().RevokeToken(gomock.Any(), db.RevokeTokenParams{Jti: "mockjti", Expires: accessExpires}).Return(nil)
+				store.EXPECT().GetSessionByTokenHash(gomock.Any(), security.HashRefreshToken("body-token")).
+					Return(db.Session{ID: 2, FamilyID: "app-family"}, nil)
+				store.EXPECT().RevokeSessionFamily(gomock.Any(), "app-family").Return([]db.RevokeSessionFamilyRow{}, nil)
+				store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Return(nil)
+
+				r.Header.Set("Authorization", "Bearer mocktoken")
+			},
+		},
+		{
+			name:          "logout handler given an expired access token still clears the cookies",
+			method:        http.MethodPost,
+			responseCode:  http.StatusNoContent,
+			route:         "/auth/logout",
+			clearsCookies: true,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				claimer.EXPECT().GetFromTokenString("mocktoken").
+					Return(&security.ClaimToken{Token: &jwt.Token{Valid: false}}, security.NewUsernameClaims(), &jwt.ValidationError{})
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Return(nil)
+
+				r.AddCookie(&http.Cookie{Name: "token", Value: "mocktoken"})
+			},
+		},
+		{
+			name:         "list sessions handler responds with the active sessions marking the current one",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			route:        "/account/me/sessions",
+			expectedSessions: []sessionResponse{
+				{ID: 3, Device: "phone", IP: "10.0.0.1", LastSeen: lastSeen, Created: "2022-10-29", Current: true},
+				{ID: 2, Device: "laptop", IP: "10.0.0.2", LastSeen: lastSeen.Add(-time.Hour), Created: "2022-10-28"},
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ListAccountSessions(gomock.Any(), int64(1)).Return([]db.Session{
+					{ID: 3, TokenHash: security.HashRefreshToken("refresh-token"), UserAgent: "phone", IP: "10.0.0.1", LastSeen: lastSeen, Created: "2022-10-29"},
+					{ID: 2, TokenHash: security.HashRefreshToken("other"), UserAgent: "laptop", IP: "10.0.0.2", LastSeen: lastSeen.Add(-time.Hour), Created: "2022-10-28"},
+				}, nil)
+				r.AddCookie(&http.Cookie{Name: refreshTokenCookie, Value: "refresh-token"})
+			},
+		},
+		{
+			name:         "delete session handler revokes the session and its access token",
+			method:       http.MethodDelete,
+			responseCode: http.StatusNoContent,
+			route:        "/account/me/sessions/2",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccountSession(gomock.Any(), db.GetAccountSessionParams{ID: 2, AccountID: 1}).
+					Return(db.Session{ID: 2, FamilyID: "family"}, nil)
+				store.EXPECT().RevokeSessionFamily(gomock.Any(), "family").
+					Return([]db.RevokeSessionFamilyRow{{AccessJti: "otherjti", AccessExpires: accessExpires}}, nil)
+				store.EXPECT().RevokeToken(gomock.Any(), db.RevokeTokenParams{Jti: "otherjti", Expires: accessExpires}).Return(nil)
+			},
+		},
+		{
+			name:         "delete session handler given another account's session responds with status not found",
+			method:       http.MethodDelete,
+			responseCode: http.StatusNotFound,
+			route:        "/account/me/sessions/5",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccountSession(gomock.Any(), db.GetAccountSessionParams{ID: 5, AccountID: 1}).
+					Return(db.Session{}, sql.ErrNoRows)
+				store.EXPECT().RevokeSessionFamily(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "claims middleware given a revoked access token responds with status unauthorized",
+			method:       http.MethodGet,
+			responseCode: http.StatusUnauthorized,
+			route:        "/account/me/sessions",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				claims := security.NewUsernameClaims()
+				claims.Id = "revokedjti"
+				claimer.EXPECT().GetFromTokenString("mocktoken").Return(&security.ClaimToken{Token: &jwt.Token{Valid: true}}, claims, nil)
+				store.EXPECT().IsTokenRevoked(gomock.Any(), "revokedjti").Return(true, nil)
+				store.EXPECT().ListAccountSessions(gomock.Any(), gomock.Any()).Times(0)
+				r.AddCookie(&http.Cookie{Name: "token", Value: "mocktoken"})
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(test.method, test.route, strings.NewReader(test.body))
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			if test.expectedSessions != nil {
+				var sessions []sessionResponse
+				if err := json.NewDecoder(result.Body).Decode(&sessions); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.expectedSessions, sessions)
+			}
+			if test.clearsCookies {
+				cleared := map[string]bool{}
+				for _, c := range result.Cookies() {
+					cleared[c.Name] = c.MaxAge < 0 && c.Value == ""
+				}
+				assert.Equal(t, true, cleared["token"])
+				assert.Equal(t, true, cleared[refreshTokenCookie])
+			}
+		})
+	}
+}
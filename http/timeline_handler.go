@@ -0,0 +1,111 @@
+package http
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+)
+
+// timelineSampleSize is the number of representative image ids returned with each bucket.
+const timelineSampleSize = 4
+
+// timelineLayouts maps each timeline granularity to the layout its bucket periods are formatted with.
+var timelineLayouts = map[string]string{
+	"day":   "2006-01-02",
+	"month": "2006-01",
+	"year":  "2006",
+}
+
+type timelineBucket struct {
+	Period   string  `json:"period"`
+	Start    string  `json:"start"`
+	Count    int64   `json:"count"`
+	ImageIDs []int64 `json:"image_ids"`
+}
+
+// timelineHandler responds with images grouped into day, month or year buckets of the tz time zone,
+// newest first, using the capture time of each image and falling back to when it was uploaded. Pages of
+// buckets are linked with signed cursors.
+func timelineHandler(ctx *gin.Context) {
+	granularity := ctx.DefaultQuery("granularity", "day")
+	layout, ok := timelineLayouts[granularity]
+	if !ok {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("granularity must be one of: day, month, year"))
+		return
+	}
+
+	tz := ctx.DefaultQuery("tz", "UTC")
+	location, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("tz must be an IANA time zone such as America/New_York"))
+		return
+	}
+
+	// the cursor is only valid for the granularity and time zone it was issued for
+	page, err := parsePageRequest(ctx, "timeline:"+granularity+":"+tz)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if !page.Desc {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("the timeline can only be ordered desc"))
+		return
+	}
+
+	var cursorBucket sql.NullTime
+	if page.Cursor != nil {
+		t, err := time.Parse(time.RFC3339, page.Cursor.Value)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		cursorBucket = sql.NullTime{Time: t, Valid: true}
+	}
+
+	rows, err := firstly.store.ImageTimeline(ctx, db.ImageTimelineParams{
+		Granularity:  granularity,
+		Tz:           tz,
+		SampleSize:   timelineSampleSize,
+		CursorBucket: cursorBucket,
+		Limit:        page.Limit + 1,
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var nextCursor string
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		nextCursor, err = page.nextCursor(last.Bucket.Format(time.RFC3339), 0)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		setNextLink(ctx, nextCursor)
+	}
+
+	buckets := make([]timelineBucket, 0, len(rows))
+	for _, row := range rows {
+		// buckets are the wall clock time the period starts at in tz
+		start := time.Date(row.Bucket.Year(), row.Bucket.Month(), row.Bucket.Day(), 0, 0, 0, 0, location)
+		imageIDs := row.ImageIds
+		if imageIDs == nil {
+			imageIDs = []int64{}
+		}
+		buckets = append(buckets, timelineBucket{
+			Period:   row.Bucket.Format(layout),
+			Start:    start.Format(time.RFC3339),
+			Count:    row.ImageCount,
+			ImageIDs: imageIDs,
+		})
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.JSON(http.StatusOK, pageResponse{Items: buckets, NextCursor: nextCursor})
+}
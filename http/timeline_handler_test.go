@@ -0,0 +1,162 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestTimelineHandler(t *testing.T) {
+	tests := []struct {
+		name              string
+		responseCode      int
+		route             string
+		expectedBuckets   []timelineBucket
+		nextCursor        bool
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:         "timeline handler responds with day buckets in UTC by default",
+			responseCode: http.StatusOK,
+			route:        "/image/timeline",
+			expectedBuckets: []timelineBucket{
+				{Period: "2022-10-30", Start: "2022-10-30T00:00:00Z", Count: 5, ImageIDs: []int64{9, 8, 7, 6}},
+				{Period: "2022-10-29", Start: "2022-10-29T00:00:00Z", Count: 1, ImageIDs: []int64{4}},
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				params := db.ImageTimelineParams{Granularity: "day", Tz: "UTC", SampleSize: 4, Limit: 51}
+				store.EXPECT().ImageTimeline(gomock.Any(), params).Return([]db.ImageTimelineRow{
+					{Bucket: time.Date(2022, 10, 30, 0, 0, 0, 0, time.UTC), ImageCount: 5, ImageIds: []int64{9, 8, 7, 6}},
+					{Bucket: time.Date(2022, 10, 29, 0, 0, 0, 0, time.UTC), ImageCount: 1, ImageIds: []int64{4}},
+				}, nil)
+			},
+		},
+		{
+			name:         "timeline handler responds with month buckets starting in the requested time zone",
+			responseCode: http.StatusOK,
+			route:        "/image/timeline?granularity=month&tz=America/New_York&limit=1",
+			nextCursor:   true,
+			expectedBuckets: []timelineBucket{
+				{Period: "2022-10", Start: "2022-10-01T00:00:00-04:00", Count: 12, ImageIDs: []int64{3}},
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				params := db.ImageTimelineParams{Granularity: "month", Tz: "America/New_York", SampleSize: 4, Limit: 2}
+				store.EXPECT().ImageTimeline(gomock.Any(), params).Return([]db.ImageTimelineRow{
+					{Bucket: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC), ImageCount: 12, ImageIds: []int64{3}},
+					{Bucket: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), ImageCount: 2, ImageIds: []int64{1, 2}},
+				}, nil)
+			},
+		},
+		{
+			name:            "timeline handler continues before the bucket in the cursor given a valid cursor",
+			responseCode:    http.StatusOK,
+			route:           "/image/timeline",
+			expectedBuckets: []timelineBucket{},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				page := pageRequest{Sort: "timeline:year:UTC", Desc: true}
+				cursor, _ := page.nextCursor("2021-01-01T00:00:00Z", 0)
+				r.URL.RawQuery = url.Values{"cursor": {cursor}, "granularity": {"year"}}.Encode()
+				params := db.ImageTimelineParams{
+					Granularity:  "year",
+					Tz:           "UTC",
+					SampleSize:   4,
+					CursorBucket: sql.NullTime{Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+					Limit:        51,
+				}
+				store.EXPECT().ImageTimeline(gomock.Any(), params).Return([]db.ImageTimelineRow{}, nil)
+			},
+		},
+		{
+			name:         "timeline handler responds with Status Code 400 given a cursor for another granularity",
+			responseCode: http.StatusBadRequest,
+			route:        "/image/timeline",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				os.Setenv("SECRET", "test")
+				page := pageRequest{Sort: "timeline:year:UTC", Desc: true}
+				cursor, _ := page.nextCursor("2021-01-01T00:00:00Z", 0)
+				r.URL.RawQuery = url.Values{"cursor": {cursor}, "granularity": {"day"}}.Encode()
+			},
+		},
+		{
+			name:         "timeline handler responds with Status Code 400 given an unknown granularity",
+			responseCode: http.StatusBadRequest,
+			route:        "/image/timeline?granularity=week",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			name:         "timeline handler responds with Status Code 400 given an unknown time zone",
+			responseCode: http.StatusBadRequest,
+			route:        "/image/timeline?tz=Mars/Olympus_Mons",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+			},
+		},
+		{
+			name:         "timeline handler responds with Status Code 500 given there is a server error",
+			responseCode: http.StatusInternalServerError,
+			route:        "/image/timeline",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ImageTimeline(gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			// Act
+			request := httptest.NewRequest(http.MethodGet, test.route, nil)
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			if test.expectedBuckets != nil {
+				response := struct {
+					Items      []timelineBucket `json:"items"`
+					NextCursor string           `json:"next_cursor"`
+				}{}
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.expectedBuckets, response.Items)
+				assert.Equal(t, test.nextCursor, response.NextCursor != "")
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+const (
+	defaultUsernameChangeCooldown = 30 * 24 * time.Hour
+	defaultUsernameHoldTTL        = 90 * 24 * time.Hour
+)
+
+var (
+	errUsernameChangedRecently = errors.New("username was changed recently, try again later")
+	errUnknownUsername         = errors.New("no account has that username")
+)
+
+// renameResponse is the account after its username changed, with the access token issued in place of the
+// one naming the previous username.
+type renameResponse struct {
+	accountResponse
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// usernameChangeCooldown reads how long an account has to wait between username changes from the
+// USERNAME_CHANGE_COOLDOWN env variable, a Go duration such as 720h.
+func usernameChangeCooldown() time.Duration {
+	if cooldown, err := time.ParseDuration(os.Getenv("USERNAME_CHANGE_COOLDOWN")); err == nil && cooldown >= 0 {
+		return cooldown
+	}
+	return defaultUsernameChangeCooldown
+}
+
+// usernameHoldTTL reads how long a previous username keeps resolving to its account, and can't be taken by
+// another, from the USERNAME_HOLD_TTL env variable, a Go duration such as 2160h.
+func usernameHoldTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("USERNAME_HOLD_TTL")); err == nil && ttl >= 0 {
+		return ttl
+	}
+	return defaultUsernameHoldTTL
+}
+
+// renameAccount changes the account's username, keeping the previous one for usernameHoldTTL, and
+// responds when it can't: the account changed its username within the cooldown, or another account has
+// or holds it.
+func renameAccount(ctx *gin.Context, account db.Account, username string) bool {
+	err := firstly.store.RenameAccount(ctx, db.RenameAccountParams{
+		ID:        account.ID,
+		Username:  username,
+		Cooldown:  usernameChangeCooldown(),
+		HeldUntil: time.Now().Add(usernameHoldTTL()),
+	})
+	var cooldown *db.UsernameCooldownError
+	if errors.As(err, &cooldown) {
+		wait := time.Until(cooldown.Until)
+		ctx.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errUsernameChangedRecently))
+		return false
+	}
+	if errors.Is(err, db.ErrUsernameHeld) || db.IsUniqueViolation(err, db.AccountUsernameIndex) {
+		ctx.JSON(http.StatusConflict, errorResponse(errUsernameTaken))
+		return false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	return true
+}
+
+// reissueAccessToken replaces the signed in access token, which names the account's previous username,
+// with one naming its current username. The session the token was issued with is moved onto the new
+// token so that revoking the session still revokes it, and the previous token is revoked. Access tokens
+// of the account's other sessions name the previous username until they are refreshed.
+func reissueAccessToken(ctx *gin.Context, account db.Account) (string, time.Time, error) {
+	previous := currentClaims(ctx)
+	claims, err := security.NewAccessClaims(account.ID, account.Username, account.Role)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	tokenString, expirationTime, err := firstly.claimer.GetFiveMinuteExpirationToken(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if err := firstly.store.UpdateSessionAccessToken(ctx, db.UpdateSessionAccessTokenParams{
+		AccessJti:     claims.Id,
+		AccessExpires: expirationTime,
+		AccountID:     account.ID,
+		PreviousJti:   previous.Id,
+	}); err != nil {
+		return "", time.Time{}, err
+	}
+	if err := revokeAccessToken(ctx, previous.Id, time.Unix(previous.ExpiresAt, 0)); err != nil {
+		return "", time.Time{}, err
+	}
+
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:    accessTokenCookie,
+		Value:   tokenString,
+		Expires: expirationTime,
+	})
+	return tokenString, expirationTime, nil
+}
+
+// resolveUsername returns the account named by the username param. A username given up within
+// usernameHoldTTL redirects to the same route under the account's current username, so links to it keep
+// working, and an unknown one responds with status not found.
+func resolveUsername(ctx *gin.Context) (db.Account, bool) {
+	username := ctx.Param("username")
+
+	account, err := firstly.store.GetAccountByUsername(ctx, username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return db.Account{}, false
+	}
+	if account.ID > 0 && !account.Deleted {
+		return account, true
+	}
+
+	held, err := firstly.store.GetUsernameHistory(ctx, username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return db.Account{}, false
+	}
+	if held.AccountID == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(errUnknownUsername))
+		return db.Account{}, false
+	}
+	account, err = firstly.store.GetAccount(ctx, held.AccountID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return db.Account{}, false
+	}
+	if err != nil || account.Deleted {
+		ctx.JSON(http.StatusNotFound, errorResponse(errUnknownUsername))
+		return db.Account{}, false
+	}
+
+	// the username can be taken by another account once it is no longer held, so the redirect is temporary
+	location := "/u/" + url.PathEscape(account.Username) + strings.TrimPrefix(ctx.FullPath(), "/u/:username")
+	if ctx.Request.URL.RawQuery != "" {
+		location += "?" + ctx.Request.URL.RawQuery
+	}
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.Redirect(http.StatusFound, location)
+	return db.Account{}, false
+}
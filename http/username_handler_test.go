@@ -0,0 +1,245 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+func TestUsernameHandlers(t *testing.T) {
+	account := db.Account{ID: 1, Username: "valid", Phrase: []byte("oldhash"), Salt: "salt", Role: security.RoleMember}
+	accessExpires := time.Now().Add(5 * time.Minute)
+
+	// passCurrentPhrase passes the claims middleware and the current phrase check of PATCH /account/me
+	passCurrentPhrase := func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+		passClaimsMiddleware(r, claimer, hasher, store)
+		store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+		hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "oldpass").Return(true, nil)
+	}
+
+	tests := []struct {
+		name              string
+		method            string
+		body              string
+		responseCode      int
+		route             string
+		expectedUsername  string
+		expectedToken     string
+		expectedLocation  string
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:             "update my account handler changes the username and reissues the access token",
+			method:           http.MethodPatch,
+			body:             `{"current_phrase":"oldpass","username":"Bobby"}`,
+			responseCode:     http.StatusOK,
+			route:            "/account/me",
+			expectedUsername: "Bobby",
+			expectedToken:    "renamedtoken",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passCurrentPhrase(r, claimer, hasher, store)
+				store.EXPECT().RenameAccount(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, arg db.RenameAccountParams) error {
+						assert.Equal(t, int64(1), arg.ID)
+						assert.Equal(t, "Bobby", arg.Username)
+						assert.Equal(t, defaultUsernameChangeCooldown, arg.Cooldown)
+						assert.Equal(t, true, arg.HeldUntil.After(time.Now().Add(89*24*time.Hour)))
+						return nil
+					})
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("Bobby")).Return("renamedtoken", accessExpires, nil)
+				store.EXPECT().UpdateSessionAccessToken(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, arg db.UpdateSessionAccessTokenParams) error {
+						assert.Equal(t, "mockjti", arg.PreviousJti)
+						assert.NotEqual(t, "mockjti", arg.AccessJti)
+						return nil
+					})
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:             "update my account handler lets an account take back one of its previous usernames",
+			method:           http.MethodPatch,
+			body:             `{"current_phrase":"oldpass","username":"old-name"}`,
+			responseCode:     http.StatusOK,
+			route:            "/account/me",
+			expectedUsername: "old-name",
+			expectedToken:    "renamedtoken",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passCurrentPhrase(r, claimer, hasher, store)
+				store.EXPECT().RenameAccount(gomock.Any(), gomock.Any()).Return(nil)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("old-name")).Return("renamedtoken", accessExpires, nil)
+				store.EXPECT().UpdateSessionAccessToken(gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:         "update my account handler changes the username and the phrase, checking the phrase against the new username",
+			method:       http.MethodPatch,
+			body:         `{"current_phrase":"oldpass","username":"lantern","phrase":"paper lantern message"}`,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passCurrentPhrase(r, claimer, hasher, store)
+				store.EXPECT().RenameAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update my account handler responds with Status Code 429 given the username was changed within the cooldown",
+			method:       http.MethodPatch,
+			body:         `{"current_phrase":"oldpass","username":"Bobby"}`,
+			responseCode: http.StatusTooManyRequests,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passCurrentPhrase(r, claimer, hasher, store)
+				store.EXPECT().RenameAccount(gomock.Any(), gomock.Any()).
+					Return(&db.UsernameCooldownError{Until: time.Now().Add(29 * 24 * time.Hour)})
+				claimer.EXPECT().GetFiveMinuteExpirationToken(gomock.Any()).Times(0)
+				store.EXPECT().UpdateAccountCredentials(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update my account handler responds with Status Code 409 given another account has the username",
+			method:       http.MethodPatch,
+			body:         `{"current_phrase":"oldpass","username":"Bobby"}`,
+			responseCode: http.StatusConflict,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passCurrentPhrase(r, claimer, hasher, store)
+				store.EXPECT().RenameAccount(gomock.Any(), gomock.Any()).
+					Return(&pq.Error{Code: "23505", Constraint: db.AccountUsernameIndex})
+			},
+		},
+		{
+			name:         "update my account handler responds with Status Code 409 given another account recently gave up the username",
+			method:       http.MethodPatch,
+			body:         `{"current_phrase":"oldpass","username":"Bobby"}`,
+			responseCode: http.StatusConflict,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passCurrentPhrase(r, claimer, hasher, store)
+				store.EXPECT().RenameAccount(gomock.Any(), gomock.Any()).Return(db.ErrUsernameHeld)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update my account handler responds with Status Code 400 given a username that isn't valid",
+			method:       http.MethodPatch,
+			body:         `{"current_phrase":"oldpass","username":"-bob"}`,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passCurrentPhrase(r, claimer, hasher, store)
+				store.EXPECT().RenameAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "update my account handler responds with Status Code 400 given neither a phrase nor a username",
+			method:       http.MethodPatch,
+			body:         `{"current_phrase":"oldpass"}`,
+			responseCode: http.StatusBadRequest,
+			route:        "/account/me",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:             "user handler responds with the account named by the username",
+			method:           http.MethodGet,
+			responseCode:     http.StatusOK,
+			route:            "/u/BOBBY",
+			expectedUsername: "bobby",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "BOBBY").Return(db.Account{ID: 2, Username: "bobby"}, nil)
+				store.EXPECT().GetProfile(gomock.Any(), int64(2)).Return(db.GetProfileRow{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:             "user handler redirects a previous username to the current one",
+			method:           http.MethodGet,
+			responseCode:     http.StatusFound,
+			route:            "/u/valid",
+			expectedLocation: "/u/Bobby",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetUsernameHistory(gomock.Any(), "valid").Return(db.UsernameHistory{AccountID: 1, Username: "valid"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Username: "Bobby"}, nil)
+			},
+		},
+		{
+			name:         "user handler responds with Status Code 404 given a previous username of a deleted account",
+			method:       http.MethodGet,
+			responseCode: http.StatusNotFound,
+			route:        "/u/valid",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetUsernameHistory(gomock.Any(), "valid").Return(db.UsernameHistory{AccountID: 1, Username: "valid"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Username: "Bobby", Deleted: true}, nil)
+			},
+		},
+		{
+			name:         "user handler responds with Status Code 404 given a username no account has",
+			method:       http.MethodGet,
+			responseCode: http.StatusNotFound,
+			route:        "/u/nobody",
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "nobody").Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetUsernameHistory(gomock.Any(), "nobody").Return(db.UsernameHistory{}, sql.ErrNoRows)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockStore := db.NewMockStore(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockClaimer := security.NewMockClaimer(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(test.method, test.route, bytes.NewBufferString(test.body))
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			assert.Equal(t, test.responseCode, result.StatusCode)
+			if test.expectedLocation != "" {
+				assert.Equal(t, test.expectedLocation, result.Header.Get("Location"))
+			}
+			if test.expectedUsername != "" {
+				response := struct {
+					Username    string `json:"username"`
+					AccessToken string `json:"access_token"`
+				}{}
+				decodeJSON(t, result.Body, &response)
+				assert.Equal(t, test.expectedUsername, response.Username)
+				assert.Equal(t, test.expectedToken, response.AccessToken)
+			}
+			if test.expectedToken != "" {
+				var cookie string
+				for _, c := range result.Cookies() {
+					if c.Name == accessTokenCookie {
+						cookie = c.Value
+					}
+				}
+				assert.Equal(t, test.expectedToken, cookie)
+			}
+		})
+	}
+}
@@ -0,0 +1,547 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+const (
+	defaultWebAuthnRPID   = "localhost"
+	defaultWebAuthnRPName = "Firstly"
+	defaultWebAuthnOrigin = "http://localhost:5000"
+
+	// webauthnTimeout is how long the user has to complete a ceremony
+	webauthnTimeout = 5 * time.Minute
+
+	// ceremonies challenges are issued for
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+var (
+	errInvalidChallenge    = errors.New("challenge is unknown or has expired, start again")
+	errUnknownPasskey      = errors.New("passkey isn't registered")
+	errPasskeyRegistered   = errors.New("passkey is already registered")
+	errPasskeyWrongAccount = errors.New("passkey belongs to another account")
+)
+
+// webauthnConfig is the relying party passkeys are created for, and the origin the ceremonies run in.
+type webauthnConfig struct {
+	RPID   string
+	RPName string
+	Origin string
+}
+
+// loadWebAuthnConfig reads the relying party from the WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and WEBAUTHN_ORIGIN
+// env variables.
+func loadWebAuthnConfig() webauthnConfig {
+	config := webauthnConfig{
+		RPID:   defaultWebAuthnRPID,
+		RPName: defaultWebAuthnRPName,
+		Origin: defaultWebAuthnOrigin,
+	}
+	if rpID := os.Getenv("WEBAUTHN_RP_ID"); rpID != "" {
+		config.RPID = rpID
+	}
+	if rpName := os.Getenv("WEBAUTHN_RP_NAME"); rpName != "" {
+		config.RPName = rpName
+	}
+	if origin := os.Getenv("WEBAUTHN_ORIGIN"); origin != "" {
+		config.Origin = origin
+	}
+	return config
+}
+
+// The WebAuthn types follow the JSON browsers produce and consume, so they are camel case unlike the rest
+// of the API, and binary values are base64url encoded.
+
+type publicKeyCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type publicKeyCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type relyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type webauthnUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey string `json:"residentKey"`
+	// RequireResidentKey is the older spelling of ResidentKey required, for browsers that don't know it
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+type creationOptions struct {
+	Challenge              string                          `json:"challenge"`
+	RP                     relyingParty                    `json:"rp"`
+	User                   webauthnUser                    `json:"user"`
+	PubKeyCredParams       []publicKeyCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                           `json:"timeout"`
+	Attestation            string                          `json:"attestation"`
+	ExcludeCredentials     []publicKeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection          `json:"authenticatorSelection"`
+}
+
+type requestOptions struct {
+	Challenge        string                          `json:"challenge"`
+	RPID             string                          `json:"rpId"`
+	Timeout          int64                           `json:"timeout"`
+	UserVerification string                          `json:"userVerification"`
+	AllowCredentials []publicKeyCredentialDescriptor `json:"allowCredentials"`
+}
+
+type creationOptionsResponse struct {
+	PublicKey creationOptions `json:"publicKey"`
+}
+
+type requestOptionsResponse struct {
+	PublicKey requestOptions `json:"publicKey"`
+}
+
+type authenticatorResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AttestationObject string `json:"attestationObject"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"userHandle"`
+}
+
+type publicKeyCredential struct {
+	ID       string                `json:"id"`
+	RawID    string                `json:"rawId" binding:"required"`
+	Type     string                `json:"type"`
+	Response authenticatorResponse `json:"response" binding:"required"`
+}
+
+type beginPasskeyRegistrationRequest struct {
+	CurrentPhrase string `json:"current_phrase" binding:"required"`
+}
+
+type finishPasskeyRegistrationRequest struct {
+	Name       string              `json:"name" binding:"required"`
+	Credential publicKeyCredential `json:"credential" binding:"required"`
+}
+
+type beginPasskeyLoginRequest struct {
+	// Username is optional, given one only the account's passkeys can answer the challenge
+	Username string `json:"username"`
+}
+
+type finishPasskeyLoginRequest struct {
+	Credential publicKeyCredential `json:"credential" binding:"required"`
+	// TokenInBody asks for the tokens in the response body, for clients that can't use cookies
+	TokenInBody bool `json:"token_in_body"`
+}
+
+type passkeyResponse struct {
+	ID       int64      `json:"id"`
+	Name     string     `json:"name"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+func newPasskeyResponse(credential db.WebauthnCredential) passkeyResponse {
+	response := passkeyResponse{ID: credential.ID, Name: credential.Name, Created: credential.Created}
+	if credential.LastUsed.Valid {
+		response.LastUsed = &credential.LastUsed.Time
+	}
+	return response
+}
+
+// webauthnUserHandle is the user id passkeys are created with, which identifies the account they sign in
+// to.
+func webauthnUserHandle(accountID int64) []byte {
+	return []byte(strconv.FormatInt(accountID, 10))
+}
+
+// passkeyDescriptors returns the descriptors of an account's passkeys.
+func passkeyDescriptors(credentials []db.WebauthnCredential) []publicKeyCredentialDescriptor {
+	descriptors := make([]publicKeyCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, publicKeyCredentialDescriptor{Type: "public-key", ID: security.EncodeWebAuthnBase64(credential.CredentialID)})
+	}
+	return descriptors
+}
+
+// issueChallenge stores a new challenge for the ceremony, for accountID when it isn't 0.
+func issueChallenge(ctx *gin.Context, ceremony string, accountID int64) ([]byte, error) {
+	challenge, err := security.NewWebAuthnChallenge()
+	if err != nil {
+		return nil, err
+	}
+	err = firstly.store.CreateWebauthnChallenge(ctx, db.CreateWebauthnChallengeParams{
+		Challenge: challenge,
+		AccountID: sql.NullInt64{Int64: accountID, Valid: accountID != 0},
+		Ceremony:  ceremony,
+		Expires:   time.Now().Add(webauthnTimeout),
+	})
+	return challenge, err
+}
+
+// consumeChallenge checks the client data of the ceremony came from the configured origin and was signed
+// for a challenge that was issued and not yet used, returning the account the challenge was issued for.
+func consumeChallenge(ctx *gin.Context, config webauthnConfig, ceremony string, typ string, clientDataJSON []byte) (sql.NullInt64, error) {
+	challenge, err := security.ParseClientData(clientDataJSON, typ, config.Origin)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	accountID, err := firstly.store.ConsumeWebauthnChallenge(ctx, db.ConsumeWebauthnChallengeParams{Challenge: challenge, Ceremony: ceremony})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.NullInt64{}, errInvalidChallenge
+		}
+		return sql.NullInt64{}, err
+	}
+	return accountID, nil
+}
+
+// isWebAuthnClientError reports whether err is the fault of the response rather than of the server.
+func isWebAuthnClientError(err error) bool {
+	return errors.Is(err, security.ErrInvalidWebAuthn) || errors.Is(err, errInvalidChallenge)
+}
+
+// beginPasskeyRegistrationHandler starts registering a passkey for the signed in account, responding with
+// the options for navigator.credentials.create. It needs the current phrase so that a stolen token can't
+// be used to add a way into the account.
+func beginPasskeyRegistrationHandler(ctx *gin.Context) {
+	var req beginPasskeyRegistrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	account, err := firstly.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	valid, err := firstly.hasher.IsValidPassword(account.Phrase, account.Salt, req.CurrentPhrase)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("current phrase is incorrect")))
+		return
+	}
+
+	credentials, err := firstly.store.ListAccountWebauthnCredentials(ctx, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	challenge, err := issueChallenge(ctx, ceremonyRegister, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	config := loadWebAuthnConfig()
+	params := make([]publicKeyCredentialParameters, 0, len(security.WebAuthnAlgorithms))
+	for _, alg := range security.WebAuthnAlgorithms {
+		params = append(params, publicKeyCredentialParameters{Type: "public-key", Alg: alg})
+	}
+	ctx.JSON(http.StatusOK, creationOptionsResponse{PublicKey: creationOptions{
+		Challenge: security.EncodeWebAuthnBase64(challenge),
+		RP:        relyingParty{ID: config.RPID, Name: config.RPName},
+		User: webauthnUser{
+			ID:          security.EncodeWebAuthnBase64(webauthnUserHandle(account.ID)),
+			Name:        account.Username,
+			DisplayName: account.Username,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            webauthnTimeout.Milliseconds(),
+		Attestation:        "none",
+		ExcludeCredentials: passkeyDescriptors(credentials),
+		// signing in never lists the account's passkeys, so they have to be discoverable
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+	}})
+}
+
+// finishPasskeyRegistrationHandler stores the passkey created for the challenge issued by
+// beginPasskeyRegistrationHandler.
+func finishPasskeyRegistrationHandler(ctx *gin.Context) {
+	var req finishPasskeyRegistrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	config := loadWebAuthnConfig()
+	clientDataJSON, err := security.DecodeWebAuthnBase64(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	attestation, err := security.DecodeWebAuthnBase64(req.Credential.Response.AttestationObject)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	challengeAccount, err := consumeChallenge(ctx, config, ceremonyRegister, security.WebAuthnCreate, clientDataJSON)
+	if err != nil {
+		if isWebAuthnClientError(err) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if challengeAccount.Int64 != accountID {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidChallenge))
+		return
+	}
+	credential, err := security.VerifyAttestation(config.RPID, attestation)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// credential ids are unique, so a passkey can't be registered to two accounts
+	if _, err := firstly.store.GetWebauthnCredential(ctx, credential.ID); err == nil {
+		ctx.JSON(http.StatusConflict, errorResponse(errPasskeyRegistered))
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	stored, err := firstly.store.CreateWebauthnCredential(ctx, db.CreateWebauthnCredentialParams{
+		AccountID:    accountID,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+		Name:         req.Name,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusCreated, newPasskeyResponse(stored))
+}
+
+// beginPasskeyLoginHandler starts signing in with a passkey, responding with the options for
+// navigator.credentials.get. The options never list any passkeys, so the authenticator offers whichever
+// discoverable passkeys it has for the site, and the response is the same whether or not the username
+// belongs to an account.
+func beginPasskeyLoginHandler(ctx *gin.Context) {
+	// the body is optional
+	var req beginPasskeyLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var accountID int64
+	if req.Username != "" {
+		account, err := firstly.store.GetAccountByUsername(ctx, req.Username)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		accountID = account.ID
+	}
+
+	challenge, err := issueChallenge(ctx, ceremonyLogin, accountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	config := loadWebAuthnConfig()
+	ctx.JSON(http.StatusOK, requestOptionsResponse{PublicKey: requestOptions{
+		Challenge:        security.EncodeWebAuthnBase64(challenge),
+		RPID:             config.RPID,
+		Timeout:          webauthnTimeout.Milliseconds(),
+		UserVerification: "required",
+		AllowCredentials: []publicKeyCredentialDescriptor{},
+	}})
+}
+
+// finishPasskeyLoginHandler signs in to the account of the passkey that signed the challenge issued by
+// beginPasskeyLoginHandler. A verified passkey is itself two factors, so no TOTP code is asked for.
+// Attempts are recorded and throttled like signing in with a phrase, under the username of the passkey's
+// account.
+func finishPasskeyLoginHandler(ctx *gin.Context) {
+	var req finishPasskeyLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	config := loadWebAuthnConfig()
+	response := req.Credential.Response
+	credentialID, err := security.DecodeWebAuthnBase64(req.Credential.RawID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	clientDataJSON, err := security.DecodeWebAuthnBase64(response.ClientDataJSON)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	authenticatorData, err := security.DecodeWebAuthnBase64(response.AuthenticatorData)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	signature, err := security.DecodeWebAuthnBase64(response.Signature)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	userHandle, err := security.DecodeWebAuthnBase64(response.UserHandle)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challengeAccount, err := consumeChallenge(ctx, config, ceremonyLogin, security.WebAuthnGet, clientDataJSON)
+	if err != nil {
+		if isWebAuthnClientError(err) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	credential, err := firstly.store.GetWebauthnCredential(ctx, credentialID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// there's no username to record against, but it still counts towards the IP's failures
+			recordLoginAttempt(ctx, "", loginUnknownPasskey)
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errUnknownPasskey))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	account, err := firstly.store.GetAccount(ctx, credential.AccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	wait, err := loginRetryAfter(ctx, account.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if wait > 0 {
+		recordLoginAttempt(ctx, account.Username, loginThrottled)
+		abortTooManyAttempts(ctx, wait)
+		return
+	}
+
+	if (challengeAccount.Valid && challengeAccount.Int64 != credential.AccountID) ||
+		(len(userHandle) > 0 && !bytes.Equal(userHandle, webauthnUserHandle(credential.AccountID))) {
+		recordLoginAttempt(ctx, account.Username, loginWrongPasskey)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errPasskeyWrongAccount))
+		return
+	}
+
+	signCount, err := security.VerifyAssertion(config.RPID, credential.PublicKey, uint32(credential.SignCount), authenticatorData, clientDataJSON, signature)
+	if err != nil {
+		recordLoginAttempt(ctx, account.Username, loginWrongPasskey)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if err := firstly.store.UpdateWebauthnCredentialSignCount(ctx, db.UpdateWebauthnCredentialSignCountParams{
+		SignCount: int64(signCount),
+		ID:        credential.ID,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	recordLoginAttempt(ctx, account.Username, loginSucceeded)
+
+	completeSignin(ctx, account, req.TokenInBody)
+}
+
+// listPasskeysHandler responds with the signed in account's passkeys, newest first.
+func listPasskeysHandler(ctx *gin.Context) {
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	credentials, err := firstly.store.ListAccountWebauthnCredentials(ctx, accountID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	items := make([]passkeyResponse, 0, len(credentials))
+	for _, credential := range credentials {
+		items = append(items, newPasskeyResponse(credential))
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
+	ctx.JSON(http.StatusOK, items)
+}
+
+// deletePasskeyHandler deletes one of the signed in account's passkeys, after which it can't sign in.
+func deletePasskeyHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("id parameter must be a valid integer"))
+		return
+	}
+	accountID := currentClaims(ctx).AccountID()
+	if accountID == 0 {
+		ctx.AbortWithError(http.StatusUnauthorized, errors.New("token doesn't identify an account, sign in again"))
+		return
+	}
+
+	deleted, err := firstly.store.DeleteAccountWebauthnCredential(ctx, db.DeleteAccountWebauthnCredentialParams{ID: id, AccountID: accountID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if deleted == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
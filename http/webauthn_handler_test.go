@@ -0,0 +1,406 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+// registrationBody returns the body of finishing registration with the authenticator's response to
+// challenge.
+func registrationBody(t *testing.T, authenticator *security.SoftAuthenticator, challenge []byte) []byte {
+	clientDataJSON, attestation, err := authenticator.Create(challenge)
+	if err != nil {
+		t.Fatalf("Error creating passkey: %v", err)
+	}
+	body, _ := json.Marshal(finishPasskeyRegistrationRequest{
+		Name: "phone",
+		Credential: publicKeyCredential{
+			ID:    security.EncodeWebAuthnBase64(authenticator.CredentialID),
+			RawID: security.EncodeWebAuthnBase64(authenticator.CredentialID),
+			Type:  "public-key",
+			Response: authenticatorResponse{
+				ClientDataJSON:    security.EncodeWebAuthnBase64(clientDataJSON),
+				AttestationObject: security.EncodeWebAuthnBase64(attestation),
+			},
+		},
+	})
+	return body
+}
+
+// assertionBody returns the body of finishing signing in with the authenticator's response to challenge.
+func assertionBody(t *testing.T, authenticator *security.SoftAuthenticator, challenge []byte) []byte {
+	clientDataJSON, authData, signature, err := authenticator.Get(challenge)
+	if err != nil {
+		t.Fatalf("Error signing with passkey: %v", err)
+	}
+	body, _ := json.Marshal(finishPasskeyLoginRequest{
+		Credential: publicKeyCredential{
+			ID:    security.EncodeWebAuthnBase64(authenticator.CredentialID),
+			RawID: security.EncodeWebAuthnBase64(authenticator.CredentialID),
+			Type:  "public-key",
+			Response: authenticatorResponse{
+				ClientDataJSON:    security.EncodeWebAuthnBase64(clientDataJSON),
+				AuthenticatorData: security.EncodeWebAuthnBase64(authData),
+				Signature:         security.EncodeWebAuthnBase64(signature),
+				UserHandle:        security.EncodeWebAuthnBase64(webauthnUserHandle(1)),
+			},
+		},
+		TokenInBody: true,
+	})
+	return body
+}
+
+func TestWebAuthnHandlers(t *testing.T) {
+	authenticator, err := security.NewSoftAuthenticator(defaultWebAuthnRPID, defaultWebAuthnOrigin)
+	if err != nil {
+		t.Fatalf("Error creating authenticator: %v", err)
+	}
+	phishing, _ := security.NewSoftAuthenticator(defaultWebAuthnRPID, "https://firstly.example.net")
+	challenge, _ := security.NewWebAuthnChallenge()
+	account := db.Account{ID: 1, Username: "valid", Phrase: []byte("valid"), Salt: "salt", Role: security.RoleMember}
+	created := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+
+	registration := registrationBody(t, authenticator, challenge)
+	assertion := assertionBody(t, authenticator, challenge)
+	phished := assertionBody(t, phishing, challenge)
+	// the credential as it is stored once registered
+	stored := db.WebauthnCredential{ID: 3, AccountID: 1, CredentialID: authenticator.CredentialID, PublicKey: authenticator.PublicKey(), Name: "phone", Created: created}
+
+	tests := []struct {
+		name              string
+		method            string
+		route             string
+		body              []byte
+		responseCode      int
+		expectedError     string
+		assertBody        func(t *testing.T, body io.Reader)
+		setupExpectations func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore)
+	}{
+		{
+			name:         "begin passkey registration handler responds with creation options for the account",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/register/begin",
+			body:         []byte(`{"current_phrase":"valid"}`),
+			responseCode: http.StatusOK,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response creationOptionsResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, defaultWebAuthnRPID, response.PublicKey.RP.ID)
+				assert.Equal(t, "valid", response.PublicKey.User.Name)
+				assert.Equal(t, security.EncodeWebAuthnBase64(webauthnUserHandle(1)), response.PublicKey.User.ID)
+				assert.Equal(t, "required", response.PublicKey.AuthenticatorSelection.UserVerification)
+				assert.Equal(t, 1, len(response.PublicKey.ExcludeCredentials))
+				assert.NotEqual(t, "", response.PublicKey.Challenge)
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "valid").Return(true, nil)
+				store.EXPECT().ListAccountWebauthnCredentials(gomock.Any(), int64(1)).Return([]db.WebauthnCredential{stored}, nil)
+				store.EXPECT().CreateWebauthnChallenge(gomock.Any(), gomock.AssignableToTypeOf(db.CreateWebauthnChallengeParams{})).
+					DoAndReturn(func(_ interface{}, arg db.CreateWebauthnChallengeParams) error {
+						assert.Equal(t, ceremonyRegister, arg.Ceremony)
+						assert.Equal(t, sql.NullInt64{Int64: 1, Valid: true}, arg.AccountID)
+						assert.Equal(t, 32, len(arg.Challenge))
+						return nil
+					})
+			},
+		},
+		{
+			name:         "begin passkey registration handler given a wrong phrase responds with status forbidden",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/register/begin",
+			body:         []byte(`{"current_phrase":"invalid"}`),
+			responseCode: http.StatusForbidden,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				hasher.EXPECT().IsValidPassword(account.Phrase, account.Salt, "invalid").Return(false, nil)
+				store.EXPECT().CreateWebauthnChallenge(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "finish passkey registration handler stores the passkey created by the authenticator",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/register/finish",
+			body:         registration,
+			responseCode: http.StatusCreated,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), db.ConsumeWebauthnChallengeParams{Challenge: challenge, Ceremony: ceremonyRegister}).
+					Return(sql.NullInt64{Int64: 1, Valid: true}, nil)
+				store.EXPECT().GetWebauthnCredential(gomock.Any(), authenticator.CredentialID).Return(db.WebauthnCredential{}, sql.ErrNoRows)
+				store.EXPECT().CreateWebauthnCredential(gomock.Any(), db.CreateWebauthnCredentialParams{
+					AccountID:    1,
+					CredentialID: authenticator.CredentialID,
+					PublicKey:    authenticator.PublicKey(),
+					Name:         "phone",
+				}).Return(stored, nil)
+			},
+		},
+		{
+			name:          "finish passkey registration handler given a challenge that wasn't issued responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/auth/webauthn/register/finish",
+			body:          registration,
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidChallenge.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), gomock.Any()).Return(sql.NullInt64{}, sql.ErrNoRows)
+				store.EXPECT().CreateWebauthnCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "finish passkey registration handler given a challenge issued to another account responds with status bad request",
+			method:        http.MethodPost,
+			route:         "/auth/webauthn/register/finish",
+			body:          registration,
+			responseCode:  http.StatusBadRequest,
+			expectedError: errInvalidChallenge.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), gomock.Any()).Return(sql.NullInt64{Int64: 2, Valid: true}, nil)
+				store.EXPECT().CreateWebauthnCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "begin passkey login handler without a username responds with options for any passkey",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/login/begin",
+			responseCode: http.StatusOK,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response requestOptionsResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, defaultWebAuthnRPID, response.PublicKey.RPID)
+				assert.Equal(t, 0, len(response.PublicKey.AllowCredentials))
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().CreateWebauthnChallenge(gomock.Any(), gomock.AssignableToTypeOf(db.CreateWebauthnChallengeParams{})).
+					DoAndReturn(func(_ interface{}, arg db.CreateWebauthnChallengeParams) error {
+						assert.Equal(t, ceremonyLogin, arg.Ceremony)
+						assert.Equal(t, false, arg.AccountID.Valid)
+						return nil
+					})
+			},
+		},
+		{
+			name:         "begin passkey login handler given an unknown username responds with options for no passkeys",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/login/begin",
+			body:         []byte(`{"username":"unknown"}`),
+			responseCode: http.StatusOK,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "unknown").Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CreateWebauthnChallenge(gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:         "begin passkey login handler given a username with passkeys responds with options for no passkeys",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/login/begin",
+			body:         []byte(`{"username":"valid"}`),
+			responseCode: http.StatusOK,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response requestOptionsResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, []publicKeyCredentialDescriptor{}, response.PublicKey.AllowCredentials)
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().GetAccountByUsername(gomock.Any(), "valid").Return(account, nil)
+				store.EXPECT().ListAccountWebauthnCredentials(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateWebauthnChallenge(gomock.Any(), gomock.AssignableToTypeOf(db.CreateWebauthnChallengeParams{})).
+					DoAndReturn(func(_ interface{}, arg db.CreateWebauthnChallengeParams) error {
+						assert.Equal(t, sql.NullInt64{Int64: 1, Valid: true}, arg.AccountID)
+						return nil
+					})
+			},
+		},
+		{
+			name:         "finish passkey login handler given a signature by a registered passkey signs in",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/login/finish",
+			body:         assertion,
+			responseCode: http.StatusOK,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response tokenResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, "mocktoken", response.AccessToken)
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), db.ConsumeWebauthnChallengeParams{Challenge: challenge, Ceremony: ceremonyLogin}).
+					Return(sql.NullInt64{}, nil)
+				store.EXPECT().GetWebauthnCredential(gomock.Any(), authenticator.CredentialID).Return(stored, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				allowLoginAttempt(store, "valid")
+				store.EXPECT().UpdateWebauthnCredentialSignCount(gomock.Any(), db.UpdateWebauthnCredentialSignCountParams{SignCount: 1, ID: 3}).Return(nil)
+				expectLoginAttempt(store, "valid", loginSucceeded)
+				claimer.EXPECT().GetFiveMinuteExpirationToken(claimsFor("valid")).
+					Return("mocktoken", time.Now().Add(5*time.Minute), nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Return(db.Session{ID: 1, Expires: time.Now().Add(time.Hour)}, nil)
+			},
+		},
+		{
+			name:         "finish passkey login handler given a sign count that didn't increase responds with status unauthorized",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/login/finish",
+			body:         assertion,
+			responseCode: http.StatusUnauthorized,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), gomock.Any()).Return(sql.NullInt64{}, nil)
+				used := stored
+				used.SignCount = 1
+				store.EXPECT().GetWebauthnCredential(gomock.Any(), authenticator.CredentialID).Return(used, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				allowLoginAttempt(store, "valid")
+				expectLoginAttempt(store, "valid", loginWrongPasskey)
+				store.EXPECT().UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "finish passkey login handler given the account is locked out responds with status too many requests",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/login/finish",
+			body:         assertion,
+			responseCode: http.StatusTooManyRequests,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), gomock.Any()).Return(sql.NullInt64{}, nil)
+				store.EXPECT().GetWebauthnCredential(gomock.Any(), authenticator.CredentialID).Return(stored, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				store.EXPECT().CountUsernameLoginFailures(gomock.Any(), usernameFailuresSince("valid")).
+					Return(db.CountUsernameLoginFailuresRow{Failures: defaultLoginMaxFailures, LastFailure: sql.NullTime{Time: time.Now(), Valid: true}}, nil)
+				store.EXPECT().CountIPLoginFailures(gomock.Any(), gomock.Any()).Return(db.CountIPLoginFailuresRow{}, nil)
+				expectLoginAttempt(store, "valid", loginThrottled)
+				store.EXPECT().UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "finish passkey login handler given a response from another origin responds with status unauthorized",
+			method:       http.MethodPost,
+			route:        "/auth/webauthn/login/finish",
+			body:         phished,
+			responseCode: http.StatusUnauthorized,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:          "finish passkey login handler given an unregistered passkey responds with status unauthorized",
+			method:        http.MethodPost,
+			route:         "/auth/webauthn/login/finish",
+			body:          assertion,
+			responseCode:  http.StatusUnauthorized,
+			expectedError: errUnknownPasskey.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), gomock.Any()).Return(sql.NullInt64{}, nil)
+				store.EXPECT().GetWebauthnCredential(gomock.Any(), gomock.Any()).Return(db.WebauthnCredential{}, sql.ErrNoRows)
+				expectLoginAttempt(store, "", loginUnknownPasskey)
+			},
+		},
+		{
+			name:          "finish passkey login handler given a challenge issued for another account responds with status unauthorized",
+			method:        http.MethodPost,
+			route:         "/auth/webauthn/login/finish",
+			body:          assertion,
+			responseCode:  http.StatusUnauthorized,
+			expectedError: errPasskeyWrongAccount.Error(),
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				store.EXPECT().ConsumeWebauthnChallenge(gomock.Any(), gomock.Any()).Return(sql.NullInt64{Int64: 2, Valid: true}, nil)
+				store.EXPECT().GetWebauthnCredential(gomock.Any(), gomock.Any()).Return(stored, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(account, nil)
+				allowLoginAttempt(store, "valid")
+				expectLoginAttempt(store, "valid", loginWrongPasskey)
+				store.EXPECT().UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:         "list passkeys handler responds with the account's passkeys",
+			method:       http.MethodGet,
+			route:        "/account/me/passkeys",
+			responseCode: http.StatusOK,
+			assertBody: func(t *testing.T, body io.Reader) {
+				var response []passkeyResponse
+				if err := json.NewDecoder(body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, []passkeyResponse{{ID: 3, Name: "phone", Created: created}}, response)
+			},
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().ListAccountWebauthnCredentials(gomock.Any(), int64(1)).Return([]db.WebauthnCredential{stored}, nil)
+			},
+		},
+		{
+			name:         "delete passkey handler given another account's passkey responds with status not found",
+			method:       http.MethodDelete,
+			route:        "/account/me/passkeys/4",
+			responseCode: http.StatusNotFound,
+			setupExpectations: func(r *http.Request, claimer *security.MockClaimer, hasher *security.MockHasher, store *db.MockStore) {
+				passClaimsMiddleware(r, claimer, hasher, store)
+				store.EXPECT().DeleteAccountWebauthnCredential(gomock.Any(), db.DeleteAccountWebauthnCredentialParams{ID: 4, AccountID: 1}).Return(int64(0), nil)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			router := gin.Default()
+			gin.SetMode(gin.TestMode)
+			ctrl := gomock.NewController(t)
+
+			mockClaimer := security.NewMockClaimer(ctrl)
+			mockHasher := security.NewMockHasher(ctrl)
+			mockStore := db.NewMockStore(ctrl)
+
+			NewFirstlyServer(mockClaimer, mockHasher, router, mockStore)
+			responseRecorder := httptest.NewRecorder()
+
+			request := httptest.NewRequest(test.method, test.route, bytes.NewReader(test.body))
+			test.setupExpectations(request, mockClaimer, mockHasher, mockStore)
+
+			// Act
+			router.ServeHTTP(responseRecorder, request)
+
+			result := responseRecorder.Result()
+			defer result.Body.Close()
+
+			// Assert
+			assert.Equal(t, test.responseCode, result.StatusCode)
+
+			if test.expectedError != "" {
+				var response map[string]string
+				if err := json.NewDecoder(result.Body).Decode(&response); err != nil {
+					t.Fatalf("Error decoding response body: %v", err)
+				}
+				assert.Equal(t, test.expectedError, response["error"])
+			}
+			if test.assertBody != nil {
+				test.assertBody(t, result.Body)
+			}
+		})
+	}
+}
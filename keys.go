@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/security"
+)
+
+const keysUsage = `usage: firstly-api keys <command>
+
+commands:
+  status        list the active keys and how many accounts' phrases still depend on each
+  retire <id>   print the SECRET_KEYS value to set once no phrase depends on key <id>`
+
+// keysCommand is the admin command for rotating the keys in SECRET_KEYS. A new key is added by putting it
+// first in SECRET_KEYS; phrases are moved over to it as their owners sign in, and once none depend on an
+// old key it can be retired.
+func keysCommand(ctx context.Context, args []string, keyring *security.Keyring, store db.Store, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+
+	usage, err := phraseKeyUsage(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "status" && len(args) == 1:
+		for i, key := range keyring.Keys {
+			current := ""
+			if i == 0 {
+				current = " (current)"
+			}
+			fmt.Fprintf(out, "%s%s: %d accounts\n", key.ID, current, usage[key.ID])
+			delete(usage, key.ID)
+		}
+		var retired []string
+		for id := range usage {
+			if id != "" {
+				retired = append(retired, id)
+			}
+		}
+		sort.Strings(retired)
+		for _, id := range retired {
+			fmt.Fprintf(out, "%s (retired): %d accounts can't sign in until their phrase is reset\n", id, usage[id])
+		}
+		return nil
+	case args[0] == "retire" && len(args) == 2:
+		id := args[1]
+		retired, err := keyring.Without(id)
+		if err != nil {
+			return err
+		}
+		if count := usage[id]; count > 0 {
+			return fmt.Errorf("%d accounts still have a phrase hashed with key %q, they are moved to the current key when they next sign in", count, id)
+		}
+		fmt.Fprintf(out, "no phrases depend on key %q, retire it with:\n\n  heroku config:set SECRET_KEYS='%s'\n\n", id, retired)
+		fmt.Fprintln(out, "tokens and cursors signed with it stop being accepted once it is removed.")
+		return nil
+	default:
+		return errors.New(keysUsage)
+	}
+}
+
+// phraseKeyUsage counts the accounts whose phrase depends on each key id.
+func phraseKeyUsage(ctx context.Context, store db.Store) (map[string]int, error) {
+	phrases, err := store.ListAccountPhrases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]int{}
+	for _, phrase := range phrases {
+		usage[security.PhraseKeyID(phrase)]++
+	}
+	return usage, nil
+}
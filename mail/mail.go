@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const defaultFrom = "Firstly <no-reply@localhost>"
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// LoadMailer returns the mailer configured by the env variables. Mail is sent through the SMTP server at
+// SMTP_ADDR, authenticating with SMTP_USERNAME and SMTP_PASSWORD when they are set. With MAIL_DRIVER=outbox
+// mail is kept in an outbox instead, written to MAIL_OUTBOX_DIR when it is set, for local development.
+func LoadMailer() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultFrom
+	}
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "outbox":
+		return NewOutbox(os.Getenv("MAIL_OUTBOX_DIR")), nil
+	case "", "smtp":
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER must be smtp or outbox, not %q", driver)
+	}
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return nil, errors.New("SMTP_ADDR is required, or set MAIL_DRIVER=outbox to keep mail in an outbox")
+	}
+	if !strings.Contains(addr, ":") {
+		return nil, errors.New("SMTP_ADDR must be a host:port")
+	}
+	return NewSMTPMailer(addr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+}
@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox keeps the mail sent rather than delivering it, for tests and local development. Each message is
+// also written to a file in dir, unless dir is empty.
+type Outbox struct {
+	dir string
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewOutbox returns an outbox writing to dir, or only keeping messages in memory when dir is empty.
+func NewOutbox(dir string) *Outbox {
+	return &Outbox{dir: dir}
+}
+
+func (outbox *Outbox) Send(ctx context.Context, message Message) error {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	outbox.messages = append(outbox.messages, message)
+
+	if outbox.dir == "" {
+		log.Printf("mail to %s kept in the outbox: %s", message.To, message.Subject)
+		return nil
+	}
+	if err := os.MkdirAll(outbox.dir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%03d.eml", time.Now().UTC().Format("20060102T150405"), len(outbox.messages))
+	return os.WriteFile(filepath.Join(outbox.dir, name), format(defaultFrom, message.To, message), 0o600)
+}
+
+// Messages returns the mail sent so far, oldest first.
+func (outbox *Outbox) Messages() []Message {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	return append([]Message(nil), outbox.messages...)
+}
+
+// Last returns the most recent mail sent to address, or false if none has been.
+func (outbox *Outbox) Last(address string) (Message, bool) {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	for i := len(outbox.messages) - 1; i >= 0; i-- {
+		if outbox.messages[i].To == address {
+			return outbox.messages[i], true
+		}
+	}
+	return Message{}, false
+}
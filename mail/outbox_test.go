@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOutbox(t *testing.T) {
+	dir := t.TempDir()
+	outbox := NewOutbox(dir)
+
+	if err := outbox.Send(context.Background(), Message{To: "bob@example.com", Subject: "first", Body: "one"}); err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	if err := outbox.Send(context.Background(), Message{To: "bob@example.com", Subject: "second", Body: "two"}); err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+
+	if len(outbox.Messages()) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(outbox.Messages()))
+	}
+	last, ok := outbox.Last("bob@example.com")
+	if !ok || last.Subject != "second" {
+		t.Fatalf("expected the second message to be the last, got %+v", last)
+	}
+	if _, ok := outbox.Last("alice@example.com"); ok {
+		t.Fatalf("expected no mail to have been sent to alice")
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 2 {
+		t.Fatalf("expected a file for each message, got %d, %v", len(files), err)
+	}
+	written, _ := os.ReadFile(dir + "/" + files[0].Name())
+	if !strings.Contains(string(written), "To: bob@example.com\r\n") || !strings.HasSuffix(string(written), "\r\n\r\none") {
+		t.Fatalf("expected the message with its headers, got %q", written)
+	}
+}
+
+func TestLoadMailer(t *testing.T) {
+	t.Setenv("SMTP_ADDR", "")
+	if _, err := LoadMailer(); err == nil {
+		t.Fatalf("expected an error without SMTP_ADDR")
+	}
+
+	t.Setenv("MAIL_DRIVER", "outbox")
+	if mailer, err := LoadMailer(); err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	} else if _, ok := mailer.(*Outbox); !ok {
+		t.Fatalf("expected an outbox with MAIL_DRIVER=outbox, got %T", mailer)
+	}
+
+	t.Setenv("MAIL_DRIVER", "sendgrid")
+	if _, err := LoadMailer(); err == nil {
+		t.Fatalf("expected an error for an unknown MAIL_DRIVER")
+	}
+	t.Setenv("MAIL_DRIVER", "")
+
+	t.Setenv("SMTP_ADDR", "smtp.example.com:587")
+	if mailer, err := LoadMailer(); err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	} else if _, ok := mailer.(*SMTPMailer); !ok {
+		t.Fatalf("expected an smtp mailer with SMTP_ADDR, got %T", mailer)
+	}
+
+	t.Setenv("SMTP_ADDR", "smtp.example.com")
+	if _, err := LoadMailer(); err == nil {
+		t.Fatalf("expected an error for an SMTP_ADDR without a port")
+	}
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSMTPMailerGivesUpWhenTheContextIsDone(t *testing.T) {
+	// the server accepts the connection but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %s", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	mailer := NewSMTPMailer(listener.Addr().String(), "", "", defaultFrom)
+	start := time.Now()
+	if err := mailer.Send(ctx, Message{To: "bob@example.com", Subject: "hi", Body: "hi"}); err == nil {
+		t.Fatalf("expected an error from a server that doesn't respond")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected to give up once the context was done but took %s", elapsed)
+	}
+}
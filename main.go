@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
@@ -17,11 +19,21 @@ import (
 	_ "github.com/heroku/x/hmetrics/onload"
 
 	db "github.com/meads/firstly-api/db"
+	"github.com/meads/firstly-api/export"
 	http_api "github.com/meads/firstly-api/http"
+	"github.com/meads/firstly-api/mail"
 	"github.com/meads/firstly-api/security"
 )
 
 func main() {
+	// parse the keys once, so that a bad SECRET_KEYS stops the server starting rather than failing requests
+	keyring, err := security.LoadKeyring()
+	if err != nil {
+		log.Fatalf("error loading the keys in SECRET_KEYS: %s", err)
+		return
+	}
+	security.UseKeyring(keyring)
+
 	dbURL := os.Getenv("DATABASE_URL")
 
 	conn, err := sql.Open("postgres", dbURL)
@@ -38,16 +50,59 @@ func main() {
 		return
 	}
 
-	m.Up()
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		log.Fatalf("error running migrations: %s", err)
+		return
+	}
 
 	fmt.Print("\nmigrations were a success. 🎉\n")
 
-	claimer := security.NewClaimsValidator()
+	store := db.NewStore(conn)
+
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := keysCommand(context.Background(), os.Args[2:], keyring, store, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "accounts" {
+		if err := accountsCommand(context.Background(), os.Args[2:], store, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	claimer, err := security.LoadClaimer()
+	if err != nil {
+		log.Fatalf("error loading the jwt signing keys: %s", err)
+		return
+	}
 	hasher := security.NewHasher()
+	mailer, err := mail.LoadMailer()
+	if err != nil {
+		log.Fatalf("error loading the mailer: %s", err)
+		return
+	}
+	phrasePolicy, err := security.LoadPhrasePolicy()
+	if err != nil {
+		log.Fatalf("error loading the phrase policy: %s", err)
+		return
+	}
+	trustedProxies, err := http_api.LoadTrustedProxies()
+	if err != nil {
+		log.Fatalf("error loading the trusted proxies: %s", err)
+		return
+	}
 	router := gin.Default()
-	store := db.NewStore(conn)
 
 	server := http_api.NewFirstlyServer(claimer, hasher, router, store)
+	server.UseMailer(mailer)
+	server.UsePhrasePolicy(phrasePolicy)
+	server.UseTrustedProxies(trustedProxies)
+
+	exporter := export.NewWorker(store)
+	server.UseExporter(exporter)
+	go exporter.Run(context.Background())
 
 	err = server.Start(":" + os.Getenv("PORT"))
 	if err != nil {
@@ -0,0 +1,234 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/meads/firstly-api/security"
+)
+
+// MockProvider is an OpenID Connect provider on a local test server, standing in for a real provider in
+// tests and local development. Every authorization request is approved as the user set on it, and its id
+// tokens are signed with an ES256 key.
+type MockProvider struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+
+	// the user every authorization request signs in as
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+
+	server *httptest.Server
+	key    security.SigningKey
+
+	mu             sync.Mutex
+	authorizations map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// NewMockProvider starts a provider for the client, which needs to close it.
+func NewMockProvider(clientID string, clientSecret string) (*MockProvider, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	provider := &MockProvider{
+		ClientID:       clientID,
+		ClientSecret:   clientSecret,
+		Subject:        "mock-subject",
+		key:            security.SigningKey{ID: "mock", Private: key},
+		authorizations: map[string]mockAuthorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discoveryHandler)
+	mux.HandleFunc("/authorize", provider.authorizeHandler)
+	mux.HandleFunc("/token", provider.tokenHandler)
+	mux.HandleFunc("/jwks", provider.jwksHandler)
+	provider.server = httptest.NewServer(mux)
+	provider.URL = provider.server.URL
+	return provider, nil
+}
+
+// Close shuts the provider's server down.
+func (p *MockProvider) Close() {
+	p.server.Close()
+}
+
+// Authorize follows an authorization URL as the user's browser would, returning the redirect back to the
+// client with the code and state.
+func (p *MockProvider) Authorize(authURL string) (*url.URL, error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		return nil, errors.New("authorization request was refused with " + res.Status)
+	}
+	return url.Parse(res.Header.Get("Location"))
+}
+
+// SignIDToken signs claims as an id token of the provider.
+func (p *MockProvider) SignIDToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = p.key.ID
+	return token.SignedString(p.key.Private)
+}
+
+func (p *MockProvider) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwt.SigningMethodES256.Alg()},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *MockProvider) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, security.JSONWebKeySet{
+		Keys: []security.JSONWebKey{security.NewJSONWebKey(jwt.SigningMethodES256, p.key)},
+	})
+}
+
+func (p *MockProvider) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	switch {
+	case query.Get("client_id") != p.ClientID:
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	case err != nil || !redirectURI.IsAbs():
+		http.Error(w, "redirect_uri must be absolute", http.StatusBadRequest)
+		return
+	case query.Get("response_type") != "code":
+		http.Error(w, "response_type must be code", http.StatusBadRequest)
+		return
+	case !strings.Contains(" "+query.Get("scope")+" ", " openid "):
+		http.Error(w, "scope must include openid", http.StatusBadRequest)
+		return
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		http.Error(w, "an S256 code_challenge is required", http.StatusBadRequest)
+		return
+	}
+
+	code, err := NewRandomValue()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.mu.Lock()
+	p.authorizations[code] = mockAuthorization{
+		redirectURI:   redirectURI.String(),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	p.mu.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *MockProvider) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "token requests must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// codes can only be redeemed once
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	authorization, ok := p.authorizations[code]
+	delete(p.authorizations, code)
+	p.mu.Unlock()
+	if !ok || authorization.redirectURI != r.PostForm.Get("redirect_uri") ||
+		CodeChallenge(r.PostForm.Get("code_verifier")) != authorization.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code is invalid or doesn't match the verifier"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.URL,
+		"sub":            p.Subject,
+		"aud":            p.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          authorization.nonce,
+		"email_verified": p.EmailVerified,
+	}
+	if p.Email != "" {
+		claims["email"] = p.Email
+	}
+	if p.PreferredUsername != "" {
+		claims["preferred_username"] = p.PreferredUsername
+	}
+	idToken, err := p.SignIDToken(claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	accessToken, _ := NewRandomValue()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,463 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/meads/firstly-api/security"
+)
+
+const (
+	defaultScopes      = "openid email profile"
+	defaultRedirectURL = "http://localhost:5000/auth/oidc/%s/callback"
+
+	// cacheTTL is how long discovery documents and key sets are kept before being fetched again
+	cacheTTL = time.Hour
+	// keySetRefetchInterval limits fetching a key set again for an unknown key id, which happens when a
+	// provider rotates its keys
+	keySetRefetchInterval = time.Minute
+	// leeway allows for the provider's clock differing from ours when checking token times
+	leeway = time.Minute
+	// maxResponseBytes limits the size of the responses read from providers
+	maxResponseBytes = 1 << 20
+)
+
+var (
+	// ErrUnknownProvider is returned for a provider that isn't listed in OIDC_PROVIDERS.
+	ErrUnknownProvider = errors.New("sign in provider isn't configured")
+	// ErrInvalidIDToken is wrapped by every error from checking an id token.
+	ErrInvalidIDToken = errors.New("id token is invalid")
+	// ErrExchange is wrapped by the error the provider's token endpoint responds with.
+	ErrExchange = errors.New("provider refused the authorization code")
+
+	providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// Provider is an OpenID Connect provider accounts can sign in with, configured by env variables named
+// after it.
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// AllowSignup creates an account for an identity that isn't linked to one, rather than refusing it
+	AllowSignup bool
+}
+
+// ProviderNames returns the providers listed in the comma separated OIDC_PROVIDERS env variable.
+func ProviderNames() []string {
+	var names []string
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// LoadProvider returns the provider name listed in OIDC_PROVIDERS, configured by the env variables
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_SCOPES,
+// OIDC_<NAME>_REDIRECT_URL and OIDC_<NAME>_ALLOW_SIGNUP.
+func LoadProvider(name string) (Provider, error) {
+	listed := false
+	for _, n := range ProviderNames() {
+		listed = listed || n == name
+	}
+	if !listed || !providerNamePattern.MatchString(name) {
+		return Provider{}, ErrUnknownProvider
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	provider := Provider{
+		Name:         name,
+		Issuer:       strings.TrimRight(os.Getenv(prefix+"ISSUER"), "/"),
+		ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+	}
+	if provider.Issuer == "" || provider.ClientID == "" {
+		return Provider{}, fmt.Errorf("%sISSUER and %sCLIENT_ID must be set for provider %s", prefix, prefix, name)
+	}
+	if provider.RedirectURL == "" {
+		provider.RedirectURL = fmt.Sprintf(defaultRedirectURL, name)
+	}
+	if len(provider.Scopes) == 0 {
+		provider.Scopes = strings.Fields(defaultScopes)
+	}
+	if signup := os.Getenv(prefix + "ALLOW_SIGNUP"); signup != "" {
+		allow, err := strconv.ParseBool(signup)
+		if err != nil {
+			return Provider{}, fmt.Errorf("%sALLOW_SIGNUP must be true or false", prefix)
+		}
+		provider.AllowSignup = allow
+	}
+	return provider, nil
+}
+
+// Discovery is the part of a provider's discovery document (OpenID Connect Discovery 1.0) used to sign
+// in.
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDToken is the identity a provider vouches for in a verified id token.
+type IDToken struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+	Expires           time.Time
+}
+
+type cachedDiscovery struct {
+	discovery Discovery
+	fetched   time.Time
+}
+
+type cachedKeySet struct {
+	keys    security.JSONWebKeySet
+	fetched time.Time
+}
+
+// Client signs in with OpenID Connect providers as a relying party, using the authorization code flow
+// with PKCE. It caches the providers' discovery documents and key sets.
+type Client struct {
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	discoveries map[string]cachedDiscovery
+	keySets     map[string]cachedKeySet
+}
+
+// NewClient returns a client making requests to providers with httpClient, or a client with a ten second
+// timeout when it is nil.
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		httpClient:  httpClient,
+		discoveries: map[string]cachedDiscovery{},
+		keySets:     map[string]cachedKeySet{},
+	}
+}
+
+// NewRandomValue returns a random value for a state, nonce or PKCE code verifier.
+func NewRandomValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// StateHash returns the hash of a state, which the browser that started the sign in keeps so that the
+// callback can check it comes back to the same browser without the cookie giving the state away.
+func StateHash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// CodeChallenge returns the S256 PKCE code challenge of verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Discover returns the discovery document of the provider, which has to be for its issuer.
+func (c *Client) Discover(ctx context.Context, provider Provider) (Discovery, error) {
+	c.mu.Lock()
+	cached, ok := c.discoveries[provider.Issuer]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetched) < cacheTTL {
+		return cached.discovery, nil
+	}
+
+	var discovery Discovery
+	if err := c.getJSON(ctx, provider.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return Discovery{}, fmt.Errorf("error fetching the discovery document of %s: %w", provider.Name, err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != provider.Issuer {
+		return Discovery{}, fmt.Errorf("discovery document of %s is for issuer %q", provider.Name, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return Discovery{}, fmt.Errorf("discovery document of %s is missing endpoints", provider.Name)
+	}
+
+	c.mu.Lock()
+	c.discoveries[provider.Issuer] = cachedDiscovery{discovery: discovery, fetched: time.Now()}
+	c.mu.Unlock()
+	return discovery, nil
+}
+
+// AuthCodeURL returns the provider's authorization URL to send the user to, for the state, nonce and the
+// challenge of the PKCE code verifier.
+func (c *Client) AuthCodeURL(ctx context.Context, provider Provider, state string, nonce string, verifier string) (string, error) {
+	discovery, err := c.Discover(ctx, provider)
+	if err != nil {
+		return "", err
+	}
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("authorization endpoint of %s is invalid: %w", provider.Name, err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.ClientID)
+	query.Set("redirect_uri", provider.RedirectURL)
+	query.Set("scope", strings.Join(provider.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange redeems the authorization code with the PKCE code verifier it was requested with, returning
+// the verified id token issued for nonce.
+func (c *Client) Exchange(ctx context.Context, provider Provider, code string, verifier string, nonce string) (*IDToken, error) {
+	discovery, err := c.Discover(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {provider.RedirectURL},
+		"client_id":     {provider.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if provider.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(provider.ClientID), url.QueryEscape(provider.ClientSecret))
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging the authorization code with %s: %w", provider.Name, err)
+	}
+	defer res.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseBytes)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("error decoding the token response of %s: %w", provider.Name, err)
+	}
+	if res.StatusCode != http.StatusOK || token.Error != "" {
+		if token.Error == "" {
+			token.Error = res.Status
+		}
+		return nil, fmt.Errorf("%w: %s %s", ErrExchange, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id token was issued", ErrInvalidIDToken)
+	}
+	return c.VerifyIDToken(ctx, provider, token.IDToken, nonce)
+}
+
+// idTokenClaims are the claims of an id token, checked by VerifyIDToken rather than by jwt-go so that
+// the audience can be a list and times are allowed some leeway.
+type idTokenClaims struct {
+	Issuer            string        `json:"iss"`
+	Subject           string        `json:"sub"`
+	Audience          audience      `json:"aud"`
+	AuthorizedParty   string        `json:"azp"`
+	ExpiresAt         float64       `json:"exp"`
+	IssuedAt          float64       `json:"iat"`
+	NotBefore         float64       `json:"nbf"`
+	Nonce             string        `json:"nonce"`
+	Email             string        `json:"email"`
+	EmailVerified     emailVerified `json:"email_verified"`
+	PreferredUsername string        `json:"preferred_username"`
+	Name              string        `json:"name"`
+}
+
+func (claims *idTokenClaims) Valid() error {
+	return nil
+}
+
+// audience is a single audience or a list of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// emailVerified is a boolean that some providers send as a string.
+type emailVerified bool
+
+func (e *emailVerified) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*e = emailVerified(b)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*e = emailVerified(s == "true")
+	return nil
+}
+
+func invalidIDToken(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidIDToken, fmt.Sprintf(format, a...))
+}
+
+// VerifyIDToken checks the id token was signed by the provider for our client and nonce, and hasn't
+// expired. Only RS256 and ES256 signatures are accepted.
+func (c *Client) VerifyIDToken(ctx context.Context, provider Provider, raw string, nonce string) (*IDToken, error) {
+	discovery, err := c.Discover(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims idTokenClaims
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}}
+	_, err = parser.ParseWithClaims(raw, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.publicKey(ctx, discovery.JWKSURI, kid)
+	})
+	if err != nil {
+		return nil, invalidIDToken("%s", err)
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != discovery.Issuer:
+		return nil, invalidIDToken("issued by %q", claims.Issuer)
+	case claims.Subject == "":
+		return nil, invalidIDToken("it has no subject")
+	case !claims.Audience.contains(provider.ClientID):
+		return nil, invalidIDToken("it isn't for this client")
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != provider.ClientID:
+		return nil, invalidIDToken("it was authorized for another client")
+	case claims.ExpiresAt == 0 || now.After(unixTime(claims.ExpiresAt).Add(leeway)):
+		return nil, invalidIDToken("it has expired")
+	case claims.IssuedAt != 0 && now.Add(leeway).Before(unixTime(claims.IssuedAt)):
+		return nil, invalidIDToken("it was issued in the future")
+	case claims.NotBefore != 0 && now.Add(leeway).Before(unixTime(claims.NotBefore)):
+		return nil, invalidIDToken("it isn't valid yet")
+	case nonce == "" || claims.Nonce != nonce:
+		return nil, invalidIDToken("it was issued for another sign in")
+	}
+
+	return &IDToken{
+		Issuer:            claims.Issuer,
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     bool(claims.EmailVerified),
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+		Expires:           unixTime(claims.ExpiresAt),
+	}, nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
+
+// publicKey returns the key kid of the key set, fetching the key set again when it is stale or doesn't
+// have the key. A token without a kid can only be verified by a key set of one key.
+func (c *Client) publicKey(ctx context.Context, jwksURI string, kid string) (interface{}, error) {
+	c.mu.Lock()
+	cached, ok := c.keySets[jwksURI]
+	c.mu.Unlock()
+
+	if !ok || time.Since(cached.fetched) >= cacheTTL || (findKey(cached.keys, kid) == nil && time.Since(cached.fetched) >= keySetRefetchInterval) {
+		var keys security.JSONWebKeySet
+		if err := c.getJSON(ctx, jwksURI, &keys); err != nil {
+			return nil, fmt.Errorf("error fetching the key set: %w", err)
+		}
+		cached = cachedKeySet{keys: keys, fetched: time.Now()}
+		c.mu.Lock()
+		c.keySets[jwksURI] = cached
+		c.mu.Unlock()
+	}
+
+	key := findKey(cached.keys, kid)
+	if key == nil {
+		return nil, fmt.Errorf("signing key %q isn't in the key set", kid)
+	}
+	return key.PublicKey()
+}
+
+func findKey(keys security.JSONWebKeySet, kid string) *security.JSONWebKey {
+	if kid == "" && len(keys.Keys) == 1 {
+		return &keys.Keys[0]
+	}
+	for i := range keys.Keys {
+		if keys.Keys[i].KeyID == kid && (keys.Keys[i].Use == "" || keys.Keys[i].Use == "sig") {
+			return &keys.Keys[i]
+		}
+	}
+	return nil
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %s", url, res.Status)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, maxResponseBytes)).Decode(v)
+}
@@ -0,0 +1,179 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func startMockProvider(t *testing.T) (*MockProvider, Provider) {
+	mock, err := NewMockProvider("firstly", "secret")
+	if err != nil {
+		t.Fatalf("unexpected error starting mock provider: %s", err)
+	}
+	t.Cleanup(mock.Close)
+	t.Setenv("OIDC_PROVIDERS", "mock")
+	t.Setenv("OIDC_MOCK_ISSUER", mock.URL)
+	t.Setenv("OIDC_MOCK_CLIENT_ID", "firstly")
+	t.Setenv("OIDC_MOCK_CLIENT_SECRET", "secret")
+	provider, err := LoadProvider("mock")
+	if err != nil {
+		t.Fatalf("unexpected error loading provider: %s", err)
+	}
+	return mock, provider
+}
+
+func TestLoadProvider(t *testing.T) {
+	t.Setenv("OIDC_PROVIDERS", "google, my-idp")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com/")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "client")
+	t.Setenv("OIDC_MY_IDP_ISSUER", "https://idp.example.com")
+	t.Setenv("OIDC_MY_IDP_CLIENT_ID", "client")
+	t.Setenv("OIDC_MY_IDP_SCOPES", "openid email")
+	t.Setenv("OIDC_MY_IDP_ALLOW_SIGNUP", "true")
+
+	google, err := LoadProvider("google")
+	if err != nil {
+		t.Fatalf("unexpected error loading provider: %s", err)
+	}
+	if google.Issuer != "https://accounts.google.com" || google.RedirectURL != "http://localhost:5000/auth/oidc/google/callback" ||
+		len(google.Scopes) != 3 || google.AllowSignup {
+		t.Fatalf("unexpected defaults %+v", google)
+	}
+
+	idp, err := LoadProvider("my-idp")
+	if err != nil {
+		t.Fatalf("unexpected error loading provider: %s", err)
+	}
+	if len(idp.Scopes) != 2 || !idp.AllowSignup {
+		t.Fatalf("unexpected provider %+v", idp)
+	}
+
+	if _, err := LoadProvider("github"); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("expected an unlisted provider to be unknown, got %v", err)
+	}
+	t.Setenv("OIDC_PROVIDERS", "google,github")
+	if _, err := LoadProvider("github"); err == nil || errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("expected a provider without an issuer to be misconfigured, got %v", err)
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	mock, provider := startMockProvider(t)
+	mock.Email = "bob@example.com"
+	mock.EmailVerified = true
+	client := NewClient(nil)
+	ctx := context.Background()
+
+	authorize := func(t *testing.T, verifier string) string {
+		authURL, err := client.AuthCodeURL(ctx, provider, "state", "nonce", verifier)
+		if err != nil {
+			t.Fatalf("unexpected error building authorization url: %s", err)
+		}
+		callback, err := mock.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("unexpected error authorizing: %s", err)
+		}
+		if callback.Query().Get("state") != "state" {
+			t.Fatalf("expected the state to be returned, got %s", callback)
+		}
+		return callback.Query().Get("code")
+	}
+
+	t.Run("code is exchanged for a verified id token", func(t *testing.T) {
+		code := authorize(t, "verifier-verifier-verifier-verifier-verifier")
+		idToken, err := client.Exchange(ctx, provider, code, "verifier-verifier-verifier-verifier-verifier", "nonce")
+		if err != nil {
+			t.Fatalf("unexpected error exchanging code: %s", err)
+		}
+		if idToken.Subject != "mock-subject" || idToken.Email != "bob@example.com" || !idToken.EmailVerified {
+			t.Fatalf("unexpected id token %+v", idToken)
+		}
+
+		if _, err := client.Exchange(ctx, provider, code, "verifier-verifier-verifier-verifier-verifier", "nonce"); !errors.Is(err, ErrExchange) {
+			t.Fatalf("expected a used code to be refused, got %v", err)
+		}
+	})
+
+	t.Run("code is refused without its verifier", func(t *testing.T) {
+		code := authorize(t, "verifier-verifier-verifier-verifier-verifier")
+		if _, err := client.Exchange(ctx, provider, code, "another-verifier-another-verifier-another", "nonce"); !errors.Is(err, ErrExchange) {
+			t.Fatalf("expected the wrong verifier to be refused, got %v", err)
+		}
+	})
+
+	t.Run("id token for another sign in is refused", func(t *testing.T) {
+		code := authorize(t, "verifier-verifier-verifier-verifier-verifier")
+		if _, err := client.Exchange(ctx, provider, code, "verifier-verifier-verifier-verifier-verifier", "another"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("expected the wrong nonce to be refused, got %v", err)
+		}
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	mock, provider := startMockProvider(t)
+	client := NewClient(nil)
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   mock.URL,
+			"sub":   "mock-subject",
+			"aud":   "firstly",
+			"iat":   now.Unix(),
+			"exp":   now.Add(5 * time.Minute).Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	tests := []struct {
+		name  string
+		claim func(claims jwt.MapClaims)
+		valid bool
+	}{
+		{name: "valid token is accepted", claim: func(jwt.MapClaims) {}, valid: true},
+		{name: "audience list including the client is accepted", claim: func(c jwt.MapClaims) {
+			c["aud"] = []string{"other", "firstly"}
+			c["azp"] = "firstly"
+		}, valid: true},
+		{name: "token for another client is refused", claim: func(c jwt.MapClaims) { c["aud"] = "other" }},
+		{name: "audience list authorized by another client is refused", claim: func(c jwt.MapClaims) {
+			c["aud"] = []string{"other", "firstly"}
+			c["azp"] = "other"
+		}},
+		{name: "token from another issuer is refused", claim: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "expired token is refused", claim: func(c jwt.MapClaims) { c["exp"] = now.Add(-2 * time.Minute).Unix() }},
+		{name: "token without a subject is refused", claim: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "token for another nonce is refused", claim: func(c jwt.MapClaims) { c["nonce"] = "other" }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := valid()
+			test.claim(claims)
+			raw, err := mock.SignIDToken(claims)
+			if err != nil {
+				t.Fatalf("unexpected error signing id token: %s", err)
+			}
+			_, err = client.VerifyIDToken(context.Background(), provider, raw, "nonce")
+			if test.valid && err != nil {
+				t.Fatalf("expected id token to be valid, got %s", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("expected id token to be invalid, got %v", err)
+			}
+		})
+	}
+
+	t.Run("token signed with the client secret is refused", func(t *testing.T) {
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatalf("unexpected error signing id token: %s", err)
+		}
+		if _, err := client.VerifyIDToken(context.Background(), provider, raw, "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("expected an HS256 id token to be refused, got %v", err)
+		}
+	})
+}
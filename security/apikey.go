@@ -0,0 +1,90 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key so that they are recognisable, for example by secret scanners, and
+// can be told apart from JWTs.
+const APIKeyPrefix = "fly_"
+
+// Scopes an API key can be limited to. Access tokens from signing in are given the scopes of the
+// account's role.
+const (
+	ScopeAccountRead  = "account:read"
+	ScopeAccountWrite = "account:write"
+	ScopeImagesRead   = "images:read"
+	ScopeImagesWrite  = "images:write"
+)
+
+// Scopes are all of the scopes, in the order they are listed in.
+var Scopes = []string{ScopeAccountRead, ScopeAccountWrite, ScopeImagesRead, ScopeImagesWrite}
+
+// NewAPIKey returns a random API key of the form fly_<prefix>_<secret>, its prefix, which is stored in
+// the clear to look the key up by and to show which key is which, and the hash of the key that is stored.
+func NewAPIKey() (string, string, []byte, error) {
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", nil, err
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	secret, err := randomString(32)
+	if err != nil {
+		return "", "", nil, err
+	}
+	key := APIKeyPrefix + prefix + "_" + secret
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hash an API key is stored as. Like refresh tokens, API keys are random so a fast
+// unsalted hash is enough.
+func HashAPIKey(key string) []byte {
+	return HashRefreshToken(key)
+}
+
+// IsAPIKey reports whether the token is an API key rather than a JWT.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// APIKeyLookupPrefix returns the prefix of an API key, or false when the key isn't well formed.
+func APIKeyLookupPrefix(key string) (string, bool) {
+	if !IsAPIKey(key) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, APIKeyPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// ParseScopes checks the scopes are known, returning them space separated as in a scope claim.
+func ParseScopes(scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("at least one scope is required, one of %s", strings.Join(Scopes, ", "))
+	}
+	for _, scope := range scopes {
+		if !hasScope(Scopes, scope) {
+			return "", fmt.Errorf("unknown scope %q, expected one of %s", scope, strings.Join(Scopes, ", "))
+		}
+	}
+	return strings.Join(scopes, " "), nil
+}
+
+// HasScope reports whether the claims allow scope. Claims without a scope claim allow none.
+func (claims *UsernameClaims) HasScope(scope string) bool {
+	return hasScope(strings.Fields(claims.Scope), scope)
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
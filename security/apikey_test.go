@@ -0,0 +1,68 @@
+package security
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatalf("expected no error generating an api key but got: %s", err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix+prefix+"_") {
+		t.Fatalf("expected key %q to start with fly_%s_", key, prefix)
+	}
+	if !bytes.Equal(hash, HashAPIKey(key)) {
+		t.Fatalf("expected the returned hash to be the hash of the key")
+	}
+	lookup, ok := APIKeyLookupPrefix(key)
+	if !ok || lookup != prefix {
+		t.Fatalf("expected lookup prefix %q, got %q", prefix, lookup)
+	}
+}
+
+func TestAPIKeyLookupPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		expected string
+		ok       bool
+	}{
+		{name: "well formed key", key: "fly_0a1b2c3d_c2VjcmV0_with_underscores", expected: "0a1b2c3d", ok: true},
+		{name: "jwt", key: "eyJhbGciOiJIUzI1NiJ9.e30.sig"},
+		{name: "missing secret", key: "fly_0a1b2c3d_"},
+		{name: "missing prefix", key: "fly__secret"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prefix, ok := APIKeyLookupPrefix(test.key)
+			if ok != test.ok || prefix != test.expected {
+				t.Fatalf("expected (%q, %v), got (%q, %v)", test.expected, test.ok, prefix, ok)
+			}
+		})
+	}
+}
+
+func TestScopes(t *testing.T) {
+	if _, err := ParseScopes(nil); err == nil {
+		t.Fatalf("expected an error given no scopes")
+	}
+	if _, err := ParseScopes([]string{ScopeImagesRead, "images:admin"}); err == nil {
+		t.Fatalf("expected an error given an unknown scope")
+	}
+	scope, err := ParseScopes([]string{ScopeImagesRead, ScopeImagesWrite})
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+
+	claims := NewUsernameClaims()
+	if claims.HasScope(ScopeAccountRead) {
+		t.Fatalf("expected claims without a scope claim to allow no scope")
+	}
+	claims.Scope = scope
+	if !claims.HasScope(ScopeImagesWrite) || claims.HasScope(ScopeAccountWrite) {
+		t.Fatalf("expected claims with scope %q to only allow those scopes", scope)
+	}
+}
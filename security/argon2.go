@@ -0,0 +1,187 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Bounds on the parameters of a stored hash, so a tampered or corrupt hash can't match any phrase, panic
+// argon2, or have a sign in allocate or spin for as long as it likes.
+const (
+	minArgon2SaltLen = 16
+	minArgon2KeyLen  = 16
+	maxArgon2Memory  = 1024 * 1024 // KiB
+	maxArgon2Time    = 64
+)
+
+// Argon2Params are the cost parameters of an argon2id hash.
+type Argon2Params struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+	KeyLen  uint32
+}
+
+// DefaultArgon2Params follow the second recommended option of RFC 9106 for memory constrained hosts.
+var DefaultArgon2Params = Argon2Params{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 2,
+	KeyLen:  32,
+}
+
+// Argon2Hasher hashes phrases with argon2id, encoding the hash in the PHC string format so the
+// parameters used are stored alongside it.
+type Argon2Hasher struct {
+	Params Argon2Params
+}
+
+func NewArgon2Hasher(params Argon2Params) *Argon2Hasher {
+	return &Argon2Hasher{Params: params}
+}
+
+// Generate a salt string with 16 bytes of crypto/rand data.
+func (*Argon2Hasher) GenerateSalt() string {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return ""
+	}
+	return base64.RawStdEncoding.EncodeToString(randomBytes)
+}
+
+func (h *Argon2Hasher) GeneratePasswordHash(phrase []byte, salt string) ([]byte, error) {
+	if len(phrase) == 0 {
+		return nil, errors.New("phrase is required")
+	}
+	if len(salt) < minArgon2SaltLen {
+		return nil, fmt.Errorf("salt must be at least %d bytes", minArgon2SaltLen)
+	}
+	keyring, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	pepper := keyring.Current()
+
+	p := h.Params
+	key := argon2.IDKey(pepperPhrase(pepper, phrase), []byte(salt), p.Time, p.Memory, p.Threads, p.KeyLen)
+	encoded := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d,keyid=%s$%s$%s", argon2idPrefix, argon2.Version, p.Memory, p.Time,
+		p.Threads, pepper.ID, base64.RawStdEncoding.EncodeToString([]byte(salt)), base64.RawStdEncoding.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+// IsValidPassword reports whether password hashes to the encoded argon2id phrase, peppered with the key
+// named by its keyid. The salt is read from the encoded phrase rather than the salt argument.
+func (*Argon2Hasher) IsValidPassword(phrase []byte, _ string, password string) (bool, error) {
+	hash, err := decodeArgon2id(string(phrase))
+	if err != nil {
+		return false, err
+	}
+
+	// hashes made before keys were versioned aren't peppered
+	input := []byte(password)
+	if hash.KeyID != "" {
+		keyring, err := currentKeyring()
+		if err != nil {
+			return false, err
+		}
+		pepper, ok := keyring.Lookup(hash.KeyID)
+		if !ok {
+			return false, fmt.Errorf("phrase was hashed with key %q, which has been retired", hash.KeyID)
+		}
+		input = pepperPhrase(pepper, input)
+	}
+
+	p := hash.Params
+	actual := argon2.IDKey(input, hash.Salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return subtle.ConstantTimeCompare(actual, hash.Key) == 1, nil
+}
+
+// NeedsRehash reports whether phrase isn't an argon2id hash using the hasher's parameters and the current key.
+func (h *Argon2Hasher) NeedsRehash(phrase []byte) bool {
+	hash, err := decodeArgon2id(string(phrase))
+	if err != nil || hash.Params != h.Params {
+		return true
+	}
+	keyring, err := currentKeyring()
+	if err != nil {
+		// the phrase can't be hashed again without a key either
+		return false
+	}
+	return hash.KeyID != keyring.Current().ID
+}
+
+// pepperPhrase keys the phrase with a secret that isn't stored in the database, so a leaked table of
+// hashes can't be cracked on its own.
+func pepperPhrase(pepper Key, phrase []byte) []byte {
+	mac := hmac.New(sha256.New, pepper.Secret)
+	mac.Write(phrase)
+	return mac.Sum(nil)
+}
+
+// argon2idHash is a decoded argon2id PHC string.
+type argon2idHash struct {
+	Params Argon2Params
+	KeyID  string
+	Salt   []byte
+	Key    []byte
+}
+
+func decodeArgon2id(encoded string) (argon2idHash, error) {
+	var hash argon2idHash
+	// "", "argon2id", "v=19", "m=...,t=...,p=...[,keyid=...]", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return hash, errors.New("phrase is not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return hash, errors.New("unsupported argon2id version")
+	}
+	params := strings.Split(parts[3], ",")
+	if len(params) < 3 || len(params) > 4 {
+		return hash, errors.New("invalid argon2id parameters")
+	}
+	if _, err := fmt.Sscanf(strings.Join(params[:3], ","), "m=%d,t=%d,p=%d",
+		&hash.Params.Memory, &hash.Params.Time, &hash.Params.Threads); err != nil {
+		return hash, fmt.Errorf("invalid argon2id parameters: %s", err)
+	}
+	if p := hash.Params; p.Time < 1 || p.Time > maxArgon2Time || p.Threads < 1 || p.Memory > maxArgon2Memory ||
+		p.Memory < 8*uint32(p.Threads) {
+		return hash, errors.New("argon2id parameters are out of bounds")
+	}
+	if len(params) == 4 {
+		if !strings.HasPrefix(params[3], "keyid=") || len(params[3]) == len("keyid=") {
+			return hash, errors.New("invalid argon2id keyid")
+		}
+		hash.KeyID = strings.TrimPrefix(params[3], "keyid=")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return hash, fmt.Errorf("invalid argon2id salt: %s", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return hash, fmt.Errorf("invalid argon2id hash: %s", err)
+	}
+	if len(salt) < minArgon2SaltLen {
+		return hash, errors.New("argon2id salt is too short")
+	}
+	if len(key) < minArgon2KeyLen {
+		return hash, errors.New("argon2id hash is too short")
+	}
+	hash.Salt = salt
+	hash.Key = key
+	hash.Params.KeyLen = uint32(len(key))
+	return hash, nil
+}
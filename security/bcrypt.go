@@ -0,0 +1,52 @@
+package security
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptHasher hashes phrases with bcrypt, for deployments that prefer it to argon2id. bcrypt generates
+// and embeds its own salt, so the salt arguments are ignored.
+type BcryptHasher struct {
+	Cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{Cost: cost}
+}
+
+// GenerateSalt returns an empty salt, as bcrypt stores its own salt in the hash.
+func (*BcryptHasher) GenerateSalt() string {
+	return ""
+}
+
+func (h *BcryptHasher) GeneratePasswordHash(phrase []byte, _ string) ([]byte, error) {
+	if len(phrase) == 0 {
+		return nil, errors.New("phrase is required")
+	}
+	// bcrypt only uses the first 72 bytes, so longer phrases would silently be truncated
+	if len(phrase) > 72 {
+		return nil, errors.New("phrase must not be longer than 72 bytes")
+	}
+	return bcrypt.GenerateFromPassword(phrase, h.Cost)
+}
+
+func (*BcryptHasher) IsValidPassword(phrase []byte, _ string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(phrase, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash reports whether phrase isn't a bcrypt hash using the hasher's cost.
+func (h *BcryptHasher) NeedsRehash(phrase []byte) bool {
+	cost, err := bcrypt.Cost(phrase)
+	return err != nil || cost != h.Cost
+}
+
+func isBcryptHash(phrase []byte) bool {
+	_, err := bcrypt.Cost(phrase)
+	return err == nil
+}
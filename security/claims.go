@@ -1,7 +1,10 @@
 package security
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -17,6 +20,12 @@ func (token *ClaimToken) SignedString(key interface{}) (string, error) {
 
 type UsernameClaims struct {
 	Username string `json:"username"`
+	// Role is the role of the account when the token was issued
+	Role string `json:"role,omitempty"`
+	// Scope is the space separated scopes the token is limited to
+	Scope string `json:"scope,omitempty"`
+	// ClientID is the app the token was issued to when it was granted through OAuth
+	ClientID string `json:"client_id,omitempty"`
 	*jwt.StandardClaims
 }
 
@@ -26,16 +35,47 @@ func NewUsernameClaims() *UsernameClaims {
 	}
 }
 
+// NewAccessClaims returns the claims of an access token for the account, with a random jti so that the
+// token can be revoked before it expires, and the scopes allowed by the account's role.
+func NewAccessClaims(accountID int64, username string, role string) (*UsernameClaims, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	return &UsernameClaims{
+		Username: username,
+		Role:     role,
+		Scope:    strings.Join(RoleScopes(role), " "),
+		StandardClaims: &jwt.StandardClaims{
+			Id:      jti,
+			Subject: strconv.FormatInt(accountID, 10),
+		},
+	}, nil
+}
+
+// AccountID returns the id of the account the token was issued to, which is 0 for tokens issued before
+// the subject was included.
+func (claims *UsernameClaims) AccountID() int64 {
+	if claims.StandardClaims == nil {
+		return 0
+	}
+	id, _ := strconv.ParseInt(claims.Subject, 10, 64)
+	return id
+}
+
 type ClaimsValidator struct {
-	signer    jwt.SigningMethod
-	validator jwt.Claims
+	signer jwt.SigningMethod
+	// keys are the asymmetric keys tokens are signed with, the first being the current key. HMAC tokens
+	// are signed with the keyring instead.
+	keys []SigningKey
 }
 
 type Claimer interface {
 	GetClaimToken() *ClaimToken
-	GetFiveMinuteExpirationToken(username string) (string, time.Time, error)
+	GetFiveMinuteExpirationToken(claims *UsernameClaims) (string, time.Time, error)
 	GetFromTokenString(tokenString string) (*ClaimToken, *UsernameClaims, error)
 	ParseWithClaims(tokenString string, claims *UsernameClaims, keyFunc jwt.Keyfunc) (*ClaimToken, error)
+	JWKS() JSONWebKeySet
 }
 
 func NewClaimsValidator() Claimer {
@@ -44,50 +84,137 @@ func NewClaimsValidator() Claimer {
 	}
 }
 
+// NewAsymmetricClaimsValidator returns a Claimer signing tokens with the first of keys using method, and
+// verifying them with whichever key is named by their kid header.
+func NewAsymmetricClaimsValidator(method jwt.SigningMethod, keys []SigningKey) (Claimer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if err := checkKeyType(method, key.Private); err != nil {
+			return nil, fmt.Errorf("signing key %s: %s", key.ID, err)
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("signing key id %q is used more than once", key.ID)
+		}
+		seen[key.ID] = true
+	}
+	return &ClaimsValidator{
+		signer: method,
+		keys:   keys,
+	}, nil
+}
+
 func (c *ClaimsValidator) GetFromTokenString(tokenString string) (*ClaimToken, *UsernameClaims, error) {
 	// Get the JWT string from the cookie
 	usernameClaims := NewUsernameClaims()
-	claimToken, err := c.ParseWithClaims(tokenString, usernameClaims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("SECRET")), nil
-	})
+	keyFunc := keyringKeyfunc
+	if len(c.keys) > 0 {
+		keyFunc = c.publicKeyfunc
+	}
+	claimToken, err := c.ParseWithClaims(tokenString, usernameClaims, keyFunc)
 	return claimToken, usernameClaims, err
 }
 
+// publicKeyfunc returns the public half of the signing key named by the token's kid header.
+func (c *ClaimsValidator) publicKeyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range c.keys {
+		if key.ID == kid {
+			return key.Private.Public(), nil
+		}
+	}
+	return nil, fmt.Errorf("token was signed with key %q, which is not active", kid)
+}
+
+// keyringKeyfunc returns the active key named by the token's kid header. Tokens signed before keys were
+// versioned have no kid and were signed with the legacy key.
+func keyringKeyfunc(token *jwt.Token) (interface{}, error) {
+	keyring, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+	key, ok := keyring.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("token was signed with key %q, which is not active", kid)
+	}
+	return key.Secret, nil
+}
+
+// GetClaimToken returns an unsigned token for the configured signing method with empty claims.
 func (c *ClaimsValidator) GetClaimToken() *ClaimToken {
+	return c.newClaimToken(NewUsernameClaims())
+}
+
+// newClaimToken returns an unsigned token for claims. Each token is built for the call rather than kept on
+// the validator, which is shared by every request.
+func (c *ClaimsValidator) newClaimToken(claims jwt.Claims) *ClaimToken {
 	return &ClaimToken{
 		&jwt.Token{
 			Header: map[string]interface{}{
 				"typ": "JWT",
 				"alg": c.signer.Alg(),
 			},
-			Claims: c.validator,
+			Claims: claims,
 			Method: c.signer,
 		},
 	}
 }
 
+// ParseWithClaims parses and verifies the token, only accepting the configured signing method so that a
+// token can't pick an algorithm that would have it verified with the wrong kind of key.
 func (c *ClaimsValidator) ParseWithClaims(tokenString string, claims *UsernameClaims, keyFunc jwt.Keyfunc) (*ClaimToken, error) {
-	t, err := jwt.ParseWithClaims(tokenString, claims, keyFunc)
+	parser := &jwt.Parser{ValidMethods: []string{c.signer.Alg()}}
+	t, err := parser.ParseWithClaims(tokenString, claims, keyFunc)
 	return &ClaimToken{t}, err
 }
 
-func (c *ClaimsValidator) GetFiveMinuteExpirationToken(username string) (string, time.Time, error) {
-	expirationTime := time.Now().Add(5 * time.Minute)
-	// Create the JWT claims, which includes the username and expiry time
-	claimsValidator := &UsernameClaims{
-		Username: username,
-		StandardClaims: &jwt.StandardClaims{
-			// In JWT, the expiry time is expressed as unix milliseconds
-			ExpiresAt: expirationTime.Unix(),
-		},
+// JWKS returns the public keys tokens are verified with. It is empty when tokens are signed with HMAC,
+// as those keys are secret.
+func (c *ClaimsValidator) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range c.keys {
+		set.Keys = append(set.Keys, NewJSONWebKey(c.signer, key))
 	}
+	return set
+}
 
-	c.validator = claimsValidator
+// GetFiveMinuteExpirationToken signs claims as an access token expiring in five minutes.
+func (c *ClaimsValidator) GetFiveMinuteExpirationToken(claims *UsernameClaims) (string, time.Time, error) {
+	expirationTime := time.Now().Add(5 * time.Minute)
+	// Create the JWT claims from a copy, so that setting the expiry time doesn't change the caller's claims
+	copied := *claims
+	claimsValidator := &copied
+	claimsValidator.StandardClaims = &jwt.StandardClaims{}
+	if claims.StandardClaims != nil {
+		*claimsValidator.StandardClaims = *claims.StandardClaims
+	}
+	// In JWT, the expiry time is expressed as unix seconds
+	claimsValidator.IssuedAt = time.Now().Unix()
+	claimsValidator.ExpiresAt = expirationTime.Unix()
 
 	// Declare the token with the algorithm used for signing, and the claims
-	claimToken := c.GetClaimToken()
+	claimToken := c.newClaimToken(claimsValidator)
+
+	var kid string
+	var key interface{}
+	if len(c.keys) > 0 {
+		kid, key = c.keys[0].ID, c.keys[0].Private
+	} else {
+		keyring, err := currentKeyring()
+		if err != nil {
+			return "", expirationTime, err
+		}
+		kid, key = keyring.Current().ID, keyring.Current().Secret
+	}
+	claimToken.Header["kid"] = kid
 
 	// Create the JWT string
-	tokenString, err := claimToken.SignedString([]byte(os.Getenv("SECRET")))
+	tokenString, err := claimToken.SignedString(key)
 	return tokenString, expirationTime, err
 }
@@ -50,7 +50,7 @@ func (mr *MockClaimerMockRecorder) GetClaimToken() *gomock.Call {
 }
 
 // GetFiveMinuteExpirationToken mocks base method.
-func (m *MockClaimer) GetFiveMinuteExpirationToken(arg0 string) (string, time.Time, error) {
+func (m *MockClaimer) GetFiveMinuteExpirationToken(arg0 *UsernameClaims) (string, time.Time, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetFiveMinuteExpirationToken", arg0)
 	ret0, _ := ret[0].(string)
@@ -81,6 +81,20 @@ func (mr *MockClaimerMockRecorder) GetFromTokenString(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFromTokenString", reflect.TypeOf((*MockClaimer)(nil).GetFromTokenString), arg0)
 }
 
+// JWKS mocks base method.
+func (m *MockClaimer) JWKS() JSONWebKeySet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(JSONWebKeySet)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockClaimerMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockClaimer)(nil).JWKS))
+}
+
 // ParseWithClaims mocks base method.
 func (m *MockClaimer) ParseWithClaims(arg0 string, arg1 *UsernameClaims, arg2 jwt_go.Keyfunc) (*ClaimToken, error) {
 	m.ctrl.T.Helper()
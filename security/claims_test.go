@@ -0,0 +1,249 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func generateSigningKeys(t *testing.T) map[string]crypto.Signer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating rsa key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating ecdsa key: %s", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating ed25519 key: %s", err)
+	}
+	return map[string]crypto.Signer{"RS256": rsaKey, "ES256": ecKey, "EdDSA": edKey}
+}
+
+// writePEM writes key as a PKCS #8 PEM file and returns its path.
+func writePEM(t *testing.T, key crypto.Signer) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error marshalling key: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("unexpected error writing key: %s", err)
+	}
+	return path
+}
+
+func TestLoadClaimerAsymmetric(t *testing.T) {
+	keys := generateSigningKeys(t)
+	for alg, key := range keys {
+		alg, key := alg, key
+		t.Run(alg+" tokens are signed and verified with the current key", func(t *testing.T) {
+			t.Setenv("JWT_SIGNING_ALG", alg)
+			t.Setenv("JWT_SIGNING_KEYS", "k1:"+writePEM(t, key))
+			sut, err := LoadClaimer()
+			if err != nil {
+				t.Fatalf("unexpected error loading claimer: %s", err)
+			}
+
+			tokenString, _, err := sut.GetFiveMinuteExpirationToken(&UsernameClaims{Username: "valid"})
+			if err != nil {
+				t.Fatalf("unexpected error signing token: %s", err)
+			}
+			token, claims, err := sut.GetFromTokenString(tokenString)
+			if err != nil {
+				t.Fatalf("expected token to be valid but error was encountered: %s", err)
+			}
+			if claims.Username != "valid" || token.Header["alg"] != alg || token.Header["kid"] != "k1" {
+				t.Fatalf("unexpected token header %v or username %s", token.Header, claims.Username)
+			}
+
+			jwks := sut.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "k1" || jwks.Keys[0].Algorithm != alg {
+				t.Fatalf("unexpected jwks %+v", jwks)
+			}
+			public, err := jwks.Keys[0].PublicKey()
+			if err != nil {
+				t.Fatalf("unexpected error decoding jwk: %s", err)
+			}
+			if !key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(public) {
+				t.Fatalf("decoded jwk doesn't match the signing key")
+			}
+		})
+	}
+
+	t.Run("key that doesn't match the algorithm generates error", func(t *testing.T) {
+		t.Setenv("JWT_SIGNING_ALG", "ES256")
+		t.Setenv("JWT_SIGNING_KEYS", "k1:"+writePEM(t, keys["EdDSA"]))
+		if _, err := LoadClaimer(); err == nil {
+			t.Fatalf("expected error loading an Ed25519 key for ES256")
+		}
+	})
+
+	t.Run("unsupported algorithm generates error", func(t *testing.T) {
+		t.Setenv("JWT_SIGNING_ALG", "none")
+		if _, err := LoadClaimer(); err == nil {
+			t.Fatalf("expected error for an unsupported algorithm")
+		}
+	})
+}
+
+func TestClaimsValidatorRejectsAlgConfusion(t *testing.T) {
+	keys := generateSigningKeys(t)
+	rsaKey := keys["RS256"].(*rsa.PrivateKey)
+	sut, err := NewAsymmetricClaimsValidator(jwt.SigningMethodRS256, []SigningKey{{ID: "k1", Private: rsaKey}})
+	if err != nil {
+		t.Fatalf("unexpected error creating claimer: %s", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error marshalling public key: %s", err)
+	}
+
+	claims := &UsernameClaims{
+		Username:       "valid",
+		StandardClaims: &jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+	}
+	sign := func(method jwt.SigningMethod, key interface{}) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = "k1"
+		tokenString, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("unexpected error signing token: %s", err)
+		}
+		return tokenString
+	}
+
+	tests := []struct {
+		name        string
+		tokenString string
+	}{
+		{
+			name:        "HS256 token using the public key as the secret is rejected",
+			tokenString: sign(jwt.SigningMethodHS256, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		},
+		{
+			name:        "unsigned token is rejected",
+			tokenString: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType),
+		},
+		{
+			name:        "token signed with another algorithm is rejected",
+			tokenString: sign(SigningMethodEdDSA, keys["EdDSA"]),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := sut.GetFromTokenString(test.tokenString); err == nil {
+				t.Fatalf("expected token to be rejected")
+			}
+		})
+	}
+}
+
+// TestClaimsRoundTrip signs and parses tokens with a real claimer, as handler tests only use a mock one.
+func TestClaimsRoundTrip(t *testing.T) {
+	t.Setenv("SECRET_KEYS", "")
+	t.Setenv("SECRET", "z")
+	sut := NewClaimsValidator()
+
+	admin, err := NewAccessClaims(1, "valid", RoleAdmin)
+	if err != nil {
+		t.Fatalf("unexpected error creating claims: %s", err)
+	}
+	app := NewUsernameClaims()
+	app.Username = "valid"
+	app.Subject = "1"
+	app.Role = RoleMember
+	app.Scope = ScopeImagesRead
+	app.ClientID = "client"
+	pending, err := NewMFAPendingClaims(1, "valid")
+	if err != nil {
+		t.Fatalf("unexpected error creating claims: %s", err)
+	}
+
+	for _, claims := range []*UsernameClaims{admin, app, pending} {
+		tokenString, _, err := sut.GetFiveMinuteExpirationToken(claims)
+		if err != nil {
+			t.Fatalf("unexpected error signing token: %s", err)
+		}
+		if claims.ExpiresAt != 0 {
+			t.Fatalf("expected signing not to change the given claims")
+		}
+		_, parsed, err := sut.GetFromTokenString(tokenString)
+		if err != nil {
+			t.Fatalf("expected token to be valid but error was encountered: %s", err)
+		}
+		if parsed.Username != claims.Username || parsed.Role != claims.Role || parsed.Scope != claims.Scope ||
+			parsed.ClientID != claims.ClientID || parsed.Subject != claims.Subject || parsed.Id != claims.Id {
+			t.Fatalf("expected parsed claims %+v to match signed claims %+v", parsed, claims)
+		}
+	}
+
+	_, parsed, _ := sut.GetFromTokenString(mustSign(t, sut, app))
+	if !parsed.HasScope(ScopeImagesRead) || parsed.HasScope(ScopeImagesWrite) || parsed.HasScope(ScopeAccountWrite) {
+		t.Fatalf("expected an app token to only allow the scope it was granted")
+	}
+	_, parsed, _ = sut.GetFromTokenString(mustSign(t, sut, pending))
+	if !parsed.IsMFAPending() || parsed.HasScope(ScopeAccountRead) {
+		t.Fatalf("expected an mfa pending token to stay pending and allow no other scope")
+	}
+}
+
+func TestClaimsSignedConcurrently(t *testing.T) {
+	t.Setenv("SECRET_KEYS", "")
+	t.Setenv("SECRET", "z")
+	sut := NewClaimsValidator()
+
+	const n = 20
+	tokens := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			claims, err := NewAccessClaims(int64(i+1), fmt.Sprintf("user%d", i), RoleMember)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			tokens[i], _, errs[i] = sut.GetFiveMinuteExpirationToken(claims)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, tokenString := range tokens {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error signing token: %s", errs[i])
+		}
+		_, parsed, err := sut.GetFromTokenString(tokenString)
+		if err != nil {
+			t.Fatalf("expected token to be valid but error was encountered: %s", err)
+		}
+		if parsed.Username != fmt.Sprintf("user%d", i) || parsed.AccountID() != int64(i+1) {
+			t.Fatalf("expected token %d to carry its own claims, got %+v", i, parsed)
+		}
+	}
+}
+
+func mustSign(t *testing.T, claimer Claimer, claims *UsernameClaims) string {
+	tokenString, _, err := claimer.GetFiveMinuteExpirationToken(claims)
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %s", err)
+	}
+	return tokenString
+}
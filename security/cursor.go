@@ -0,0 +1,80 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("cursor is invalid")
+
+// SignCursor encodes payload as an opaque pagination cursor, signed with the current key so that clients
+// can't forge or tamper with it.
+func SignCursor(payload []byte) (string, error) {
+	return signPayload("cursor", payload)
+}
+
+// VerifyCursor checks the signature of a cursor created by SignCursor against every active key and
+// returns its payload.
+func VerifyCursor(cursor string) ([]byte, error) {
+	payload, valid, err := verifyPayload("cursor", cursor)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidCursor
+	}
+	return payload, nil
+}
+
+// signPayload encodes payload with a signature of the current key. The purpose is signed along with it so
+// that a value signed for one purpose can't be passed off as another.
+func signPayload(purpose string, payload []byte) (string, error) {
+	keyring, err := currentKeyring()
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(payloadMAC(keyring.Current().Secret, purpose, encoded)), nil
+}
+
+// verifyPayload checks the signature of a value created by signPayload for purpose against every active
+// key, and returns its payload when it is valid.
+func verifyPayload(purpose, signed string) ([]byte, bool, error) {
+	keyring, err := currentKeyring()
+	if err != nil {
+		return nil, false, err
+	}
+	parts := strings.SplitN(signed, ".", 2)
+	if len(parts) != 2 {
+		return nil, false, nil
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false, nil
+	}
+	valid := false
+	for _, key := range keyring.Keys {
+		if hmac.Equal(mac, payloadMAC(key.Secret, purpose, parts[0])) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, false, nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, false, nil
+	}
+	return payload, true, nil
+}
+
+func payloadMAC(secret []byte, purpose, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose + ":"))
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,40 @@
+package security
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExportToken = errors.New("export link is invalid or has expired")
+
+// SignExportToken returns the token of a link to download an account export, valid until expires. The link
+// needs no other credentials so it can be opened in a browser, which is why it is signed and short lived.
+func SignExportToken(id int64, expires time.Time) (string, error) {
+	return signPayload("export", []byte(strconv.FormatInt(id, 10)+"."+strconv.FormatInt(expires.Unix(), 10)))
+}
+
+// VerifyExportToken checks a token created by SignExportToken and returns the id of the export it links to.
+func VerifyExportToken(token string, now time.Time) (int64, error) {
+	payload, valid, err := verifyPayload("export", token)
+	if err != nil {
+		return 0, err
+	}
+	if !valid {
+		return 0, ErrInvalidExportToken
+	}
+	parts := strings.SplitN(string(payload), ".", 2)
+	if len(parts) != 2 {
+		return 0, ErrInvalidExportToken
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidExportToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || !now.Before(time.Unix(expires, 0)) {
+		return 0, ErrInvalidExportToken
+	}
+	return id, nil
+}
@@ -0,0 +1,43 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExportToken(t *testing.T) {
+	t.Setenv("SECRET_KEYS", "")
+	t.Setenv("SECRET", "z")
+	now := time.Unix(1700000000, 0)
+
+	token, err := SignExportToken(42, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error signing export token: %s", err)
+	}
+	if id, err := VerifyExportToken(token, now); err != nil || id != 42 {
+		t.Fatalf("expected export 42 but got %d, err: %v", id, err)
+	}
+	if _, err := VerifyExportToken(token, now.Add(time.Hour)); err != ErrInvalidExportToken {
+		t.Fatalf("expected an expired token to be rejected, err: %v", err)
+	}
+
+	// a cursor is signed with the same keys but can't be used as an export token
+	cursor, err := SignCursor([]byte("42.1700003600"))
+	if err != nil {
+		t.Fatalf("unexpected error signing cursor: %s", err)
+	}
+	if _, err := VerifyExportToken(cursor, now); err != ErrInvalidExportToken {
+		t.Fatalf("expected a cursor to be rejected as an export token, err: %v", err)
+	}
+	if _, err := VerifyCursor(token); err != ErrInvalidCursor {
+		t.Fatalf("expected an export token to be rejected as a cursor, err: %v", err)
+	}
+	if _, err := VerifyExportToken(token[:len(token)-2]+"AA", now); err != ErrInvalidExportToken {
+		t.Fatalf("expected a tampered token to be rejected, err: %v", err)
+	}
+
+	t.Setenv("SECRET_KEYS", "v2:y")
+	if _, err := VerifyExportToken(token, now); err != ErrInvalidExportToken {
+		t.Fatalf("expected a token signed with a retired key to be rejected, err: %v", err)
+	}
+}
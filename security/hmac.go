@@ -6,21 +6,12 @@ import (
 	"crypto/sha512"
 	"encoding/base64"
 	"errors"
-	"os"
 )
 
-type Hasher interface {
-	GenerateSalt() string
-	IsValidPassword(phrase []byte, salt, password string) (bool, error)
-	GeneratePasswordHash(phrase []byte, salt string) ([]byte, error)
-}
-
+// HashLib is the legacy hasher, storing an HMAC-SHA512 of the phrase and salt keyed by the SECRET env
+// variable. It is only kept to verify phrases hashed before argon2id was introduced.
 type HashLib struct{}
 
-func NewHasher() Hasher {
-	return &HashLib{}
-}
-
 // Generate a salt string with 4096 bytes of crypto/rand data.
 func (*HashLib) GenerateSalt() string {
 	randomBytes := make([]byte, 4096)
@@ -31,26 +22,37 @@ func (*HashLib) GenerateSalt() string {
 	return base64.URLEncoding.EncodeToString(randomBytes)
 }
 
-// IsValidPassword reports whether password generates a valid HMAC matching the stored phrase.
+// IsValidPassword reports whether password generates a valid HMAC matching the stored phrase. The key the
+// phrase was made with isn't stored alongside it, so every active key is tried.
 func (*HashLib) IsValidPassword(phrase []byte, salt, password string) (bool, error) {
-	secret := os.Getenv("SECRET")
-	if len(secret) == 0 {
-		return false, errors.New("SECRET env variable not set")
+	keyring, err := currentKeyring()
+	if err != nil {
+		return false, err
 	}
-	mac := hmac.New(sha512.New, []byte(secret))
-	mac.Write(append([]byte(password), salt...))
-	return hmac.Equal(mac.Sum(nil), phrase), nil
+	for _, key := range keyring.Keys {
+		mac := hmac.New(sha512.New, key.Secret)
+		mac.Write(append([]byte(password), salt...))
+		if hmac.Equal(mac.Sum(nil), phrase) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 func (*HashLib) GeneratePasswordHash(phrase []byte, salt string) ([]byte, error) {
 	if len(phrase) > 0 {
-		secret := os.Getenv("SECRET")
-		if len(secret) > 0 {
-			mac := hmac.New(sha512.New, []byte(secret))
-			mac.Write(append(phrase, salt...))
-			return mac.Sum(nil), nil
+		keyring, err := currentKeyring()
+		if err != nil {
+			return nil, err
 		}
-		return nil, errors.New("SECRET env variable not set")
+		mac := hmac.New(sha512.New, keyring.Current().Secret)
+		mac.Write(append(phrase, salt...))
+		return mac.Sum(nil), nil
 	}
 	return nil, errors.New("phrase is required")
 }
+
+// NeedsRehash always reports true, as HMAC hashes are upgraded on the next successful sign in.
+func (*HashLib) NeedsRehash(phrase []byte) bool {
+	return true
+}
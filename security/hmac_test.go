@@ -41,7 +41,7 @@ func TestGeneratePasswordHash(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.secret()
-			sut := NewHasher()
+			sut := &HashLib{}
 			actualHash, err := sut.GeneratePasswordHash(test.phrase, test.salt)
 			if !reflect.DeepEqual(test.expectedHash, actualHash) {
 				t.Fatalf("expected hash: '%+v', doesn't match actual: '%+v'", test.expectedHash, actualHash)
@@ -85,7 +85,7 @@ func TestIsValidPassword(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.secret()
-			sut := NewHasher()
+			sut := &HashLib{}
 			matches, err := sut.IsValidPassword(test.phrase, test.salt, test.password)
 			if err != nil && !test.expectedErr {
 				t.Fatalf("expected no error for test case but error was encountered: %s", err)
@@ -0,0 +1,211 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningKey is an asymmetric key tokens are signed with, published by its id in the JWKS.
+type SigningKey struct {
+	ID      string
+	Private crypto.Signer
+}
+
+// JSONWebKey is the public half of a SigningKey as published in the JWKS (RFC 7517).
+type JSONWebKey struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is the body of the JWKS endpoint.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// asymmetricMethods are the signing methods that can be configured with JWT_SIGNING_ALG besides HS256.
+var asymmetricMethods = map[string]jwt.SigningMethod{
+	jwt.SigningMethodRS256.Alg(): jwt.SigningMethodRS256,
+	jwt.SigningMethodES256.Alg(): jwt.SigningMethodES256,
+	SigningMethodEdDSA.Alg():     SigningMethodEdDSA,
+}
+
+// LoadClaimer returns the Claimer configured by the JWT_SIGNING_ALG env variable. HS256, the default, signs
+// with the SECRET_KEYS keyring. RS256, ES256 and EdDSA sign with the private keys in the PEM files listed
+// by JWT_SIGNING_KEYS, a comma separated list of id:path pairs with the current key first.
+func LoadClaimer() (Claimer, error) {
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	if alg == "" || alg == jwt.SigningMethodHS256.Alg() {
+		return NewClaimsValidator(), nil
+	}
+	method, ok := asymmetricMethods[alg]
+	if !ok {
+		return nil, errors.New("JWT_SIGNING_ALG must be one of: HS256, RS256, ES256, EdDSA")
+	}
+
+	keys := os.Getenv("JWT_SIGNING_KEYS")
+	if keys == "" {
+		return nil, fmt.Errorf("JWT_SIGNING_KEYS must be set to sign with %s", alg)
+	}
+	var signingKeys []SigningKey
+	for _, pair := range strings.Split(keys, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.New("JWT_SIGNING_KEYS must be a comma separated list of id:path pairs")
+		}
+		data, err := os.ReadFile(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("error reading signing key %s: %s", parts[0], err)
+		}
+		key, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing signing key %s: %s", parts[0], err)
+		}
+		signingKeys = append(signingKeys, SigningKey{ID: parts[0], Private: key})
+	}
+
+	return NewAsymmetricClaimsValidator(method, signingKeys)
+}
+
+// ParsePrivateKeyPEM parses a PKCS #8, PKCS #1 (RSA) or SEC 1 (EC) PEM encoded private key.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("key can't be used for signing")
+	}
+	return signer, nil
+}
+
+// checkKeyType ensures the key matches the signing method, so that a misconfigured key is found at start
+// up rather than when the first token is signed.
+func checkKeyType(method jwt.SigningMethod, key crypto.Signer) error {
+	switch public := key.Public().(type) {
+	case *rsa.PublicKey:
+		if method == jwt.SigningMethodRS256 {
+			if public.N.BitLen() < 2048 {
+				return errors.New("RSA keys must be at least 2048 bits")
+			}
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if method == jwt.SigningMethodES256 {
+			if public.Curve != elliptic.P256() {
+				return errors.New("ES256 keys must use the P-256 curve")
+			}
+			return nil
+		}
+	case ed25519.PublicKey:
+		if method == SigningMethodEdDSA {
+			return nil
+		}
+	}
+	return fmt.Errorf("%T can't be used to sign with %s", key, method.Alg())
+}
+
+// NewJSONWebKey encodes the public half of key as a JWK.
+func NewJSONWebKey(method jwt.SigningMethod, key SigningKey) JSONWebKey {
+	jwk := JSONWebKey{KeyID: key.ID, Algorithm: method.Alg(), Use: "sig"}
+	switch public := key.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = public.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// PublicKey decodes the RSA, P-256 EC or Ed25519 public key of the JWK.
+func (jwk JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	decode := func(name string, value string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("jwk %q has an invalid %s", jwk.KeyID, name)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch {
+	case jwk.KeyType == "RSA":
+		n, err := decode("n", jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode("e", jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if n.BitLen() < 2048 || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("jwk %q isn't an RSA key of at least 2048 bits", jwk.KeyID)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case jwk.KeyType == "EC" && jwk.Curve == elliptic.P256().Params().Name:
+		x, err := decode("x", jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode("y", jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("jwk %q isn't on the P-256 curve", jwk.KeyID)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case jwk.KeyType == "OKP" && jwk.Curve == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.X, "="))
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk %q has an invalid x", jwk.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("jwk %q is of unsupported type %s %s", jwk.KeyID, jwk.KeyType, jwk.Curve)
+	}
+}
@@ -0,0 +1,119 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LegacyKeyID is the id given to the SECRET env variable when SECRET_KEYS isn't set, and so the id of
+// the secret everything was signed with before keys were versioned.
+const LegacyKeyID = "v1"
+
+var ErrNoSecret = errors.New("SECRET env variable not set")
+
+// Key is a versioned secret used to pepper phrase hashes and sign tokens and cursors.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring holds the active secrets, the first being the current key that new hashes and tokens are made
+// with and the rest being previous keys that are still accepted while traffic moves over.
+type Keyring struct {
+	Keys []Key
+}
+
+// LoadKeyring reads the keyring from the SECRET_KEYS env variable, a comma separated list of id:secret
+// pairs with the current key first, e.g. "v2:new-secret,v1:old-secret". When it isn't set the SECRET env
+// variable is used as the only key, with the id v1.
+func LoadKeyring() (*Keyring, error) {
+	keys := os.Getenv("SECRET_KEYS")
+	if keys == "" {
+		secret := os.Getenv("SECRET")
+		if len(secret) == 0 {
+			return nil, ErrNoSecret
+		}
+		return &Keyring{Keys: []Key{{ID: LegacyKeyID, Secret: []byte(secret)}}}, nil
+	}
+	return ParseKeyring(keys)
+}
+
+// activeKeyring is the keyring parsed at startup and set with UseKeyring.
+var activeKeyring *Keyring
+
+// UseKeyring sets the keyring phrases, tokens and cursors are hashed and signed with, so that the keys are
+// parsed once at startup rather than on every use. It has to be called before the server starts serving.
+func UseKeyring(keyring *Keyring) {
+	activeKeyring = keyring
+}
+
+// currentKeyring returns the keyring set with UseKeyring, or reads it from the env when none was set, as
+// in tests.
+func currentKeyring() (*Keyring, error) {
+	if activeKeyring != nil {
+		return activeKeyring, nil
+	}
+	return LoadKeyring()
+}
+
+// ParseKeyring parses a SECRET_KEYS value.
+func ParseKeyring(keys string) (*Keyring, error) {
+	keyring := &Keyring{}
+	for _, pair := range strings.Split(keys, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.New("SECRET_KEYS must be a comma separated list of id:secret pairs")
+		}
+		if strings.ContainsAny(parts[0], "$,=.") {
+			return nil, fmt.Errorf("key id %q must not contain any of $ , = .", parts[0])
+		}
+		if _, ok := keyring.Lookup(parts[0]); ok {
+			return nil, fmt.Errorf("key id %q is used more than once in SECRET_KEYS", parts[0])
+		}
+		keyring.Keys = append(keyring.Keys, Key{ID: parts[0], Secret: []byte(parts[1])})
+	}
+	return keyring, nil
+}
+
+// Current returns the key new hashes and tokens are made with.
+func (k *Keyring) Current() Key {
+	return k.Keys[0]
+}
+
+// Lookup returns the active key with the given id.
+func (k *Keyring) Lookup(id string) (Key, bool) {
+	for _, key := range k.Keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Without returns the keyring with the key of the given id removed, refusing to remove the current key.
+func (k *Keyring) Without(id string) (*Keyring, error) {
+	if _, ok := k.Lookup(id); !ok {
+		return nil, fmt.Errorf("key %q is not in the keyring", id)
+	}
+	if k.Current().ID == id {
+		return nil, fmt.Errorf("key %q is the current key, add a new key in front of it before retiring it", id)
+	}
+	retired := &Keyring{}
+	for _, key := range k.Keys {
+		if key.ID != id {
+			retired.Keys = append(retired.Keys, key)
+		}
+	}
+	return retired, nil
+}
+
+// String formats the keyring as a SECRET_KEYS value.
+func (k *Keyring) String() string {
+	pairs := make([]string, 0, len(k.Keys))
+	for _, key := range k.Keys {
+		pairs = append(pairs, key.ID+":"+string(key.Secret))
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,205 @@
+package security
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoadKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		secret      string
+		secretKeys  string
+		expectedIDs []string
+		expectedErr bool
+	}{
+		{
+			name:        "SECRET is used as the legacy key when SECRET_KEYS isn't set",
+			secret:      "z",
+			expectedIDs: []string{LegacyKeyID},
+		},
+		{
+			name:        "SECRET_KEYS is used in order with the current key first",
+			secret:      "z",
+			secretKeys:  "v2:new, v1:z",
+			expectedIDs: []string{"v2", "v1"},
+		},
+		{
+			name:        "no keys generates error",
+			expectedErr: true,
+		},
+		{
+			name:        "pair without a secret generates error",
+			secretKeys:  "v2:new,v1",
+			expectedErr: true,
+		},
+		{
+			name:        "key id used twice generates error",
+			secretKeys:  "v2:new,v2:old",
+			expectedErr: true,
+		},
+		{
+			name:        "key id that can't be embedded in a hash generates error",
+			secretKeys:  "v$2:new",
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("SECRET", test.secret)
+			t.Setenv("SECRET_KEYS", test.secretKeys)
+			keyring, err := LoadKeyring()
+			if err != nil {
+				if !test.expectedErr {
+					t.Fatalf("expected no error for test case but error was encountered: %s", err)
+				}
+				return
+			}
+			if test.expectedErr {
+				t.Fatalf("expected error for test case but none was encountered")
+			}
+			var ids []string
+			for _, key := range keyring.Keys {
+				ids = append(ids, key.ID)
+			}
+			if len(ids) != len(test.expectedIDs) {
+				t.Fatalf("expected key ids %v but were %v", test.expectedIDs, ids)
+			}
+			for i := range ids {
+				if ids[i] != test.expectedIDs[i] {
+					t.Fatalf("expected key ids %v but were %v", test.expectedIDs, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyringWithout(t *testing.T) {
+	keyring, err := ParseKeyring("v3:c,v2:b,v1:a")
+	if err != nil {
+		t.Fatalf("unexpected error parsing keyring: %s", err)
+	}
+
+	retired, err := keyring.Without("v2")
+	if err != nil {
+		t.Fatalf("unexpected error retiring a previous key: %s", err)
+	}
+	if retired.String() != "v3:c,v1:a" {
+		t.Fatalf("expected SECRET_KEYS 'v3:c,v1:a' but was '%s'", retired)
+	}
+	if _, err := keyring.Without("v3"); err == nil {
+		t.Fatalf("expected error retiring the current key")
+	}
+	if _, err := keyring.Without("v4"); err == nil {
+		t.Fatalf("expected error retiring a key that isn't in the keyring")
+	}
+}
+
+// TestKeyRotation covers moving from SECRET to a new key: everything made with the old key is still
+// accepted while it is in the keyring, and phrases are flagged to be hashed again with the new key.
+func TestKeyRotation(t *testing.T) {
+	t.Setenv("SECRET_KEYS", "")
+	t.Setenv("SECRET", "z")
+	hasher := NewPasswordHasher("argon2id", testArgon2Params, bcrypt.MinCost)
+	claimer := NewClaimsValidator()
+
+	salt := hasher.GenerateSalt()
+	phrase, err := hasher.GeneratePasswordHash([]byte("bar"), salt)
+	if err != nil {
+		t.Fatalf("unexpected error hashing with argon2id: %s", err)
+	}
+	if id := PhraseKeyID(phrase); id != LegacyKeyID {
+		t.Fatalf("expected phrase to be hashed with key %s but was %s", LegacyKeyID, id)
+	}
+	legacyPhrase, err := (&HashLib{}).GeneratePasswordHash([]byte("bar"), "salt the snail")
+	if err != nil {
+		t.Fatalf("unexpected error hashing with the legacy hmac: %s", err)
+	}
+	token, _, err := claimer.GetFiveMinuteExpirationToken(&UsernameClaims{Username: "valid"})
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %s", err)
+	}
+	cursor, err := SignCursor([]byte("cursor"))
+	if err != nil {
+		t.Fatalf("unexpected error signing cursor: %s", err)
+	}
+
+	// a new key is added in front of the old one
+	t.Setenv("SECRET_KEYS", "v2:y,v1:z")
+
+	if matches, err := hasher.IsValidPassword(phrase, salt, "bar"); err != nil || !matches {
+		t.Fatalf("expected phrase hashed with the previous key to match, err: %v", err)
+	}
+	if matches, err := hasher.IsValidPassword(legacyPhrase, "salt the snail", "bar"); err != nil || !matches {
+		t.Fatalf("expected legacy phrase to match, err: %v", err)
+	}
+	if !hasher.NeedsRehash(phrase) {
+		t.Fatalf("expected phrase hashed with the previous key to need rehashing")
+	}
+	if _, claims, err := claimer.GetFromTokenString(token); err != nil || claims.Username != "valid" {
+		t.Fatalf("expected token signed with the previous key to be valid, err: %v", err)
+	}
+	if _, err := VerifyCursor(cursor); err != nil {
+		t.Fatalf("expected cursor signed with the previous key to be valid, err: %s", err)
+	}
+
+	rehashed, err := hasher.GeneratePasswordHash([]byte("bar"), salt)
+	if err != nil {
+		t.Fatalf("unexpected error hashing with argon2id: %s", err)
+	}
+	if hasher.NeedsRehash(rehashed) || PhraseKeyID(rehashed) != "v2" {
+		t.Fatalf("expected phrase to be hashed with the current key")
+	}
+	newToken, _, err := claimer.GetFiveMinuteExpirationToken(&UsernameClaims{Username: "valid"})
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %s", err)
+	}
+
+	// the old key is retired
+	t.Setenv("SECRET_KEYS", "v2:y")
+
+	if matches, err := hasher.IsValidPassword(rehashed, salt, "bar"); err != nil || !matches {
+		t.Fatalf("expected phrase hashed with the current key to match, err: %v", err)
+	}
+	if _, err := hasher.IsValidPassword(phrase, salt, "bar"); err == nil {
+		t.Fatalf("expected error verifying a phrase hashed with a retired key")
+	}
+	if _, _, err := claimer.GetFromTokenString(token); err == nil {
+		t.Fatalf("expected token signed with a retired key to be rejected")
+	}
+	if _, _, err := claimer.GetFromTokenString(newToken); err != nil {
+		t.Fatalf("expected token signed with the current key to be valid, err: %s", err)
+	}
+	if _, err := VerifyCursor(cursor); err != ErrInvalidCursor {
+		t.Fatalf("expected cursor signed with a retired key to be rejected, err: %v", err)
+	}
+}
+
+func TestUseKeyring(t *testing.T) {
+	keyring, err := ParseKeyring("v2:y,v1:z")
+	if err != nil {
+		t.Fatalf("unexpected error parsing keyring: %s", err)
+	}
+	UseKeyring(keyring)
+	t.Cleanup(func() { UseKeyring(nil) })
+
+	// the keyring set at startup is used rather than the env, which isn't read again
+	t.Setenv("SECRET_KEYS", "not a keyring")
+	t.Setenv("SECRET", "")
+
+	cursor, err := SignCursor([]byte("cursor"))
+	if err != nil {
+		t.Fatalf("unexpected error signing cursor: %s", err)
+	}
+	if _, err := VerifyCursor(cursor); err != nil {
+		t.Fatalf("expected cursor to be valid, err: %s", err)
+	}
+	phrase, err := NewArgon2Hasher(testArgon2Params).GeneratePasswordHash([]byte("bar"), "salt the snail, twice")
+	if err != nil {
+		t.Fatalf("unexpected error hashing with argon2id: %s", err)
+	}
+	if id := PhraseKeyID(phrase); id != "v2" {
+		t.Fatalf("expected phrase to be hashed with key v2 but was %s", id)
+	}
+}
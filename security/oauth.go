@@ -0,0 +1,76 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// OAuthScopes are the scopes third-party apps can ask for. Changing the account itself, such as its phrase
+// or sign in methods, is left to the first-party clients.
+var OAuthScopes = []string{ScopeAccountRead, ScopeImagesRead, ScopeImagesWrite}
+
+// NewOAuthClientID returns a random id for a registered app, which isn't secret.
+func NewOAuthClientID() (string, error) {
+	return randomString(16)
+}
+
+// NewOAuthClientSecret returns a random secret for a confidential app along with the hash of it that is
+// stored. Like API keys, secrets are random so a fast unsalted hash is enough.
+func NewOAuthClientSecret() (string, []byte, error) {
+	return NewRefreshToken()
+}
+
+// HashOAuthClientSecret returns the hash a client secret is stored as.
+func HashOAuthClientSecret(secret string) []byte {
+	return HashRefreshToken(secret)
+}
+
+// NewOAuthTicket returns a random ticket for an authorization waiting for the user's consent, which the
+// consent screen sends back so that consent can't be given by another site.
+func NewOAuthTicket() (string, error) {
+	return randomString(32)
+}
+
+// NewOAuthCode returns a random authorization code along with the hash of it that is stored.
+func NewOAuthCode() (string, []byte, error) {
+	return NewRefreshToken()
+}
+
+// HashOAuthCode returns the hash an authorization code is stored and looked up by.
+func HashOAuthCode(code string) []byte {
+	return HashRefreshToken(code)
+}
+
+// ParseOAuthScopes checks the scopes can be asked for by apps, returning them space separated as in a
+// scope claim.
+func ParseOAuthScopes(scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("at least one scope is required, one of %s", strings.Join(OAuthScopes, ", "))
+	}
+	for _, scope := range scopes {
+		if !hasScope(OAuthScopes, scope) {
+			return "", fmt.Errorf("scope %q can't be granted to apps, expected one of %s", scope, strings.Join(OAuthScopes, ", "))
+		}
+	}
+	return strings.Join(scopes, " "), nil
+}
+
+// VerifyCodeChallenge reports whether verifier is the PKCE code verifier of an S256 code challenge, as
+// described in RFC 7636 section 4.6.
+func VerifyCodeChallenge(verifier string, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// IsFirstParty reports whether the claims are of a token from signing in, rather than of an API key or a
+// token issued to an app. API keys have no jti.
+func (claims *UsernameClaims) IsFirstParty() bool {
+	return claims.ClientID == "" && claims.StandardClaims != nil && claims.Id != ""
+}
@@ -0,0 +1,56 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestParseOAuthScopes(t *testing.T) {
+	scope, err := ParseOAuthScopes([]string{ScopeAccountRead, ScopeImagesWrite})
+	if err != nil || scope != "account:read images:write" {
+		t.Fatalf("expected scopes to be joined, got %q, %v", scope, err)
+	}
+	if _, err := ParseOAuthScopes([]string{ScopeAccountWrite}); err == nil {
+		t.Fatalf("expected account:write to be refused to apps")
+	}
+	if _, err := ParseOAuthScopes(nil); err == nil {
+		t.Fatalf("expected at least one scope to be required")
+	}
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// the example from RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if !VerifyCodeChallenge(verifier, challenge) {
+		t.Fatalf("expected the verifier to match its challenge")
+	}
+	if VerifyCodeChallenge(verifier+"x", challenge) {
+		t.Fatalf("expected another verifier not to match")
+	}
+	if VerifyCodeChallenge("short", challenge) {
+		t.Fatalf("expected a verifier shorter than 43 characters to be refused")
+	}
+}
+
+func TestIsFirstParty(t *testing.T) {
+	claims, err := NewAccessClaims(1, "bob", RoleMember)
+	if err != nil {
+		t.Fatalf("unexpected error creating claims: %s", err)
+	}
+	if !claims.IsFirstParty() {
+		t.Fatalf("expected a signed in token to be first party")
+	}
+
+	claims.ClientID = "app"
+	if claims.IsFirstParty() {
+		t.Fatalf("expected a token issued to an app not to be first party")
+	}
+
+	apiKey := &UsernameClaims{Username: "bob", StandardClaims: &jwt.StandardClaims{Subject: "1"}}
+	if apiKey.IsFirstParty() {
+		t.Fatalf("expected an api key not to be first party")
+	}
+}
@@ -0,0 +1,112 @@
+package security
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Hasher interface {
+	GenerateSalt() string
+	IsValidPassword(phrase []byte, salt, password string) (bool, error)
+	GeneratePasswordHash(phrase []byte, salt string) ([]byte, error)
+	NeedsRehash(phrase []byte) bool
+}
+
+// PasswordHasher hashes new phrases with the configured scheme and verifies phrases hashed with any of
+// the supported schemes, recognising argon2id and bcrypt hashes by their prefix and treating anything
+// else as a legacy HMAC.
+type PasswordHasher struct {
+	current Hasher
+	argon2  *Argon2Hasher
+	bcrypt  *BcryptHasher
+	legacy  *HashLib
+}
+
+// NewHasher returns a PasswordHasher configured from the PASSWORD_HASH_SCHEME (argon2id or bcrypt),
+// ARGON2_MEMORY, ARGON2_TIME, ARGON2_THREADS and BCRYPT_COST env variables.
+func NewHasher() Hasher {
+	params := DefaultArgon2Params
+	if v, err := strconv.ParseUint(os.Getenv("ARGON2_MEMORY"), 10, 32); err == nil && v > 0 {
+		params.Memory = uint32(v)
+	}
+	if v, err := strconv.ParseUint(os.Getenv("ARGON2_TIME"), 10, 32); err == nil && v > 0 {
+		params.Time = uint32(v)
+	}
+	if v, err := strconv.ParseUint(os.Getenv("ARGON2_THREADS"), 10, 8); err == nil && v > 0 {
+		params.Threads = uint8(v)
+	}
+	cost := bcrypt.DefaultCost
+	if v, err := strconv.Atoi(os.Getenv("BCRYPT_COST")); err == nil && v >= bcrypt.MinCost && v <= bcrypt.MaxCost {
+		cost = v
+	}
+
+	return NewPasswordHasher(os.Getenv("PASSWORD_HASH_SCHEME"), params, cost)
+}
+
+// NewPasswordHasher returns a PasswordHasher hashing new phrases with scheme, which is argon2id unless
+// bcrypt is given.
+func NewPasswordHasher(scheme string, params Argon2Params, cost int) *PasswordHasher {
+	h := &PasswordHasher{
+		argon2: NewArgon2Hasher(params),
+		bcrypt: NewBcryptHasher(cost),
+		legacy: &HashLib{},
+	}
+	h.current = h.argon2
+	if scheme == "bcrypt" {
+		h.current = h.bcrypt
+	}
+	return h
+}
+
+func (h *PasswordHasher) GenerateSalt() string {
+	return h.current.GenerateSalt()
+}
+
+func (h *PasswordHasher) GeneratePasswordHash(phrase []byte, salt string) ([]byte, error) {
+	return h.current.GeneratePasswordHash(phrase, salt)
+}
+
+// IsValidPassword reports whether password matches the stored phrase, whichever scheme it was hashed with.
+func (h *PasswordHasher) IsValidPassword(phrase []byte, salt, password string) (bool, error) {
+	return h.schemeOf(phrase).IsValidPassword(phrase, salt, password)
+}
+
+// NeedsRehash reports whether phrase should be hashed again with the current scheme and parameters.
+func (h *PasswordHasher) NeedsRehash(phrase []byte) bool {
+	if h.schemeOf(phrase) != h.current {
+		return true
+	}
+	return h.current.NeedsRehash(phrase)
+}
+
+func (h *PasswordHasher) schemeOf(phrase []byte) Hasher {
+	switch {
+	case bytes.HasPrefix(phrase, []byte(argon2idPrefix)):
+		return h.argon2
+	case isBcryptHash(phrase):
+		return h.bcrypt
+	default:
+		return h.legacy
+	}
+}
+
+// PhraseKeyID returns the id of the key a stored phrase depends on, so that a key is only retired once no
+// phrase needs it. Legacy HMAC phrases were keyed with the unversioned SECRET and report LegacyKeyID, and
+// phrases that aren't keyed by any secret report an empty id.
+func PhraseKeyID(phrase []byte) string {
+	switch {
+	case bytes.HasPrefix(phrase, []byte(argon2idPrefix)):
+		hash, err := decodeArgon2id(string(phrase))
+		if err != nil {
+			return ""
+		}
+		return hash.KeyID
+	case isBcryptHash(phrase):
+		return ""
+	default:
+		return LegacyKeyID
+	}
+}
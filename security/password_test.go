@@ -0,0 +1,172 @@
+package security
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params keep the tests fast, the defaults take a noticeable amount of time and memory.
+var testArgon2Params = Argon2Params{Memory: 64, Time: 1, Threads: 1, KeyLen: 32}
+
+func TestPasswordHasherIsValidPassword(t *testing.T) {
+	os.Setenv("SECRET", "z")
+	argon2Hasher := NewPasswordHasher("argon2id", testArgon2Params, bcrypt.MinCost)
+	bcryptHasher := NewPasswordHasher("bcrypt", testArgon2Params, bcrypt.MinCost)
+
+	argon2Salt := argon2Hasher.GenerateSalt()
+	argon2Phrase, err := argon2Hasher.GeneratePasswordHash([]byte("bar"), argon2Salt)
+	if err != nil {
+		t.Fatalf("unexpected error hashing with argon2id: %s", err)
+	}
+	bcryptPhrase, err := bcryptHasher.GeneratePasswordHash([]byte("bar"), "")
+	if err != nil {
+		t.Fatalf("unexpected error hashing with bcrypt: %s", err)
+	}
+	legacyPhrase, err := (&HashLib{}).GeneratePasswordHash([]byte("bar"), "salt the snail")
+	if err != nil {
+		t.Fatalf("unexpected error hashing with the legacy hmac: %s", err)
+	}
+
+	tests := []struct {
+		name          string
+		phrase        []byte
+		salt          string
+		password      string
+		expectedMatch bool
+		expectedErr   bool
+	}{
+		{
+			name:          "argon2id hash matches the password it was made from",
+			phrase:        argon2Phrase,
+			salt:          argon2Salt,
+			password:      "bar",
+			expectedMatch: true,
+		},
+		{
+			name:     "argon2id hash doesn't match a different password",
+			phrase:   argon2Phrase,
+			salt:     argon2Salt,
+			password: "baz",
+		},
+		{
+			name:          "bcrypt hash matches the password it was made from",
+			phrase:        bcryptPhrase,
+			password:      "bar",
+			expectedMatch: true,
+		},
+		{
+			name:     "bcrypt hash doesn't match a different password",
+			phrase:   bcryptPhrase,
+			password: "baz",
+		},
+		{
+			name:          "legacy hmac hash matches the password it was made from",
+			phrase:        legacyPhrase,
+			salt:          "salt the snail",
+			password:      "bar",
+			expectedMatch: true,
+		},
+		{
+			name:        "malformed argon2id hash generates error",
+			phrase:      []byte("$argon2id$v=19$m=64"),
+			salt:        argon2Salt,
+			password:    "bar",
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, err := argon2Hasher.IsValidPassword(test.phrase, test.salt, test.password)
+			if err != nil && !test.expectedErr {
+				t.Fatalf("expected no error for test case but error was encountered: %s", err)
+			}
+			if err == nil && test.expectedErr {
+				t.Fatalf("expected error for test case but none was encountered")
+			}
+			if matches != test.expectedMatch {
+				t.Fatalf("expected match to be %t but was %t", test.expectedMatch, matches)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	os.Setenv("SECRET", "z")
+	sut := NewPasswordHasher("argon2id", testArgon2Params, bcrypt.MinCost)
+
+	current, err := sut.GeneratePasswordHash([]byte("bar"), sut.GenerateSalt())
+	if err != nil {
+		t.Fatalf("unexpected error hashing with argon2id: %s", err)
+	}
+	weaker := testArgon2Params
+	weaker.Memory = 32
+	old, err := NewArgon2Hasher(weaker).GeneratePasswordHash([]byte("bar"), sut.GenerateSalt())
+	if err != nil {
+		t.Fatalf("unexpected error hashing with argon2id: %s", err)
+	}
+	bcryptPhrase, err := NewBcryptHasher(bcrypt.MinCost).GeneratePasswordHash([]byte("bar"), "")
+	if err != nil {
+		t.Fatalf("unexpected error hashing with bcrypt: %s", err)
+	}
+	legacyPhrase, err := (&HashLib{}).GeneratePasswordHash([]byte("bar"), "salt the snail")
+	if err != nil {
+		t.Fatalf("unexpected error hashing with the legacy hmac: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		phrase   []byte
+		expected bool
+	}{
+		{name: "hash made with the current scheme and parameters is kept", phrase: current},
+		{name: "argon2id hash made with other parameters is rehashed", phrase: old, expected: true},
+		{name: "bcrypt hash is rehashed when argon2id is configured", phrase: bcryptPhrase, expected: true},
+		{name: "legacy hmac hash is always rehashed", phrase: legacyPhrase, expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := sut.NeedsRehash(test.phrase); actual != test.expected {
+				t.Fatalf("expected NeedsRehash to be %t but was %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestArgon2HasherRejectsMalformedHashes(t *testing.T) {
+	os.Setenv("SECRET", "z")
+	sut := NewArgon2Hasher(testArgon2Params)
+	salt := base64.RawStdEncoding.EncodeToString([]byte("salt the snail, twice"))
+	key := base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+
+	tests := []struct {
+		name   string
+		phrase string
+	}{
+		{name: "empty hash", phrase: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$"},
+		{name: "short hash", phrase: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + base64.RawStdEncoding.EncodeToString(make([]byte, 8))},
+		{name: "empty salt", phrase: "$argon2id$v=19$m=64,t=1,p=1$$" + key},
+		{name: "short salt", phrase: "$argon2id$v=19$m=64,t=1,p=1$" + base64.RawStdEncoding.EncodeToString([]byte("salt")) + "$" + key},
+		{name: "no passes", phrase: "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key},
+		{name: "too many passes", phrase: "$argon2id$v=19$m=64,t=4294967295,p=1$" + salt + "$" + key},
+		{name: "no threads", phrase: "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key},
+		{name: "too little memory for the threads", phrase: "$argon2id$v=19$m=8,t=1,p=2$" + salt + "$" + key},
+		{name: "too much memory", phrase: "$argon2id$v=19$m=4294967295,t=1,p=1$" + salt + "$" + key},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, err := sut.IsValidPassword([]byte(test.phrase), "", "bar")
+			if err == nil {
+				t.Fatalf("expected an error for a malformed hash")
+			}
+			if matches {
+				t.Fatalf("expected a malformed hash not to match")
+			}
+			if !sut.NeedsRehash([]byte(test.phrase)) {
+				t.Fatalf("expected a malformed hash to need rehashing")
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Codes of the ways a phrase can fail the phrase policy, responded with alongside the reason.
+const (
+	PhraseTooShort    = "phrase_too_short"
+	PhraseTooWeak     = "phrase_too_weak"
+	PhraseHasUsername = "phrase_contains_username"
+	PhraseBreached    = "phrase_breached"
+)
+
+// minUsernameLength is the shortest username a phrase is checked for, shorter ones being too likely to
+// turn up by chance.
+const minUsernameLength = 3
+
+// PhrasePolicy is what a new phrase has to satisfy when an account is created or its phrase is changed.
+type PhrasePolicy struct {
+	// MinLength is the fewest characters a phrase can have
+	MinLength int
+	// MinEntropy is the fewest bits of entropy a phrase can have, as estimated by EstimateEntropy
+	MinEntropy float64
+	// Breached are the phrases known from data breaches, which aren't checked when nil
+	Breached *BreachedPhrases
+}
+
+// DefaultPhrasePolicy needs at least 8 characters and 30 bits of entropy, without checking breaches.
+var DefaultPhrasePolicy = PhrasePolicy{MinLength: 8, MinEntropy: 30}
+
+// PhrasePolicyError describes why a phrase doesn't satisfy the policy, with one of the phrase codes.
+type PhrasePolicyError struct {
+	Code   string
+	Reason string
+}
+
+func (e *PhrasePolicyError) Error() string {
+	return e.Reason
+}
+
+// LoadPhrasePolicy returns the policy configured by the PASSWORD_MIN_LENGTH, PASSWORD_MIN_ENTROPY and
+// PASSWORD_BREACHED_FILE env variables, falling back to DefaultPhrasePolicy.
+func LoadPhrasePolicy() (PhrasePolicy, error) {
+	policy := DefaultPhrasePolicy
+	if v, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LENGTH")); err == nil && v > 0 {
+		policy.MinLength = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("PASSWORD_MIN_ENTROPY"), 64); err == nil && v >= 0 {
+		policy.MinEntropy = v
+	}
+	if path := os.Getenv("PASSWORD_BREACHED_FILE"); path != "" {
+		breached, err := LoadBreachedPhrases(path)
+		if err != nil {
+			return PhrasePolicy{}, fmt.Errorf("loading breached phrases from %s: %w", path, err)
+		}
+		policy.Breached = breached
+	}
+	return policy, nil
+}
+
+// Check returns a *PhrasePolicyError when phrase doesn't satisfy the policy for the account named
+// username.
+func (p PhrasePolicy) Check(phrase string, username string) error {
+	if length := utf8.RuneCountInString(phrase); length < p.MinLength {
+		return &PhrasePolicyError{
+			Code:   PhraseTooShort,
+			Reason: fmt.Sprintf("phrase must be at least %d characters long, it is %d", p.MinLength, length),
+		}
+	}
+	if utf8.RuneCountInString(username) >= minUsernameLength && strings.Contains(strings.ToLower(phrase), strings.ToLower(username)) {
+		return &PhrasePolicyError{Code: PhraseHasUsername, Reason: "phrase must not contain the username"}
+	}
+	if entropy := EstimateEntropy(phrase); entropy < p.MinEntropy {
+		return &PhrasePolicyError{
+			Code: PhraseTooWeak,
+			Reason: fmt.Sprintf("phrase is too easy to guess, with about %.0f bits of entropy where %.0f are needed: "+
+				"use more words, or a mix of letters, digits and symbols, without repeats or runs like abc", entropy, p.MinEntropy),
+		}
+	}
+	if p.Breached != nil && p.Breached.Contains(phrase) {
+		return &PhrasePolicyError{Code: PhraseBreached, Reason: "phrase has appeared in a data breach, choose another"}
+	}
+	return nil
+}
+
+// EstimateEntropy roughly estimates the bits of entropy of phrase from the kinds of characters it uses and
+// its length, not counting characters that repeat the one before or continue a run like abc or 321.
+func EstimateEntropy(phrase string) float64 {
+	var lower, upper, digit, symbol, other bool
+	var prev, step rune
+	counted := 0
+	for i, r := range []rune(phrase) {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+
+		diff := r - prev
+		repeats := i > 0 && diff == 0
+		continuesRun := i > 1 && (diff == 1 || diff == -1) && diff == step
+		if !repeats && !continuesRun {
+			counted++
+		}
+		prev, step = r, diff
+	}
+
+	pool := 0
+	for _, kind := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if kind.used {
+			pool += kind.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(counted) * math.Log2(float64(pool))
+}
+
+// BreachedPhrases are the SHA-1 hashes of phrases known from data breaches, such as the Pwned Passwords
+// download. Like the k-anonymity range API they come from, the hashes are grouped by their first five hex
+// characters, so a lookup only searches the suffixes sharing its prefix.
+type BreachedPhrases struct {
+	ranges map[string][]string
+	count  int
+}
+
+// LoadBreachedPhrases reads the breached phrase hashes in the file at path.
+func LoadBreachedPhrases(path string) (*BreachedPhrases, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBreachedPhrases(f)
+}
+
+// ReadBreachedPhrases reads one hex SHA-1 hash per line, optionally followed by :count as in the Pwned
+// Passwords download. Blank lines and lines starting with # are skipped.
+func ReadBreachedPhrases(r io.Reader) (*BreachedPhrases, error) {
+	breached := &BreachedPhrases{ranges: map[string][]string{}}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if i := strings.IndexByte(text, ':'); i >= 0 {
+			text = text[:i]
+		}
+		hash := strings.ToUpper(text)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("line %d isn't a hex SHA-1 hash", line)
+		}
+		breached.ranges[hash[:5]] = append(breached.ranges[hash[:5]], hash[5:])
+		breached.count++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, suffixes := range breached.ranges {
+		sort.Strings(suffixes)
+	}
+	return breached, nil
+}
+
+// Len returns how many breached phrase hashes there are.
+func (b *BreachedPhrases) Len() int {
+	return b.count
+}
+
+// Range returns the sorted suffixes of the hashes starting with the five hex character prefix.
+func (b *BreachedPhrases) Range(prefix string) []string {
+	return b.ranges[strings.ToUpper(prefix)]
+}
+
+// Contains reports whether phrase is one of the breached phrases.
+func (b *BreachedPhrases) Contains(phrase string) bool {
+	sum := sha1.Sum([]byte(phrase))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes := b.Range(hash[:5])
+	i := sort.SearchStrings(suffixes, hash[5:])
+	return i < len(suffixes) && suffixes[i] == hash[5:]
+}
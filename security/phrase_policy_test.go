@@ -0,0 +1,129 @@
+package security
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(phrase string) string {
+	sum := sha1.Sum([]byte(phrase))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestEstimateEntropy(t *testing.T) {
+	tests := []struct {
+		phrase string
+		min    float64
+		max    float64
+	}{
+		{phrase: "", min: 0, max: 0},
+		{phrase: "aaaaaaaaaaaa", min: 4, max: 5},
+		{phrase: "abcdefghijkl", min: 9, max: 10},
+		{phrase: "87654321", min: 6, max: 7},
+		{phrase: "qwhtnbzx", min: 37, max: 38},
+		{phrase: "correct horse battery staple", min: 140, max: 170},
+		{phrase: "Tr0ub4dor&3", min: 70, max: 73},
+	}
+	for _, test := range tests {
+		t.Run(test.phrase, func(t *testing.T) {
+			entropy := EstimateEntropy(test.phrase)
+			if entropy < test.min || entropy > test.max {
+				t.Fatalf("expected between %.0f and %.0f bits, got %.1f", test.min, test.max, entropy)
+			}
+		})
+	}
+}
+
+func TestReadBreachedPhrases(t *testing.T) {
+	list := strings.Join([]string{
+		"# a few of the most common phrases",
+		strings.ToUpper(sha1Hex("password1")) + ":2427660",
+		"",
+		sha1Hex("correct horse battery staple"),
+	}, "\n")
+	breached, err := ReadBreachedPhrases(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("unexpected error reading breached phrases: %s", err)
+	}
+	if breached.Len() != 2 {
+		t.Fatalf("expected 2 hashes, got %d", breached.Len())
+	}
+	if !breached.Contains("password1") || !breached.Contains("correct horse battery staple") {
+		t.Fatalf("expected the listed phrases to be breached")
+	}
+	if breached.Contains("Password1") {
+		t.Fatalf("expected an unlisted phrase not to be breached")
+	}
+	if suffixes := breached.Range(sha1Hex("password1")[:5]); len(suffixes) != 1 || suffixes[0] != strings.ToUpper(sha1Hex("password1")[5:]) {
+		t.Fatalf("expected the range to hold the suffix, got %v", suffixes)
+	}
+
+	if _, err := ReadBreachedPhrases(strings.NewReader("not a hash\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("expected the malformed line to be reported, got %v", err)
+	}
+}
+
+func TestPhrasePolicyCheck(t *testing.T) {
+	breached, err := ReadBreachedPhrases(strings.NewReader(sha1Hex("correct horse battery staple")))
+	if err != nil {
+		t.Fatalf("unexpected error reading breached phrases: %s", err)
+	}
+	policy := PhrasePolicy{MinLength: 8, MinEntropy: 30, Breached: breached}
+
+	tests := []struct {
+		name     string
+		phrase   string
+		username string
+		code     string
+	}{
+		{name: "strong phrase is accepted", phrase: "paper lantern message", username: "bob"},
+		{name: "short phrase is refused", phrase: "Sh0rt!", username: "bob", code: PhraseTooShort},
+		{name: "phrase containing the username is refused", phrase: "my name is Alice!", username: "alice", code: PhraseHasUsername},
+		{name: "short username isn't looked for", phrase: "paper lantern jo", username: "jo"},
+		{name: "repetitive phrase is refused", phrase: "aaaaaaaaaaaaaaaa", username: "bob", code: PhraseTooWeak},
+		{name: "breached phrase is refused", phrase: "correct horse battery staple", username: "bob", code: PhraseBreached},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := policy.Check(test.phrase, test.username)
+			if test.code == "" {
+				if err != nil {
+					t.Fatalf("expected phrase to be accepted, got %s", err)
+				}
+				return
+			}
+			var policyErr *PhrasePolicyError
+			if !errors.As(err, &policyErr) || policyErr.Code != test.code {
+				t.Fatalf("expected %s, got %v", test.code, err)
+			}
+		})
+	}
+}
+
+func TestLoadPhrasePolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(sha1Hex("password1")+":3\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error writing breached phrases: %s", err)
+	}
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_MIN_ENTROPY", "50")
+	t.Setenv("PASSWORD_BREACHED_FILE", path)
+
+	policy, err := LoadPhrasePolicy()
+	if err != nil {
+		t.Fatalf("unexpected error loading policy: %s", err)
+	}
+	if policy.MinLength != 12 || policy.MinEntropy != 50 || policy.Breached == nil || !policy.Breached.Contains("password1") {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+
+	t.Setenv("PASSWORD_BREACHED_FILE", filepath.Join(t.TempDir(), "missing.txt"))
+	if _, err := LoadPhrasePolicy(); err == nil {
+		t.Fatalf("expected a missing breached phrases file to fail loading")
+	}
+}
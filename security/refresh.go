@@ -0,0 +1,48 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewRefreshToken returns a random opaque refresh token along with the hash of it that is stored, so
+// that a leaked session table can't be used to refresh tokens.
+func NewRefreshToken() (string, []byte, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the hash a refresh token is stored and looked up by. Refresh tokens are random
+// rather than chosen by users, so a fast unsalted hash is enough.
+func HashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// NewSessionFamilyID returns a random id shared by a session and every session it is rotated into.
+func NewSessionFamilyID() (string, error) {
+	return randomString(16)
+}
+
+func randomString(n int) (string, error) {
+	randomBytes := make([]byte, n)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+// NewOneTimeToken returns a random token for a link sent by email, such as to verify an address or reset a
+// phrase, along with the hash of it that is stored.
+func NewOneTimeToken() (string, []byte, error) {
+	return NewRefreshToken()
+}
+
+// HashOneTimeToken returns the hash a one-time token is stored and looked up by.
+func HashOneTimeToken(token string) []byte {
+	return HashRefreshToken(token)
+}
@@ -0,0 +1,51 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Roles an account can have. New accounts are members.
+const (
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "read-only"
+)
+
+// Roles are all of the roles, in the order they are listed in.
+var Roles = []string{RoleAdmin, RoleMember, RoleReadOnly}
+
+// ParseRole checks role is known.
+func ParseRole(role string) (string, error) {
+	for _, r := range Roles {
+		if r == role {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role %q, expected one of %s", role, strings.Join(Roles, ", "))
+}
+
+// RoleScopes returns the scopes an account with role is allowed. Read-only accounts can only read, and
+// an unknown role isn't allowed anything.
+func RoleScopes(role string) []string {
+	switch role {
+	case RoleAdmin, RoleMember:
+		return Scopes
+	case RoleReadOnly:
+		return []string{ScopeAccountRead, ScopeImagesRead}
+	default:
+		return nil
+	}
+}
+
+// LimitScopes returns the space separated scopes that are also allowed by role.
+func LimitScopes(scope string, role string) string {
+	allowed := RoleScopes(role)
+	var limited []string
+	for _, s := range strings.Fields(scope) {
+		if hasScope(allowed, s) {
+			limited = append(limited, s)
+		}
+	}
+	return strings.Join(limited, " ")
+}
@@ -0,0 +1,46 @@
+package security
+
+import "testing"
+
+func TestAccessClaimsScopes(t *testing.T) {
+	tests := []struct {
+		role    string
+		allowed []string
+		denied  []string
+	}{
+		{role: RoleAdmin, allowed: Scopes},
+		{role: RoleMember, allowed: Scopes},
+		{role: RoleReadOnly, allowed: []string{ScopeAccountRead, ScopeImagesRead}, denied: []string{ScopeAccountWrite, ScopeImagesWrite}},
+		{role: "unknown", denied: Scopes},
+	}
+	for _, test := range tests {
+		t.Run(test.role, func(t *testing.T) {
+			claims, err := NewAccessClaims(1, "valid", test.role)
+			if err != nil {
+				t.Fatalf("expected no error but got: %s", err)
+			}
+			for _, scope := range test.allowed {
+				if !claims.HasScope(scope) {
+					t.Fatalf("expected %s to allow %s", test.role, scope)
+				}
+			}
+			for _, scope := range test.denied {
+				if claims.HasScope(scope) {
+					t.Fatalf("expected %s not to allow %s", test.role, scope)
+				}
+			}
+		})
+	}
+}
+
+func TestLimitScopes(t *testing.T) {
+	if limited := LimitScopes("images:read images:write", RoleReadOnly); limited != "images:read" {
+		t.Fatalf("expected a read-only account's key to be limited to images:read, got %q", limited)
+	}
+	if limited := LimitScopes("images:write", RoleReadOnly); limited != "" {
+		t.Fatalf("expected no scopes to be left, got %q", limited)
+	}
+	if limited := LimitScopes("images:read images:write", RoleMember); limited != "images:read images:write" {
+		t.Fatalf("expected a member's key to keep its scopes, got %q", limited)
+	}
+}
@@ -0,0 +1,120 @@
+package security
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// TOTPIssuer names the service in authenticator apps.
+const TOTPIssuer = "firstly"
+
+// ScopeMFAPending is the only scope of the token returned by signing in to an account with two-factor
+// authentication, which can't be used for anything other than verifying the second factor. It isn't one
+// of Scopes so that API keys can't be created with it.
+const ScopeMFAPending = "mfa:pending"
+
+// RecoveryCodeCount is how many recovery codes are issued when two-factor authentication is enabled.
+const RecoveryCodeCount = 10
+
+// totpQRSize is the width and height of the QR code image in pixels.
+const totpQRSize = 256
+
+// NewTOTPKey returns a random TOTP secret for the account, the otpauth URI authenticator apps are set up
+// with, and the URI as a QR code PNG.
+func NewTOTPKey(username string) (string, string, []byte, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      TOTPIssuer,
+		AccountName: username,
+	})
+	if err != nil {
+		return "", "", nil, err
+	}
+	qr, err := key.Image(totpQRSize, totpQRSize)
+	if err != nil {
+		return "", "", nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, qr); err != nil {
+		return "", "", nil, err
+	}
+	return key.Secret(), key.URL(), buf.Bytes(), nil
+}
+
+// totpPeriod is how many seconds each TOTP code is valid for, the default authenticator apps use.
+const totpPeriod = 30
+
+// ValidateTOTP reports whether code is the current code for secret, allowing for a step of clock drift
+// either way, along with the time step the code is for. Callers record the step so that the code, or
+// one for an earlier step, can't be used again.
+func ValidateTOTP(code string, secret string) (int64, bool) {
+	if secret == "" {
+		return 0, false
+	}
+	code = strings.TrimSpace(code)
+	now := time.Now()
+	for _, skew := range []int64{-1, 0, 1} {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, at, totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// NewRecoveryCodes returns n random one-time recovery codes of the form xxxxx-xxxxx, along with the hashes
+// of them that are stored.
+func NewRecoveryCodes(n int) ([]string, [][]byte, error) {
+	codes := make([]string, 0, n)
+	hashes := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		randomBytes := make([]byte, 6)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes))
+		code := encoded[:5] + "-" + encoded[5:10]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns the hash a recovery code is stored and looked up by. Codes are compared
+// ignoring case, spaces and dashes, so that they can be typed as they are read. Like refresh tokens they
+// are random, so a fast unsalted hash is enough.
+func HashRecoveryCode(code string) []byte {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+	return HashRefreshToken(normalized)
+}
+
+// NewMFAPendingClaims returns the claims of the token proving the account's phrase was given, which is
+// exchanged for an access token once the second factor is verified.
+func NewMFAPendingClaims(accountID int64, username string) (*UsernameClaims, error) {
+	claims, err := NewAccessClaims(accountID, username, "")
+	if err != nil {
+		return nil, err
+	}
+	claims.Scope = ScopeMFAPending
+	return claims, nil
+}
+
+// IsMFAPending reports whether the claims are of a token still waiting for the second factor.
+func (claims *UsernameClaims) IsMFAPending() bool {
+	return claims.Scope == ScopeMFAPending
+}
@@ -0,0 +1,99 @@
+package security
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestTOTP(t *testing.T) {
+	secret, uri, qr, err := NewTOTPKey("bob")
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("expected an otpauth uri but got %q: %s", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Query().Get("secret") != secret || parsed.Query().Get("issuer") != TOTPIssuer {
+		t.Fatalf("expected an otpauth uri with the secret and issuer, got %q", uri)
+	}
+	if !bytes.HasPrefix(qr, []byte("\x89PNG")) {
+		t.Fatalf("expected the qr code to be a png")
+	}
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	step, ok := ValidateTOTP(code, secret)
+	if !ok {
+		t.Fatalf("expected the current code to be valid")
+	}
+	if now := time.Now().Unix() / 30; step < now-1 || step > now+1 {
+		t.Fatalf("expected the step of the current code to be within one of %d, got %d", now, step)
+	}
+	if _, ok := ValidateTOTP(" "+code+" ", secret); !ok {
+		t.Fatalf("expected the current code to be valid with surrounding spaces")
+	}
+	next, _ := totp.GenerateCode(secret, time.Now().Add(30*time.Second))
+	if nextStep, ok := ValidateTOTP(next, secret); next != code && (!ok || nextStep <= step) {
+		t.Fatalf("expected the next code to be valid for a later step than %d, got %d", step, nextStep)
+	}
+	stale, _ := totp.GenerateCode(secret, time.Now().Add(-5*time.Minute))
+	if _, ok := ValidateTOTP(stale, secret); stale != code && ok {
+		t.Fatalf("expected a code from five minutes ago to be invalid")
+	}
+	if _, ok := ValidateTOTP(code, ""); ok {
+		t.Fatalf("expected no code to be valid without a secret")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("expected %d codes and hashes, got %d and %d", RecoveryCodeCount, len(codes), len(hashes))
+	}
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("expected a code of the form xxxxx-xxxxx, got %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("expected the codes to be unique, %q is repeated", code)
+		}
+		seen[code] = true
+		if !bytes.Equal(hashes[i], HashRecoveryCode(code)) {
+			t.Fatalf("expected the hash of %q to be returned with it", code)
+		}
+		typed := strings.ToUpper(strings.Replace(code, "-", " ", 1))
+		if !bytes.Equal(hashes[i], HashRecoveryCode(typed)) {
+			t.Fatalf("expected %q to be accepted as %q", typed, code)
+		}
+	}
+}
+
+func TestMFAPendingClaims(t *testing.T) {
+	claims, err := NewMFAPendingClaims(1, "bob")
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	if !claims.IsMFAPending() || claims.Id == "" || claims.AccountID() != 1 {
+		t.Fatalf("expected pending claims for account 1 with a jti, got %+v", claims)
+	}
+	for _, scope := range Scopes {
+		if claims.HasScope(scope) {
+			t.Fatalf("expected an mfa pending token not to allow %s", scope)
+		}
+	}
+	if _, err := ParseScopes([]string{ScopeMFAPending}); err == nil {
+		t.Fatalf("expected api keys not to be given the %s scope", ScopeMFAPending)
+	}
+}
@@ -0,0 +1,309 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ugorji/go/codec"
+)
+
+// COSE algorithms of the passkeys accepted, ES256 being what phones and security keys use and RS256 what
+// Windows Hello uses.
+const (
+	COSEAlgES256 = -7
+	COSEAlgRS256 = -257
+)
+
+// WebAuthnAlgorithms are the COSE algorithms passkeys can be created with, in order of preference.
+var WebAuthnAlgorithms = []int{COSEAlgES256, COSEAlgRS256}
+
+// client data types of the two ceremonies
+const (
+	WebAuthnCreate = "webauthn.create"
+	WebAuthnGet    = "webauthn.get"
+)
+
+// authenticator data flags
+const (
+	authDataUserPresent  = 0x01
+	authDataUserVerified = 0x04
+	authDataAttested     = 0x40
+)
+
+// COSE key parameters
+const (
+	coseKeyType    = 1
+	coseKeyAlg     = 3
+	coseKeyCurve   = -1
+	coseKeyX       = -2
+	coseKeyY       = -3
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+	coseCurveP256  = 1
+	coseKeyRSAN    = -1
+	coseKeyRSAE    = -2
+)
+
+// ErrInvalidWebAuthn is wrapped by every error from checking a WebAuthn response, all of which are the
+// client's fault.
+var ErrInvalidWebAuthn = errors.New("webauthn response is invalid")
+
+// cborHandle decodes WebAuthn cbor, and encodes it canonically so that the same key is always encoded the
+// same way.
+var cborHandle = newCBORHandle()
+
+func newCBORHandle() *codec.CborHandle {
+	handle := &codec.CborHandle{}
+	handle.Canonical = true
+	return handle
+}
+
+// WebAuthnCredential is a passkey created by a registration ceremony. PublicKey is COSE encoded.
+type WebAuthnCredential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type collectedClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type attestationObject struct {
+	Format   string                 `codec:"fmt"`
+	AttStmt  map[string]interface{} `codec:"attStmt"`
+	AuthData []byte                 `codec:"authData"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+func invalidWebAuthn(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidWebAuthn, fmt.Sprintf(format, a...))
+}
+
+// NewWebAuthnChallenge returns a random challenge for a ceremony.
+func NewWebAuthnChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// EncodeWebAuthnBase64 encodes binary values of WebAuthn JSON as unpadded base64url.
+func EncodeWebAuthnBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeWebAuthnBase64 decodes the base64url values of WebAuthn JSON, with or without padding.
+func DecodeWebAuthnBase64(s string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, invalidWebAuthn("not base64url: %s", err)
+	}
+	return decoded, nil
+}
+
+// ParseClientData checks the client data is of the ceremony typ and was collected by origin, returning
+// the challenge it was signed for, which the caller has to check it issued.
+func ParseClientData(clientDataJSON []byte, typ string, origin string) ([]byte, error) {
+	var clientData collectedClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return nil, invalidWebAuthn("client data isn't json: %s", err)
+	}
+	if clientData.Type != typ {
+		return nil, invalidWebAuthn("client data is of type %q rather than %q", clientData.Type, typ)
+	}
+	if clientData.Origin != origin {
+		return nil, invalidWebAuthn("client data is from origin %q rather than %q", clientData.Origin, origin)
+	}
+	challenge, err := DecodeWebAuthnBase64(clientData.Challenge)
+	if err != nil || len(challenge) == 0 {
+		return nil, invalidWebAuthn("client data has no challenge")
+	}
+	return challenge, nil
+}
+
+// VerifyAttestation checks the attestation object of a registration ceremony for rpID, returning the new
+// credential. Only the "none" attestation format is accepted, since registration asks for no attestation,
+// and the user has to have been verified so that the passkey can stand in for the phrase.
+func VerifyAttestation(rpID string, attestation []byte) (*WebAuthnCredential, error) {
+	var object attestationObject
+	if err := codec.NewDecoderBytes(attestation, cborHandle).Decode(&object); err != nil {
+		return nil, invalidWebAuthn("attestation object isn't cbor: %s", err)
+	}
+	if object.Format != "none" {
+		return nil, invalidWebAuthn("attestation format %q isn't supported", object.Format)
+	}
+	authData, err := parseAuthenticatorData(object.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := authData.verify(rpID); err != nil {
+		return nil, err
+	}
+	if authData.flags&authDataAttested == 0 {
+		return nil, invalidWebAuthn("authenticator data has no credential")
+	}
+	if _, _, err := parseCOSEKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+	return &WebAuthnCredential{
+		ID:        authData.credentialID,
+		PublicKey: authData.publicKey,
+		SignCount: authData.signCount,
+	}, nil
+}
+
+// VerifyAssertion checks the signature of an authentication ceremony for rpID was made with publicKey,
+// returning the authenticator's new sign count. A sign count that doesn't increase means the passkey may
+// have been cloned, unless the authenticator doesn't count at all.
+func VerifyAssertion(rpID string, publicKey []byte, signCount uint32, authenticatorDataBytes []byte, clientDataJSON []byte, signature []byte) (uint32, error) {
+	authData, err := parseAuthenticatorData(authenticatorDataBytes)
+	if err != nil {
+		return 0, err
+	}
+	if err := authData.verify(rpID); err != nil {
+		return 0, err
+	}
+
+	key, alg, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorDataBytes...), clientDataHash[:]...))
+	switch alg {
+	case COSEAlgES256:
+		if !ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], signature) {
+			return 0, invalidWebAuthn("signature doesn't match")
+		}
+	case COSEAlgRS256:
+		if err := rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature); err != nil {
+			return 0, invalidWebAuthn("signature doesn't match")
+		}
+	}
+
+	if (authData.signCount != 0 || signCount != 0) && authData.signCount <= signCount {
+		return 0, invalidWebAuthn("sign count didn't increase, the passkey may have been cloned")
+	}
+	return authData.signCount, nil
+}
+
+// parseAuthenticatorData parses the authenticator data, including the credential when it is attested.
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, invalidWebAuthn("authenticator data is too short")
+	}
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authData.flags&authDataAttested == 0 {
+		return authData, nil
+	}
+
+	// attested credential data is the aaguid, the length of the credential id, the id and the COSE key
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, invalidWebAuthn("attested credential data is too short")
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLength == 0 || len(rest) < idLength {
+		return nil, invalidWebAuthn("credential id is missing")
+	}
+	authData.credentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// the key is followed by any extensions, so its length is however much of the cbor it takes up
+	var key map[int64]interface{}
+	decoder := codec.NewDecoderBytes(rest, cborHandle)
+	if err := decoder.Decode(&key); err != nil {
+		return nil, invalidWebAuthn("credential public key isn't cbor: %s", err)
+	}
+	authData.publicKey = rest[:decoder.NumBytesRead()]
+	return authData, nil
+}
+
+// verify checks the authenticator data is for rpID and that the user was present and verified.
+func (authData *authenticatorData) verify(rpID string) error {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rpIDHash[:]) != 1 {
+		return invalidWebAuthn("authenticator data is for another relying party")
+	}
+	if authData.flags&authDataUserPresent == 0 {
+		return invalidWebAuthn("user wasn't present")
+	}
+	if authData.flags&authDataUserVerified == 0 {
+		return invalidWebAuthn("user wasn't verified")
+	}
+	return nil
+}
+
+// parseCOSEKey returns the public key and algorithm of a COSE encoded ES256 or RS256 key.
+func parseCOSEKey(cose []byte) (crypto.PublicKey, int, error) {
+	var key map[int64]interface{}
+	if err := codec.NewDecoderBytes(cose, cborHandle).Decode(&key); err != nil {
+		return nil, 0, invalidWebAuthn("credential public key isn't cbor: %s", err)
+	}
+	kty, _ := coseInt(key[coseKeyType])
+	alg, _ := coseInt(key[coseKeyAlg])
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == COSEAlgES256:
+		crv, _ := coseInt(key[coseKeyCurve])
+		x, _ := key[coseKeyX].([]byte)
+		y, _ := key[coseKeyY].([]byte)
+		if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, invalidWebAuthn("credential public key isn't a P-256 key")
+		}
+		public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !public.Curve.IsOnCurve(public.X, public.Y) {
+			return nil, 0, invalidWebAuthn("credential public key isn't on the P-256 curve")
+		}
+		return public, COSEAlgES256, nil
+	case kty == coseKeyTypeRSA && alg == COSEAlgRS256:
+		n, _ := key[coseKeyRSAN].([]byte)
+		e, _ := key[coseKeyRSAE].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, invalidWebAuthn("credential public key isn't an RSA key of at least 2048 bits")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, COSEAlgRS256, nil
+	default:
+		return nil, 0, invalidWebAuthn("credential public key algorithm %d isn't supported", alg)
+	}
+}
+
+// coseInt returns a cbor integer, which is decoded as either signed or unsigned.
+func coseInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
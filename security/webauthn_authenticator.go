@@ -0,0 +1,110 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/ugorji/go/codec"
+)
+
+// SoftAuthenticator is a software passkey holding an ES256 key, standing in for a phone or security key in
+// tests. It always reports the user as present and verified.
+type SoftAuthenticator struct {
+	RPID         string
+	Origin       string
+	CredentialID []byte
+	SignCount    uint32
+
+	key *ecdsa.PrivateKey
+}
+
+// NewSoftAuthenticator returns an authenticator with a new credential for rpID, used from origin.
+func NewSoftAuthenticator(rpID string, origin string) (*SoftAuthenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &SoftAuthenticator{RPID: rpID, Origin: origin, CredentialID: id, key: key}, nil
+}
+
+// PublicKey returns the COSE encoding of the credential's public key.
+func (a *SoftAuthenticator) PublicKey() []byte {
+	var cose []byte
+	codec.NewEncoderBytes(&cose, cborHandle).MustEncode(map[int]interface{}{
+		coseKeyType:  coseKeyTypeEC2,
+		coseKeyAlg:   COSEAlgES256,
+		coseKeyCurve: coseCurveP256,
+		coseKeyX:     padTo32(a.key.X.Bytes()),
+		coseKeyY:     padTo32(a.key.Y.Bytes()),
+	})
+	return cose
+}
+
+// Create responds to a registration ceremony for challenge with the client data and a "none" attestation
+// object.
+func (a *SoftAuthenticator) Create(challenge []byte) ([]byte, []byte, error) {
+	clientDataJSON, err := a.clientData(WebAuthnCreate, challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	authData := a.authenticatorData(authDataUserPresent | authDataUserVerified | authDataAttested)
+	authData = append(authData, make([]byte, 16)...)
+	authData = append(authData, byte(len(a.CredentialID)>>8), byte(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, a.PublicKey()...)
+
+	var attestation []byte
+	err = codec.NewEncoderBytes(&attestation, cborHandle).Encode(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	return clientDataJSON, attestation, err
+}
+
+// Get responds to an authentication ceremony for challenge with the client data, authenticator data and
+// signature, counting the signature.
+func (a *SoftAuthenticator) Get(challenge []byte) ([]byte, []byte, []byte, error) {
+	clientDataJSON, err := a.clientData(WebAuthnGet, challenge)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	a.SignCount++
+	authData := a.authenticatorData(authDataUserPresent | authDataUserVerified)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	return clientDataJSON, authData, signature, err
+}
+
+func (a *SoftAuthenticator) clientData(typ string, challenge []byte) ([]byte, error) {
+	return json.Marshal(collectedClientData{
+		Type:      typ,
+		Challenge: EncodeWebAuthnBase64(challenge),
+		Origin:    a.Origin,
+	})
+}
+
+func (a *SoftAuthenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	authData := append(rpIDHash[:], flags)
+	count := make([]byte, 4)
+	binary.BigEndian.PutUint32(count, a.SignCount)
+	return append(authData, count...)
+}
+
+func padTo32(b []byte) []byte {
+	if len(b) >= 32 {
+		return b
+	}
+	return append(make([]byte, 32-len(b)), b...)
+}
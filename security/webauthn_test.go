@@ -0,0 +1,63 @@
+package security
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWebAuthnCeremonies(t *testing.T) {
+	authenticator, err := NewSoftAuthenticator("localhost", "http://localhost:5000")
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+
+	challenge, _ := NewWebAuthnChallenge()
+	clientDataJSON, attestation, err := authenticator.Create(challenge)
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	parsed, err := ParseClientData(clientDataJSON, WebAuthnCreate, "http://localhost:5000")
+	if err != nil || string(parsed) != string(challenge) {
+		t.Fatalf("expected the challenge back from the client data, got %v, %v", parsed, err)
+	}
+	if _, err := ParseClientData(clientDataJSON, WebAuthnGet, "http://localhost:5000"); !errors.Is(err, ErrInvalidWebAuthn) {
+		t.Fatalf("expected client data of a registration to be rejected for authentication, got %v", err)
+	}
+	if _, err := ParseClientData(clientDataJSON, WebAuthnCreate, "https://evil.example"); !errors.Is(err, ErrInvalidWebAuthn) {
+		t.Fatalf("expected client data from another origin to be rejected, got %v", err)
+	}
+
+	if _, err := VerifyAttestation("example.com", attestation); !errors.Is(err, ErrInvalidWebAuthn) {
+		t.Fatalf("expected an attestation for another relying party to be rejected, got %v", err)
+	}
+	credential, err := VerifyAttestation("localhost", attestation)
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	if string(credential.ID) != string(authenticator.CredentialID) || string(credential.PublicKey) != string(authenticator.PublicKey()) {
+		t.Fatalf("expected the authenticator's credential, got %+v", credential)
+	}
+
+	clientDataJSON, authData, signature, err := authenticator.Get(challenge)
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	signCount, err := VerifyAssertion("localhost", credential.PublicKey, credential.SignCount, authData, clientDataJSON, signature)
+	if err != nil {
+		t.Fatalf("expected no error but got: %s", err)
+	}
+	if signCount != 1 {
+		t.Fatalf("expected the sign count to be 1, got %d", signCount)
+	}
+
+	// the same assertion can't be replayed once its sign count has been stored
+	if _, err := VerifyAssertion("localhost", credential.PublicKey, signCount, authData, clientDataJSON, signature); !errors.Is(err, ErrInvalidWebAuthn) {
+		t.Fatalf("expected a sign count that didn't increase to be rejected, got %v", err)
+	}
+
+	other, _ := NewSoftAuthenticator("localhost", "http://localhost:5000")
+	clientDataJSON, authData, signature, _ = other.Get(challenge)
+	if _, err := VerifyAssertion("localhost", credential.PublicKey, 0, authData, clientDataJSON, signature); !errors.Is(err, ErrInvalidWebAuthn) {
+		t.Fatalf("expected a signature by another key to be rejected, got %v", err)
+	}
+}